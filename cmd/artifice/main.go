@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/evedata/services/artifice"
@@ -29,6 +30,7 @@ func main() {
 		os.Getenv("ESI_SECRET_TOKENSTORE"),
 		os.Getenv("ESI_REFRESHKEY"),
 		os.Getenv("ESI_REFRESHCHARID"),
+		nsqhelper.Prod,
 	)
 
 	go artifice.Run()
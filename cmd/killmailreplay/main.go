@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/antihax/evedata/internal/archivestore"
+	"github.com/antihax/evedata/internal/nsqhelper"
+	"github.com/antihax/evedata/internal/redigohelper"
+	"github.com/antihax/evedata/internal/sqlhelper"
+	"github.com/antihax/evedata/services/killmailreplay"
+	"github.com/antihax/evedata/services/nail"
+	"github.com/antihax/evedata/services/tailor"
+
+	nsq "github.com/nsqio/go-nsq"
+)
+
+const dateFormat = "2006-01-02"
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	log.SetPrefix("evedata killmailreplay: ")
+
+	dir := flag.String("dir", "", "directory of killmaildumper json or tailor json.gz files")
+	from := flag.String("from", "", "first day to replay, YYYY-MM-DD")
+	to := flag.String("to", "", "day to stop before, YYYY-MM-DD")
+	ships := flag.String("ships", "", "comma separated victim ship type IDs")
+	systems := flag.String("systems", "", "comma separated solar system IDs")
+	rate := flag.Float64("rate", 0, "killmails per second, 0 is unlimited")
+	topic := flag.String("topic", "killmail", "NSQ topic to publish to")
+	handlers := flag.String("handlers", "", "comma separated services to call in process instead of NSQ: nail, tailor")
+	flag.Parse()
+
+	filter := killmailreplay.Filter{
+		From:         parseDate(*from),
+		To:           parseDate(*to),
+		ShipTypes:    parseIDs(*ships),
+		SolarSystems: parseIDs(*systems),
+	}
+
+	// Without a directory, look up the range in nail's table and read tailor's archive
+	var source killmailreplay.Source
+	if *dir != "" {
+		s, err := killmailreplay.NewDirectorySource(*dir)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		source = s
+	} else {
+		if filter.From.IsZero() || filter.To.IsZero() {
+			log.Fatalln("either -dir or both -from and -to are required")
+		}
+		keys, err := killmailreplay.KillmailsBetween(sqlhelper.NewDatabase(), filter.From, filter.To)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("replaying up to %d killmails from the archive\n", len(keys))
+		source = killmailreplay.NewArchiveSource(archivestore.NewFromEnvironment(), keys)
+	}
+
+	var sink killmailreplay.Sink
+	if *handlers == "" {
+		producer, err := nsqhelper.NewNSQProducer()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer producer.Stop()
+		sink = killmailreplay.NewNSQSink(producer, *topic)
+	} else {
+		// Services are given no lookupds so they only see the replay
+		db := sqlhelper.NewDatabase()
+		h := []nsq.Handler{}
+		for _, name := range strings.Split(*handlers, ",") {
+			switch name {
+			case "nail":
+				n := nail.NewNail(redigohelper.ConnectRedisProdPool(), db, nil)
+				defer n.Close()
+				h = append(h, n.KillmailHandler())
+			case "tailor":
+				t := tailor.NewTailor(db, archivestore.NewFromEnvironment(), nil, os.Getenv("AXIOM_URL"))
+				defer t.Close()
+				h = append(h, t.KillmailHandler())
+			default:
+				log.Fatalf("unknown handler %s\n", name)
+			}
+		}
+		sink = killmailreplay.NewHandlerSink(h...)
+	}
+
+	// Stop early on SIGINT and SIGTERM.
+	stop := make(chan bool)
+	ch := make(chan os.Signal, 2)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		log.Println(<-ch)
+		close(stop)
+	}()
+
+	stats := killmailreplay.NewReplayer(source, sink, filter, *rate).Run(stop)
+	log.Printf("sent %d, skipped %d, failed %d\n", stats.Sent, stats.Skipped, stats.Failed)
+}
+
+func parseDate(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return t
+}
+
+func parseIDs(s string) map[int32]bool {
+	ids := make(map[int32]bool)
+	for _, v := range strings.Split(s, ",") {
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		ids[int32(id)] = true
+	}
+	return ids
+}
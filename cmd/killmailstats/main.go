@@ -8,6 +8,7 @@ import (
 	"runtime"
 	"syscall"
 
+	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/evedata/services/killmailstats"
 )
@@ -18,7 +19,7 @@ func main() {
 	log.Printf("Starting killmailstats Microservice Go: %s\n", runtime.Version())
 
 	// Make a new service and send it into the background.
-	kms := killmailstats.NewKillmailStats(sqlhelper.NewDatabase())
+	kms := killmailstats.NewKillmailStats(sqlhelper.NewDatabase(), nsqhelper.Prod)
 	go kms.Run()
 
 	// Handle SIGINT and SIGTERM.
@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/antihax/evedata/internal/redigohelper"
+	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/evedata/services/mailserver"
 )
 
@@ -19,6 +20,7 @@ func main() {
 	mailserver, err := mailserver.NewMailServer(
 		redigohelper.ConnectRedisProdPool(),
 		redigohelper.ConnectLedisProdPool(),
+		sqlhelper.NewDatabase(),
 		os.Getenv("ESI_CLIENTID_TOKENSTORE"),
 		os.Getenv("ESI_SECRET_TOKENSTORE"),
 	)
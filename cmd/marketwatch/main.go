@@ -6,6 +6,7 @@ import (
 	_ "net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/antihax/evedata/internal/redigohelper"
@@ -27,17 +28,27 @@ func main() {
 		os.Getenv("ESI_SECRET_TOKENSTORE"),
 		db,
 		redigohelper.ConnectLedisProdPool(),
+		os.Getenv("MARKETWATCH_SNAPSHOT"),
+		strings.Split(os.Getenv("MARKETWATCH_ORIGINS"), ","),
 	)
 	go mw.Run()
 
 	// Run metrics
 	http.Handle("/metrics", promhttp.Handler())
 
+	// Live market feed
+	http.HandleFunc("/feed", mw.FeedHandler)
+
 	log.Println("started evedata-marketwatch")
-	go log.Fatalln(http.ListenAndServe(":3000", nil))
+	go func() { log.Fatalln(http.ListenAndServe(":3000", nil)) }()
 
 	// Handle SIGINT and SIGTERM.
 	ch := make(chan os.Signal)
 	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
 	log.Println(<-ch)
+
+	// Save state for a quick restart
+	if err := mw.SaveSnapshot(); err != nil {
+		log.Println(err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 	"time"
 
 	"github.com/antihax/evedata/internal/redigohelper"
@@ -17,6 +18,18 @@ func main() {
 	log.SetPrefix("evedata squirrel: ")
 
 	db := sqlhelper.NewDatabase()
+
+	// Import a static data export instead of polling ESI
+	if len(os.Args) > 2 && os.Args[1] == "sde" {
+		log.Printf("Importing SDE from %s\n", os.Args[2])
+		report, err := squirrel.ImportSDE(db, os.Args[2])
+		if err != nil {
+			log.Fatalln(err)
+		}
+		log.Printf("SDE import complete\n%s", report)
+		return
+	}
+
 	// Run metrics
 	go func() {
 		http.Handle("/metrics", promhttp.Handler())
@@ -8,10 +8,10 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/antihax/evedata/internal/archivestore"
 	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/evedata/services/tailor"
-	backblaze "gopkg.in/kothar/go-backblaze.v0"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -22,19 +22,16 @@ func main() {
 
 	db := sqlhelper.NewDatabase()
 
-	b2, err := backblaze.NewB2(backblaze.Credentials{
-		AccountID:      os.Getenv("B2_ACCOUNTID"),
-		ApplicationKey: os.Getenv("B2_APPLICATION_KEY"),
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
+	// Select the archive backend from ARCHIVE_STORE
+	store := archivestore.NewFromEnvironment()
 
 	// Make a new service and send it into the background.
+	// AXIOM_URL optionally enables the axiom service as a fallback evaluator.
 	tailor := tailor.NewTailor(
 		db,
-		b2,
+		store,
 		nsqhelper.Prod,
+		os.Getenv("AXIOM_URL"),
 	)
 
 	defer tailor.Close()
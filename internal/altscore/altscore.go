@@ -0,0 +1,117 @@
+// Package altscore combines association signals into a confidence that two
+// characters are flown by the same player, with the reasons behind it.
+package altscore
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// HoursPerWeek is the number of buckets in an activity heatmap
+const HoursPerWeek = 168
+
+// MinimumActivity is how many killmails each character needs before their
+// activity overlap is trusted.
+const MinimumActivity = 10
+
+// Signals seen between a pair of characters
+type Signals struct {
+	CoFleet         int     `db:"coFleet" json:"coFleet"`                         // Killmails fought on together
+	CorpHops        int     `db:"corpHops" json:"corpHops"`                       // Same day joins of the same corporation
+	LocatorTargets  int     `db:"locatorTargets" json:"locatorTargets,omitempty"` // Characters both have located
+	ActivityOverlap float64 `db:"activityOverlap" json:"activityOverlap"`         // Similarity of weekly activity, 0 to 1
+	ContactSync     bool    `db:"contactSync" json:"contactSync,omitempty"`       // Contacts synced from the same source
+}
+
+// Public drops the signals built from private character data, locator agent
+// results and contact syncs, leaving those seen in killmails and corporation history.
+func (s Signals) Public() Signals {
+	s.LocatorTargets = 0
+	s.ContactSync = false
+	return s
+}
+
+// Reason explains how much a signal contributed to a score
+type Reason struct {
+	Signal     string  `json:"signal"`
+	Confidence float64 `json:"confidence"`
+	Detail     string  `json:"detail"`
+}
+
+// Score combines signals into a confidence between 0 and 1. Each signal is treated
+// as independent evidence so the score is the chance that any one of them is right.
+func Score(s Signals) (float64, []Reason) {
+	reasons := []Reason{}
+
+	if s.CoFleet > 0 {
+		reasons = append(reasons, Reason{"coFleet", saturate(0.5, float64(s.CoFleet), 5),
+			fmt.Sprintf("Fought together on %d %s", s.CoFleet, plural(s.CoFleet, "killmail", "killmails"))})
+	}
+	if s.CorpHops > 0 {
+		reasons = append(reasons, Reason{"corpHops", saturate(0.7, float64(s.CorpHops), 2),
+			fmt.Sprintf("Joined the same corporation on the same day %d %s", s.CorpHops, plural(s.CorpHops, "time", "times"))})
+	}
+	if s.LocatorTargets > 0 {
+		reasons = append(reasons, Reason{"locatorTargets", saturate(0.4, float64(s.LocatorTargets), 3),
+			fmt.Sprintf("Used locator agents on %d of the same %s", s.LocatorTargets, plural(s.LocatorTargets, "character", "characters"))})
+	}
+	if s.ActivityOverlap > 0.5 {
+		reasons = append(reasons, Reason{"activityOverlap", 0.3 * (s.ActivityOverlap - 0.5) / 0.5,
+			fmt.Sprintf("Active at the same times of the week (%.0f%% overlap)", s.ActivityOverlap*100)})
+	}
+	if s.ContactSync {
+		reasons = append(reasons, Reason{"contactSync", 0.95,
+			"Sync contacts from the same character"})
+	}
+
+	doubt := 1.0
+	for _, r := range reasons {
+		doubt *= 1 - r.Confidence
+	}
+
+	sort.SliceStable(reasons, func(i, j int) bool {
+		return reasons[i].Confidence > reasons[j].Confidence
+	})
+	return 1 - doubt, reasons
+}
+
+// saturate approaches max confidence as count grows past scale
+func saturate(max, count, scale float64) float64 {
+	return max * (1 - math.Exp(-count/scale))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// Activity is a weekly heatmap of killmails by hour of the week
+type Activity [HoursPerWeek]float64
+
+// Total killmails in the heatmap
+func (a *Activity) Total() float64 {
+	total := 0.0
+	for _, v := range a {
+		total += v
+	}
+	return total
+}
+
+// ActivityOverlap returns the cosine similarity of two heatmaps, or zero when
+// either character has too little activity to compare.
+func ActivityOverlap(a, b *Activity) float64 {
+	if a.Total() < MinimumActivity || b.Total() < MinimumActivity {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	return dot / math.Sqrt(normA*normB)
+}
@@ -0,0 +1,59 @@
+package altscore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScore(t *testing.T) {
+	score, reasons := Score(Signals{})
+	assert.Equal(t, 0.0, score)
+	assert.Len(t, reasons, 0)
+
+	weak, _ := Score(Signals{CoFleet: 1})
+	strong, _ := Score(Signals{CoFleet: 20})
+	assert.True(t, weak < strong)
+	assert.True(t, strong < 0.5)
+
+	combined, reasons := Score(Signals{CoFleet: 20, CorpHops: 4, ActivityOverlap: 0.9})
+	assert.True(t, combined > strong)
+	assert.True(t, combined < 1)
+	assert.Len(t, reasons, 3)
+	assert.Equal(t, "corpHops", reasons[0].Signal)
+	assert.Equal(t, "Joined the same corporation on the same day 4 times", reasons[0].Detail)
+
+	synced, reasons := Score(Signals{ContactSync: true, LocatorTargets: 1})
+	assert.True(t, synced > 0.95)
+	assert.Equal(t, "contactSync", reasons[0].Signal)
+	assert.Equal(t, "Used locator agents on 1 of the same character", reasons[1].Detail)
+
+	// Weak overlap alone is not evidence
+	score, reasons = Score(Signals{ActivityOverlap: 0.4})
+	assert.Equal(t, 0.0, score)
+	assert.Len(t, reasons, 0)
+}
+
+func TestPublic(t *testing.T) {
+	s := Signals{CoFleet: 3, LocatorTargets: 2, ContactSync: true}.Public()
+	assert.Equal(t, Signals{CoFleet: 3}, s)
+
+	_, reasons := Score(s)
+	assert.Len(t, reasons, 1)
+	assert.Equal(t, "coFleet", reasons[0].Signal)
+}
+
+func TestActivityOverlap(t *testing.T) {
+	a, b, c := &Activity{}, &Activity{}, &Activity{}
+	a[20], a[21] = 10, 5
+	b[20], b[21] = 20, 10
+	c[100] = 30
+
+	assert.InDelta(t, 1.0, ActivityOverlap(a, b), 0.0001)
+	assert.Equal(t, 0.0, ActivityOverlap(a, c))
+
+	// Too little activity to compare
+	sparse := &Activity{}
+	sparse[20] = 2
+	assert.Equal(t, 0.0, ActivityOverlap(a, sparse))
+}
@@ -18,7 +18,8 @@ func CreateHTTPClientCache(redis *redis.Pool) *http.Client {
 
 	// Attach a basic transport with our chained custom transport.
 	transportCache.Transport = &APICacheTransport{
-		&http.Transport{
+		Budget: NewErrorBudget(redis),
+		Transport: &http.Transport{
 			MaxIdleConns: 200,
 			DialContext: (&net.Dialer{
 				Timeout:   60 * time.Second,
@@ -49,7 +50,8 @@ func CreateLimitedHTTPClientCache(redis *redis.Pool) *http.Client {
 
 	cache := &LimitedTransport{
 		&APICacheTransport{
-			&http.Transport{
+			Budget: NewErrorBudget(redis),
+			Transport: &http.Transport{
 				MaxIdleConns: 200,
 				DialContext: (&net.Dialer{
 					Timeout:   60 * time.Second,
@@ -81,7 +83,7 @@ func CreateHTTPClient() *http.Client {
 
 	// Attach a basic transport with our chained custom transport.
 	t := &APICacheTransport{
-		&http.Transport{
+		Transport: &http.Transport{
 			MaxIdleConns: 200,
 			DialContext: (&net.Dialer{
 				Timeout:   10 * time.Second,
@@ -1,10 +1,12 @@
 package apicache
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/stretchr/testify/assert"
@@ -31,3 +33,43 @@ func TestAPICache(t *testing.T) {
 	assert.Equal(t, 200, res.StatusCode)
 	assert.Equal(t, "1", res.Header.Get("x-from-cache"))
 }
+
+func TestEndpointGroup(t *testing.T) {
+	assert.Equal(t, "characters/assets", endpointGroup("/v4/characters/90000001/assets/"))
+	assert.Equal(t, "characters/assets", endpointGroup("/latest/characters/90000002/assets/"))
+	assert.Equal(t, "universe/structures", endpointGroup("/v2/universe/structures/1000000017013/"))
+}
+
+func TestErrorBudget(t *testing.T) {
+	// Setup a server that always refuses
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	budget := NewErrorBudget(redigohelper.ConnectLedisTestPool())
+	budget.SetLimits(2, 100, time.Minute)
+	client := &http.Client{Transport: &APICacheTransport{Transport: http.DefaultTransport, Budget: budget}}
+
+	get := func(owner string) (*http.Response, error) {
+		req, err := http.NewRequest("GET", "http://"+server.Listener.Addr().String()+"/v1/characters/1/assets/", nil)
+		assert.Nil(t, err)
+		return client.Do(req.WithContext(WithBudgetOwner(context.Background(), owner)))
+	}
+
+	// Use up the budget
+	for i := 0; i < 2; i++ {
+		res, err := get(CharacterOwner(1))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	}
+
+	// This owner is now refused without hitting the server
+	_, err := get(CharacterOwner(1))
+	assert.True(t, IsBudgetExhausted(err))
+
+	// Other owners are unaffected
+	res, err := get(CharacterOwner(2))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+}
@@ -0,0 +1,220 @@
+package apicache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/garyburd/redigo/redis"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// DefaultOwnerBudget is how many errors a single token owner may cause per window.
+	DefaultOwnerBudget = 10
+	// DefaultGroupBudget is how many errors a single endpoint group may cause per window.
+	DefaultGroupBudget = 50
+	// DefaultBudgetWindow matches the ESI error limit window.
+	DefaultBudgetWindow = time.Minute
+)
+
+type budgetContextKey struct{}
+
+// WithBudgetOwner tags a request context with the owner of the token used,
+// so errors caused by the request are charged against that owner's budget.
+func WithBudgetOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, budgetContextKey{}, owner)
+}
+
+// CharacterOwner returns the budget owner for a character token.
+func CharacterOwner(characterID int32) string {
+	return "character:" + strconv.FormatInt(int64(characterID), 10)
+}
+
+// BudgetExhaustedError is returned when a request is refused because its
+// owner or endpoint group has used up its error budget.
+type BudgetExhaustedError struct {
+	Budget string
+	Reset  time.Duration
+}
+
+func (e *BudgetExhaustedError) Error() string {
+	return fmt.Sprintf("error budget exhausted for %s, resets in %s", e.Budget, e.Reset)
+}
+
+// IsBudgetExhausted returns true if the error, or the error wrapped by the
+// http client, is a BudgetExhaustedError.
+func IsBudgetExhausted(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	_, ok := err.(*BudgetExhaustedError)
+	return ok
+}
+
+// ErrorBudget tracks ESI errors per token owner and per endpoint group in redis
+// so the budgets are shared between all services.
+type ErrorBudget struct {
+	redis       *redis.Pool
+	ownerBudget int
+	groupBudget int
+	window      time.Duration
+}
+
+// NewErrorBudget creates an error budget using the default limits.
+func NewErrorBudget(r *redis.Pool) *ErrorBudget {
+	return &ErrorBudget{
+		redis:       r,
+		ownerBudget: DefaultOwnerBudget,
+		groupBudget: DefaultGroupBudget,
+		window:      DefaultBudgetWindow,
+	}
+}
+
+// SetLimits changes the number of errors allowed per owner and per group each window.
+func (b *ErrorBudget) SetLimits(owner, group int, window time.Duration) {
+	b.ownerBudget = owner
+	b.groupBudget = group
+	b.window = window
+}
+
+// budgetKeys returns the owner and group keys for a request. Owner is empty
+// for requests without a tagged token owner.
+func budgetKeys(req *http.Request) (owner string, group string) {
+	if o, ok := req.Context().Value(budgetContextKey{}).(string); ok {
+		owner = o
+	}
+	return owner, endpointGroup(req.URL.Path)
+}
+
+// endpointGroup strips versions and IDs from a path so /v4/characters/1/assets/
+// and /v4/characters/2/assets/ share the group characters/assets.
+func endpointGroup(path string) string {
+	parts := []string{}
+	for _, p := range strings.Split(path, "/") {
+		if p == "" || p == "latest" || p == "dev" || p == "legacy" {
+			continue
+		}
+		if _, err := strconv.ParseInt(p, 10, 64); err == nil {
+			continue
+		}
+		if len(p) > 1 && p[0] == 'v' {
+			if _, err := strconv.Atoi(p[1:]); err == nil {
+				continue
+			}
+		}
+		parts = append(parts, p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Check refuses the request if its owner or endpoint group has no budget remaining.
+func (b *ErrorBudget) Check(req *http.Request) error {
+	owner, group := budgetKeys(req)
+
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	if owner != "" {
+		if err := b.check(conn, "owner", owner, b.ownerBudget); err != nil {
+			return err
+		}
+	}
+	return b.check(conn, "group", group, b.groupBudget)
+}
+
+func (b *ErrorBudget) check(conn redis.Conn, kind, name string, budget int) error {
+	key := budgetKey(kind, name)
+	used, err := redis.Int(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		setBudgetRemaining(kind, name, budget)
+		return nil
+	} else if err != nil {
+		// Do not block requests when redis is unavailable.
+		log.Println(err)
+		return nil
+	}
+
+	if used >= budget {
+		ttl, _ := redis.Int(conn.Do("TTL", key))
+		metricBudgetRefused.With(prometheus.Labels{"kind": kind}).Inc()
+		return &BudgetExhaustedError{Budget: kind + " " + name, Reset: time.Duration(ttl) * time.Second}
+	}
+	return nil
+}
+
+// Charge records an error response against the owner and endpoint group of the request.
+func (b *ErrorBudget) Charge(req *http.Request) {
+	owner, group := budgetKeys(req)
+
+	conn := b.redis.Get()
+	defer conn.Close()
+
+	if owner != "" {
+		b.charge(conn, "owner", owner, b.ownerBudget)
+	}
+	b.charge(conn, "group", group, b.groupBudget)
+}
+
+func (b *ErrorBudget) charge(conn redis.Conn, kind, name string, budget int) {
+	key := budgetKey(kind, name)
+
+	used, err := redis.Int(conn.Do("INCR", key))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	// Start the window on the first error.
+	if used == 1 {
+		if _, err := conn.Do("EXPIRE", key, int(b.window/time.Second)); err != nil {
+			log.Println(err)
+		}
+	}
+
+	setBudgetRemaining(kind, name, budget-used)
+}
+
+// setBudgetRemaining reports endpoint group budgets. Owners are not reported as
+// there is a budget for every character.
+func setBudgetRemaining(kind, name string, remaining int) {
+	if kind == "group" {
+		metricBudgetRemaining.With(prometheus.Labels{"group": name}).Set(float64(remaining))
+	}
+}
+
+func budgetKey(kind, name string) string {
+	return "evedata_esi_budget:" + kind + ":" + name
+}
+
+var (
+	metricBudgetRemaining = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "evedata",
+		Subsystem: "api",
+		Name:      "budgetRemaining",
+		Help:      "ESI errors remaining for each endpoint group budget.",
+	},
+		[]string{"group"},
+	)
+
+	metricBudgetRefused = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "evedata",
+		Subsystem: "api",
+		Name:      "budgetRefused",
+		Help:      "Count of requests refused for an exhausted error budget.",
+	},
+		[]string{"kind"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		metricBudgetRemaining,
+		metricBudgetRefused,
+	)
+}
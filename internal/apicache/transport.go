@@ -11,9 +11,16 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+// lowErrorLimit is the remaining ESI error limit below which all requests back off.
+const lowErrorLimit = 20
+
 // APICacheTransport to chain into the HTTPClient to gather statistics.
 type APICacheTransport struct {
 	Transport http.RoundTripper
+
+	// Budget refuses requests for owners and endpoint groups causing too many errors.
+	// Nil disables budgeting.
+	Budget *ErrorBudget
 }
 
 // RoundTrip wraps http.DefaultTransport.RoundTrip to provide stats and handle error rates.
@@ -25,6 +32,13 @@ func (t *APICacheTransport) RoundTrip(req *http.Request) (*http.Response, error)
 		// Tickup retry counter
 		tries++
 
+		// Refuse early if this owner or endpoint has used its error budget
+		if t.Budget != nil {
+			if err := t.Budget.Check(req); err != nil {
+				return nil, err
+			}
+		}
+
 		// Time our response
 		start := time.Now()
 
@@ -45,6 +59,9 @@ func (t *APICacheTransport) RoundTrip(req *http.Request) (*http.Response, error)
 			if res.StatusCode >= 400 {
 				metricAPIErrors.Inc()
 				log.Printf("St: %d Res: %s Tok: %s - %s\n", res.StatusCode, resetS, tokensS, req.URL)
+				if t.Budget != nil {
+					t.Budget.Charge(req)
+				}
 			}
 
 			// If we cannot decode this is likely from another source.
@@ -62,11 +79,11 @@ func (t *APICacheTransport) RoundTrip(req *http.Request) (*http.Response, error)
 			if res.StatusCode == 420 { // Something went wrong
 				duration := reset * ((1 + rand.Float64()) * 5)
 				time.Sleep(time.Duration(duration) * time.Second)
-			} else if esiRateLimiter { // Sleep based on error rate.
+			} else if esiRateLimiter && tokens < lowErrorLimit { // Only slow everyone when the global limit is nearly gone.
 				percentRemain := 1 - (tokens / 100)
 				duration := reset * percentRemain * (1 + rand.Float64())
 				time.Sleep(time.Second * time.Duration(duration))
-			} else if !esiRateLimiter { // Not an ESI error
+			} else if !esiRateLimiter && res.StatusCode >= 400 { // Not an ESI error
 				time.Sleep(time.Second * time.Duration(tries))
 			}
 
@@ -0,0 +1,66 @@
+// Package archivestore provides storage backends for killmail archives.
+package archivestore
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+)
+
+// DefaultBucket the killmail archives are stored in.
+const DefaultBucket = "evedata-killmails"
+
+// ArchiveStore stores and retrieves archive objects by key.
+// Content is stored as given so the encoding is the same across backends.
+type ArchiveStore interface {
+	// Put stores size bytes from content under key, replacing any existing object.
+	Put(key string, content io.Reader, size int64) error
+
+	// Get returns the content stored under key. The caller must close it.
+	Get(key string) (io.ReadCloser, error)
+}
+
+// ErrNotFound is returned when a key does not exist in the store.
+var ErrNotFound = errors.New("archive key not found")
+
+// NewFromEnvironment creates the archive store selected by ARCHIVE_STORE.
+//
+//	b2    (default) Backblaze B2 using B2_ACCOUNTID and B2_APPLICATION_KEY
+//	s3    S3 compatible storage using S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY and S3_SECURE
+//	local a directory named by ARCHIVE_PATH
+//
+// ARCHIVE_BUCKET overrides the bucket name for b2 and s3.
+func NewFromEnvironment() ArchiveStore {
+	bucket := os.Getenv("ARCHIVE_BUCKET")
+	if bucket == "" {
+		bucket = DefaultBucket
+	}
+
+	var (
+		store ArchiveStore
+		err   error
+	)
+
+	switch os.Getenv("ARCHIVE_STORE") {
+	case "", "b2":
+		store, err = NewB2Store(os.Getenv("B2_ACCOUNTID"), os.Getenv("B2_APPLICATION_KEY"), bucket)
+	case "s3":
+		store, err = NewS3Store(
+			os.Getenv("S3_ENDPOINT"),
+			os.Getenv("S3_ACCESS_KEY"),
+			os.Getenv("S3_SECRET_KEY"),
+			os.Getenv("S3_SECURE") != "false",
+			bucket,
+		)
+	case "local":
+		store, err = NewLocalStore(os.Getenv("ARCHIVE_PATH"))
+	default:
+		log.Fatalf("unknown ARCHIVE_STORE %s\n", os.Getenv("ARCHIVE_STORE"))
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return store
+}
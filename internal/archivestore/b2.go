@@ -0,0 +1,52 @@
+package archivestore
+
+import (
+	"io"
+
+	backblaze "gopkg.in/kothar/go-backblaze.v0"
+)
+
+// B2Store stores archives in a Backblaze B2 bucket.
+type B2Store struct {
+	b2     *backblaze.B2
+	bucket *backblaze.Bucket
+}
+
+// NewB2Store connects to B2 and opens the bucket.
+func NewB2Store(accountID, applicationKey, bucket string) (*B2Store, error) {
+	b2, err := backblaze.NewB2(backblaze.Credentials{
+		AccountID:      accountID,
+		ApplicationKey: applicationKey,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	b2.MaxIdleUploads = 100
+
+	b, err := b2.Bucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	return &B2Store{b2: b2, bucket: b}, nil
+}
+
+// Put stores the content in the bucket.
+func (s *B2Store) Put(key string, content io.Reader, size int64) error {
+	metadata := make(map[string]string)
+	_, err := s.bucket.UploadFile(key, metadata, content)
+	return err
+}
+
+// Get downloads the content from the bucket.
+func (s *B2Store) Get(key string) (io.ReadCloser, error) {
+	_, r, err := s.bucket.DownloadFileByName(key)
+	if err != nil {
+		if b2err, ok := err.(*backblaze.B2Error); ok && b2err.Status == 404 {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return r, nil
+}
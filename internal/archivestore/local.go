@@ -0,0 +1,72 @@
+package archivestore
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore stores archives as files in a directory.
+type LocalStore struct {
+	path string
+}
+
+// NewLocalStore uses the directory at path, creating it if needed.
+func NewLocalStore(path string) (*LocalStore, error) {
+	if path == "" {
+		return nil, errors.New("archive path is required")
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStore{path: path}, nil
+}
+
+func (s *LocalStore) file(key string) (string, error) {
+	if key == "" || strings.Contains(key, "..") || filepath.IsAbs(key) {
+		return "", errors.New("invalid archive key " + key)
+	}
+	return filepath.Join(s.path, filepath.FromSlash(key)), nil
+}
+
+// Put writes the content to a file, replacing it atomically.
+func (s *LocalStore) Put(key string, content io.Reader, size int64) error {
+	name, err := s.file(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(name), ".archive")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
+// Get opens the file for the key.
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	name, err := s.file(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
@@ -0,0 +1,36 @@
+package archivestore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archivestore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store, err := NewLocalStore(dir)
+	assert.Nil(t, err)
+
+	content := []byte{0x1f, 0x8b, 0x08, 0x00}
+	err = store.Put("1234.json.gz", bytes.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+
+	r, err := store.Get("1234.json.gz")
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, content, b)
+
+	_, err = store.Get("4321.json.gz")
+	assert.Equal(t, ErrNotFound, err)
+
+	err = store.Put("../escape.json.gz", bytes.NewReader(content), int64(len(content)))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,58 @@
+package archivestore
+
+import (
+	"errors"
+	"io"
+
+	minio "github.com/minio/minio-go"
+)
+
+// S3Store stores archives in an S3 compatible bucket such as MinIO.
+// It uses the minio-go v6 API, pinned to v6.0.14 in .travis.yml.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3Store connects to the endpoint and creates the bucket if it does not exist.
+func NewS3Store(endpoint, accessKey, secretKey string, secure bool, bucket string) (*S3Store, error) {
+	if endpoint == "" {
+		return nil, errors.New("s3 endpoint is required")
+	}
+	client, err := minio.New(endpoint, accessKey, secretKey, secure)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err := client.MakeBucket(bucket, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return &S3Store{client: client, bucket: bucket}, nil
+}
+
+// Put stores the content in the bucket.
+func (s *S3Store) Put(key string, content io.Reader, size int64) error {
+	_, err := s.client.PutObject(s.bucket, key, content, size, minio.PutObjectOptions{
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+// Get downloads the content from the bucket.
+func (s *S3Store) Get(key string) (io.ReadCloser, error) {
+	// Stat first as GetObject does not fail until read.
+	if _, err := s.client.StatObject(s.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.client.GetObject(s.bucket, key, minio.GetObjectOptions{})
+}
@@ -0,0 +1,153 @@
+package archivestore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 implements enough of the S3 API for the store.
+type fakeS3 struct {
+	sync.Mutex
+	buckets map[string]bool
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	parts := strings.SplitN(path, "/", 2)
+	bucket := parts[0]
+
+	if len(parts) == 1 || parts[1] == "" {
+		switch {
+		case r.Method == "GET" && r.URL.Query()["location"] != nil:
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`))
+		case r.Method == "HEAD":
+			if !f.buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == "PUT":
+			f.buckets[bucket] = true
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+
+	content, ok := f.objects[path]
+	switch r.Method {
+	case "PUT":
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			b = decodeChunks(b)
+		}
+		f.objects[path] = b
+		w.Header().Set("ETag", `"fake"`)
+	case "HEAD", "GET":
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == "GET" {
+				w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>missing</Message></Error>`))
+			}
+			return
+		}
+		w.Header().Set("ETag", `"fake"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == "GET" {
+			w.Write(content)
+		}
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// decodeChunks strips the signatures from a signed streaming upload
+func decodeChunks(b []byte) []byte {
+	content := []byte{}
+	for len(b) > 0 {
+		header := bytes.SplitN(b, []byte("\r\n"), 2)
+		if len(header) < 2 {
+			break
+		}
+		size, err := strconv.ParseInt(string(bytes.SplitN(header[0], []byte(";"), 2)[0]), 16, 64)
+		if err != nil || size == 0 || int64(len(header[1])) < size {
+			break
+		}
+		content = append(content, header[1][:size]...)
+		b = bytes.TrimPrefix(header[1][size:], []byte("\r\n"))
+	}
+	return content
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{buckets: make(map[string]bool), objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	endpoint := strings.TrimPrefix(server.URL, "http://")
+
+	// The bucket is created when missing
+	store, err := NewS3Store(endpoint, "access", "secret", false, DefaultBucket)
+	assert.Nil(t, err)
+	assert.True(t, fake.buckets[DefaultBucket])
+
+	content := []byte{0x1f, 0x8b, 0x08, 0x00}
+	err = store.Put("1234.json.gz", bytes.NewReader(content), int64(len(content)))
+	assert.Nil(t, err)
+	assert.Equal(t, content, fake.objects[DefaultBucket+"/1234.json.gz"])
+
+	r, err := store.Get("1234.json.gz")
+	assert.Nil(t, err)
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	assert.Nil(t, err)
+	assert.Equal(t, content, b)
+
+	_, err = store.Get("4321.json.gz")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = NewS3Store("", "access", "secret", false, DefaultBucket)
+	assert.NotNil(t, err)
+}
+
+func TestNewFromEnvironment(t *testing.T) {
+	fake := &fakeS3{buckets: make(map[string]bool), objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	os.Setenv("ARCHIVE_STORE", "s3")
+	os.Setenv("ARCHIVE_BUCKET", "evedata-test")
+	os.Setenv("S3_ENDPOINT", strings.TrimPrefix(server.URL, "http://"))
+	os.Setenv("S3_SECURE", "false")
+	defer func() {
+		for _, e := range []string{"ARCHIVE_STORE", "ARCHIVE_BUCKET", "ARCHIVE_PATH", "S3_ENDPOINT", "S3_SECURE"} {
+			os.Unsetenv(e)
+		}
+	}()
+
+	_, ok := NewFromEnvironment().(*S3Store)
+	assert.True(t, ok)
+	assert.True(t, fake.buckets["evedata-test"])
+
+	dir, err := ioutil.TempDir("", "archivestore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("ARCHIVE_STORE", "local")
+	os.Setenv("ARCHIVE_PATH", dir)
+	_, ok = NewFromEnvironment().(*LocalStore)
+	assert.True(t, ok)
+}
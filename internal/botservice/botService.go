@@ -10,6 +10,7 @@ type Name struct {
 // Integration provides access to an authenticated service
 type Integration interface {
 	SendMessageToChannel(channel, message string) error
+	SendRichMessageToChannel(channel string, message *Message) error
 	SendMessageToUser(user, message string) error
 	KickUser(user, message string) error
 	GetName() (string, error)
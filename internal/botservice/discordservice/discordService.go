@@ -1,6 +1,8 @@
 package discordservice
 
 import (
+	"time"
+
 	"github.com/antihax/evedata/internal/botservice"
 	"github.com/bwmarrin/discordgo"
 )
@@ -23,6 +25,47 @@ func (c DiscordService) SendMessageToChannel(channel, message string) error {
 	return err
 }
 
+// SendRichMessageToChannel sends a message as an embed to a discord channel ID
+func (c DiscordService) SendRichMessageToChannel(channel string, message *botservice.Message) error {
+	_, err := c.session.ChannelMessageSendComplex(channel, &discordgo.MessageSend{
+		Content: message.Content,
+		Embed:   embed(message),
+	})
+	return err
+}
+
+// embed converts a message to a discord embed
+func embed(message *botservice.Message) *discordgo.MessageEmbed {
+	e := &discordgo.MessageEmbed{
+		Title:       message.Title,
+		URL:         message.URL,
+		Description: message.Description,
+		Color:       message.Color,
+	}
+
+	if thumbnail := message.ThumbnailURL(); thumbnail != "" {
+		e.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: thumbnail}
+	}
+
+	for _, f := range message.Fields {
+		e.Fields = append(e.Fields, &discordgo.MessageEmbedField{
+			Name:   f.Name,
+			Value:  f.Value,
+			Inline: f.Inline,
+		})
+	}
+
+	if message.Footer != "" {
+		e.Footer = &discordgo.MessageEmbedFooter{Text: message.Footer}
+	}
+
+	if !message.Timestamp.IsZero() {
+		e.Timestamp = message.Timestamp.UTC().Format(time.RFC3339)
+	}
+
+	return e
+}
+
 // SendMessageToUser sends a message to a discord user ID
 func (c DiscordService) SendMessageToUser(user, message string) error {
 	ch, err := c.session.UserChannelCreate(user)
@@ -0,0 +1,147 @@
+package botservice
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Colours used for messages
+const (
+	ColorInfo    = 0x3498DB
+	ColorWarning = 0xF39C12
+	ColorDanger  = 0xE74C3C
+)
+
+// Message carries structured content which each integration renders natively,
+// or degrades to text with Text() when it has no rich formatting.
+type Message struct {
+	// Content is sent as plain text alongside the message, such as mentions
+	Content string
+
+	Title string
+	URL   string
+
+	// Description may contain [text](url) links
+	Description string
+
+	Color           int
+	ThumbnailTypeID int32
+	Fields          []Field
+	Footer          string
+	Timestamp       time.Time
+}
+
+// Field is a name and value pair shown in a message
+type Field struct {
+	Name   string
+	Value  string
+	Inline bool
+}
+
+// AddField appends a field to the message
+func (m *Message) AddField(name, value string, inline bool) *Message {
+	m.Fields = append(m.Fields, Field{Name: name, Value: value, Inline: inline})
+	return m
+}
+
+// ThumbnailURL returns the image for the thumbnail type ID, or empty if there is none
+func (m *Message) ThumbnailURL() string {
+	if m.ThumbnailTypeID == 0 {
+		return ""
+	}
+	return TypeImageURL(m.ThumbnailTypeID, 64)
+}
+
+var markdownLink = regexp.MustCompile(`\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// FormatLinks rewrites [text](url) links using a template where $1 is the text and $2 the url
+func FormatLinks(s, template string) string {
+	return markdownLink.ReplaceAllString(s, template)
+}
+
+// StripLinks replaces [text](url) links with text (url)
+func StripLinks(s string) string {
+	return FormatLinks(s, "$1 ($2)")
+}
+
+// Text renders the message as plain text for integrations without rich messages
+func (m *Message) Text() string {
+	lines := []string{}
+	if m.Content != "" {
+		lines = append(lines, m.Content)
+	}
+
+	if m.Title != "" {
+		if m.URL != "" {
+			lines = append(lines, m.Title+" - "+m.URL)
+		} else {
+			lines = append(lines, m.Title)
+		}
+	}
+
+	if m.Description != "" {
+		lines = append(lines, StripLinks(m.Description))
+	}
+
+	for _, f := range m.Fields {
+		lines = append(lines, f.Name+": "+StripLinks(f.Value))
+	}
+
+	footer := m.Footer
+	if !m.Timestamp.IsZero() {
+		footer = strings.TrimSpace(footer + " " + m.Timestamp.UTC().Format("2006-01-02 15:04 MST"))
+	}
+	if footer != "" {
+		lines = append(lines, footer)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// TypeImageURL returns the image server URL for a type icon
+func TypeImageURL(typeID int32, size int) string {
+	return fmt.Sprintf("https://imageserver.eveonline.com/Type/%d_%d.png", typeID, size)
+}
+
+// securityColors are the in game colours for each security status from 0.0 to 1.0
+var securityColors = []int{
+	0xF00000, 0xD73000, 0xF04800, 0xF06000, 0xD77700, 0xEFEF00,
+	0x8FEF2F, 0x00F000, 0x00EF47, 0x48F0C0, 0x2FEFEF,
+}
+
+// RoundSecurity rounds a security status the way it is shown in game
+func RoundSecurity(security float64) float64 {
+	if security > 0 && security < 0.05 {
+		return 0.1
+	}
+	return math.Round(security*10) / 10
+}
+
+// SecurityColor returns the in game colour for a security status
+func SecurityColor(security float64) int {
+	i := int(math.Round(RoundSecurity(security) * 10))
+	if i < 0 {
+		i = 0
+	} else if i > 10 {
+		i = 10
+	}
+	return securityColors[i]
+}
+
+// FormatISK abbreviates an ISK value such as 1.25b
+func FormatISK(value float64) string {
+	switch {
+	case value >= 1e12:
+		return fmt.Sprintf("%.2ft ISK", value/1e12)
+	case value >= 1e9:
+		return fmt.Sprintf("%.2fb ISK", value/1e9)
+	case value >= 1e6:
+		return fmt.Sprintf("%.2fm ISK", value/1e6)
+	case value >= 1e3:
+		return fmt.Sprintf("%.2fk ISK", value/1e3)
+	}
+	return fmt.Sprintf("%.0f ISK", value)
+}
@@ -0,0 +1,46 @@
+package botservice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageText(t *testing.T) {
+	m := &Message{
+		Content:     "@everyone",
+		Title:       "Rifter destroyed in Jita",
+		URL:         "https://www.evedata.org/killmail?id=1",
+		Description: "[Someone](https://www.evedata.org/character?id=2) lost their Rifter",
+		Timestamp:   time.Date(2018, 5, 1, 12, 30, 0, 0, time.UTC),
+	}
+	m.AddField("Value", FormatISK(1250000), true).AddField("System", "Jita 0.9", true)
+
+	assert.Equal(t, "@everyone\n"+
+		"Rifter destroyed in Jita - https://www.evedata.org/killmail?id=1\n"+
+		"Someone (https://www.evedata.org/character?id=2) lost their Rifter\n"+
+		"Value: 1.25m ISK\n"+
+		"System: Jita 0.9\n"+
+		"2018-05-01 12:30 UTC", m.Text())
+
+	assert.Equal(t, "", (&Message{}).ThumbnailURL())
+	assert.Equal(t, "https://imageserver.eveonline.com/Type/587_64.png", (&Message{ThumbnailTypeID: 587}).ThumbnailURL())
+}
+
+func TestSecurityColor(t *testing.T) {
+	assert.Equal(t, 0x2FEFEF, SecurityColor(1.0))
+	assert.Equal(t, 0x48F0C0, SecurityColor(0.946))
+	assert.Equal(t, 0xEFEF00, SecurityColor(0.45))
+	assert.Equal(t, 0xD73000, SecurityColor(0.01))
+	assert.Equal(t, 0xF00000, SecurityColor(0.0))
+	assert.Equal(t, 0xF00000, SecurityColor(-0.8))
+	assert.Equal(t, 0.1, RoundSecurity(0.02))
+}
+
+func TestFormatISK(t *testing.T) {
+	assert.Equal(t, "950 ISK", FormatISK(950))
+	assert.Equal(t, "12.50k ISK", FormatISK(12500))
+	assert.Equal(t, "2.50b ISK", FormatISK(2.5e9))
+	assert.Equal(t, "1.00t ISK", FormatISK(1e12))
+}
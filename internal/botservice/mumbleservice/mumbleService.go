@@ -0,0 +1,414 @@
+package mumbleservice
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/antihax/evedata/internal/botservice"
+	"github.com/golang/protobuf/proto"
+	"layeh.com/gumble/gumble"
+	"layeh.com/gumble/gumble/MumbleProto"
+	"layeh.com/gumble/gumbleutil"
+)
+
+// How long to wait for the server to answer ACL and user list requests
+const requestTimeout = time.Second * 10
+
+// MumbleService provides access to a murmur server
+// Groups on the root channel are used as roles and registered users as members.
+// The bot must log in as SuperUser or a user with write ACL on the root channel.
+type MumbleService struct {
+	client *gumble.Client
+
+	// Only one ACL or user list request may be waiting at a time
+	requestLock sync.Mutex
+	acl         chan *gumble.ACL
+	userList    chan gumble.RegisteredUsers
+
+	// Held while a group is changed so concurrent updates do not overwrite each other
+	updateLock sync.Mutex
+}
+
+// NewMumbleService connects to a murmur server at address
+func NewMumbleService(address, user, pass string) (*MumbleService, error) {
+	s := &MumbleService{
+		acl:      make(chan *gumble.ACL, 1),
+		userList: make(chan gumble.RegisteredUsers, 1),
+	}
+
+	config := gumble.NewConfig()
+	config.Username = user
+	config.Password = pass
+	config.Attach(gumbleutil.Listener{
+		ACL: func(e *gumble.ACLEvent) {
+			if e.ACL.Channel.ID == 0 {
+				select {
+				case s.acl <- e.ACL:
+				default:
+				}
+			}
+		},
+		UserList: func(e *gumble.UserListEvent) {
+			select {
+			case s.userList <- e.UserList:
+			default:
+			}
+		},
+	})
+
+	// Most murmur servers use self signed certificates
+	client, err := gumble.DialWithDialer(&net.Dialer{Timeout: requestTimeout}, address, config, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		return nil, err
+	}
+	s.client = client
+
+	return s, nil
+}
+
+// Connected returns false once the server has dropped the connection
+func (c *MumbleService) Connected() bool {
+	return c.client.State() != gumble.StateDisconnected
+}
+
+// Close disconnects from the server
+func (c *MumbleService) Close() error {
+	return c.client.Disconnect()
+}
+
+// SendRichMessageToChannel sends a message to a channel ID as text
+func (c *MumbleService) SendRichMessageToChannel(channel string, message *botservice.Message) error {
+	return c.SendMessageToChannel(channel, message.Text())
+}
+
+// SendMessageToChannel sends a message to a channel ID
+func (c *MumbleService) SendMessageToChannel(channel, message string) error {
+	id, err := strconv.ParseUint(channel, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	err = errors.New("unknown channel " + channel)
+	c.client.Do(func() {
+		if ch := c.client.Channels[uint32(id)]; ch != nil {
+			ch.Send(message, false)
+			err = nil
+		}
+	})
+	return err
+}
+
+// SendMessageToUser sends a message to a registered user ID if they are connected
+func (c *MumbleService) SendMessageToUser(user, message string) error {
+	return c.withUser(user, func(u *gumble.User) {
+		u.Send(message)
+	})
+}
+
+// KickUser kicks a registered user ID from the server if they are connected
+func (c *MumbleService) KickUser(user, message string) error {
+	return c.withUser(user, func(u *gumble.User) {
+		u.Kick(message)
+	})
+}
+
+// GetName gets the server name from the root channel
+func (c *MumbleService) GetName() (string, error) {
+	name := ""
+	c.client.Do(func() {
+		if root := c.client.Channels[0]; root != nil {
+			name = root.Name
+		}
+	})
+	if name == "" {
+		return "", errors.New("root channel is unavailable")
+	}
+	return name, nil
+}
+
+// GetChannels gets all channels on the server
+func (c *MumbleService) GetChannels() ([]botservice.Name, error) {
+	channels := []botservice.Name{}
+	c.client.Do(func() {
+		for _, ch := range c.client.Channels {
+			channels = append(channels, botservice.Name{ID: strconv.FormatUint(uint64(ch.ID), 10), Name: ch.Name})
+		}
+	})
+	return channels, nil
+}
+
+// GetRoles gets all the groups on the root channel that can be assigned
+func (c *MumbleService) GetRoles() ([]botservice.Name, error) {
+	acl, err := c.getACL()
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []botservice.Name{}
+	for _, group := range acl.Groups {
+		if isAssignable(group.Name) {
+			roles = append(roles, botservice.Name{ID: group.Name, Name: group.Name})
+		}
+	}
+	return roles, nil
+}
+
+// GetMembers gets all registered users with the root channel groups they belong to
+func (c *MumbleService) GetMembers() ([]botservice.Name, error) {
+	users, err := c.getUserList()
+	if err != nil {
+		return nil, err
+	}
+
+	acl, err := c.getACL()
+	if err != nil {
+		return nil, err
+	}
+
+	members := []botservice.Name{}
+	for _, u := range users {
+		// Skip SuperUser
+		if u.UserID == 0 {
+			continue
+		}
+		roles := []string{}
+		for _, group := range acl.Groups {
+			if _, ok := group.UsersAdd[u.UserID]; ok && isAssignable(group.Name) {
+				roles = append(roles, group.Name)
+			}
+		}
+		members = append(members, botservice.Name{ID: strconv.FormatUint(uint64(u.UserID), 10), Name: u.Name, Roles: roles})
+	}
+	return members, nil
+}
+
+// RemoveRole removes a registered user ID from a root channel group
+func (c *MumbleService) RemoveRole(user, role string) error {
+	return c.updateGroup(user, role, false)
+}
+
+// AddRole adds a registered user ID to a root channel group
+func (c *MumbleService) AddRole(user, role string) error {
+	return c.updateGroup(user, role, true)
+}
+
+// AddUser verifies the user ID is registered on the server.
+// Users register themselves with their client certificate, the bot cannot do it for them.
+func (c *MumbleService) AddUser(auth, user, name string) error {
+	id, err := strconv.ParseUint(user, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	users, err := c.getUserList()
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if u.UserID == uint32(id) {
+			return nil
+		}
+	}
+	return errors.New("user is not registered on the server")
+}
+
+// GetUserByHash finds the registered user ID connected with a certificate hash.
+// Members link by giving the hash shown in their client, which only their certificate can present.
+func (c *MumbleService) GetUserByHash(hash string) (string, error) {
+	user := ""
+	c.client.Do(func() {
+		user = userByHash(c.client.Users, hash)
+	})
+	if user == "" {
+		return "", errors.New("no registered user is connected with that certificate, register on the server first")
+	}
+	return user, nil
+}
+
+// userByHash finds the registered user ID among connected users with a certificate hash
+func userByHash(users gumble.Users, hash string) string {
+	hash = strings.TrimSpace(hash)
+	if hash == "" {
+		return ""
+	}
+	for _, u := range users {
+		if u.IsRegistered() && strings.EqualFold(u.Hash, hash) {
+			return strconv.FormatUint(uint64(u.UserID), 10)
+		}
+	}
+	return ""
+}
+
+// withUser runs f against a connected registered user
+func (c *MumbleService) withUser(user string, f func(u *gumble.User)) error {
+	id, err := strconv.ParseUint(user, 10, 32)
+	if err != nil {
+		return err
+	}
+
+	err = errors.New("user is not connected")
+	c.client.Do(func() {
+		for _, u := range c.client.Users {
+			if u.IsRegistered() && u.UserID == uint32(id) {
+				f(u)
+				err = nil
+				return
+			}
+		}
+	})
+	return err
+}
+
+// getACL requests the root channel ACL and waits for the response
+func (c *MumbleService) getACL() (*gumble.ACL, error) {
+	c.requestLock.Lock()
+	defer c.requestLock.Unlock()
+
+	// Drop any stale response
+	select {
+	case <-c.acl:
+	default:
+	}
+
+	c.client.Do(func() {
+		if root := c.client.Channels[0]; root != nil {
+			root.RequestACL()
+		}
+	})
+
+	select {
+	case acl := <-c.acl:
+		return acl, nil
+	case <-time.After(requestTimeout):
+		return nil, errors.New("timeout waiting for ACL, does the bot have write permission?")
+	}
+}
+
+// getUserList requests the registered users and waits for the response
+func (c *MumbleService) getUserList() (gumble.RegisteredUsers, error) {
+	c.requestLock.Lock()
+	defer c.requestLock.Unlock()
+
+	select {
+	case <-c.userList:
+	default:
+	}
+
+	if err := c.client.Conn.WriteProto(&MumbleProto.UserList{}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case users := <-c.userList:
+		return users, nil
+	case <-time.After(requestTimeout):
+		return nil, errors.New("timeout waiting for user list, does the bot have register permission?")
+	}
+}
+
+// updateGroup adds or removes a user from a root channel group and writes the ACL back
+func (c *MumbleService) updateGroup(user, role string, add bool) error {
+	id, err := strconv.ParseUint(user, 10, 32)
+	if err != nil {
+		return err
+	}
+	userID := uint32(id)
+
+	c.updateLock.Lock()
+	defer c.updateLock.Unlock()
+
+	acl, err := c.getACL()
+	if err != nil {
+		return err
+	}
+
+	if err := setGroupUser(acl, userID, role, add); err != nil {
+		return err
+	}
+
+	return c.client.Conn.WriteProto(aclPacket(acl))
+}
+
+// setGroupUser adds or removes a user from a group in an ACL
+func setGroupUser(acl *gumble.ACL, userID uint32, role string, add bool) error {
+	found := false
+	for _, group := range acl.Groups {
+		if group.Name != role {
+			continue
+		}
+		found = true
+		if add {
+			group.UsersAdd[userID] = &gumble.ACLUser{UserID: userID}
+			delete(group.UsersRemove, userID)
+		} else {
+			delete(group.UsersAdd, userID)
+		}
+	}
+	if !found {
+		return errors.New("unknown group " + role)
+	}
+	return nil
+}
+
+// aclPacket converts an ACL back into the protocol message used to update it.
+// The root channel has no parent so nothing is inherited.
+func aclPacket(acl *gumble.ACL) *MumbleProto.ACL {
+	packet := &MumbleProto.ACL{
+		ChannelId:   proto.Uint32(acl.Channel.ID),
+		InheritAcls: proto.Bool(acl.Inherits),
+		Query:       proto.Bool(false),
+	}
+
+	for _, group := range acl.Groups {
+		g := &MumbleProto.ACL_ChanGroup{
+			Name:        proto.String(group.Name),
+			Inherit:     proto.Bool(group.InheritUsers),
+			Inheritable: proto.Bool(group.Inheritable),
+		}
+		for id := range group.UsersAdd {
+			g.Add = append(g.Add, id)
+		}
+		for id := range group.UsersRemove {
+			g.Remove = append(g.Remove, id)
+		}
+		packet.Groups = append(packet.Groups, g)
+	}
+
+	for _, rule := range acl.Rules {
+		r := &MumbleProto.ACL_ChanACL{
+			ApplyHere: proto.Bool(rule.AppliesCurrent),
+			ApplySubs: proto.Bool(rule.AppliesChildren),
+			Grant:     proto.Uint32(uint32(rule.Granted)),
+			Deny:      proto.Uint32(uint32(rule.Denied)),
+		}
+		if rule.User != nil {
+			r.UserId = proto.Uint32(rule.User.UserID)
+		}
+		if rule.Group != nil {
+			r.Group = proto.String(rule.Group.Name)
+		}
+		packet.Acls = append(packet.Acls, r)
+	}
+
+	return packet
+}
+
+// isAssignable returns false for the groups murmur manages itself
+func isAssignable(group string) bool {
+	switch group {
+	case "all", "auth", "in", "out", "sub", "":
+		return false
+	}
+	// ~group, $token and #hash groups are evaluated by the server
+	switch group[0] {
+	case '~', '$', '#', '!':
+		return false
+	}
+	return true
+}
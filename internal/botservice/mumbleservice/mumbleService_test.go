@@ -0,0 +1,49 @@
+package mumbleservice
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"layeh.com/gumble/gumble"
+)
+
+func TestIsAssignable(t *testing.T) {
+	for _, group := range []string{"", "all", "auth", "in", "out", "sub", "~sub", "$token", "#hash", "!admin"} {
+		assert.False(t, isAssignable(group), group)
+	}
+	for _, group := range []string{"admin", "members", "plusTen"} {
+		assert.True(t, isAssignable(group), group)
+	}
+}
+
+func TestSetGroupUser(t *testing.T) {
+	acl := &gumble.ACL{
+		Channel: &gumble.Channel{ID: 0},
+		Groups: []*gumble.ACLGroup{{
+			Name:        "members",
+			UsersAdd:    map[uint32]*gumble.ACLUser{5: {UserID: 5}},
+			UsersRemove: map[uint32]*gumble.ACLUser{7: {UserID: 7}},
+		}},
+	}
+
+	assert.NotNil(t, setGroupUser(acl, 7, "missing", true))
+
+	assert.Nil(t, setGroupUser(acl, 7, "members", true))
+	assert.Nil(t, setGroupUser(acl, 5, "members", false))
+
+	packet := aclPacket(acl)
+	assert.Equal(t, []uint32{7}, packet.Groups[0].Add)
+	assert.Empty(t, packet.Groups[0].Remove)
+}
+
+func TestUserByHash(t *testing.T) {
+	users := gumble.Users{
+		1: &gumble.User{Session: 1, UserID: 12, Hash: "abcdef0123"},
+		2: &gumble.User{Session: 2, Hash: "fedcba3210"},
+	}
+
+	assert.Equal(t, "12", userByHash(users, " ABCDEF0123 "))
+	// Unregistered users cannot be linked
+	assert.Equal(t, "", userByHash(users, "fedcba3210"))
+	assert.Equal(t, "", userByHash(users, ""))
+}
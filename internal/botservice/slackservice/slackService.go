@@ -0,0 +1,293 @@
+package slackservice
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/antihax/evedata/internal/botservice"
+	"github.com/nlopes/slack"
+)
+
+// SlackService provides access to a slack workspace through a bot token
+// User groups are used as roles.
+type SlackService struct {
+	client *slack.Client
+	teamID string
+}
+
+// NewSlackService creates a service for the workspace the bot token belongs to
+func NewSlackService(teamID, token string, options ...slack.Option) (*SlackService, error) {
+	client := slack.New(token, options...)
+
+	auth, err := client.AuthTest()
+	if err != nil {
+		return nil, err
+	}
+
+	if auth.TeamID != teamID {
+		return nil, errors.New("token does not belong to team " + teamID)
+	}
+
+	return &SlackService{client, teamID}, nil
+}
+
+// SendMessageToChannel sends a message to a slack channel ID
+func (c *SlackService) SendMessageToChannel(channel, message string) error {
+	_, _, err := c.client.PostMessage(channel, slack.MsgOptionText(message, false), slack.MsgOptionAsUser(true))
+	return err
+}
+
+// SendRichMessageToChannel sends a message as an attachment to a slack channel ID
+func (c *SlackService) SendRichMessageToChannel(channel string, message *botservice.Message) error {
+	_, _, err := c.client.PostMessage(channel,
+		slack.MsgOptionText(message.Content, false),
+		slack.MsgOptionAttachments(attachment(message)),
+		slack.MsgOptionAsUser(true),
+	)
+	return err
+}
+
+// attachment converts a message to a slack attachment
+func attachment(message *botservice.Message) slack.Attachment {
+	a := slack.Attachment{
+		Fallback:  message.Text(),
+		Title:     message.Title,
+		TitleLink: message.URL,
+		Text:      slackLinks(message.Description),
+		Color:     fmt.Sprintf("#%06X", message.Color),
+		ThumbURL:  message.ThumbnailURL(),
+		Footer:    message.Footer,
+	}
+
+	for _, f := range message.Fields {
+		a.Fields = append(a.Fields, slack.AttachmentField{
+			Title: f.Name,
+			Value: slackLinks(f.Value),
+			Short: f.Inline,
+		})
+	}
+
+	if !message.Timestamp.IsZero() {
+		a.Ts = json.Number(strconv.FormatInt(message.Timestamp.Unix(), 10))
+	}
+
+	return a
+}
+
+// slackLinks converts [text](url) links to slack's <url|text>
+func slackLinks(s string) string {
+	return botservice.FormatLinks(s, "<$2|$1>")
+}
+
+// SendMessageToUser sends a direct message to a slack user ID
+func (c *SlackService) SendMessageToUser(user, message string) error {
+	_, _, channel, err := c.client.OpenIMChannel(user)
+	if err != nil {
+		return err
+	}
+	return c.SendMessageToChannel(channel, message)
+}
+
+// KickUser messages a slack user ID and removes them from every channel the bot is in.
+// Removing users from the workspace needs an admin token which bots do not have.
+func (c *SlackService) KickUser(user, message string) error {
+	if message != "" {
+		if err := c.SendMessageToUser(user, message); err != nil {
+			return err
+		}
+	}
+
+	channels, err := c.client.GetChannels(true)
+	if err != nil {
+		return err
+	}
+
+	for _, ch := range channels {
+		if !ch.IsMember || !inSlice(user, ch.Members) {
+			continue
+		}
+		if err := c.client.KickUserFromChannel(ch.ID, user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetName gets the workspace name
+func (c *SlackService) GetName() (string, error) {
+	team, err := c.client.GetTeamInfo()
+	if err != nil {
+		return "", err
+	}
+	return team.Name, nil
+}
+
+// GetChannels gets all public channels in the workspace
+func (c *SlackService) GetChannels() ([]botservice.Name, error) {
+	g, err := c.client.GetChannels(true)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []botservice.Name{}
+	for _, ch := range g {
+		channels = append(channels, botservice.Name{ID: ch.ID, Name: ch.Name})
+	}
+
+	return channels, nil
+}
+
+// GetRoles gets all the user groups which can be assigned.
+// Disabled groups are included as RemoveRole disables groups it empties and AddRole enables them again.
+func (c *SlackService) GetRoles() ([]botservice.Name, error) {
+	g, err := c.client.GetUserGroups(slack.GetUserGroupsOptionIncludeDisabled(true))
+	if err != nil {
+		return nil, err
+	}
+
+	roles := []botservice.Name{}
+	for _, group := range g {
+		roles = append(roles, botservice.Name{ID: group.ID, Name: group.Name})
+	}
+
+	return roles, nil
+}
+
+// GetMembers gets all members of the workspace with the user groups they belong to
+func (c *SlackService) GetMembers() ([]botservice.Name, error) {
+	groups, err := c.client.GetUserGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	// Map users to their groups
+	userGroups := make(map[string][]string)
+	for _, group := range groups {
+		if group.DateDelete != 0 {
+			continue
+		}
+		users, err := c.client.GetUserGroupMembers(group.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			userGroups[u] = append(userGroups[u], group.ID)
+		}
+	}
+
+	users, err := c.client.GetUsers()
+	if err != nil {
+		return nil, err
+	}
+
+	members := []botservice.Name{}
+	for _, u := range users {
+		if u.Deleted || u.IsBot || u.ID == "USLACKBOT" {
+			continue
+		}
+		members = append(members, botservice.Name{ID: u.ID, Name: displayName(u), Roles: userGroups[u.ID]})
+	}
+
+	return members, nil
+}
+
+// RemoveRole removes a user from a user group
+func (c *SlackService) RemoveRole(user, role string) error {
+	users, err := c.client.GetUserGroupMembers(role)
+	if err != nil {
+		return err
+	}
+
+	members := []string{}
+	for _, u := range users {
+		if u != user {
+			members = append(members, u)
+		}
+	}
+
+	// Slack refuses to empty a group, disable it instead.
+	if len(members) == 0 {
+		_, err = c.client.DisableUserGroup(role)
+		return err
+	}
+
+	_, err = c.client.UpdateUserGroupMembers(role, strings.Join(members, ","))
+	return err
+}
+
+// AddRole adds a user to a user group
+func (c *SlackService) AddRole(user, role string) error {
+	group, err := c.getUserGroup(role)
+	if err != nil {
+		return err
+	}
+
+	users := group.Users
+	if group.DateDelete != 0 {
+		// RemoveRole disables groups instead of emptying them, so the last
+		// member removed is still listed and must not be kept.
+		if _, err = c.client.EnableUserGroup(role); err != nil {
+			return err
+		}
+		users = nil
+	} else if inSlice(user, users) {
+		return nil
+	}
+
+	_, err = c.client.UpdateUserGroupMembers(role, strings.Join(append(users, user), ","))
+	return err
+}
+
+// getUserGroup finds a user group with its members, including disabled groups
+func (c *SlackService) getUserGroup(role string) (slack.UserGroup, error) {
+	groups, err := c.client.GetUserGroups(
+		slack.GetUserGroupsOptionIncludeDisabled(true),
+		slack.GetUserGroupsOptionIncludeUsers(true),
+	)
+	if err != nil {
+		return slack.UserGroup{}, err
+	}
+
+	for _, group := range groups {
+		if group.ID == role {
+			return group, nil
+		}
+	}
+	return slack.UserGroup{}, errors.New("unknown user group " + role)
+}
+
+// AddUser verifies the user is in the workspace.
+// Bots cannot add users to slack, they must join by invitation.
+func (c *SlackService) AddUser(auth, user, name string) error {
+	u, err := c.client.GetUserInfo(user)
+	if err != nil {
+		return err
+	}
+	if u.Deleted {
+		return errors.New("user has been removed from the workspace")
+	}
+	return nil
+}
+
+// displayName returns the name a user is shown as in slack
+func displayName(u slack.User) string {
+	if u.Profile.DisplayName != "" {
+		return u.Profile.DisplayName
+	}
+	if u.RealName != "" {
+		return u.RealName
+	}
+	return u.Name
+}
+
+func inSlice(a string, list []string) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package slackservice
+
+import (
+	"testing"
+
+	"github.com/antihax/evedata/internal/botservice/slackservice/slackfake"
+	"github.com/nlopes/slack"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDisplayName(t *testing.T) {
+	u := slack.User{Name: "handle"}
+	assert.Equal(t, "handle", displayName(u))
+
+	u.RealName = "Real Name"
+	assert.Equal(t, "Real Name", displayName(u))
+
+	u.Profile.DisplayName = "Display"
+	assert.Equal(t, "Display", displayName(u))
+}
+
+func TestRoles(t *testing.T) {
+	fake := slackfake.NewServer("T1")
+	defer fake.Close()
+
+	fake.AddUser("U1", "first")
+	fake.AddUser("U2", "second")
+	fake.AddGroup("S1", "members", "U1")
+
+	_, err := NewSlackService("T2", "xoxb-test", slack.OptionAPIURL(fake.URL()))
+	assert.NotNil(t, err)
+
+	s, err := NewSlackService("T1", "xoxb-test", slack.OptionAPIURL(fake.URL()))
+	assert.Nil(t, err)
+
+	assert.Nil(t, s.AddUser("", "U2", "second"))
+	assert.NotNil(t, s.AddUser("", "U3", "missing"))
+
+	assert.Nil(t, s.AddRole("U2", "S1"))
+	assert.Equal(t, []string{"U1", "U2"}, fake.Members("S1"))
+
+	// Adding twice does nothing
+	assert.Nil(t, s.AddRole("U2", "S1"))
+	assert.Equal(t, []string{"U1", "U2"}, fake.Members("S1"))
+
+	assert.Nil(t, s.RemoveRole("U1", "S1"))
+	assert.Equal(t, []string{"U2"}, fake.Members("S1"))
+
+	members, err := s.GetMembers()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"S1"}, members[1].Roles)
+
+	// Removing the last member disables the group but it can still be assigned
+	assert.Nil(t, s.RemoveRole("U2", "S1"))
+	assert.True(t, fake.Disabled("S1"))
+
+	roles, err := s.GetRoles()
+	assert.Nil(t, err)
+	assert.Equal(t, "S1", roles[0].ID)
+
+	members, err = s.GetMembers()
+	assert.Nil(t, err)
+	assert.Empty(t, members[1].Roles)
+
+	assert.Nil(t, s.AddRole("U1", "S1"))
+	assert.False(t, fake.Disabled("S1"))
+	assert.Equal(t, []string{"U1"}, fake.Members("S1"))
+}
@@ -0,0 +1,205 @@
+// Package slackfake provides a fake Slack Web API for tests.
+package slackfake
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server keeps users and user groups in memory and answers the Web API methods
+// used to sync roles. Unknown methods fail with unknown_method.
+type Server struct {
+	server *httptest.Server
+	teamID string
+
+	mu     sync.Mutex
+	users  map[string]*user
+	groups map[string]*group
+}
+
+type user struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Deleted bool   `json:"deleted"`
+}
+
+type group struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	DateDelete int64    `json:"date_delete"`
+	Users      []string `json:"users,omitempty"`
+}
+
+// NewServer starts a fake Slack Web API for a workspace
+func NewServer(teamID string) *Server {
+	s := &Server{
+		teamID: teamID,
+		users:  make(map[string]*user),
+		groups: make(map[string]*group),
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// URL returns the API URL to pass to slack.OptionAPIURL
+func (s *Server) URL() string {
+	return s.server.URL + "/"
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// AddUser adds a member to the workspace
+func (s *Server) AddUser(id, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[id] = &user{ID: id, Name: name}
+}
+
+// DeleteUser deactivates a member of the workspace
+func (s *Server) DeleteUser(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		u.Deleted = true
+	}
+}
+
+// AddGroup adds a user group with members
+func (s *Server) AddGroup(id, name string, users ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.groups[id] = &group{ID: id, Name: name, Users: users}
+}
+
+// Members returns the members of a user group
+func (s *Server) Members(id string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if g, ok := s.groups[id]; ok {
+		return append([]string{}, g.Users...)
+	}
+	return nil
+}
+
+// Disabled returns true if a user group has been disabled
+func (s *Server) Disabled(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.groups[id]
+	return ok && g.DateDelete != 0
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	method := strings.TrimPrefix(r.URL.Path, "/")
+	response := map[string]interface{}{"ok": true}
+	fail := func(e string) {
+		response = map[string]interface{}{"ok": false, "error": e}
+	}
+
+	switch method {
+	case "auth.test":
+		response["team_id"] = s.teamID
+		response["user_id"] = "UBOT"
+
+	case "users.info":
+		if u, ok := s.users[r.FormValue("user")]; ok {
+			response["user"] = u
+		} else {
+			fail("user_not_found")
+		}
+
+	case "users.list":
+		members := []*user{}
+		for _, id := range s.userIDs() {
+			members = append(members, s.users[id])
+		}
+		response["members"] = members
+		response["response_metadata"] = map[string]string{"next_cursor": ""}
+
+	case "usergroups.list":
+		groups := []group{}
+		for _, id := range s.groupIDs() {
+			g := *s.groups[id]
+			if g.DateDelete != 0 && r.FormValue("include_disabled") != "true" {
+				continue
+			}
+			if r.FormValue("include_users") != "true" {
+				g.Users = nil
+			}
+			groups = append(groups, g)
+		}
+		response["usergroups"] = groups
+
+	case "usergroups.users.list":
+		if g, ok := s.groups[r.FormValue("usergroup")]; ok {
+			response["users"] = append([]string{}, g.Users...)
+		} else {
+			fail("no_such_subteam")
+		}
+
+	case "usergroups.users.update":
+		g, ok := s.groups[r.FormValue("usergroup")]
+		if !ok {
+			fail("no_such_subteam")
+			break
+		}
+		if r.FormValue("users") == "" {
+			fail("invalid_users")
+			break
+		}
+		g.Users = strings.Split(r.FormValue("users"), ",")
+		response["usergroup"] = g
+
+	case "usergroups.enable", "usergroups.disable":
+		g, ok := s.groups[r.FormValue("usergroup")]
+		if !ok {
+			fail("no_such_subteam")
+			break
+		}
+		g.DateDelete = 0
+		if method == "usergroups.disable" {
+			g.DateDelete = time.Now().Unix()
+		}
+		response["usergroup"] = g
+
+	default:
+		fail("unknown_method")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s *Server) userIDs() []string {
+	ids := []string{}
+	for id := range s.users {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (s *Server) groupIDs() []string {
+	ids := []string{}
+	for id := range s.groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
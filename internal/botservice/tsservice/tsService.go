@@ -1,20 +1,39 @@
 package tsservice
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 
 	"github.com/antihax/evedata/internal/botservice"
 
 	ts3 "github.com/multiplay/go-ts3"
 )
 
-// AuthService provides access to a discord session
+// LinkIdent is the custom client property set by join tokens
+const LinkIdent = "evedata"
+
+// TS3 error returned when a query has no results
+const errEmptyResult = 1281
+
+// TSService provides access to a TS3 virtual server through ServerQuery
+// Server groups are used as roles and client database IDs as members.
 type TSService struct {
 	session *ts3.Client
 }
 
-// NewTSService sends a message to a discord channel ID
+// NewTSService connects to a TS3 ServerQuery address and logs in.
+// The address may end in /<virtual server ID> to select a server, otherwise server 1 is used.
 func NewTSService(address, user, pass string) (*TSService, error) {
+	serverID := 1
+	if i := strings.LastIndex(address, "/"); i > 0 {
+		id, err := strconv.Atoi(address[i+1:])
+		if err != nil {
+			return nil, err
+		}
+		address, serverID = address[:i], id
+	}
+
 	conn, err := ts3.NewClient(address)
 	if err != nil {
 		return nil, err
@@ -22,12 +41,30 @@ func NewTSService(address, user, pass string) (*TSService, error) {
 
 	err = conn.Login(user, pass)
 	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	err = conn.Use(serverID)
+	if err != nil {
+		conn.Close()
 		return nil, err
 	}
 
 	return &TSService{conn}, nil
 }
 
+// Close the ServerQuery connection
+func (c *TSService) Close() error {
+	return c.session.Close()
+}
+
+// Connected returns false once the ServerQuery connection stops responding
+func (c *TSService) Connected() bool {
+	_, err := c.session.ExecCmd(ts3.NewCmd("whoami"))
+	return err == nil
+}
+
 // GetServerList gets the available TS3 virtual servers
 func (c *TSService) UseServer(serverID int) error {
 	err := c.session.Use(serverID)
@@ -78,17 +115,31 @@ func (c *TSService) SendMessageToChannel(channel, message string) error {
 	return err
 }
 
-// SendMessageToUser sends a message to a discord user ID
+// SendRichMessageToChannel sends a message to a channel ID as text
+func (c *TSService) SendRichMessageToChannel(channel string, message *botservice.Message) error {
+	return c.SendMessageToChannel(channel, message.Text())
+}
+
+// SendMessageToUser sends a message to a client database ID if they are connected
 func (c *TSService) SendMessageToUser(user, message string) error {
-	_, err := c.session.ExecCmd(ts3.NewCmd("sendtextmessage").WithArgs(
-		ts3.NewArg("targetmode", "1"),
-		ts3.NewArg("target", user),
-		ts3.NewArg("msg", message),
-	))
-	return err
+	clients, err := c.onlineClients(user)
+	if err != nil {
+		return err
+	}
+
+	for _, cl := range clients {
+		if _, err := c.session.ExecCmd(ts3.NewCmd("sendtextmessage").WithArgs(
+			ts3.NewArg("targetmode", "1"),
+			ts3.NewArg("target", cl.ID),
+			ts3.NewArg("msg", message),
+		)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// SendMessageToUser sends a message to a discord user ID
+// SendMessageToServer sends a message to a virtual server ID
 func (c *TSService) SendMessageToServer(user, message string) error {
 	_, err := c.session.ExecCmd(ts3.NewCmd("sendtextmessage").WithArgs(
 		ts3.NewArg("targetmode", "3"),
@@ -98,49 +149,252 @@ func (c *TSService) SendMessageToServer(user, message string) error {
 	return err
 }
 
-// KickUser kicks a discord user ID
+// KickUser kicks every connection of a client database ID from the server
 func (c *TSService) KickUser(user, message string) error {
+	clients, err := c.onlineClients(user)
+	if err != nil {
+		return err
+	}
+
+	for _, cl := range clients {
+		if _, err := c.session.ExecCmd(ts3.NewCmd("clientkick").WithArgs(
+			ts3.NewArg("clid", cl.ID),
+			ts3.NewArg("reasonid", 5), // Kick from server
+			ts3.NewArg("reasonmsg", message),
+		)); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// Get the server name
+// GetName gets the virtual server name
 func (c *TSService) GetName() (string, error) {
 	server := ts3.Server{}
 	_, err := c.session.ExecCmd(ts3.NewCmd("serverinfo").WithResponse(&server))
 	return server.Name, err
 }
 
-// IMPLIMENT
+// GetChannels gets all channels on the virtual server
 func (c *TSService) GetChannels() ([]botservice.Name, error) {
+	list, err := c.GetChannelList()
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, nil
+	channels := []botservice.Name{}
+	for id, name := range list {
+		channels = append(channels, botservice.Name{ID: id, Name: name})
+	}
+	return channels, nil
 }
 
-// IMPLIMENT
+// GetRoles gets all regular server groups which can be assigned
 func (c *TSService) GetRoles() ([]botservice.Name, error) {
+	groups, err := c.serverGroups()
+	if err != nil {
+		return nil, err
+	}
 
-	return nil, nil
+	roles := []botservice.Name{}
+	for _, g := range groups {
+		roles = append(roles, botservice.Name{ID: strconv.Itoa(g.ID), Name: g.Name})
+	}
+	return roles, nil
 }
 
-// IMPLIMENT
+// GetMembers gets all clients in the server database with the server groups they belong to
 func (c *TSService) GetMembers() ([]botservice.Name, error) {
+	groups, err := c.serverGroups()
+	if err != nil {
+		return nil, err
+	}
+
+	// Map client database IDs to their groups
+	clientGroups := make(map[int][]string)
+	for _, g := range groups {
+		members := []*groupMember{}
+		if _, err := c.session.ExecCmd(ts3.NewCmd("servergroupclientlist").WithArgs(
+			ts3.NewArg("sgid", g.ID),
+		).WithResponse(&members)); err != nil && !isEmptyResult(err) {
+			return nil, err
+		}
+		for _, m := range members {
+			clientGroups[m.DatabaseID] = append(clientGroups[m.DatabaseID], strconv.Itoa(g.ID))
+		}
+	}
+
+	// Page through the client database
+	const pageSize = 200
+	members := []botservice.Name{}
+	for start := 0; ; start += pageSize {
+		clients := []*dbClient{}
+		if _, err := c.session.ExecCmd(ts3.NewCmd("clientdblist").WithArgs(
+			ts3.NewArg("start", start),
+			ts3.NewArg("duration", pageSize),
+		).WithResponse(&clients)); err != nil && !isEmptyResult(err) {
+			return nil, err
+		}
 
-	return nil, nil
+		for _, cl := range clients {
+			// Skip the serveradmin query account
+			if cl.DatabaseID == 1 {
+				continue
+			}
+			members = append(members, botservice.Name{
+				ID:    strconv.Itoa(cl.DatabaseID),
+				Name:  cl.Nickname,
+				Roles: clientGroups[cl.DatabaseID],
+			})
+		}
+
+		if len(clients) < pageSize {
+			break
+		}
+	}
+
+	return members, nil
 }
 
-// IMPLIMENT
+// RemoveRole removes a client database ID from a server group
 func (c *TSService) RemoveRole(user, role string) error {
-
-	return nil
+	_, err := c.session.ExecCmd(ts3.NewCmd("servergroupdelclient").WithArgs(
+		ts3.NewArg("sgid", role),
+		ts3.NewArg("cldbid", user),
+	))
+	return err
 }
 
-// IMPLIMENT
+// AddRole adds a client database ID to a server group
 func (c *TSService) AddRole(user, role string) error {
-
-	return nil
+	_, err := c.session.ExecCmd(ts3.NewCmd("servergroupaddclient").WithArgs(
+		ts3.NewArg("sgid", role),
+		ts3.NewArg("cldbid", user),
+	))
+	return err
 }
 
-// AddUser
+// AddUser verifies the client database ID exists.
+// Clients are linked to characters by redeeming a join token instead.
 func (c *TSService) AddUser(auth, user, name string) error {
-	return nil
+	_, err := c.session.ExecCmd(ts3.NewCmd("clientdbinfo").WithArgs(
+		ts3.NewArg("cldbid", user),
+	))
+	return err
+}
+
+// CreateJoinToken creates a privilege key which adds the client to the server group
+// and tags them with value so they can be linked once redeemed.
+func (c *TSService) CreateJoinToken(role, description, value string) (string, error) {
+	token := &privilegeKey{}
+	if _, err := c.session.ExecCmd(ts3.NewCmd("privilegekeyadd").WithArgs(
+		ts3.NewArg("tokentype", 0), // Server group
+		ts3.NewArg("tokenid1", role),
+		ts3.NewArg("tokenid2", 0),
+		ts3.NewArg("tokendescription", description),
+		ts3.NewArg("tokencustomset", "ident="+LinkIdent+" value="+value),
+	).WithResponse(token)); err != nil {
+		return "", err
+	}
+
+	if token.Token == "" {
+		return "", errors.New("server did not return a token")
+	}
+	return token.Token, nil
+}
+
+// GetLinkedClients returns the value set by join tokens for each client database ID
+func (c *TSService) GetLinkedClients() (map[string]string, error) {
+	props := []*customProperty{}
+	if _, err := c.session.ExecCmd(ts3.NewCmd("customsearch").WithArgs(
+		ts3.NewArg("ident", LinkIdent),
+		ts3.NewArg("pattern", "%"),
+	).WithResponse(&props)); err != nil && !isEmptyResult(err) {
+		return nil, err
+	}
+
+	linked := make(map[string]string)
+	for _, p := range props {
+		linked[strconv.Itoa(p.DatabaseID)] = p.Value
+	}
+	return linked, nil
+}
+
+// onlineClients returns the connections of a client database ID
+func (c *TSService) onlineClients(user string) ([]*onlineClient, error) {
+	dbID, err := strconv.Atoi(user)
+	if err != nil {
+		return nil, err
+	}
+
+	list := []*onlineClient{}
+	if _, err := c.session.ExecCmd(ts3.NewCmd("clientlist").WithResponse(&list)); err != nil {
+		return nil, err
+	}
+
+	clients := []*onlineClient{}
+	for _, cl := range list {
+		// Ignore query clients
+		if cl.DatabaseID == dbID && cl.Type == 0 {
+			clients = append(clients, cl)
+		}
+	}
+
+	if len(clients) == 0 {
+		return nil, errors.New("client is not connected")
+	}
+	return clients, nil
+}
+
+// serverGroups returns the regular server groups, ignoring templates and query groups
+func (c *TSService) serverGroups() ([]*serverGroup, error) {
+	list := []*serverGroup{}
+	if _, err := c.session.ExecCmd(ts3.NewCmd("servergrouplist").WithResponse(&list)); err != nil {
+		return nil, err
+	}
+
+	groups := []*serverGroup{}
+	for _, g := range list {
+		if g.Type == 1 {
+			groups = append(groups, g)
+		}
+	}
+	return groups, nil
+}
+
+func isEmptyResult(err error) bool {
+	e, ok := err.(*ts3.Error)
+	return ok && e.ID == errEmptyResult
+}
+
+type serverGroup struct {
+	ID   int    `ms:"sgid"`
+	Name string `ms:"name"`
+	Type int    `ms:"type"`
+}
+
+type groupMember struct {
+	DatabaseID int `ms:"cldbid"`
+}
+
+type dbClient struct {
+	DatabaseID int    `ms:"cldbid"`
+	Nickname   string `ms:"client_nickname"`
+}
+
+type onlineClient struct {
+	ID         int    `ms:"clid"`
+	DatabaseID int    `ms:"client_database_id"`
+	Nickname   string `ms:"client_nickname"`
+	Type       int    `ms:"client_type"`
+}
+
+type privilegeKey struct {
+	Token string `ms:"token"`
+}
+
+type customProperty struct {
+	DatabaseID int    `ms:"cldbid"`
+	Ident      string `ms:"ident"`
+	Value      string `ms:"value"`
 }
@@ -3,6 +3,7 @@ package tsservice
 import (
 	"testing"
 
+	"github.com/antihax/evedata/internal/botservice/tsservice/tsfake"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -33,3 +34,79 @@ func TestTS3(t *testing.T) {
 	assert.Nil(t, err)
 
 }
+
+func TestTSServerQuery(t *testing.T) {
+	fake, err := tsfake.NewServer()
+	assert.Nil(t, err)
+	defer fake.Close()
+
+	fake.SetResponse("servergrouplist", `sgid=1 name=Guest\sServer\sQuery type=2|sgid=6 name=Server\sAdmin type=1|sgid=7 name=Members type=1|sgid=8 name=Template type=0`)
+	fake.SetResponse("servergroupclientlist sgid=6", "cldbid=2")
+	fake.SetResponse("servergroupclientlist sgid=7", "cldbid=2|cldbid=5")
+	fake.SetResponse("clientdblist", `cldbid=1 client_nickname=serveradmin|cldbid=2 client_nickname=Admin|cldbid=5 client_nickname=Bob`)
+	fake.SetResponse("clientlist", `clid=3 cid=1 client_database_id=5 client_nickname=Bob client_type=0|clid=4 cid=1 client_database_id=1 client_nickname=serveradmin client_type=1`)
+	fake.SetResponse("privilegekeyadd", `token=abcdefgh`)
+	fake.SetResponse("customsearch", `cldbid=5 ident=evedata value=1234`)
+
+	ts, err := NewTSService(fake.Addr()+"/2", "serveradmin", "nothinguseful")
+	assert.Nil(t, err)
+	assert.NotNil(t, ts)
+	defer ts.Close()
+	assert.True(t, fake.Received("use 2"))
+
+	roles, err := ts.GetRoles()
+	assert.Nil(t, err)
+	assert.Len(t, roles, 2)
+	assert.Equal(t, "6", roles[0].ID)
+	assert.Equal(t, "Members", roles[1].Name)
+
+	members, err := ts.GetMembers()
+	assert.Nil(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, "Admin", members[0].Name)
+	assert.Equal(t, []string{"6", "7"}, members[0].Roles)
+	assert.Equal(t, "5", members[1].ID)
+	assert.Equal(t, []string{"7"}, members[1].Roles)
+
+	err = ts.AddRole("5", "6")
+	assert.Nil(t, err)
+	assert.True(t, fake.Received("servergroupaddclient sgid=6 cldbid=5"))
+
+	err = ts.RemoveRole("5", "7")
+	assert.Nil(t, err)
+	assert.True(t, fake.Received("servergroupdelclient sgid=7 cldbid=5"))
+
+	// Messages and kicks go to the online client ID
+	err = ts.SendMessageToUser("5", "hello")
+	assert.Nil(t, err)
+	assert.True(t, fake.Received("sendtextmessage targetmode=1 target=3"))
+
+	err = ts.KickUser("5", "bye")
+	assert.Nil(t, err)
+	assert.True(t, fake.Received("clientkick clid=3"))
+
+	// Query clients and offline clients cannot be messaged
+	err = ts.SendMessageToUser("1", "hello")
+	assert.NotNil(t, err)
+	err = ts.SendMessageToUser("9", "hello")
+	assert.NotNil(t, err)
+
+	token, err := ts.CreateJoinToken("7", "EVEData: Bob", "1234")
+	assert.Nil(t, err)
+	assert.Equal(t, "abcdefgh", token)
+	assert.True(t, fake.Received("privilegekeyadd tokentype=0 tokenid1=7"))
+
+	linked, err := ts.GetLinkedClients()
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"5": "1234"}, linked)
+
+	// No linked clients is not an error
+	fake.SetError("customsearch", errEmptyResult, "database empty result set")
+	linked, err = ts.GetLinkedClients()
+	assert.Nil(t, err)
+	assert.Empty(t, linked)
+
+	fake.SetError("clientdbinfo", 512, "invalid clientID")
+	err = ts.AddUser("", "9", "Nobody")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,147 @@
+// Package tsfake provides a fake TS3 ServerQuery listener for tests.
+package tsfake
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+const banner = "TS3\n\rWelcome to the TeamSpeak 3 ServerQuery interface, type \"help\" for a list of commands and \"help <command>\" for information on a specific command.\n\r"
+
+// Server answers ServerQuery commands with canned responses and records every command received.
+// Commands without a response are answered with success and no data.
+type Server struct {
+	listener net.Listener
+
+	mu        sync.Mutex
+	responses map[string]string
+	errors    map[string]string
+	commands  []string
+	wg        sync.WaitGroup
+}
+
+// NewServer starts a fake ServerQuery listener on a random local port
+func NewServer() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		listener:  l,
+		responses: make(map[string]string),
+		errors:    make(map[string]string),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address to connect to
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops the listener
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+// SetResponse sets the data line returned for a command.
+// command may be the command name, or the full command line to match specific arguments.
+func (s *Server) SetResponse(command, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[command] = response
+}
+
+// SetError makes a command fail with the ServerQuery error id and message.
+func (s *Server) SetError(command string, id int, msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors[command] = fmt.Sprintf("error id=%d msg=%s", id, strings.Replace(msg, " ", `\s`, -1))
+}
+
+// Commands returns every command line received so far
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// Received returns true if a command line starting with prefix was received
+func (s *Server) Received(prefix string) bool {
+	for _, c := range s.Commands() {
+		if strings.HasPrefix(c, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(banner)); err != nil {
+		return
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "quit" {
+			return
+		}
+
+		response, status := s.answer(line)
+		if response != "" {
+			if _, err := conn.Write([]byte(response + "\n\r")); err != nil {
+				return
+			}
+		}
+		if _, err := conn.Write([]byte(status + "\n\r")); err != nil {
+			return
+		}
+	}
+}
+
+// answer finds the response for a command line, preferring a full line match
+func (s *Server) answer(line string) (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.commands = append(s.commands, line)
+	name := strings.SplitN(line, " ", 2)[0]
+
+	for _, key := range []string{line, name} {
+		if e, ok := s.errors[key]; ok {
+			return "", e
+		}
+	}
+	for _, key := range []string{line, name} {
+		if r, ok := s.responses[key]; ok {
+			return r, "error id=0 msg=ok"
+		}
+	}
+	return "", "error id=0 msg=ok"
+}
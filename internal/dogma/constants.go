@@ -0,0 +1,125 @@
+package dogma
+
+// Attribute IDs used by the evaluator
+const (
+	attrMass                     int32 = 4
+	attrCapacitorNeed            int32 = 6
+	attrHP                       int32 = 9
+	attrPowerOutput              int32 = 11
+	attrSpeedFactor              int32 = 20
+	attrPower                    int32 = 30
+	attrMaxVelocity              int32 = 37
+	attrCPUOutput                int32 = 48
+	attrCPU                      int32 = 50
+	attrRateOfFire               int32 = 51
+	attrRechargeRate             int32 = 55
+	attrDamageMultiplier         int32 = 64
+	attrShieldBonus              int32 = 68
+	attrAgility                  int32 = 70
+	attrDuration                 int32 = 73
+	attrStructureDamageAmount    int32 = 83
+	attrArmorDamageAmount        int32 = 84
+	attrPowerTransferAmount      int32 = 90
+	attrEnergyNeutralizerAmount  int32 = 97
+	attrWarpScrambleStrength     int32 = 105
+	attrKineticDamageResonance   int32 = 109
+	attrThermalDamageResonance   int32 = 110
+	attrExplosiveDamageResonance int32 = 111
+	attrEmDamageResonance        int32 = 113
+	attrEmDamage                 int32 = 114
+	attrExplosiveDamage          int32 = 116
+	attrKineticDamage            int32 = 117
+	attrThermalDamage            int32 = 118
+	attrRequiredSkill1           int32 = 182
+	attrRequiredSkill2           int32 = 183
+	attrRequiredSkill3           int32 = 184
+	attrScanRadarStrength        int32 = 208
+	attrScanLadarStrength        int32 = 209
+	attrScanMagnetometric        int32 = 210
+	attrScanGravimetric          int32 = 211
+	attrMissileDamageMultiplier  int32 = 212
+	attrShieldCapacity           int32 = 263
+	attrArmorHP                  int32 = 265
+	attrArmorEmResonance         int32 = 267
+	attrArmorExplosiveResonance  int32 = 268
+	attrArmorKineticResonance    int32 = 269
+	attrArmorThermalResonance    int32 = 270
+	attrShieldEmResonance        int32 = 271
+	attrShieldExplosiveResonance int32 = 272
+	attrShieldKineticResonance   int32 = 273
+	attrShieldThermalResonance   int32 = 274
+	attrSkillLevel               int32 = 280
+	attrCapacitorCapacity        int32 = 482
+	attrSignatureRadius          int32 = 552
+	attrScanResolution           int32 = 564
+	attrSpeedBoostFactor         int32 = 567
+	attrWarpSpeedMultiplier      int32 = 600
+	attrMassAddition             int32 = 796
+	attrDroneBandwidth           int32 = 1271
+	attrDroneBandwidthUsed       int32 = 1272
+	attrRequiredSkill4           int32 = 1285
+	attrRequiredSkill5           int32 = 1289
+	attrRequiredSkill6           int32 = 1290
+)
+
+var requiredSkillAttributes = []int32{
+	attrRequiredSkill1, attrRequiredSkill2, attrRequiredSkill3,
+	attrRequiredSkill4, attrRequiredSkill5, attrRequiredSkill6,
+}
+
+// Effect IDs and categories
+const (
+	effectLauncherFitted int32 = 40
+	effectTurretFitted   int32 = 42
+
+	effectPassive = 0
+	effectActive  = 1
+	effectTarget  = 2
+	effectOnline  = 4
+)
+
+// Modifier operators in the order they are applied
+const (
+	opPreAssign   = -1
+	opPreMul      = 0
+	opPreDiv      = 1
+	opModAdd      = 2
+	opModSub      = 3
+	opPostMul     = 4
+	opPostDiv     = 5
+	opPostPercent = 6
+	opPostAssign  = 7
+)
+
+// Category and group IDs
+const (
+	categoryShip      int32 = 6
+	categoryModule    int32 = 7
+	categoryCharge    int32 = 8
+	categorySkill     int32 = 16
+	categoryDrone     int32 = 18
+	categoryImplant   int32 = 20
+	categorySubsystem int32 = 32
+
+	groupWarpScrambler  int32 = 52
+	groupStasisWeb      int32 = 65
+	groupPropulsion     int32 = 46
+	groupCloakingDevice int32 = 330
+
+	characterTypeID int32 = 1373
+)
+
+// Killmail item flags
+const (
+	flagLowSlot0       int32 = 11
+	flagHighSlot7      int32 = 34
+	flagDroneBay       int32 = 87
+	flagImplant        int32 = 89
+	flagRigSlot0       int32 = 92
+	flagRigSlot7       int32 = 99
+	flagSubSystemSlot0 int32 = 125
+	flagSubSystemSlot7 int32 = 132
+)
+
+// maxActiveDrones a character can launch
+const maxActiveDrones = 5
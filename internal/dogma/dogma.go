@@ -0,0 +1,181 @@
+// Package dogma evaluates ship fittings against the static data export dogma tables,
+// computing the derived attributes tailor stores for each killmail.
+package dogma
+
+import (
+	"github.com/jmoiron/sqlx"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Attribute describes a dogma attribute
+type Attribute struct {
+	ID           int32   `db:"attributeID"`
+	Name         string  `db:"attributeName"`
+	DefaultValue float64 `db:"defaultValue"`
+	Stackable    bool    `db:"stackable"`
+	HighIsGood   bool    `db:"highIsGood"`
+}
+
+// Modifier is one entry of an effect's modifierInfo
+type Modifier struct {
+	Func                 string `yaml:"func"`
+	Domain               string `yaml:"domain"`
+	ModifiedAttributeID  int32  `yaml:"modifiedAttributeID"`
+	ModifyingAttributeID int32  `yaml:"modifyingAttributeID"`
+	Operator             int    `yaml:"operator"`
+	GroupID              int32  `yaml:"groupID"`
+	SkillTypeID          int32  `yaml:"skillTypeID"`
+}
+
+// Effect is a dogma effect and the modifiers it applies
+type Effect struct {
+	ID                  int32
+	Name                string
+	Category            int
+	DurationAttributeID int32
+	Modifiers           []Modifier
+}
+
+// Type is an item type with its base attributes and effects
+type Type struct {
+	ID         int32
+	GroupID    int32
+	CategoryID int32
+	Attributes map[int32]float64
+	Effects    []int32
+}
+
+// Data holds the dogma tables in memory
+type Data struct {
+	Types      map[int32]*Type
+	Attributes map[int32]*Attribute
+	Effects    map[int32]*Effect
+	Skills     []int32
+}
+
+// NewData creates empty dogma data
+func NewData() *Data {
+	return &Data{
+		Types:      make(map[int32]*Type),
+		Attributes: make(map[int32]*Attribute),
+		Effects:    make(map[int32]*Effect),
+	}
+}
+
+// LoadData reads the dogma tables from the eve database
+func LoadData(db *sqlx.DB) (*Data, error) {
+	d := NewData()
+
+	types := []struct {
+		TypeID     int32 `db:"typeID"`
+		GroupID    int32 `db:"groupID"`
+		CategoryID int32 `db:"categoryID"`
+	}{}
+	if err := db.Select(&types, `
+		SELECT typeID, T.groupID, categoryID FROM eve.invTypes T
+		INNER JOIN eve.invGroups G ON G.groupID = T.groupID`); err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		d.Types[t.TypeID] = &Type{ID: t.TypeID, GroupID: t.GroupID, CategoryID: t.CategoryID, Attributes: make(map[int32]float64)}
+		if t.CategoryID == categorySkill {
+			d.Skills = append(d.Skills, t.TypeID)
+		}
+	}
+
+	attributes := []Attribute{}
+	if err := db.Select(&attributes, `
+		SELECT attributeID, attributeName, IFNULL(defaultValue, 0) AS defaultValue,
+			stackable, highIsGood
+		FROM eve.dgmAttributeTypes`); err != nil {
+		return nil, err
+	}
+	for i := range attributes {
+		d.Attributes[attributes[i].ID] = &attributes[i]
+	}
+
+	values := []struct {
+		TypeID      int32   `db:"typeID"`
+		AttributeID int32   `db:"attributeID"`
+		Value       float64 `db:"value"`
+	}{}
+	if err := db.Select(&values, `
+		SELECT typeID, attributeID, COALESCE(valueFloat, valueInt, 0) AS value
+		FROM eve.dgmTypeAttributes`); err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		if t := d.Types[v.TypeID]; t != nil {
+			t.Attributes[v.AttributeID] = v.Value
+		}
+	}
+
+	effects := []struct {
+		EffectID            int32  `db:"effectID"`
+		EffectName          string `db:"effectName"`
+		EffectCategory      int    `db:"effectCategory"`
+		DurationAttributeID int32  `db:"durationAttributeID"`
+		ModifierInfo        string `db:"modifierInfo"`
+	}{}
+	if err := db.Select(&effects, `
+		SELECT effectID, effectName, effectCategory, IFNULL(durationAttributeID, 0) AS durationAttributeID,
+			IFNULL(modifierInfo, '') AS modifierInfo
+		FROM eve.dgmEffects`); err != nil {
+		return nil, err
+	}
+	for _, e := range effects {
+		effect := &Effect{ID: e.EffectID, Name: e.EffectName, Category: e.EffectCategory, DurationAttributeID: e.DurationAttributeID}
+		if e.ModifierInfo != "" {
+			if err := yaml.Unmarshal([]byte(e.ModifierInfo), &effect.Modifiers); err != nil {
+				return nil, err
+			}
+		}
+		d.Effects[e.EffectID] = effect
+	}
+
+	typeEffects := []struct {
+		TypeID   int32 `db:"typeID"`
+		EffectID int32 `db:"effectID"`
+	}{}
+	if err := db.Select(&typeEffects, `SELECT typeID, effectID FROM eve.dgmTypeEffects`); err != nil {
+		return nil, err
+	}
+	for _, e := range typeEffects {
+		if t := d.Types[e.TypeID]; t != nil {
+			t.Effects = append(t.Effects, e.EffectID)
+		}
+	}
+
+	return d, nil
+}
+
+// hasEffect checks if a type has an effect
+func (t *Type) hasEffect(effectID int32) bool {
+	for _, e := range t.Effects {
+		if e == effectID {
+			return true
+		}
+	}
+	return false
+}
+
+// hasEffectCategory checks if a type has any effect of a category
+func (d *Data) hasEffectCategory(t *Type, category int) bool {
+	for _, e := range t.Effects {
+		if effect := d.Effects[e]; effect != nil && effect.Category == category {
+			return true
+		}
+	}
+	return false
+}
+
+// durationAttribute finds the attribute holding the cycle time of a type's active effect
+func (d *Data) durationAttribute(t *Type) int32 {
+	for _, e := range t.Effects {
+		if effect := d.Effects[e]; effect != nil && effect.DurationAttributeID > 0 &&
+			(effect.Category == effectActive || effect.Category == effectTarget) {
+			return effect.DurationAttributeID
+		}
+	}
+	return attrDuration
+}
@@ -0,0 +1,176 @@
+package dogma
+
+import (
+	"math"
+	"testing"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+// Fixture types
+const (
+	testShip        int32 = 100
+	testTurret      int32 = 200
+	testCharge      int32 = 300
+	testDamageMod   int32 = 400
+	testMWD         int32 = 500
+	testWeb         int32 = 600
+	testDrone       int32 = 700
+	testSkill       int32 = 3300
+	testTurretGroup int32 = 55
+
+	testVelocityBonus  int32 = 1001
+	testSignatureBonus int32 = 554
+)
+
+// testData is a small dogma set covering each kind of modifier the stats rely on
+func testData() *Data {
+	d := NewData()
+
+	d.Attributes[attrDamageMultiplier] = &Attribute{ID: attrDamageMultiplier, DefaultValue: 1}
+	d.Attributes[attrMaxVelocity] = &Attribute{ID: attrMaxVelocity, Stackable: true}
+	d.Attributes[attrSignatureRadius] = &Attribute{ID: attrSignatureRadius}
+	d.Attributes[attrMissileDamageMultiplier] = &Attribute{ID: attrMissileDamageMultiplier, DefaultValue: 1}
+
+	d.Effects[effectTurretFitted] = &Effect{ID: effectTurretFitted, Category: effectPassive}
+	d.Effects[10] = &Effect{ID: 10, Category: effectTarget, DurationAttributeID: attrRateOfFire}
+	d.Effects[500] = &Effect{ID: 500, Category: effectOnline, Modifiers: []Modifier{
+		{Func: "LocationGroupModifier", Domain: "shipID", GroupID: testTurretGroup,
+			ModifiedAttributeID: attrDamageMultiplier, ModifyingAttributeID: attrDamageMultiplier, Operator: opPostMul},
+	}}
+	d.Effects[600] = &Effect{ID: 600, Category: effectPassive, Modifiers: []Modifier{
+		{Func: "ItemModifier", Domain: "shipID",
+			ModifiedAttributeID: attrMaxVelocity, ModifyingAttributeID: testVelocityBonus, Operator: opPostPercent},
+	}}
+	d.Effects[700] = &Effect{ID: 700, Category: effectActive, DurationAttributeID: attrDuration, Modifiers: []Modifier{
+		{Func: "ItemModifier", Domain: "shipID",
+			ModifiedAttributeID: attrSignatureRadius, ModifyingAttributeID: testSignatureBonus, Operator: opPostPercent},
+	}}
+	d.Effects[800] = &Effect{ID: 800, Category: effectTarget, DurationAttributeID: attrDuration}
+
+	add := func(id, group, category int32, effects []int32, attributes map[int32]float64) {
+		d.Types[id] = &Type{ID: id, GroupID: group, CategoryID: category, Effects: effects, Attributes: attributes}
+	}
+
+	add(testShip, 25, categoryShip, nil, map[int32]float64{
+		attrShieldCapacity: 1000, attrArmorHP: 1000, attrHP: 1000,
+		attrShieldEmResonance: 0.5, attrShieldThermalResonance: 0.5, attrShieldKineticResonance: 0.5, attrShieldExplosiveResonance: 0.5,
+		attrArmorEmResonance: 1, attrArmorThermalResonance: 1, attrArmorKineticResonance: 1, attrArmorExplosiveResonance: 1,
+		attrEmDamageResonance: 1, attrThermalDamageResonance: 1, attrKineticDamageResonance: 1, attrExplosiveDamageResonance: 1,
+		attrMass: 1000000, attrMaxVelocity: 300, attrSignatureRadius: 40,
+		attrCapacitorCapacity: 1000, attrRechargeRate: 100000,
+		attrCPUOutput: 300, attrPowerOutput: 1000, attrDroneBandwidth: 25, attrScanRadarStrength: 10,
+	})
+	add(testTurret, testTurretGroup, categoryModule, []int32{effectTurretFitted, 10}, map[int32]float64{
+		attrDamageMultiplier: 2, attrRateOfFire: 4000, attrCPU: 20, attrPower: 100,
+	})
+	add(testCharge, 83, categoryCharge, nil, map[int32]float64{attrEmDamage: 10, attrThermalDamage: 10})
+	add(testDamageMod, 59, categoryModule, []int32{500}, map[int32]float64{attrDamageMultiplier: 1.1})
+	add(testMWD, groupPropulsion, categoryModule, []int32{700}, map[int32]float64{
+		attrSpeedFactor: 500, attrSpeedBoostFactor: 1500000, attrMassAddition: 500000,
+		attrCapacitorNeed: 100, attrDuration: 10000, testSignatureBonus: 500,
+	})
+	add(testWeb, groupStasisWeb, categoryModule, []int32{800}, map[int32]float64{attrSpeedFactor: -60, attrDuration: 5000})
+	add(testDrone, 100, categoryDrone, nil, map[int32]float64{
+		attrEmDamage: 5, attrDamageMultiplier: 2, attrRateOfFire: 2000, attrDroneBandwidthUsed: 10,
+	})
+	add(testSkill, 255, categorySkill, []int32{600}, map[int32]float64{testVelocityBonus: 5})
+	d.Skills = []int32{testSkill}
+
+	return d
+}
+
+// testKillmail is a recorded style loss of the fixture ship
+func testKillmail() *esi.GetKillmailsKillmailIdKillmailHashOk {
+	return &esi.GetKillmailsKillmailIdKillmailHashOk{
+		KillmailId: 1,
+		Victim: esi.GetKillmailsKillmailIdKillmailHashVictim{
+			ShipTypeId: testShip,
+			Items: []esi.GetKillmailsKillmailIdKillmailHashItem{
+				{ItemTypeId: testTurret, Flag: 27, QuantityDestroyed: 1},
+				{ItemTypeId: testCharge, Flag: 27, QuantityDropped: 1},
+				{ItemTypeId: testDamageMod, Flag: 11, QuantityDestroyed: 1},
+				{ItemTypeId: testDamageMod, Flag: 12, QuantityDropped: 1},
+				{ItemTypeId: testMWD, Flag: 19, QuantityDestroyed: 1},
+				{ItemTypeId: testWeb, Flag: 20, QuantityDestroyed: 1},
+				{ItemTypeId: testDrone, Flag: flagDroneBay, QuantityDestroyed: 2, QuantityDropped: 1},
+				{ItemTypeId: 9999, Flag: 5, QuantityDropped: 1},
+			},
+		},
+	}
+}
+
+func TestFitFromKillmail(t *testing.T) {
+	d := testData()
+
+	fit, err := d.FitFromKillmail(testKillmail())
+	assert.Nil(t, err)
+	assert.Equal(t, testShip, fit.ShipTypeID)
+	assert.Len(t, fit.Modules, 5)
+	assert.Equal(t, Module{TypeID: testTurret, ChargeTypeID: testCharge, Flag: 27}, fit.Modules[0])
+	assert.Equal(t, []Drone{{TypeID: testDrone, Quantity: 3}}, fit.Drones)
+
+	km := testKillmail()
+	km.Victim.ShipTypeId = 1
+	_, err = d.FitFromKillmail(km)
+	assert.Equal(t, ErrUnknownShip, err)
+}
+
+func TestEvaluate(t *testing.T) {
+	d := testData()
+
+	fit, err := d.FitFromKillmail(testKillmail())
+	assert.Nil(t, err)
+
+	a, err := d.Evaluate(fit)
+	assert.Nil(t, err)
+	assert.Equal(t, testShip, a.TypeID)
+	assert.Len(t, a.Modules, 5)
+	assert.Equal(t, 3.0, a.Drones[0]["quantity"])
+
+	s := a.Ship
+	assert.InDelta(t, 4000, s["avgEHP"], 0.001)
+
+	// Two penalized damage mods, the second at 87% effectiveness
+	multiplier := 2 * 1.1 * (1 + 0.1*stackingPenalty(1))
+	alpha := 20 * multiplier
+	assert.InDelta(t, alpha+20, s["totalAlphaDamage"], 0.001)
+	assert.InDelta(t, alpha/4+10, s["totalDPS"], 0.001, "two of three drones fit the bandwidth")
+
+	// The skill is trained to level five
+	assert.InDelta(t, 375, s["maxVelocity"], 0.001)
+	assert.InDelta(t, 375*6, s["maxVelocityMWD"], 0.001)
+	assert.InDelta(t, 40, s["signatureRadius"], 0.001)
+	assert.InDelta(t, 240, s["signatureRadiusMWD"], 0.001)
+
+	assert.Equal(t, 60.0, s["stasisWebifierStrength"])
+	assert.Equal(t, 10.0, s["scanRadarStrength"])
+	assert.Equal(t, 280.0, s["cpuRemaining"])
+	assert.Equal(t, 900.0, s["powerRemaining"])
+
+	assert.Equal(t, 1.0, s["capacitorFraction"])
+	root := (1 + math.Sqrt(0.6)) / 2
+	assert.InDelta(t, root*root, s["capacitorFractionMWD"], 0.0001)
+	assert.Equal(t, 0.0, s["capacitorDurationMWD"])
+}
+
+func TestCapacitorUnstable(t *testing.T) {
+	d := testData()
+	d.Types[testMWD].Attributes[attrCapacitorNeed] = 1000
+
+	a, err := d.Evaluate(&Fit{ShipTypeID: testShip, Modules: []Module{{TypeID: testMWD}}})
+	assert.Nil(t, err)
+	assert.Equal(t, 0.0, a.Ship["capacitorFractionMWD"])
+	assert.True(t, a.Ship["capacitorDurationMWD"] > 0)
+	assert.True(t, a.Ship["capacitorDurationMWD"] < 20*capacitorDurationScale)
+}
+
+func TestApplyFactors(t *testing.T) {
+	assert.Equal(t, 10.0, applyFactors(10, nil))
+	assert.InDelta(t, 20, applyFactors(10, []factor{{2, false}}), 0.001)
+
+	// Strongest bonus goes first at full strength
+	v := applyFactors(10, []factor{{1.1, true}, {1.5, true}, {0.5, true}})
+	assert.InDelta(t, 10*1.5*(1+0.1*stackingPenalty(1))*0.5, v, 0.001)
+}
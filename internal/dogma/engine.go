@@ -0,0 +1,368 @@
+package dogma
+
+import (
+	"math"
+	"sort"
+)
+
+// Item states, deciding which effect categories apply
+const (
+	statePassive = iota
+	stateOnline
+	stateActive
+)
+
+// skillLevel every skill is evaluated at, as we cannot see the victim's skills
+const skillLevel = 5
+
+// item is an instance of a type within an evaluation
+type item struct {
+	t         *Type
+	state     int
+	other     *item // The charge of a module or the module of a charge
+	located   bool  // Fitted to the ship
+	owned     bool  // Owned by the character
+	overrides map[int32]float64
+	incoming  map[int32][]modification
+	values    map[int32]float64
+	busy      map[int32]bool
+}
+
+// modification of an attribute by an attribute of another item
+type modification struct {
+	source    *item
+	attribute int32
+	operator  int
+}
+
+// evaluation of a fit in one state
+type evaluation struct {
+	d        *Data
+	char     *item
+	ship     *item
+	items    []*item
+	modules  []*item
+	drones   []*item
+	quantity map[*item]int32
+}
+
+// evaluate a fit with propulsion modules either off or running
+func (d *Data) evaluate(fit *Fit, propulsion bool) *evaluation {
+	e := &evaluation{d: d, quantity: make(map[*item]int32)}
+
+	e.char = e.add(characterTypeID, statePassive)
+	e.ship = e.add(fit.ShipTypeID, statePassive)
+
+	for _, skill := range d.Skills {
+		if s := e.add(skill, statePassive); s != nil {
+			s.overrides[attrSkillLevel] = skillLevel
+		}
+	}
+
+	for _, implant := range fit.Implants {
+		e.add(implant, statePassive)
+	}
+
+	for _, m := range fit.Modules {
+		t := d.Types[m.TypeID]
+		if t == nil {
+			continue
+		}
+		module := e.add(m.TypeID, d.moduleState(t, propulsion))
+		module.located, module.owned = true, true
+		e.modules = append(e.modules, module)
+
+		if charge := e.add(m.ChargeTypeID, statePassive); charge != nil {
+			charge.located, charge.owned = true, true
+			charge.other, module.other = module, charge
+		}
+	}
+
+	for _, stack := range fit.Drones {
+		if drone := e.add(stack.TypeID, statePassive); drone != nil {
+			drone.owned = true
+			e.drones = append(e.drones, drone)
+			e.quantity[drone] = stack.Quantity
+		}
+	}
+
+	for _, it := range e.items {
+		e.applyEffects(it)
+	}
+
+	return e
+}
+
+// moduleState decides if a module is running. Propulsion only runs when asked and
+// cloaks are left off so they do not cripple the ship.
+func (d *Data) moduleState(t *Type, propulsion bool) int {
+	switch {
+	case t.GroupID == groupPropulsion && !propulsion:
+		return stateOnline
+	case t.GroupID == groupCloakingDevice:
+		return stateOnline
+	case d.hasEffectCategory(t, effectActive) || d.hasEffectCategory(t, effectTarget):
+		return stateActive
+	}
+	return stateOnline
+}
+
+// add an item of a type, returning nil for unknown types
+func (e *evaluation) add(typeID int32, state int) *item {
+	t := e.d.Types[typeID]
+	if t == nil {
+		if typeID != characterTypeID {
+			return nil
+		}
+		// Characters carry little, missing one only loses the default multipliers
+		t = &Type{ID: typeID, Attributes: make(map[int32]float64)}
+	}
+
+	it := &item{
+		t:         t,
+		state:     state,
+		overrides: make(map[int32]float64),
+		incoming:  make(map[int32][]modification),
+		values:    make(map[int32]float64),
+		busy:      make(map[int32]bool),
+	}
+	e.items = append(e.items, it)
+	return it
+}
+
+// applies checks if an effect category is active for the item's state
+func (it *item) applies(category int) bool {
+	switch category {
+	case effectPassive:
+		return true
+	case effectOnline:
+		return it.state >= stateOnline
+	case effectActive:
+		return it.state >= stateActive
+	}
+	return false
+}
+
+// requires checks if an item requires a skill
+func (it *item) requires(skill int32) bool {
+	for _, a := range requiredSkillAttributes {
+		if int32(it.t.Attributes[a]) == skill {
+			return true
+		}
+	}
+	return false
+}
+
+// applyEffects registers the modifiers of an item's effects on their targets
+func (e *evaluation) applyEffects(it *item) {
+	for _, id := range it.t.Effects {
+		effect := e.d.Effects[id]
+		if effect == nil || !it.applies(effect.Category) {
+			continue
+		}
+		for _, m := range effect.Modifiers {
+			for _, target := range e.targets(it, m) {
+				target.incoming[m.ModifiedAttributeID] = append(target.incoming[m.ModifiedAttributeID],
+					modification{source: it, attribute: m.ModifyingAttributeID, operator: m.Operator})
+			}
+		}
+	}
+}
+
+// targets finds the items a modifier applies to
+func (e *evaluation) targets(source *item, m Modifier) []*item {
+	skill := m.SkillTypeID
+	if skill == -1 {
+		skill = source.t.ID
+	}
+
+	switch m.Func {
+	case "ItemModifier":
+		var target *item
+		switch m.Domain {
+		case "itemID":
+			target = source
+		case "shipID":
+			target = e.ship
+		case "charID":
+			target = e.char
+		case "otherID":
+			target = source.other
+		}
+		if target != nil {
+			return []*item{target}
+		}
+	case "LocationModifier":
+		return e.filter(func(it *item) bool { return it.located })
+	case "LocationGroupModifier":
+		return e.filter(func(it *item) bool { return it.located && it.t.GroupID == m.GroupID })
+	case "LocationRequiredSkillModifier":
+		return e.filter(func(it *item) bool { return it.located && it.requires(skill) })
+	case "OwnerRequiredSkillModifier":
+		return e.filter(func(it *item) bool { return it.owned && it.requires(skill) })
+	}
+	return nil
+}
+
+func (e *evaluation) filter(f func(*item) bool) []*item {
+	items := []*item{}
+	for _, it := range e.items {
+		if f(it) {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// base value of an attribute before modification
+func (e *evaluation) base(it *item, attribute int32) float64 {
+	if v, ok := it.overrides[attribute]; ok {
+		return v
+	}
+	if v, ok := it.t.Attributes[attribute]; ok {
+		return v
+	}
+	if a := e.d.Attributes[attribute]; a != nil {
+		return a.DefaultValue
+	}
+	return 0
+}
+
+// value of an attribute after all modifications
+func (e *evaluation) value(it *item, attribute int32) float64 {
+	if v, ok := it.values[attribute]; ok {
+		return v
+	}
+
+	base := e.base(it, attribute)
+	if it.busy[attribute] {
+		// Attributes modifying themselves through a cycle keep their base value
+		return base
+	}
+	it.busy[attribute] = true
+	defer delete(it.busy, attribute)
+
+	v := e.modify(it, attribute, base)
+	it.values[attribute] = v
+	return v
+}
+
+// modifyingValue is the value a source contributes. Skill bonuses scale with level.
+func (e *evaluation) modifyingValue(source *item, attribute int32) float64 {
+	v := e.value(source, attribute)
+	if source.t.CategoryID == categorySkill && attribute != attrSkillLevel {
+		v *= e.value(source, attrSkillLevel)
+	}
+	return v
+}
+
+// factor is a multiplier which may be subject to stacking penalties
+type factor struct {
+	value     float64
+	penalized bool
+}
+
+// modify applies operators in order: assignments, pre multipliers, additions,
+// post multipliers then post assignments.
+func (e *evaluation) modify(it *item, attribute int32, v float64) float64 {
+	mods := it.incoming[attribute]
+	if len(mods) == 0 {
+		return v
+	}
+
+	stackable := true
+	if a := e.d.Attributes[attribute]; a != nil {
+		stackable = a.Stackable
+	}
+
+	var (
+		preAssign, postAssign []float64
+		pre, post             []factor
+		add                   float64
+	)
+
+	for _, m := range mods {
+		x := e.modifyingValue(m.source, m.attribute)
+		penalized := !stackable && e.penalized(m.source)
+
+		switch m.operator {
+		case opPreAssign:
+			preAssign = append(preAssign, x)
+		case opPreMul:
+			pre = append(pre, factor{x, penalized})
+		case opPreDiv:
+			if x != 0 {
+				pre = append(pre, factor{1 / x, penalized})
+			}
+		case opModAdd:
+			add += x
+		case opModSub:
+			add -= x
+		case opPostMul:
+			post = append(post, factor{x, penalized})
+		case opPostDiv:
+			if x != 0 {
+				post = append(post, factor{1 / x, penalized})
+			}
+		case opPostPercent:
+			post = append(post, factor{1 + x/100, penalized})
+		case opPostAssign:
+			postAssign = append(postAssign, x)
+		}
+	}
+
+	if len(preAssign) > 0 {
+		v = preAssign[len(preAssign)-1]
+	}
+	v = applyFactors(v, pre)
+	v += add
+	v = applyFactors(v, post)
+	if len(postAssign) > 0 {
+		v = postAssign[len(postAssign)-1]
+	}
+	return v
+}
+
+// penalized checks if modifications from a source are stacking penalized.
+// Ships, skills, implants, subsystems and the character are exempt.
+func (e *evaluation) penalized(source *item) bool {
+	if source == e.char {
+		return false
+	}
+	switch source.t.CategoryID {
+	case categoryShip, categorySkill, categoryImplant, categorySubsystem:
+		return false
+	}
+	return true
+}
+
+// stackingPenalty is the effectiveness of the nth strongest penalized modifier
+func stackingPenalty(n int) float64 {
+	return math.Exp(-math.Pow(float64(n)/2.67, 2))
+}
+
+// applyFactors multiplies a value, penalizing bonuses and maluses separately from strongest to weakest
+func applyFactors(v float64, factors []factor) float64 {
+	bonuses, maluses := []float64{}, []float64{}
+	for _, f := range factors {
+		switch {
+		case !f.penalized:
+			v *= f.value
+		case f.value > 1:
+			bonuses = append(bonuses, f.value)
+		case f.value < 1:
+			maluses = append(maluses, f.value)
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.Float64Slice(bonuses)))
+	sort.Float64s(maluses)
+
+	for i, f := range bonuses {
+		v *= 1 + (f-1)*stackingPenalty(i)
+	}
+	for i, f := range maluses {
+		v *= 1 + (f-1)*stackingPenalty(i)
+	}
+	return v
+}
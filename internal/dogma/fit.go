@@ -0,0 +1,80 @@
+package dogma
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/antihax/goesi/esi"
+)
+
+// ErrUnknownShip is returned when the ship is not in the dogma data
+var ErrUnknownShip = errors.New("unknown ship type")
+
+// Module fitted to a slot and the charge loaded into it
+type Module struct {
+	TypeID       int32
+	ChargeTypeID int32
+	Flag         int32
+}
+
+// Drone stack in the drone bay
+type Drone struct {
+	TypeID   int32
+	Quantity int32
+}
+
+// Fit is a ship and everything fitted to it
+type Fit struct {
+	ShipTypeID int32
+	Modules    []Module
+	Drones     []Drone
+	Implants   []int32
+}
+
+// isFittingFlag checks if a killmail flag is a module, rig or subsystem slot
+func isFittingFlag(flag int32) bool {
+	return (flag >= flagLowSlot0 && flag <= flagHighSlot7) ||
+		(flag >= flagRigSlot0 && flag <= flagRigSlot7) ||
+		(flag >= flagSubSystemSlot0 && flag <= flagSubSystemSlot7)
+}
+
+// FitFromKillmail rebuilds the victim's fit from the items on a killmail
+func (d *Data) FitFromKillmail(km *esi.GetKillmailsKillmailIdKillmailHashOk) (*Fit, error) {
+	if d.Types[km.Victim.ShipTypeId] == nil {
+		return nil, ErrUnknownShip
+	}
+
+	fit := &Fit{ShipTypeID: km.Victim.ShipTypeId}
+	charges := make(map[int32]int32)
+	drones := make(map[int32]int32)
+
+	for _, item := range km.Victim.Items {
+		t := d.Types[item.ItemTypeId]
+		if t == nil {
+			continue
+		}
+
+		switch {
+		case item.Flag == flagDroneBay && t.CategoryID == categoryDrone:
+			drones[item.ItemTypeId] += int32(item.QuantityDestroyed + item.QuantityDropped)
+		case item.Flag == flagImplant:
+			fit.Implants = append(fit.Implants, item.ItemTypeId)
+		case isFittingFlag(item.Flag) && t.CategoryID == categoryCharge:
+			charges[item.Flag] = item.ItemTypeId
+		case isFittingFlag(item.Flag):
+			fit.Modules = append(fit.Modules, Module{TypeID: item.ItemTypeId, Flag: item.Flag})
+		}
+	}
+
+	// Load charges into the modules sharing their slot
+	for i := range fit.Modules {
+		fit.Modules[i].ChargeTypeID = charges[fit.Modules[i].Flag]
+	}
+
+	for typeID, quantity := range drones {
+		fit.Drones = append(fit.Drones, Drone{TypeID: typeID, Quantity: quantity})
+	}
+	sort.Slice(fit.Drones, func(i, j int) bool { return fit.Drones[i].TypeID < fit.Drones[j].TypeID })
+
+	return fit, nil
+}
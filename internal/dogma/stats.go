@@ -0,0 +1,255 @@
+package dogma
+
+import "math"
+
+// Attributes of an evaluated fit, keyed the same as the axiom service so
+// consumers can use either source.
+type Attributes struct {
+	TypeID  int32                `json:"typeID"`
+	Ship    map[string]float64   `json:"ship"`
+	Modules []map[string]float64 `json:"modules"`
+	Drones  []map[string]float64 `json:"drones"`
+}
+
+// maxCapacitorSimulation caps how long an unstable capacitor is simulated in seconds
+const maxCapacitorSimulation = 24 * 60 * 60
+
+// capacitorDurationScale converts seconds to the capacitorDuration units axiom reports
+const capacitorDurationScale = 10000
+
+// Evaluate a fit, once with propulsion modules off and once with them running
+func (d *Data) Evaluate(fit *Fit) (*Attributes, error) {
+	if d.Types[fit.ShipTypeID] == nil {
+		return nil, ErrUnknownShip
+	}
+
+	idle := d.evaluate(fit, false)
+	prop := d.evaluate(fit, true)
+
+	a := &Attributes{
+		TypeID:  fit.ShipTypeID,
+		Ship:    make(map[string]float64),
+		Modules: []map[string]float64{},
+		Drones:  []map[string]float64{},
+	}
+
+	ship := idle.ship
+	a.Ship["avgEHP"] = idle.ehp()
+	a.Ship["totalDPS"], a.Ship["totalAlphaDamage"] = idle.damage()
+	a.Ship["scanResolution"] = idle.value(ship, attrScanResolution)
+	a.Ship["signatureRadius"] = idle.value(ship, attrSignatureRadius)
+	a.Ship["signatureRadiusMWD"] = prop.value(prop.ship, attrSignatureRadius)
+	a.Ship["agility"] = idle.value(ship, attrAgility)
+	a.Ship["warpSpeedMultiplier"] = idle.value(ship, attrWarpSpeedMultiplier)
+	a.Ship["maxVelocity"] = idle.value(ship, attrMaxVelocity)
+	a.Ship["maxVelocityMWD"] = prop.velocity()
+
+	a.Ship["scanRadarStrength"] = idle.value(ship, attrScanRadarStrength)
+	a.Ship["scanLadarStrength"] = idle.value(ship, attrScanLadarStrength)
+	a.Ship["scanMagnetometricStrength"] = idle.value(ship, attrScanMagnetometric)
+	a.Ship["scanGravimetricStrength"] = idle.value(ship, attrScanGravimetric)
+
+	a.Ship["remoteArmorRepairPerSecond"] = idle.perSecond(effectTarget, attrArmorDamageAmount)
+	a.Ship["remoteShieldBonusAmountPerSecond"] = idle.perSecond(effectTarget, attrShieldBonus)
+	a.Ship["remotePowerTransferAmountPerSecond"] = idle.perSecond(effectTarget, attrPowerTransferAmount)
+	a.Ship["energyNeutralizerAmountPerSecond"] = idle.perSecond(effectTarget, attrEnergyNeutralizerAmount)
+	a.Ship["avgRPS"] = idle.perSecond(effectActive, attrArmorDamageAmount) +
+		idle.perSecond(effectActive, attrShieldBonus) +
+		idle.perSecond(effectActive, attrStructureDamageAmount)
+
+	a.Ship["cpuRemaining"] = idle.value(ship, attrCPUOutput) - idle.sumModules(attrCPU)
+	a.Ship["powerRemaining"] = idle.value(ship, attrPowerOutput) - idle.sumModules(attrPower)
+
+	a.Ship["capacitorFraction"], a.Ship["capacitorDuration"] = idle.capacitor()
+	a.Ship["capacitorFractionMWD"], a.Ship["capacitorDurationMWD"] = prop.capacitor()
+
+	for _, m := range idle.modules {
+		if m.t.GroupID == groupStasisWeb {
+			a.Ship["stasisWebifierStrength"] -= idle.value(m, attrSpeedFactor)
+		}
+		if m.t.GroupID == groupWarpScrambler {
+			a.Ship["totalWarpScrambleStrength"] += idle.value(m, attrWarpScrambleStrength)
+		}
+	}
+
+	for _, m := range fit.Modules {
+		a.Modules = append(a.Modules, map[string]float64{
+			"typeID":   float64(m.TypeID),
+			"chargeID": float64(m.ChargeTypeID),
+		})
+	}
+	for _, drone := range fit.Drones {
+		a.Drones = append(a.Drones, map[string]float64{
+			"typeID":   float64(drone.TypeID),
+			"quantity": float64(drone.Quantity),
+		})
+	}
+
+	return a, nil
+}
+
+// ehp is the average effective hitpoints over all damage types
+func (e *evaluation) ehp() float64 {
+	layers := []struct {
+		hp         int32
+		resonances []int32
+	}{
+		{attrShieldCapacity, []int32{attrShieldEmResonance, attrShieldThermalResonance, attrShieldKineticResonance, attrShieldExplosiveResonance}},
+		{attrArmorHP, []int32{attrArmorEmResonance, attrArmorThermalResonance, attrArmorKineticResonance, attrArmorExplosiveResonance}},
+		{attrHP, []int32{attrEmDamageResonance, attrThermalDamageResonance, attrKineticDamageResonance, attrExplosiveDamageResonance}},
+	}
+
+	ehp := 0.0
+	for _, l := range layers {
+		resonance := 0.0
+		for _, r := range l.resonances {
+			resonance += e.value(e.ship, r)
+		}
+		resonance /= float64(len(l.resonances))
+		if resonance > 0 {
+			ehp += e.value(e.ship, l.hp) / resonance
+		}
+	}
+	return ehp
+}
+
+// chargeDamage is the total raw damage of an item
+func (e *evaluation) chargeDamage(it *item) float64 {
+	return e.value(it, attrEmDamage) + e.value(it, attrThermalDamage) +
+		e.value(it, attrKineticDamage) + e.value(it, attrExplosiveDamage)
+}
+
+// damage is the sustained damage per second and volley of weapons and launched drones
+func (e *evaluation) damage() (dps float64, alpha float64) {
+	for _, m := range e.modules {
+		if m.other == nil {
+			continue
+		}
+
+		var volley float64
+		switch {
+		case m.t.hasEffect(effectTurretFitted):
+			volley = e.chargeDamage(m.other) * e.value(m, attrDamageMultiplier)
+		case m.t.hasEffect(effectLauncherFitted):
+			volley = e.chargeDamage(m.other) * e.value(e.char, attrMissileDamageMultiplier)
+		default:
+			continue
+		}
+
+		alpha += volley
+		if rate := e.value(m, attrRateOfFire); rate > 0 {
+			dps += volley / (rate / 1000)
+		}
+	}
+
+	// Launch drones in bay order until bandwidth or the drone limit runs out
+	bandwidth := e.value(e.ship, attrDroneBandwidth)
+	launched := 0
+	for _, drone := range e.drones {
+		used := e.value(drone, attrDroneBandwidthUsed)
+		for i := int32(0); i < e.quantity[drone] && launched < maxActiveDrones; i++ {
+			if used > bandwidth {
+				break
+			}
+			bandwidth -= used
+			launched++
+
+			volley := e.chargeDamage(drone) * e.value(drone, attrDamageMultiplier)
+			alpha += volley
+			if rate := e.value(drone, attrRateOfFire); rate > 0 {
+				dps += volley / (rate / 1000)
+			}
+		}
+	}
+
+	return dps, alpha
+}
+
+// velocity with running propulsion modules applying their speed boost
+func (e *evaluation) velocity() float64 {
+	v := e.value(e.ship, attrMaxVelocity)
+	mass := e.value(e.ship, attrMass)
+	for _, m := range e.modules {
+		if m.t.GroupID == groupPropulsion && m.state == stateActive {
+			mass += e.value(m, attrMassAddition)
+		}
+	}
+	if mass <= 0 {
+		return v
+	}
+
+	for _, m := range e.modules {
+		if m.t.GroupID == groupPropulsion && m.state == stateActive {
+			v *= 1 + e.value(m, attrSpeedFactor)/100*e.value(m, attrSpeedBoostFactor)/mass
+		}
+	}
+	return v
+}
+
+// cycleTime of a running module in seconds
+func (e *evaluation) cycleTime(m *item) float64 {
+	return e.value(m, e.d.durationAttribute(m.t)) / 1000
+}
+
+// perSecond sums an attribute over running modules with an effect category, per second of cycle time
+func (e *evaluation) perSecond(category int, attribute int32) float64 {
+	total := 0.0
+	for _, m := range e.modules {
+		if m.state != stateActive || !e.d.hasEffectCategory(m.t, category) {
+			continue
+		}
+		if t := e.cycleTime(m); t > 0 {
+			total += e.value(m, attribute) / t
+		}
+	}
+	return total
+}
+
+// sumModules sums an attribute over all fitted modules
+func (e *evaluation) sumModules(attribute int32) float64 {
+	total := 0.0
+	for _, m := range e.modules {
+		total += e.value(m, attribute)
+	}
+	return total
+}
+
+// capacitor finds the stable capacitor fraction, or how long until it is empty.
+// Durations are in ten thousandths of a second, the scale axiom reports.
+func (e *evaluation) capacitor() (fraction float64, duration float64) {
+	capacity := e.value(e.ship, attrCapacitorCapacity)
+	tau := e.value(e.ship, attrRechargeRate) / 1000
+	if capacity <= 0 || tau <= 0 {
+		return 0, 0
+	}
+
+	usage := 0.0
+	for _, m := range e.modules {
+		if m.state != stateActive {
+			continue
+		}
+		if t := e.cycleTime(m); t > 0 {
+			usage += e.value(m, attrCapacitorNeed) / t
+		}
+	}
+	if usage <= 0 {
+		return 1, 0
+	}
+
+	// Recharge is 10C/tau * (sqrt(x) - x), peaking at 25%
+	k := usage * tau / (10 * capacity)
+	if k <= 0.25 {
+		s := (1 + math.Sqrt(1-4*k)) / 2
+		return s * s, 0
+	}
+
+	c := capacity
+	for t := 1; t <= maxCapacitorSimulation; t++ {
+		x := c / capacity
+		c += 10*capacity/tau*(math.Sqrt(x)-x) - usage
+		if c <= 0 {
+			return 0, float64(t) * capacitorDurationScale
+		}
+	}
+	return 0, maxCapacitorSimulation * capacitorDurationScale
+}
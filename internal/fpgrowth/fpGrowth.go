@@ -1,5 +1,7 @@
 package fpgrowth
 
+import "sort"
+
 type ItemSet map[int][]int
 
 type Pattern struct {
@@ -23,9 +25,19 @@ type FPTree struct {
 	Root                    *FPNode
 	HeaderTable             map[int]*FPNode
 	MinimumSupportThreshold uint
+	MaximumPatternLength    int // Longest pattern to grow, zero for no limit
 }
 
 func NewFPTree(transactions ItemSet, minimumSupportThreshold uint) *FPTree {
+	paths := make([]Pattern, 0, len(transactions))
+	for _, t := range transactions {
+		paths = append(paths, Pattern{Items: t, Frequency: 1})
+	}
+	return newWeightedFPTree(paths, minimumSupportThreshold)
+}
+
+// newWeightedFPTree builds a tree from paths which each stand for Frequency transactions
+func newWeightedFPTree(paths []Pattern, minimumSupportThreshold uint) *FPTree {
 	fp := &FPTree{
 		MinimumSupportThreshold: minimumSupportThreshold,
 		HeaderTable:             make(map[int]*FPNode),
@@ -34,9 +46,9 @@ func NewFPTree(transactions ItemSet, minimumSupportThreshold uint) *FPTree {
 
 	// Find support for each item
 	frequencyByItem := make(map[int]uint)
-	for _, t := range transactions {
-		for _, i := range t {
-			frequencyByItem[i]++
+	for _, p := range paths {
+		for _, i := range p.Items {
+			frequencyByItem[i] += p.Frequency
 		}
 	}
 
@@ -49,145 +61,141 @@ func NewFPTree(transactions ItemSet, minimumSupportThreshold uint) *FPTree {
 
 	// Sort decending by frequency by support
 	sortedFrequencies := rank(frequencyByItem)
+	order := make(map[int]int, len(sortedFrequencies))
+	for i, freq := range sortedFrequencies {
+		order[freq.Item] = i
+	}
 
 	// Construct the FP-Tree
-	for _, t := range transactions { // Look through all transactions
-		currentNode := fp.Root
-		for _, freq := range sortedFrequencies { // and all item frequencies
-			for _, item := range t {
-				if freq.Item == item { // for matching items
-					found := false
-
-					// if the node exists, increase the frequency
-					for index := range currentNode.Children {
-						node := currentNode.Children[index]
-						if node.Item == item {
-							node.Frequency++
-							found = true
-
-							// and advance to the next node
-							currentNode = node
-							break
-						}
-					}
-
-					// Otherwise add as a new child.
-					if found == false {
-						newChild := NewFPNode(item, currentNode)
-						currentNode.Children = append(currentNode.Children, newChild)
-
-						// and set the new node as current
-						currentNode = newChild
-
-						// Update the linked list
-						if fp.HeaderTable[item] != nil {
-							prev := fp.HeaderTable[item]
-							for prev.Link != nil {
-								prev = prev.Link
-							}
-							prev.Link = newChild
-						} else {
-							fp.HeaderTable[item] = newChild
-						}
-					}
-					break
-				}
-			}
-		}
+	for _, p := range paths {
+		fp.insert(orderItems(p.Items, order), p.Frequency)
 	}
 	return fp
 }
 
-func (fp *FPTree) Growth() []Pattern {
-	if fp.IsEmpty() {
-		return nil
+// orderItems returns the frequent items in a transaction in tree order, without duplicates
+func orderItems(items []int, order map[int]int) []int {
+	ordered := make([]int, 0, len(items))
+	seen := make(map[int]bool, len(items))
+	for _, item := range items {
+		if _, ok := order[item]; ok && !seen[item] {
+			seen[item] = true
+			ordered = append(ordered, item)
+		}
 	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return order[ordered[i]] < order[ordered[j]]
+	})
+	return ordered
+}
 
-	patterns := []Pattern{}
+// insert adds ordered items to the tree count times
+func (fp *FPTree) insert(items []int, count uint) {
+	currentNode := fp.Root
+	for _, item := range items {
+		found := false
+
+		// if the node exists, increase the frequency
+		for _, node := range currentNode.Children {
+			if node.Item == item {
+				node.Frequency += count
+				found = true
+
+				// and advance to the next node
+				currentNode = node
+				break
+			}
+		}
 
-	if containSinglePath(fp.Root) {
-		currentNode := fp.Root.Children[0]
-		for currentNode != nil {
-			p := Pattern{Items: []int{currentNode.Item}, Frequency: currentNode.Frequency}
-			patterns = append([]Pattern{p}, patterns...)
+		// Otherwise add as a new child.
+		if found == false {
+			newChild := NewFPNode(item, currentNode)
+			newChild.Frequency = count
+			currentNode.Children = append(currentNode.Children, newChild)
 
-			for _, p := range patterns {
-				p.Items = append([]int{currentNode.Item}, p.Items...)
-				p.Frequency = currentNode.Frequency
-				patterns = append([]Pattern{p}, patterns...)
-			}
+			// and set the new node as current
+			currentNode = newChild
 
-			if len(currentNode.Children) == 1 {
-				currentNode = currentNode.Children[0]
+			// Update the linked list
+			if fp.HeaderTable[item] != nil {
+				prev := fp.HeaderTable[item]
+				for prev.Link != nil {
+					prev = prev.Link
+				}
+				prev.Link = newChild
 			} else {
-				currentNode = nil
+				fp.HeaderTable[item] = newChild
 			}
 		}
-	} else {
-		patternChan := make(chan []Pattern)
-		count := 0
-		for currentItem, node := range fp.HeaderTable {
-			go fp.ProcessNode(patternChan, currentItem, node)
-			count++
-		}
-		for count > 0 {
-			patterns = append(patterns, <-patternChan...)
-			count--
-		}
 	}
-	return patterns
 }
 
-func (fp *FPTree) ProcessNode(patternChan chan []Pattern, currentItem int, node *FPNode) {
-	transactionID := 1
-	conditionalPatternBase := []Pattern{}
-	startingNode := node
-	for startingNode != nil {
-		currentNode := startingNode.Parent
-		if currentNode.Parent != nil {
-			transformedPrefixPath := Pattern{Frequency: startingNode.Frequency}
-			for currentNode.Parent != nil {
-				transformedPrefixPath.Items = append(transformedPrefixPath.Items, currentNode.Item)
-				currentNode = currentNode.Parent
-			}
-			if len(transformedPrefixPath.Items) > 1 {
-				conditionalPatternBase = append(conditionalPatternBase, transformedPrefixPath)
-			}
-		}
-		startingNode = startingNode.Link
+// Growth mines the tree for patterns of two or more items meeting the minimum support
+func (fp *FPTree) Growth() []Pattern {
+	if fp.IsEmpty() {
+		return nil
 	}
 
-	conditionalTransactions := ItemSet{}
-
-	for _, transformedPrefixPath := range conditionalPatternBase {
-		transaction := []int{}
-		transaction = append(transaction, transformedPrefixPath.Items...)
-
-		for i := uint(0); i < transformedPrefixPath.Frequency; i++ {
-			conditionalTransactions[transactionID] = transaction
-			transactionID++
+	patterns := []Pattern{}
+	patternChan := make(chan []Pattern)
+	for item, node := range fp.HeaderTable {
+		go func(item int, node *FPNode) {
+			found := []Pattern{}
+			fp.growItem(item, node, nil, &found)
+			patternChan <- found
+		}(item, node)
+	}
+	for range fp.HeaderTable {
+		for _, p := range <-patternChan {
+			if len(p.Items) > 1 {
+				patterns = append(patterns, p)
+			}
 		}
 	}
+	return patterns
+}
 
-	conditionalFPTree := NewFPTree(conditionalTransactions, fp.MinimumSupportThreshold)
-	conditionalPatterns := conditionalFPTree.Growth()
+// grow mines every item in a conditional tree
+func (fp *FPTree) grow(suffix []int, patterns *[]Pattern) {
+	for item, node := range fp.HeaderTable {
+		fp.growItem(item, node, suffix, patterns)
+	}
+}
 
-	currentFrequency := uint(0)
+// growItem adds the pattern of an item and the suffix, then mines its conditional tree
+func (fp *FPTree) growItem(item int, node *FPNode, suffix []int, patterns *[]Pattern) {
+	support := uint(0)
+	for n := node; n != nil; n = n.Link {
+		support += n.Frequency
+	}
+	if support < fp.MinimumSupportThreshold {
+		return
+	}
 
-	currentPatterns := []Pattern{}
-	fpNode := node
-	for fpNode != nil {
-		currentFrequency += fpNode.Frequency
-		fpNode = fpNode.Link
+	items := append([]int{item}, suffix...)
+	*patterns = append(*patterns, Pattern{Items: items, Frequency: support})
+	if fp.MaximumPatternLength > 0 && len(items) >= fp.MaximumPatternLength {
+		return
 	}
 
-	for _, pattern := range conditionalPatterns {
-		pattern.Items = append(pattern.Items, currentItem)
-		if len(pattern.Items) > 1 {
-			currentPatterns = append(currentPatterns, pattern)
+	conditional := newWeightedFPTree(fp.conditionalPatternBase(node), fp.MinimumSupportThreshold)
+	conditional.MaximumPatternLength = fp.MaximumPatternLength
+	conditional.grow(items, patterns)
+}
+
+// conditionalPatternBase returns the prefix paths leading to each node of an item
+func (fp *FPTree) conditionalPatternBase(node *FPNode) []Pattern {
+	base := []Pattern{}
+	for ; node != nil; node = node.Link {
+		path := Pattern{Frequency: node.Frequency}
+		for parent := node.Parent; parent != nil && parent.Parent != nil; parent = parent.Parent {
+			path.Items = append(path.Items, parent.Item)
+		}
+		if len(path.Items) > 0 {
+			base = append(base, path)
 		}
 	}
-	patternChan <- currentPatterns
+	return base
 }
 
 func (fp *FPTree) IsEmpty() bool {
@@ -200,12 +208,3 @@ func (fp *FPTree) IsEmpty() bool {
 func (fp *FPTree) HeaderTableSize() int {
 	return len(fp.HeaderTable)
 }
-
-func containSinglePath(fpnode *FPNode) bool {
-	if len(fpnode.Children) == 0 {
-		return true
-	} else if len(fpnode.Children) > 1 {
-		return false
-	}
-	return containSinglePath(fpnode.Children[0])
-}
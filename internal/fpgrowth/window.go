@@ -0,0 +1,199 @@
+package fpgrowth
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Options configure pattern mining
+type Options struct {
+	MinimumSupport uint // Times a pattern must occur
+	MinimumLength  int  // Shortest pattern to return
+	MaximumLength  int  // Longest pattern to return, zero for no limit
+}
+
+// Window mines patterns from a sliding window of transactions. Transactions are
+// kept in a tree ordered by item rather than frequency so they can be added as they
+// arrive and removed as they expire without rebuilding it.
+type Window struct {
+	Options         Options
+	Length          time.Duration // Transactions older than this expire
+	MaxTransactions int           // Oldest transactions expire beyond this, zero for no limit
+
+	lock         sync.Mutex
+	root         *FPNode
+	transactions map[int]*windowTransaction
+	queue        transactionQueue
+	cutoff       time.Time
+}
+
+type windowTransaction struct {
+	id    int
+	items []int
+	time  time.Time
+}
+
+// NewWindow creates a window keeping transactions for length
+func NewWindow(length time.Duration, options Options) *Window {
+	return &Window{
+		Options:      options,
+		Length:       length,
+		root:         NewFPNode(0, nil),
+		transactions: make(map[int]*windowTransaction),
+	}
+}
+
+// Add a transaction at time t. Returns false if it is already in the window or has expired.
+func (w *Window) Add(id int, items []int, t time.Time) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if _, ok := w.transactions[id]; ok || t.Before(w.cutoff) {
+		return false
+	}
+
+	items = canonicalItems(items)
+	tx := &windowTransaction{id: id, items: items, time: t}
+	w.transactions[id] = tx
+	heap.Push(&w.queue, tx)
+	w.insert(items)
+
+	for w.MaxTransactions > 0 && len(w.transactions) > w.MaxTransactions {
+		w.remove(heap.Pop(&w.queue).(*windowTransaction))
+	}
+	return true
+}
+
+// Expire removes transactions older than the window, returning how many were removed
+func (w *Window) Expire(now time.Time) int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.cutoff = now.Add(-w.Length)
+	removed := 0
+	for len(w.queue) > 0 && w.queue[0].time.Before(w.cutoff) {
+		w.remove(heap.Pop(&w.queue).(*windowTransaction))
+		removed++
+	}
+	return removed
+}
+
+// Len returns the number of transactions in the window
+func (w *Window) Len() int {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return len(w.transactions)
+}
+
+// Patterns mines the window for frequent patterns
+func (w *Window) Patterns() []Pattern {
+	w.lock.Lock()
+	paths := []Pattern{}
+	prefix := []int{}
+	for _, child := range w.root.Children {
+		collectPaths(child, prefix, &paths)
+	}
+	w.lock.Unlock()
+
+	fp := newWeightedFPTree(paths, w.Options.MinimumSupport)
+	fp.MaximumPatternLength = w.Options.MaximumLength
+
+	patterns := []Pattern{}
+	for _, p := range fp.Growth() {
+		if len(p.Items) >= w.Options.MinimumLength {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// collectPaths turns the tree back into weighted transactions. A node ends as many
+// transactions as its frequency exceeds that of its children.
+func collectPaths(node *FPNode, prefix []int, paths *[]Pattern) {
+	items := append(append([]int{}, prefix...), node.Item)
+
+	ending := node.Frequency
+	for _, child := range node.Children {
+		ending -= child.Frequency
+		collectPaths(child, items, paths)
+	}
+	if ending > 0 {
+		*paths = append(*paths, Pattern{Items: items, Frequency: ending})
+	}
+}
+
+// insert adds canonical items to the tree
+func (w *Window) insert(items []int) {
+	node := w.root
+	for _, item := range items {
+		var next *FPNode
+		for _, child := range node.Children {
+			if child.Item == item {
+				next = child
+				break
+			}
+		}
+		if next == nil {
+			next = NewFPNode(item, node)
+			next.Frequency = 0
+			node.Children = append(node.Children, next)
+		}
+		next.Frequency++
+		node = next
+	}
+}
+
+// remove takes a transaction out of the tree, pruning empty nodes
+func (w *Window) remove(tx *windowTransaction) {
+	delete(w.transactions, tx.id)
+
+	node := w.root
+	for _, item := range tx.items {
+		for i, child := range node.Children {
+			if child.Item != item {
+				continue
+			}
+			child.Frequency--
+			if child.Frequency == 0 {
+				node.Children = append(node.Children[:i], node.Children[i+1:]...)
+				return
+			}
+			node = child
+			break
+		}
+	}
+}
+
+// canonicalItems sorts and removes duplicates
+func canonicalItems(items []int) []int {
+	sorted := append([]int{}, items...)
+	sort.Ints(sorted)
+	out := sorted[:0]
+	for i, item := range sorted {
+		if i == 0 || item != sorted[i-1] {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// transactionQueue is a heap of transactions by time, oldest first
+type transactionQueue []*windowTransaction
+
+func (q transactionQueue) Len() int           { return len(q) }
+func (q transactionQueue) Less(i, j int) bool { return q[i].time.Before(q[j].time) }
+func (q transactionQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *transactionQueue) Push(x interface{}) {
+	*q = append(*q, x.(*windowTransaction))
+}
+
+func (q *transactionQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	tx := old[n-1]
+	*q = old[:n-1]
+	return tx
+}
@@ -0,0 +1,83 @@
+package fpgrowth
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pairs returns the frequency of two item patterns
+func pairs(patterns []Pattern) map[[2]int]uint {
+	out := make(map[[2]int]uint)
+	for _, p := range patterns {
+		if len(p.Items) == 2 {
+			items := append([]int{}, p.Items...)
+			sort.Ints(items)
+			out[[2]int{items[0], items[1]}] = p.Frequency
+		}
+	}
+	return out
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := NewWindow(time.Hour*24, Options{MinimumSupport: 2, MinimumLength: 2})
+
+	assert.True(t, w.Add(1, []int{1, 2, 3}, start))
+	assert.True(t, w.Add(2, []int{2, 1}, start.Add(time.Hour)))
+	assert.True(t, w.Add(3, []int{3, 4, 1}, start.Add(time.Hour*20)))
+	assert.False(t, w.Add(3, []int{3, 4, 1}, start.Add(time.Hour*20)))
+	assert.Equal(t, 3, w.Len())
+
+	p := pairs(w.Patterns())
+	assert.Equal(t, uint(2), p[[2]int{1, 2}])
+	assert.Equal(t, uint(2), p[[2]int{1, 3}])
+	assert.Equal(t, uint(0), p[[2]int{3, 4}])
+
+	// The first two expire
+	assert.Equal(t, 2, w.Expire(start.Add(time.Hour*26)))
+	assert.Equal(t, 1, w.Len())
+	assert.False(t, w.Add(4, []int{1, 2}, start))
+	assert.True(t, w.Add(5, []int{4, 3}, start.Add(time.Hour*22)))
+
+	p = pairs(w.Patterns())
+	assert.Equal(t, uint(0), p[[2]int{1, 2}])
+	assert.Equal(t, uint(2), p[[2]int{3, 4}])
+
+	// Everything expires and the tree is empty
+	w.Expire(start.Add(time.Hour * 100))
+	assert.Equal(t, 0, w.Len())
+	assert.Equal(t, 0, len(w.root.Children))
+}
+
+func TestWindowMaxTransactions(t *testing.T) {
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := NewWindow(time.Hour*24, Options{MinimumSupport: 1, MinimumLength: 2})
+	w.MaxTransactions = 2
+
+	w.Add(1, []int{1, 2}, start.Add(time.Hour))
+	w.Add(2, []int{1, 3}, start)
+	w.Add(3, []int{1, 4}, start.Add(time.Hour*2))
+	assert.Equal(t, 2, w.Len())
+
+	// The oldest is dropped, not the first added
+	p := pairs(w.Patterns())
+	assert.Equal(t, uint(1), p[[2]int{1, 2}])
+	assert.Equal(t, uint(0), p[[2]int{1, 3}])
+	assert.Equal(t, uint(1), p[[2]int{1, 4}])
+}
+
+func TestPatternLength(t *testing.T) {
+	w := NewWindow(time.Hour, Options{MinimumSupport: 2, MinimumLength: 2, MaximumLength: 2})
+	now := time.Now()
+	w.Add(1, []int{1, 2, 3}, now)
+	w.Add(2, []int{1, 2, 3}, now)
+	w.Add(3, []int{1, 2, 3, 4}, now)
+
+	for _, p := range w.Patterns() {
+		assert.Equal(t, 2, len(p.Items))
+	}
+	assert.Equal(t, 3, len(pairs(w.Patterns())))
+}
@@ -0,0 +1,154 @@
+//go:build ignore
+// +build ignore
+
+// gen builds helpers_gen.go from the hammer:operation annotations in payloads.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type field struct {
+	Name  string
+	Param string
+	Type  string
+}
+
+type operation struct {
+	Name    string
+	Version int
+	Payload string
+	Fields  []field
+}
+
+func main() {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "payloads.go", nil, parser.ParseComments)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var (
+		operations []operation
+		payloads   = make(map[string][]field)
+		order      []string
+	)
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE || gen.Doc == nil {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok {
+				continue
+			}
+
+			var fields []field
+			for _, fl := range st.Fields.List {
+				var typ bytes.Buffer
+				if err := printer.Fprint(&typ, fset, fl.Type); err != nil {
+					log.Fatalln(err)
+				}
+				for _, n := range fl.Names {
+					fields = append(fields, field{Name: n.Name, Param: lowerFirst(n.Name), Type: typ.String()})
+				}
+			}
+
+			for _, line := range strings.Split(gen.Doc.Text(), "\n") {
+				parts := strings.Fields(line)
+				if len(parts) != 3 || parts[0] != "hammer:operation" {
+					continue
+				}
+				version, err := strconv.Atoi(parts[2])
+				if err != nil {
+					log.Fatalf("%s: bad version %s\n", ts.Name.Name, parts[2])
+				}
+				if _, ok := payloads[ts.Name.Name]; !ok {
+					order = append(order, ts.Name.Name)
+				}
+				payloads[ts.Name.Name] = fields
+				operations = append(operations, operation{parts[1], version, ts.Name.Name, fields})
+			}
+		}
+	}
+
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Name < operations[j].Name })
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by go run gen.go; DO NOT EDIT.\n\n")
+	b.WriteString("package hammerwork\n\n")
+	b.WriteString("import (\n\"errors\"\n\n\"github.com/antihax/evedata/internal/redisqueue\"\n)\n\n")
+
+	b.WriteString("func init() {\n")
+	for _, o := range operations {
+		fmt.Fprintf(&b, "redisqueue.RegisterOperation(%q, %d, %s{})\n", o.Name, o.Version, o.Payload)
+	}
+	b.WriteString("}\n\n")
+
+	for _, name := range order {
+		fmt.Fprintf(&b, "// Validate checks all fields of the %s are set\n", name)
+		fmt.Fprintf(&b, "func (p %s) Validate() error {\n", name)
+		for _, fl := range payloads[name] {
+			zero := "0"
+			if fl.Type == "string" {
+				zero = `""`
+			}
+			fmt.Fprintf(&b, "if p.%s == %s {\nreturn errors.New(%q)\n}\n", fl.Name, zero, name+": "+fl.Name+" is required")
+		}
+		b.WriteString("return nil\n}\n\n")
+	}
+
+	for _, o := range operations {
+		var params, args, values []string
+		for _, fl := range o.Fields {
+			params = append(params, fl.Param+" "+fl.Type)
+			args = append(args, fl.Param)
+			values = append(values, fl.Name+": "+fl.Param)
+		}
+		fn := upperFirst(o.Name)
+
+		fmt.Fprintf(&b, "// %sWork returns %s work for hammer\n", fn, o.Name)
+		fmt.Fprintf(&b, "func %sWork(%s) redisqueue.Work {\n", fn, strings.Join(params, ", "))
+		fmt.Fprintf(&b, "return redisqueue.Work{Operation: %q, Parameter: %s{%s}}\n}\n\n", o.Name, o.Payload, strings.Join(values, ", "))
+
+		fmt.Fprintf(&b, "// Queue%s queues %s work for hammer\n", fn, o.Name)
+		fmt.Fprintf(&b, "func Queue%s(%s) error {\n", fn, strings.Join(append([]string{"q Queuer", "priority int"}, params...), ", "))
+		fmt.Fprintf(&b, "return q.QueueWork([]redisqueue.Work{%sWork(%s)}, priority)\n}\n\n", fn, strings.Join(args, ", "))
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if err := ioutil.WriteFile("helpers_gen.go", src, 0644); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func lowerFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+func upperFirst(s string) string {
+	r := []rune(s)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
@@ -0,0 +1,8 @@
+package hammerwork
+
+import "github.com/antihax/evedata/internal/redisqueue"
+
+// Queuer accepts work for hammer such as a RedisQueue or a service wrapping one.
+type Queuer interface {
+	QueueWork(work []redisqueue.Work, priority int) error
+}
@@ -0,0 +1,138 @@
+// Package hammerwork defines the typed payloads for work queued to hammer.
+// Each payload is registered against its operations with redisqueue so bad
+// work is rejected when it is queued rather than when it is consumed.
+//
+// Payload structs are annotated with one or more lines of the form
+//
+//	hammer:operation <name> <version>
+//
+// and `go generate` builds the helpers in helpers_gen.go from them.
+// Bump the version whenever the fields of a payload change.
+package hammerwork
+
+//go:generate go run gen.go
+
+// CharacterToken identifies a character and the token used to access it
+//
+//	hammer:operation characterAssets 1
+//	hammer:operation characterAuthOwner 1
+//	hammer:operation characterNotifications 1
+//	hammer:operation characterOrders 1
+//	hammer:operation characterWalletTransactions 1
+//	hammer:operation characterWalletJournal 1
+type CharacterToken struct {
+	CharacterID      int32
+	TokenCharacterID int32
+}
+
+// CharacterStructure identifies a structure visible to a character token
+//
+//	hammer:operation characterStructures 1
+//	hammer:operation characterStructureMarket 1
+type CharacterStructure struct {
+	CharacterID      int32
+	TokenCharacterID int32
+	StructureID      int64
+}
+
+// AllianceContacts pulls an alliance contact list through a character token
+//
+//	hammer:operation allianceContacts 1
+type AllianceContacts struct {
+	CharacterID      int32
+	TokenCharacterID int32
+	AllianceID       int32
+}
+
+// CorporationContacts pulls a corporation contact list through a character token
+//
+//	hammer:operation corporationContacts 1
+type CorporationContacts struct {
+	CharacterID      int32
+	TokenCharacterID int32
+	CorporationID    int32
+}
+
+// CharacterContactSync copies war contacts from a source character to destinations.
+// Destinations is a comma separated list of character IDs.
+//
+//	hammer:operation characterContactSync 1
+type CharacterContactSync struct {
+	CharacterID  int32
+	Source       int32
+	Destinations string
+}
+
+// Killmail identifies a killmail to pull
+//
+//	hammer:operation killmail 1
+type Killmail struct {
+	Hash       string
+	KillmailID int32
+}
+
+// MutatedItem identifies a dynamic item to resolve
+//
+//	hammer:operation mutatedItem 1
+type MutatedItem struct {
+	ItemID int64
+	TypeID int32
+}
+
+// MarketHistory identifies an item history in a region
+//
+//	hammer:operation marketHistory 1
+type MarketHistory struct {
+	RegionID int32
+	TypeID   int32
+}
+
+// MarketHistoryTrigger starts a pull of all market history
+//
+//	hammer:operation marketHistoryTrigger 1
+type MarketHistoryTrigger struct{}
+
+// Structure identifies a public structure
+//
+//	hammer:operation structure 1
+type Structure struct {
+	StructureID int64
+}
+
+// CharacterSearch looks up a character by name
+//
+//	hammer:operation charSearch 1
+type CharacterSearch struct {
+	Name string
+}
+
+// Alliance identifies an alliance
+//
+//	hammer:operation alliance 1
+type Alliance struct {
+	AllianceID int32
+}
+
+// Corporation identifies a corporation
+//
+//	hammer:operation corporation 1
+//	hammer:operation allianceHistory 1
+//	hammer:operation loyaltyStore 1
+type Corporation struct {
+	CorporationID int32
+}
+
+// Character identifies a character
+//
+//	hammer:operation character 1
+//	hammer:operation corporationHistory 1
+type Character struct {
+	CharacterID int32
+}
+
+// War identifies a war
+//
+//	hammer:operation war 1
+type War struct {
+	WarID int32
+}
@@ -0,0 +1,135 @@
+// Package killfilter parses and evaluates killmail filter expressions such as
+//
+//	loss and region = 10000060 and group in (30, 485, 547, 659, 883, 1538)
+//	solo and value > 1b
+//
+// Expressions combine comparisons with and, or, not and parentheses.
+// Numbers may use k, m and b suffixes for thousands, millions and billions.
+package killfilter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MaxLength is the longest expression accepted
+const MaxLength = 1000
+
+// Kill contains the facts about a killmail that filters are evaluated against
+type Kill struct {
+	RegionID      int32
+	SolarSystemID int32
+	Security      float64
+	ShipTypeID    int32
+	ShipGroupID   int32
+	Value         float64
+
+	// Attackers is the number of player attackers
+	Attackers int
+
+	// Character, corporation, alliance and faction IDs of the victim and attackers
+	VictimEntities   []int32
+	AttackerEntities []int32
+
+	// Loss is true when the victim belongs to the channel owner, Kill when an attacker does
+	Loss bool
+	Kill bool
+}
+
+// FieldType describes how a field can be compared
+type FieldType string
+
+const (
+	// Number fields support all comparisons and in
+	Number FieldType = "number"
+	// Entity fields match if any involved ID is equal, and support =, != and in
+	Entity FieldType = "entity"
+	// Flag fields are used on their own
+	Flag FieldType = "flag"
+)
+
+// Field describes a field that can be used in an expression
+type Field struct {
+	Name        string    `json:"name"`
+	Type        FieldType `json:"type"`
+	Description string    `json:"description"`
+
+	number func(k *Kill) float64
+	entity func(k *Kill) []int32
+	flag   func(k *Kill) bool
+}
+
+var fields = []Field{
+	{Name: "region", Type: Number, Description: "Region ID of the kill", number: func(k *Kill) float64 { return float64(k.RegionID) }},
+	{Name: "system", Type: Number, Description: "Solar system ID of the kill", number: func(k *Kill) float64 { return float64(k.SolarSystemID) }},
+	{Name: "security", Type: Number, Description: "Security status of the solar system", number: func(k *Kill) float64 { return k.Security }},
+	{Name: "ship", Type: Number, Description: "Type ID of the victim ship", number: func(k *Kill) float64 { return float64(k.ShipTypeID) }},
+	{Name: "group", Type: Number, Description: "Group ID of the victim ship", number: func(k *Kill) float64 { return float64(k.ShipGroupID) }},
+	{Name: "value", Type: Number, Description: "Estimated ISK value of the ship and items", number: func(k *Kill) float64 { return k.Value }},
+	{Name: "attackers", Type: Number, Description: "Number of player attackers", number: func(k *Kill) float64 { return float64(k.Attackers) }},
+	{Name: "victim", Type: Entity, Description: "Character, corporation, alliance or faction ID of the victim", entity: func(k *Kill) []int32 { return k.VictimEntities }},
+	{Name: "attacker", Type: Entity, Description: "Character, corporation, alliance or faction ID of any attacker", entity: func(k *Kill) []int32 { return k.AttackerEntities }},
+	{Name: "involved", Type: Entity, Description: "Character, corporation, alliance or faction ID of the victim or any attacker", entity: func(k *Kill) []int32 {
+		return append(append([]int32{}, k.VictimEntities...), k.AttackerEntities...)
+	}},
+	{Name: "solo", Type: Flag, Description: "Killed by a single player", flag: func(k *Kill) bool { return k.Attackers == 1 }},
+	{Name: "gang", Type: Flag, Description: "Killed by more than one player", flag: func(k *Kill) bool { return k.Attackers > 1 }},
+	{Name: "loss", Type: Flag, Description: "The victim belongs to the channel owner", flag: func(k *Kill) bool { return k.Loss }},
+	{Name: "kill", Type: Flag, Description: "An attacker belongs to the channel owner", flag: func(k *Kill) bool { return k.Kill }},
+}
+
+// Fields returns the fields available to expressions
+func Fields() []Field {
+	return append([]Field{}, fields...)
+}
+
+func lookupField(name string) (*Field, bool) {
+	for i := range fields {
+		if fields[i].Name == name {
+			return &fields[i], true
+		}
+	}
+	return nil, false
+}
+
+// Filter is a compiled expression
+type Filter struct {
+	expression string
+	root       node
+}
+
+// Parse compiles an expression into a Filter
+func Parse(expression string) (*Filter, error) {
+	if len(expression) > MaxLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", MaxLength)
+	}
+	if strings.TrimSpace(expression) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	tokens, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+	}
+
+	return &Filter{expression: expression, root: root}, nil
+}
+
+// Match returns true if the kill matches the filter
+func (f *Filter) Match(k *Kill) bool {
+	return f.root.match(k)
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	return f.expression
+}
@@ -0,0 +1,103 @@
+package killfilter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	capitalLoss := &Kill{
+		RegionID:         10000060,
+		SolarSystemID:    30004759,
+		Security:         -0.4,
+		ShipTypeID:       23757,
+		ShipGroupID:      547,
+		Value:            2.5e9,
+		Attackers:        40,
+		VictimEntities:   []int32{90000001, 98000001, 99000001},
+		AttackerEntities: []int32{90000002, 98000002, 99000002, 90000003, 98000002, 99000002},
+		Loss:             true,
+	}
+
+	soloKill := &Kill{
+		RegionID:         10000002,
+		SolarSystemID:    30000142,
+		Security:         0.9,
+		ShipTypeID:       17738,
+		ShipGroupID:      27,
+		Value:            1.2e9,
+		Attackers:        1,
+		VictimEntities:   []int32{90000004, 98000004},
+		AttackerEntities: []int32{90000001, 98000001, 99000001},
+		Kill:             true,
+	}
+
+	tests := []struct {
+		expression string
+		capital    bool
+		solo       bool
+	}{
+		{"loss and region = 10000060 and group in (30, 485, 547, 659, 883, 1538)", true, false},
+		{"solo and value > 1b", false, true},
+		{"solo and value > 1.5b", false, false},
+		{"value >= 2500m", true, false},
+		{"gang", true, false},
+		{"not gang", false, true},
+		{"attackers <= 1", false, true},
+		{"security < 0", true, false},
+		{"security >= 0.5", false, true},
+		{"system == 30000142 or system = 30004759", true, true},
+		{"victim = 99000001", true, false},
+		{"attacker = 99000001", false, true},
+		{"involved = 99000001", true, true},
+		{"involved in (99000002, 98000004)", true, true},
+		{"victim != 99000001", false, true},
+		{"kill and (ship = 17738 or ship = 1)", false, true},
+		{"not (loss or kill)", false, false},
+		{"LOSS AND Region = 10000060", true, false},
+		{"value > 500k and value < 2000M", false, true},
+	}
+
+	for _, test := range tests {
+		f, err := Parse(test.expression)
+		if !assert.Nil(t, err, test.expression) {
+			continue
+		}
+		assert.Equal(t, test.capital, f.Match(capitalLoss), test.expression)
+		assert.Equal(t, test.solo, f.Match(soloKill), test.expression)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"   ",
+		"region",
+		"region =",
+		"region = abc",
+		"unknown = 1",
+		"victim > 1",
+		"solo and",
+		"(solo",
+		"solo)",
+		"group in (1, 2",
+		"group in 1",
+		"group in (1 2)",
+		"value > 1bn",
+		"value > 1.2.3",
+		"!solo",
+		"solo & gang",
+		"solo gang",
+	} {
+		_, err := Parse(expression)
+		assert.NotNil(t, err, expression)
+	}
+
+	long := make([]byte, MaxLength+1)
+	for i := range long {
+		long[i] = ' '
+	}
+	_, err := Parse("solo" + string(long))
+	assert.NotNil(t, err)
+}
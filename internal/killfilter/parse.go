@@ -0,0 +1,341 @@
+package killfilter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenOperator
+	tokenOpen
+	tokenClose
+	tokenComma
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+// lex splits an expression into tokens
+func lex(expression string) ([]token, error) {
+	tokens := []token{}
+	r := []rune(expression)
+
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: i})
+			i++
+		case strings.ContainsRune("=!<>", c):
+			start := i
+			i++
+			if i < len(r) && r[i] == '=' {
+				i++
+			}
+			op := string(r[start:i])
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected ! at %d, use != or not", start)
+			}
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: start})
+		case unicode.IsDigit(c) || c == '-' || c == '.':
+			start := i
+			i++
+			for i < len(r) && (unicode.IsDigit(r[i]) || r[i] == '.') {
+				i++
+			}
+			text := string(r[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q at %d", text, start)
+			}
+
+			// Apply any k, m, b suffix
+			if i < len(r) {
+				switch unicode.ToLower(r[i]) {
+				case 'k':
+					value *= 1e3
+					i++
+				case 'm':
+					value *= 1e6
+					i++
+				case 'b':
+					value *= 1e9
+					i++
+				}
+			}
+			if i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i])) {
+				return nil, fmt.Errorf("bad number %q at %d", string(r[start:i+1]), start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(r[start:i]), value: value, pos: start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i]) || r[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: strings.ToLower(string(r[start:i])), pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, text: "end of expression", pos: len(r)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenIdent && t.text == word
+}
+
+func (p *parser) expect(kind tokenKind, what string) (token, error) {
+	t := p.next()
+	if t.kind != kind {
+		return t, fmt.Errorf("expected %s at %d, found %q", what, t.pos, t.text)
+	}
+	return t, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isKeyword("not") {
+		p.next()
+		n, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenOpen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenClose, ")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case tokenIdent:
+		return p.parseField(t)
+	}
+	return nil, fmt.Errorf("expected a field or ( at %d, found %q", t.pos, t.text)
+}
+
+func (p *parser) parseField(t token) (node, error) {
+	f, ok := lookupField(t.text)
+	if !ok {
+		return nil, fmt.Errorf("unknown field %q at %d", t.text, t.pos)
+	}
+
+	if f.Type == Flag {
+		return flagNode{f}, nil
+	}
+
+	// Lists
+	if p.isKeyword("in") {
+		p.next()
+		values, err := p.parseList()
+		if err != nil {
+			return nil, err
+		}
+		return inNode{f, values}, nil
+	}
+
+	op, err := p.expect(tokenOperator, "an operator after "+f.Name)
+	if err != nil {
+		return nil, err
+	}
+	if f.Type == Entity && op.text != "=" && op.text != "!=" {
+		return nil, fmt.Errorf("%s only supports =, != and in at %d", f.Name, op.pos)
+	}
+
+	value, err := p.expect(tokenNumber, "a number")
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{f, op.text, value.value}, nil
+}
+
+func (p *parser) parseList() ([]float64, error) {
+	if _, err := p.expect(tokenOpen, "("); err != nil {
+		return nil, err
+	}
+
+	values := []float64{}
+	for {
+		v, err := p.expect(tokenNumber, "a number")
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v.value)
+
+		t := p.next()
+		if t.kind == tokenClose {
+			return values, nil
+		}
+		if t.kind != tokenComma {
+			return nil, fmt.Errorf("expected , or ) at %d, found %q", t.pos, t.text)
+		}
+	}
+}
+
+type node interface {
+	match(k *Kill) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) match(k *Kill) bool { return n.left.match(k) && n.right.match(k) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) match(k *Kill) bool { return n.left.match(k) || n.right.match(k) }
+
+type notNode struct{ n node }
+
+func (n notNode) match(k *Kill) bool { return !n.n.match(k) }
+
+type flagNode struct{ f *Field }
+
+func (n flagNode) match(k *Kill) bool { return n.f.flag(k) }
+
+type compareNode struct {
+	f     *Field
+	op    string
+	value float64
+}
+
+func (n compareNode) match(k *Kill) bool {
+	if n.f.Type == Entity {
+		found := containsEntity(n.f.entity(k), n.value)
+		if n.op == "!=" {
+			return !found
+		}
+		return found
+	}
+
+	v := n.f.number(k)
+	switch n.op {
+	case "=":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "<":
+		return v < n.value
+	case "<=":
+		return v <= n.value
+	case ">":
+		return v > n.value
+	case ">=":
+		return v >= n.value
+	}
+	return false
+}
+
+type inNode struct {
+	f      *Field
+	values []float64
+}
+
+func (n inNode) match(k *Kill) bool {
+	if n.f.Type == Entity {
+		entities := n.f.entity(k)
+		for _, value := range n.values {
+			if containsEntity(entities, value) {
+				return true
+			}
+		}
+		return false
+	}
+
+	v := n.f.number(k)
+	for _, value := range n.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsEntity(entities []int32, value float64) bool {
+	for _, e := range entities {
+		if e != 0 && float64(e) == value {
+			return true
+		}
+	}
+	return false
+}
@@ -21,6 +21,33 @@ end
 assert(not isempty(action), 'ERR1: Action is missing')
 assert(not isempty(queueName), 'ERR2: Queue name is missing')
 
+-- Keys used for leasing work
+local processingName = queueName .. ':processing'
+local attemptsName = queueName .. ':attempts'
+local priorityName = queueName .. ':priority'
+local deadLetterName = queueName .. ':deadletter'
+local signalName = queueName .. ':signal'
+
+-- Forget the lease bookkeeping for an item
+local function forget(item)
+    redis.call('HDEL', attemptsName, item)
+    redis.call('HDEL', priorityName, item)
+end
+
+-- Return a failed item to the queue, or dead letter it when out of attempts
+local function fail(item, maxAttempts)
+    local attempts = redis.call('HINCRBY', attemptsName, item, 1)
+    local priority = redis.call('HGET', priorityName, item) or 0
+    if attempts >= maxAttempts then
+        forget(item)
+        redis.call('LPUSH', deadLetterName, item)
+        return 2
+    end
+    redis.call('ZADD', queueName, 'NX', priority, item)
+    redis.call('LPUSH', signalName, 1)
+    return 1
+end
+
 if action == 'push'
 then
      -- Define vars
@@ -30,8 +57,11 @@ then
     -- Making sure required fields are not nil
     assert(not isempty(item), 'ERR5: Item is missing')
 
-    -- Add item to queue
-    return redis.call('ZADD', queueName, 'NX', priority, item)
+    -- Add item to queue and wake any waiting consumers
+    local added = redis.call('ZADD', queueName, 'NX', priority, item)
+    redis.call('LPUSH', signalName, 1)
+    redis.call('LTRIM', signalName, 0, 1023)
+    return added
 elseif action == 'pop'
 then
     -- Retrieve items
@@ -44,6 +74,91 @@ then
         end
     end
 return nil;
+elseif action == 'lease'
+then
+    -- Define vars
+    local now = tonumber(ARGV[3]);
+    local deadline = tonumber(ARGV[4]);
+    local maxAttempts = tonumber(ARGV[5]);
+
+    assert(now and deadline and maxAttempts, 'ERR6: Lease times are missing')
+
+    -- Return expired leases to the queue
+    local expired = redis.call('ZRANGEBYSCORE', processingName, '-inf', now)
+    for _,item in ipairs(expired) do
+        redis.call('ZREM', processingName, item)
+        fail(item, maxAttempts)
+    end
+
+    -- Retrieve the item and lease it until the deadline
+    local popped = redis.call('ZREVRANGEBYSCORE', queueName, '+inf', '-inf', 'WITHSCORES', 'LIMIT', 0, '1')
+    if popped[1] then
+        local item = popped[1]
+        local priority = popped[2]
+        redis.call('ZREM', queueName, item)
+        redis.call('ZADD', processingName, deadline, item)
+        redis.call('HSET', priorityName, item, priority)
+        local attempts = redis.call('HGET', attemptsName, item) or 0
+        return {item, priority, attempts}
+    end
+return nil;
+elseif action == 'ack'
+then
+    local item = ARGV[3];
+    assert(not isempty(item), 'ERR5: Item is missing')
+
+    -- Remove the lease
+    local removed = redis.call('ZREM', processingName, item)
+    forget(item)
+    return removed
+elseif action == 'extend'
+then
+    local item = ARGV[3];
+    local deadline = tonumber(ARGV[4]);
+    assert(not isempty(item), 'ERR5: Item is missing')
+    assert(deadline, 'ERR6: Lease times are missing')
+
+    -- Only extend leases which have not expired and been returned
+    if not redis.call('ZSCORE', processingName, item) then
+        return 0
+    end
+    redis.call('ZADD', processingName, deadline, item)
+    return 1
+elseif action == 'nack'
+then
+    local item = ARGV[3];
+    local maxAttempts = tonumber(ARGV[4]);
+    assert(not isempty(item), 'ERR5: Item is missing')
+
+    -- Lease has already expired and been returned
+    if redis.call('ZREM', processingName, item) == 0 then
+        return 0
+    end
+    return fail(item, maxAttempts)
+elseif action == 'deadletter'
+then
+    local item = ARGV[3];
+    assert(not isempty(item), 'ERR5: Item is missing')
+
+    redis.call('ZREM', processingName, item)
+    forget(item)
+    return redis.call('LPUSH', deadLetterName, item)
+elseif action == 'replay'
+then
+    local priority = ARGV[3] or 100;
+    local count = 0
+
+    -- Move dead letters back onto the queue
+    local item = redis.call('RPOP', deadLetterName)
+    while item do
+        redis.call('ZADD', queueName, 'NX', priority, item)
+        count = count + 1
+        item = redis.call('RPOP', deadLetterName)
+    end
+    if count > 0 then
+        redis.call('LPUSH', signalName, 1)
+    end
+    return count
 elseif action == 'count'
 then
     -- Define vars
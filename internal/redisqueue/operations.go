@@ -0,0 +1,116 @@
+package redisqueue
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// ErrVersionMismatch is returned when queued work was encoded with a
+// different payload version than the one registered.
+var ErrVersionMismatch = errors.New("work payload version mismatch")
+
+// Validator is implemented by payloads that check their own values
+// before they are queued.
+type Validator interface {
+	Validate() error
+}
+
+type operation struct {
+	version int
+	payload reflect.Type
+}
+
+var (
+	operations   = make(map[string]operation)
+	operationsMu sync.RWMutex
+)
+
+// RegisterOperation declares the payload struct and version for an operation.
+// Work for registered operations is checked against the payload when queued,
+// and decoded back into the payload type when leased.
+func RegisterOperation(name string, version int, payload interface{}) {
+	t := reflect.TypeOf(payload)
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("redisqueue: payload for " + name + " must be a struct")
+	}
+
+	operationsMu.Lock()
+	defer operationsMu.Unlock()
+	if _, ok := operations[name]; ok {
+		panic("redisqueue: operation " + name + " registered twice")
+	}
+	operations[name] = operation{version: version, payload: t}
+}
+
+// OperationPayload returns the registered payload type for an operation, or nil.
+func OperationPayload(name string) reflect.Type {
+	operationsMu.RLock()
+	defer operationsMu.RUnlock()
+	return operations[name].payload
+}
+
+func lookupOperation(name string) (operation, bool) {
+	operationsMu.RLock()
+	defer operationsMu.RUnlock()
+	op, ok := operations[name]
+	return op, ok
+}
+
+// checkWork validates work against its registered payload and stamps the version.
+func checkWork(w *Work) error {
+	op, ok := lookupOperation(w.Operation)
+	if !ok {
+		return fmt.Errorf("unregistered operation %s", w.Operation)
+	}
+
+	if t := reflect.TypeOf(w.Parameter); t != op.payload {
+		return fmt.Errorf("operation %s expects %s, got %v", w.Operation, op.payload, t)
+	}
+
+	if v, ok := w.Parameter.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return fmt.Errorf("operation %s: %v", w.Operation, err)
+		}
+	}
+
+	w.Version = op.version
+	return nil
+}
+
+// wireWork holds the parameters undecoded until the payload type is known.
+type wireWork struct {
+	Operation string   `bson:"operation"`
+	Parameter bson.Raw `bson:"parameter"`
+	Version   int      `bson:"version"`
+}
+
+// decodeWork decodes work into its registered payload type.
+func decodeWork(b []byte, w *Work) error {
+	var raw wireWork
+	if err := bson.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	w.Operation = raw.Operation
+	w.Version = raw.Version
+
+	op, ok := lookupOperation(raw.Operation)
+	if !ok {
+		return fmt.Errorf("unregistered operation %s", raw.Operation)
+	}
+	if raw.Version != op.version {
+		return ErrVersionMismatch
+	}
+
+	p := reflect.New(op.payload)
+	if err := raw.Parameter.Unmarshal(p.Interface()); err != nil {
+		return err
+	}
+	w.Parameter = p.Elem().Interface()
+
+	return nil
+}
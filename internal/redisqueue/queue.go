@@ -1,6 +1,7 @@
 package redisqueue
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -20,24 +21,49 @@ const (
 	Priority_Urgent = iota
 )
 
+const (
+	// DefaultVisibilityTimeout is how long leased work is hidden before it returns to the queue
+	DefaultVisibilityTimeout = time.Minute * 5
+	// DefaultMaxAttempts is how many times work may fail before it is dead lettered
+	DefaultMaxAttempts = 5
+)
+
+// ErrLeaseExpired is returned when extending work which has already been returned to the queue
+var ErrLeaseExpired = errors.New("lease expired")
+
 // RedisQueue operation queue to CCP APIs
 type RedisQueue struct {
-	redisPool   *redis.Pool
-	key         string
-	queueScript *redis.Script
+	redisPool         *redis.Pool
+	key               string
+	queueScript       *redis.Script
+	visibilityTimeout time.Duration
+	maxAttempts       int
 }
 
 // Work to be performed
 type Work struct {
 	Operation string      `json:"operation"`
 	Parameter interface{} `json:"parameters"`
+	Version   int         `json:"version"`
+
+	// Number of times this work has previously failed
+	Attempts int `json:"-" bson:"-"`
+
+	// Raw item for acknowledging the lease
+	item []byte
 }
 
 // NewRedisQueue creates a new work queue with an existing
 // redigo pool and key name.
 func NewRedisQueue(r *redis.Pool, key string) *RedisQueue {
 
-	rq := &RedisQueue{redisPool: r, key: key, queueScript: redis.NewScript(0, priorityQueueScript)}
+	rq := &RedisQueue{
+		redisPool:         r,
+		key:               key,
+		queueScript:       redis.NewScript(0, priorityQueueScript),
+		visibilityTimeout: DefaultVisibilityTimeout,
+		maxAttempts:       DefaultMaxAttempts,
+	}
 	conn := r.Get()
 	defer conn.Close()
 
@@ -83,12 +109,20 @@ func (hq *RedisQueue) ScaledSize() (int, error) {
 	return low + (norm * 64) + (high * 1024) + (urg * 8192), nil
 }
 
-// QueueWork adds work to the queue
+// QueueWork adds work to the queue. All work must be for a registered
+// operation and carry a valid payload, otherwise nothing is queued.
 func (hq *RedisQueue) QueueWork(work []Work, priority int) error {
 	// Get a redis connection from the pool
 	conn := hq.redisPool.Get()
 	defer conn.Close()
 
+	// Reject bad payloads before anything is queued.
+	for i := range work {
+		if err := checkWork(&work[i]); err != nil {
+			return err
+		}
+	}
+
 	// Pipeline our work to the connection.
 	for i := range work {
 		b, err := gobcoder.GobEncoder(work[i])
@@ -103,44 +137,187 @@ func (hq *RedisQueue) QueueWork(work []Work, priority int) error {
 	return nil
 }
 
-// GetWork retreives items from the queue
-func (hq *RedisQueue) GetWork() (*Work, error) {
+// SetVisibilityTimeout changes how long leased work is hidden from other consumers
+// before it is returned to the queue.
+func (hq *RedisQueue) SetVisibilityTimeout(d time.Duration) {
+	hq.visibilityTimeout = d
+}
+
+// SetMaxAttempts changes how many times work may fail before it is dead lettered.
+func (hq *RedisQueue) SetMaxAttempts(attempts int) {
+	hq.maxAttempts = attempts
+}
+
+// GetWork leases an item from the queue, blocking until work is available or
+// the context ends. The work must be returned with Ack or Nack before the
+// visibility timeout, otherwise it is returned to the queue.
+func (hq *RedisQueue) GetWork(ctx context.Context) (*Work, error) {
 	// Get a redis connection from the pool
 	conn := hq.redisPool.Get()
 	defer conn.Close()
 
-	var (
-		w   Work
-		v   interface{}
-		err error
-	)
-
-	// Poll until we get data.
 	for {
-		v, err = hq.queueScript.Do(conn, "pop", hq.key)
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
-		if v == nil {
-			time.Sleep(time.Millisecond * 100)
-			continue
+
+		now := time.Now().UnixNano() / int64(time.Millisecond)
+		deadline := now + int64(hq.visibilityTimeout/time.Millisecond)
+		v, err := redis.Values(hq.queueScript.Do(conn, "lease", hq.key, now, deadline, hq.maxAttempts))
+		if err != nil && err != redis.ErrNil {
+			return nil, err
+		}
+
+		if len(v) == 3 {
+			return hq.decodeLease(v)
+		}
+
+		// Wait for a producer to signal new work.
+		if _, err := conn.Do("BRPOP", hq.key+":signal", 1); err != nil && err != redis.ErrNil {
+			return nil, err
 		}
-		break
 	}
+}
 
-	b, ok := v.([]byte)
-	if !ok {
+func (hq *RedisQueue) decodeLease(v []interface{}) (*Work, error) {
+	var w Work
+
+	b, err := redis.Bytes(v[0], nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
 		return nil, errors.New("empty result")
 	}
 
-	// Decode the data back into its structure
-	if err := gobcoder.GobDecoder(b, &w); err != nil {
+	attempts, err := redis.Int(v[2], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the data back into its payload
+	if err := decodeWork(b, &w); err != nil {
+		// Undecodable work can never succeed.
+		if dlErr := hq.deadLetter(b); dlErr != nil {
+			log.Println(dlErr)
+		}
 		return nil, err
 	}
 
+	w.item = b
+	w.Attempts = attempts
+
 	return &w, nil
 }
 
+// Ack completes leased work and removes it from the queue.
+func (hq *RedisQueue) Ack(w *Work) error {
+	if w.item == nil {
+		return errors.New("work is not leased")
+	}
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+
+	_, err := hq.queueScript.Do(conn, "ack", hq.key, w.item)
+	return err
+}
+
+// Extend the lease on work by the visibility timeout. Long running consumers
+// call this to keep the work from being returned to the queue.
+func (hq *RedisQueue) Extend(w *Work) error {
+	if w.item == nil {
+		return errors.New("work is not leased")
+	}
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+
+	deadline := time.Now().Add(hq.visibilityTimeout).UnixNano() / int64(time.Millisecond)
+	extended, err := redis.Int(hq.queueScript.Do(conn, "extend", hq.key, w.item, deadline))
+	if err != nil {
+		return err
+	}
+	if extended == 0 {
+		return ErrLeaseExpired
+	}
+	return nil
+}
+
+// VisibilityTimeout returns how long leased work is hidden before it returns to the queue
+func (hq *RedisQueue) VisibilityTimeout() time.Duration {
+	return hq.visibilityTimeout
+}
+
+// Nack fails leased work, returning it to the queue at its original priority.
+// Work that has failed too many times is moved to the dead letter list.
+func (hq *RedisQueue) Nack(w *Work) error {
+	if w.item == nil {
+		return errors.New("work is not leased")
+	}
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+
+	_, err := hq.queueScript.Do(conn, "nack", hq.key, w.item, hq.maxAttempts)
+	return err
+}
+
+// DeadLetter moves leased work straight to the dead letter list.
+func (hq *RedisQueue) DeadLetter(w *Work) error {
+	if w.item == nil {
+		return errors.New("work is not leased")
+	}
+	return hq.deadLetter(w.item)
+}
+
+func (hq *RedisQueue) deadLetter(item []byte) error {
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+
+	_, err := hq.queueScript.Do(conn, "deadletter", hq.key, item)
+	return err
+}
+
+// DeadLetterSize returns number of elements in the dead letter list
+func (hq *RedisQueue) DeadLetterSize() (int, error) {
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+	return redis.Int(conn.Do("LLEN", hq.key+":deadletter"))
+}
+
+// GetDeadLetters returns up to count items from the dead letter list, newest first,
+// without removing them.
+func (hq *RedisQueue) GetDeadLetters(count int) ([]Work, error) {
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+
+	items, err := redis.ByteSlices(conn.Do("LRANGE", hq.key+":deadletter", 0, count-1))
+	if err != nil {
+		return nil, err
+	}
+
+	work := []Work{}
+	for _, b := range items {
+		var w Work
+		if err := decodeWork(b, &w); err != nil {
+			// Fall back to the raw parameters so bad payloads can be inspected.
+			if err := gobcoder.GobDecoder(b, &w); err != nil {
+				log.Println(err)
+				continue
+			}
+		}
+		work = append(work, w)
+	}
+
+	return work, nil
+}
+
+// ReplayDeadLetters moves all dead lettered work back onto the queue with
+// the given priority and returns how many items were replayed.
+func (hq *RedisQueue) ReplayDeadLetters(priority int) (int, error) {
+	conn := hq.redisPool.Get()
+	defer conn.Close()
+	return redis.Int(hq.queueScript.Do(conn, "replay", hq.key, priority))
+}
+
 // CheckWorkCompleted takes a key and checks if the ID has been completed to prevent duplicates
 func (hq *RedisQueue) CheckWorkCompleted(key string, id interface{}) bool {
 	conn := hq.redisPool.Get()
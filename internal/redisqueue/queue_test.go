@@ -1,32 +1,40 @@
 package redisqueue
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/stretchr/testify/assert"
 )
 
+type testPayload struct {
+	ID int
+}
+
+func (p testPayload) Validate() error {
+	if p.ID == 0 {
+		return errors.New("ID is required")
+	}
+	return nil
+}
+
+func init() {
+	RegisterOperation("alliance", 1, testPayload{})
+}
+
 func TestHQ(t *testing.T) {
 	pool := redigohelper.ConnectRedisTestPool()
 	hq := NewRedisQueue(pool, "test-redisqueue")
 	err := hq.QueueWork(
 		[]Work{
-			{"alliance",
-				2,
-			},
-			{"alliance",
-				3,
-			},
-			{"alliance",
-				4,
-			},
-			{"alliance",
-				5,
-			},
-			{"alliance",
-				6,
-			},
+			{Operation: "alliance", Parameter: testPayload{2}},
+			{Operation: "alliance", Parameter: testPayload{3}},
+			{Operation: "alliance", Parameter: testPayload{4}},
+			{Operation: "alliance", Parameter: testPayload{5}},
+			{Operation: "alliance", Parameter: testPayload{6}},
 		},
 		Priority_High,
 	)
@@ -41,18 +49,133 @@ func TestHQ(t *testing.T) {
 
 	var work []*Work
 	for i := 0; i < 5; i++ {
-		w, err := hq.GetWork()
+		w, err := hq.GetWork(context.Background())
 		assert.Nil(t, err)
+		assert.Nil(t, hq.Ack(w))
 		work = append(work, w)
 	}
 	check := map[int]bool{2: true, 3: true, 4: true, 5: true, 6: true}
 
 	for i := range work {
-		delete(check, work[i].Parameter.(int))
+		delete(check, work[i].Parameter.(testPayload).ID)
 	}
 	assert.Empty(t, check)
 }
 
+func TestGetWorkContext(t *testing.T) {
+	pool := redigohelper.ConnectRedisTestPool()
+	hq := NewRedisQueue(pool, "test-redisqueue-context")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+	defer cancel()
+
+	w, err := hq.GetWork(ctx)
+	assert.Nil(t, w)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestNackDeadLetter(t *testing.T) {
+	pool := redigohelper.ConnectRedisTestPool()
+	hq := NewRedisQueue(pool, "test-redisqueue-nack")
+	hq.SetMaxAttempts(2)
+
+	err := hq.QueueWork([]Work{{Operation: "alliance", Parameter: testPayload{1}}}, Priority_High)
+	assert.Nil(t, err)
+
+	w, err := hq.GetWork(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, w.Attempts)
+	assert.Nil(t, hq.Nack(w))
+
+	w, err = hq.GetWork(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, w.Attempts)
+	assert.Nil(t, hq.Nack(w))
+
+	size, err := hq.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, size)
+
+	size, err = hq.DeadLetterSize()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, size)
+
+	dead, err := hq.GetDeadLetters(10)
+	assert.Nil(t, err)
+	assert.Equal(t, "alliance", dead[0].Operation)
+
+	replayed, err := hq.ReplayDeadLetters(Priority_Low)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, replayed)
+
+	size, err = hq.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, size)
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	pool := redigohelper.ConnectRedisTestPool()
+	hq := NewRedisQueue(pool, "test-redisqueue-visibility")
+	hq.SetVisibilityTimeout(time.Millisecond * 10)
+
+	err := hq.QueueWork([]Work{{Operation: "alliance", Parameter: testPayload{1}}}, Priority_High)
+	assert.Nil(t, err)
+
+	// Lease and abandon the work
+	_, err = hq.GetWork(context.Background())
+	assert.Nil(t, err)
+
+	time.Sleep(time.Millisecond * 20)
+
+	w, err := hq.GetWork(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, w.Attempts)
+	assert.Nil(t, hq.Ack(w))
+}
+
+func TestExtendLease(t *testing.T) {
+	pool := redigohelper.ConnectRedisTestPool()
+	hq := NewRedisQueue(pool, "test-redisqueue-extend")
+	hq.SetVisibilityTimeout(time.Millisecond * 50)
+
+	err := hq.QueueWork([]Work{{Operation: "alliance", Parameter: testPayload{1}}}, Priority_High)
+	assert.Nil(t, err)
+
+	w, err := hq.GetWork(context.Background())
+	assert.Nil(t, err)
+
+	// Keep the lease past the original deadline
+	time.Sleep(time.Millisecond * 30)
+	assert.Nil(t, hq.Extend(w))
+	time.Sleep(time.Millisecond * 30)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err = hq.GetWork(ctx)
+	assert.NotNil(t, err)
+
+	assert.Nil(t, hq.Ack(w))
+	assert.Equal(t, ErrLeaseExpired, hq.Extend(w))
+}
+
+func TestQueueBadPayload(t *testing.T) {
+	pool := redigohelper.ConnectRedisTestPool()
+	hq := NewRedisQueue(pool, "test-redisqueue-bad")
+
+	err := hq.QueueWork([]Work{{Operation: "alliance", Parameter: 1}}, Priority_High)
+	assert.NotNil(t, err)
+
+	err = hq.QueueWork([]Work{{Operation: "alliance", Parameter: testPayload{}}}, Priority_High)
+	assert.NotNil(t, err)
+
+	err = hq.QueueWork([]Work{{Operation: "nothing", Parameter: testPayload{1}}}, Priority_High)
+	assert.NotNil(t, err)
+
+	size, err := hq.Size()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, size)
+}
+
 func TestExpired(t *testing.T) {
 	pool := redigohelper.ConnectRedisTestPool()
 	hq := NewRedisQueue(pool, "test-redisqueue")
@@ -0,0 +1,66 @@
+package routeplanner
+
+import (
+	"math"
+	"time"
+)
+
+// LightYear in meters
+const LightYear = 9460730472580800.0
+
+const (
+	minimumFatigue    = time.Minute * 10
+	maximumFatigue    = time.Hour * 5
+	maximumActivation = time.Minute * 30
+)
+
+// lightYears between two systems
+func lightYears(a, b *System) float64 {
+	dx, dy, dz := a.X-b.X, a.Y-b.Y, a.Z-b.Z
+	return math.Sqrt(dx*dx+dy*dy+dz*dz) / LightYear
+}
+
+// jumpFatigue after a jump of the effective distance in light years, and the
+// jump activation timer before the next jump can be made.
+func jumpFatigue(fatigue time.Duration, distance float64) (time.Duration, time.Duration) {
+	if fatigue < minimumFatigue {
+		fatigue = minimumFatigue
+	}
+
+	fatigue = time.Duration(float64(fatigue) * (1 + distance))
+	if fatigue > maximumFatigue {
+		fatigue = maximumFatigue
+	}
+
+	activation := fatigue / 10
+	if activation > maximumActivation {
+		activation = maximumActivation
+	}
+
+	return fatigue.Round(time.Second), activation.Round(time.Second)
+}
+
+// jumpNeighbors returns the allowed systems within jump range. Jump drives cannot
+// be used in high security space or wormholes, and systems off the stargate
+// network cannot be reached.
+func (m *Map) jumpNeighbors(jumpRange float64, allowed func(*System) bool) func(*System) []*System {
+	candidates := []*System{}
+	for id, s := range m.systems {
+		if !HighSec(s.Security) && len(m.gates[id]) > 0 && allowed(s) {
+			candidates = append(candidates, s)
+		}
+	}
+
+	return func(s *System) []*System {
+		if HighSec(s.Security) {
+			return nil
+		}
+		neighbors := []*System{}
+		for _, n := range candidates {
+			if n != s && lightYears(s, n) <= jumpRange {
+				neighbors = append(neighbors, n)
+			}
+		}
+		return neighbors
+	}
+}
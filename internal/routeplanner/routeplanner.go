@@ -0,0 +1,269 @@
+// Package routeplanner finds routes through New Eden over stargates or by
+// jump drive, weighted by security preference, avoidance and recent kills.
+package routeplanner
+
+import (
+	"container/heap"
+	"errors"
+	"math"
+	"time"
+)
+
+// Mode is the security preference of a gate route
+type Mode int
+
+const (
+	// Shortest takes the fewest jumps
+	Shortest Mode = iota
+	// Safest stays in high security space where it can
+	Safest
+	// LessSecure stays out of high security space where it can
+	LessSecure
+)
+
+// securityPenalty is the extra cost, in jumps, of entering space the mode avoids.
+// It is large enough that any route through preferred space wins.
+const securityPenalty = 10000
+
+var (
+	// ErrUnknownSystem is returned when a system is not in the map
+	ErrUnknownSystem = errors.New("unknown solar system")
+	// ErrNoRoute is returned when no route exists with the options given
+	ErrNoRoute = errors.New("no route found")
+)
+
+// System is a solar system with its location in meters
+type System struct {
+	SolarSystemID   int32   `db:"solarSystemID"`
+	SolarSystemName string  `db:"solarSystemName"`
+	RegionID        int32   `db:"regionID"`
+	Security        float64 `db:"security"`
+	X               float64 `db:"x"`
+	Y               float64 `db:"y"`
+	Z               float64 `db:"z"`
+}
+
+// Options for a route
+type Options struct {
+	Mode         Mode
+	AvoidSystems []int32
+	AvoidRegions []int32
+
+	// Kills in each system recently, each costing KillPenalty extra jumps
+	Kills       map[int32]int
+	KillPenalty float64
+
+	// JumpRange in light years routes by jump drive instead of stargates.
+	// FatigueBonus is the fraction of distance ignored by jump fatigue, and
+	// Fatigue is the fatigue the pilot already has.
+	JumpRange    float64
+	FatigueBonus float64
+	Fatigue      time.Duration
+}
+
+// Hop is a system along a route. Distance, Wait and Fatigue are only set for jump drive routes.
+type Hop struct {
+	SolarSystemID   int32         `json:"solarSystemID"`
+	SolarSystemName string        `json:"solarSystemName"`
+	RegionID        int32         `json:"regionID"`
+	Security        float64       `json:"security"`
+	Kills           int           `json:"kills"`
+	Distance        float64       `json:"distance,omitempty"` // Light years from the previous system
+	Wait            time.Duration `json:"wait,omitempty"`     // Jump activation timer to wait out before jumping here
+	Fatigue         time.Duration `json:"fatigue,omitempty"`  // Jump fatigue after arriving
+}
+
+// Route from the first system to the last
+type Route struct {
+	Systems  []Hop         `json:"systems"`
+	Jumps    int           `json:"jumps"`
+	Distance float64       `json:"distance,omitempty"`
+	Wait     time.Duration `json:"wait,omitempty"`
+	Fatigue  time.Duration `json:"fatigue,omitempty"`
+}
+
+// Map of systems and the stargates between them. It is not changed after
+// creation so routes may be planned concurrently.
+type Map struct {
+	systems map[int32]*System
+	gates   map[int32][]int32
+}
+
+// NewMap creates a map from systems and the stargate connections of each
+func NewMap(systems []System, gates map[int32][]int32) *Map {
+	m := &Map{
+		systems: make(map[int32]*System),
+		gates:   gates,
+	}
+	for i := range systems {
+		m.systems[systems[i].SolarSystemID] = &systems[i]
+	}
+	return m
+}
+
+// Route finds the cheapest route between two systems
+func (m *Map) Route(from, to int32, o Options) (*Route, error) {
+	if m.systems[from] == nil || m.systems[to] == nil {
+		return nil, ErrUnknownSystem
+	}
+
+	avoidSystems := make(map[int32]bool)
+	for _, id := range o.AvoidSystems {
+		avoidSystems[id] = true
+	}
+	avoidRegions := make(map[int32]bool)
+	for _, id := range o.AvoidRegions {
+		avoidRegions[id] = true
+	}
+
+	// The start and destination are always allowed, as in game
+	allowed := func(s *System) bool {
+		if s.SolarSystemID == from || s.SolarSystemID == to {
+			return true
+		}
+		return !avoidSystems[s.SolarSystemID] && !avoidRegions[s.RegionID]
+	}
+
+	var path []int32
+	if o.JumpRange > 0 {
+		path = m.search(from, to, m.jumpNeighbors(o.JumpRange, allowed), func(a, b *System) float64 {
+			// Prefer shorter jumps between routes with the same number of jumps
+			return 1 + o.KillPenalty*float64(o.Kills[b.SolarSystemID]) + lightYears(a, b)/(o.JumpRange*1000)
+		})
+	} else {
+		path = m.search(from, to, m.gateNeighbors(allowed), func(a, b *System) float64 {
+			return 1 + o.KillPenalty*float64(o.Kills[b.SolarSystemID]) + o.Mode.penalty(b)
+		})
+	}
+	if path == nil {
+		return nil, ErrNoRoute
+	}
+
+	return m.describe(path, o), nil
+}
+
+// penalty for entering a system the mode prefers to avoid
+func (mode Mode) penalty(s *System) float64 {
+	switch mode {
+	case Safest:
+		if !HighSec(s.Security) {
+			return securityPenalty
+		}
+	case LessSecure:
+		if HighSec(s.Security) {
+			return securityPenalty
+		}
+	}
+	return 0
+}
+
+// HighSec reports if a security status displays as 0.5 or above
+func HighSec(security float64) bool {
+	return math.Floor(security*10+0.5) >= 5
+}
+
+// gateNeighbors returns the allowed systems connected by stargate
+func (m *Map) gateNeighbors(allowed func(*System) bool) func(*System) []*System {
+	return func(s *System) []*System {
+		neighbors := []*System{}
+		for _, id := range m.gates[s.SolarSystemID] {
+			if n := m.systems[id]; n != nil && allowed(n) {
+				neighbors = append(neighbors, n)
+			}
+		}
+		return neighbors
+	}
+}
+
+// describe fills in the details of each system along a path
+func (m *Map) describe(path []int32, o Options) *Route {
+	route := &Route{Jumps: len(path) - 1}
+	fatigue := o.Fatigue
+	var reactivation time.Duration
+
+	for i, id := range path {
+		s := m.systems[id]
+		hop := Hop{
+			SolarSystemID:   s.SolarSystemID,
+			SolarSystemName: s.SolarSystemName,
+			RegionID:        s.RegionID,
+			Security:        s.Security,
+			Kills:           o.Kills[id],
+		}
+
+		if o.JumpRange > 0 && i > 0 {
+			hop.Distance = lightYears(m.systems[path[i-1]], s)
+			hop.Wait = reactivation
+			fatigue, reactivation = jumpFatigue(fatigue-reactivation, hop.Distance*(1-o.FatigueBonus))
+			hop.Fatigue = fatigue
+
+			route.Distance += hop.Distance
+			route.Wait += hop.Wait
+			route.Fatigue = fatigue
+		}
+
+		route.Systems = append(route.Systems, hop)
+	}
+
+	return route
+}
+
+// search runs Dijkstra from one system to another, returning nil if there is no path
+func (m *Map) search(from, to int32, neighbors func(*System) []*System, cost func(a, b *System) float64) []int32 {
+	dist := map[int32]float64{from: 0}
+	prev := make(map[int32]int32)
+	done := make(map[int32]bool)
+
+	q := &queue{{id: from}}
+	for q.Len() > 0 {
+		cur := heap.Pop(q).(item)
+		if done[cur.id] {
+			continue
+		}
+		done[cur.id] = true
+
+		if cur.id == to {
+			path := []int32{to}
+			for id := to; id != from; {
+				id = prev[id]
+				path = append([]int32{id}, path...)
+			}
+			return path
+		}
+
+		s := m.systems[cur.id]
+		for _, n := range neighbors(s) {
+			if done[n.SolarSystemID] {
+				continue
+			}
+			d := cur.cost + cost(s, n)
+			if old, ok := dist[n.SolarSystemID]; !ok || d < old {
+				dist[n.SolarSystemID] = d
+				prev[n.SolarSystemID] = cur.id
+				heap.Push(q, item{id: n.SolarSystemID, cost: d})
+			}
+		}
+	}
+
+	return nil
+}
+
+type item struct {
+	id   int32
+	cost float64
+}
+
+// queue is a min heap of systems by cost
+type queue []item
+
+func (q queue) Len() int            { return len(q) }
+func (q queue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q queue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *queue) Push(x interface{}) { *q = append(*q, x.(item)) }
+func (q *queue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,132 @@
+package routeplanner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testMap() *Map {
+	systems := []System{
+		{SolarSystemID: 1, RegionID: 1, Security: 0.9},
+		{SolarSystemID: 2, RegionID: 1, Security: 0.44},
+		{SolarSystemID: 3, RegionID: 1, Security: 0.8},
+		{SolarSystemID: 4, RegionID: 2, Security: 0.45},
+		{SolarSystemID: 5, RegionID: 2, Security: 0.7},
+
+		// Jump drive systems laid out along X in light years
+		{SolarSystemID: 10, Security: 0.1, X: 0},
+		{SolarSystemID: 11, Security: 0.6, X: 2 * LightYear},
+		{SolarSystemID: 12, Security: -0.2, X: 4 * LightYear},
+		{SolarSystemID: 13, Security: -1, X: 6 * LightYear},
+		{SolarSystemID: 14, Security: -0.5, X: 7 * LightYear},
+		{SolarSystemID: 15, Security: 0.3, X: 8 * LightYear},
+	}
+	gates := map[int32][]int32{
+		1: {2, 4}, 2: {1, 3}, 3: {2, 5}, 4: {1, 5}, 5: {4, 3},
+		10: {11}, 11: {10, 12}, 12: {11, 14}, 14: {12, 15}, 15: {14},
+	}
+	return NewMap(systems, gates)
+}
+
+func routeIDs(r *Route) []int32 {
+	ids := []int32{}
+	for _, h := range r.Systems {
+		ids = append(ids, h.SolarSystemID)
+	}
+	return ids
+}
+
+func TestGateRoutes(t *testing.T) {
+	m := testMap()
+
+	r, err := m.Route(1, 3, Options{})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2, 3}, routeIDs(r))
+	assert.Equal(t, 2, r.Jumps)
+
+	r, err = m.Route(1, 3, Options{Mode: Safest})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 4, 5, 3}, routeIDs(r))
+
+	r, err = m.Route(1, 3, Options{Mode: LessSecure})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2, 3}, routeIDs(r))
+
+	r, err = m.Route(1, 3, Options{AvoidSystems: []int32{2}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 4, 5, 3}, routeIDs(r))
+
+	r, err = m.Route(1, 3, Options{Kills: map[int32]int{2: 5}, KillPenalty: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 4, 5, 3}, routeIDs(r))
+	assert.Equal(t, 0, r.Systems[1].Kills)
+
+	// Avoided destinations are still reachable
+	r, err = m.Route(1, 2, Options{AvoidSystems: []int32{2}})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2}, routeIDs(r))
+
+	_, err = m.Route(1, 3, Options{AvoidSystems: []int32{2}, AvoidRegions: []int32{2}})
+	assert.Equal(t, ErrNoRoute, err)
+
+	_, err = m.Route(1, 99, Options{})
+	assert.Equal(t, ErrUnknownSystem, err)
+}
+
+func TestJumpRoutes(t *testing.T) {
+	m := testMap()
+
+	r, err := m.Route(10, 15, Options{JumpRange: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{10, 12, 15}, routeIDs(r))
+	assert.Equal(t, 2, r.Jumps)
+	assert.InDelta(t, 8, r.Distance, 1e-9)
+
+	assert.Equal(t, time.Duration(0), r.Systems[1].Wait)
+	assert.Equal(t, time.Minute*50, r.Systems[1].Fatigue)
+	assert.Equal(t, time.Minute*5, r.Systems[2].Wait)
+	assert.Equal(t, time.Minute*225, r.Systems[2].Fatigue)
+	assert.Equal(t, time.Minute*5, r.Wait)
+	assert.Equal(t, time.Minute*225, r.Fatigue)
+
+	// Shorter range has to stop on the way
+	r, err = m.Route(12, 15, Options{JumpRange: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{12, 15}, routeIDs(r))
+
+	r, err = m.Route(12, 15, Options{JumpRange: 3.5})
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{12, 14, 15}, routeIDs(r))
+
+	// High security space and wormholes cannot be jumped to
+	_, err = m.Route(10, 11, Options{JumpRange: 5})
+	assert.Equal(t, ErrNoRoute, err)
+	_, err = m.Route(10, 13, Options{JumpRange: 10})
+	assert.Equal(t, ErrNoRoute, err)
+	_, err = m.Route(10, 15, Options{JumpRange: 3})
+	assert.Equal(t, ErrNoRoute, err)
+}
+
+func TestJumpFatigue(t *testing.T) {
+	fatigue, activation := jumpFatigue(0, 4)
+	assert.Equal(t, time.Minute*50, fatigue)
+	assert.Equal(t, time.Minute*5, activation)
+
+	fatigue, activation = jumpFatigue(time.Hour*4, 5)
+	assert.Equal(t, time.Hour*5, fatigue)
+	assert.Equal(t, time.Minute*30, activation)
+
+	// Jump freighters ignore most of the distance
+	fatigue, activation = jumpFatigue(0, 4*(1-0.9))
+	assert.Equal(t, time.Minute*14, fatigue)
+	assert.Equal(t, time.Second*84, activation)
+}
+
+func TestHighSec(t *testing.T) {
+	assert.True(t, HighSec(0.45))
+	assert.True(t, HighSec(1))
+	assert.False(t, HighSec(0.44))
+	assert.False(t, HighSec(-0.3))
+}
@@ -83,4 +83,5 @@ type MailUser struct {
 	CharacterID      int32
 	TokenCharacterID int32
 	Token            *oauth2.Token
+	MaxCost          int64 // Maximum CSPA charge approved for sent mail
 }
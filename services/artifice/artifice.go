@@ -3,16 +3,19 @@ package artifice
 
 import (
 	"log"
+	"os"
 	"strconv"
 	"time"
 
 	"github.com/antihax/evedata/internal/apicache"
+	"github.com/antihax/evedata/internal/fpgrowth"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/goesi"
 	"github.com/antihax/goesi/esi"
 	"github.com/garyburd/redigo/redis"
 	"github.com/jmoiron/sqlx"
+	nsq "github.com/nsqio/go-nsq"
 	"golang.org/x/oauth2"
 )
 
@@ -25,6 +28,10 @@ type Artifice struct {
 	db      *sqlx.DB
 	mail    chan esi.PostCharactersCharacterIdMailMail
 
+	// killmail associations
+	consumer       *nsq.Consumer
+	killmailWindow *fpgrowth.Window
+
 	// authentication
 	token       *oauth2.TokenSource
 	tokenCharID int32
@@ -32,7 +39,7 @@ type Artifice struct {
 }
 
 // NewArtifice Service.
-func NewArtifice(redis *redis.Pool, ledis *redis.Pool, db *sqlx.DB, clientID string, secret string, refresh string, refreshCharID string) *Artifice {
+func NewArtifice(redis *redis.Pool, ledis *redis.Pool, db *sqlx.DB, clientID string, secret string, refresh string, refreshCharID string, consumerAddresses []string) *Artifice {
 
 	if clientID == "" {
 		log.Fatalln("Missing clientID")
@@ -85,14 +92,35 @@ func NewArtifice(redis *redis.Pool, ledis *redis.Pool, db *sqlx.DB, clientID str
 
 		tokenCharID: int32(charID),
 		token:       &token,
+
+		killmailWindow: newKillmailWindow(),
+	}
+
+	nsqcfg := nsq.NewConfig()
+	nsqcfg.MaxInFlight = 50
+
+	c, err := nsq.NewConsumer("killmail", "artifice", nsqcfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	s.consumer = c
+
+	c.AddConcurrentHandlers(nsq.HandlerFunc(s.killmailAssociationHandler), 10)
+	err = c.ConnectToNSQLookupds(consumerAddresses)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
+	// Stop the logger being so verbose
+	c.SetLogger(log.New(os.Stderr, "", log.Flags()), nsq.LogLevelError)
+
 	return s
 }
 
 // Close the service
 func (s *Artifice) Close() {
 	close(s.stop)
+	s.consumer.Stop()
 }
 
 // ChangeBasePath for ESI (sisi/mock/tranquility)
@@ -119,6 +147,7 @@ func (s *Artifice) QueueSize() (int, error) {
 // Run the service
 func (s *Artifice) Run() {
 	go s.startup()
+	go s.loadKillmailWindow()
 	go s.zkillboardPost()
 	go s.warKillmails()
 	go s.runMetrics()
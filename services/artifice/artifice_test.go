@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/stretchr/testify/assert"
@@ -21,7 +22,7 @@ func TestMain(m *testing.M) {
 	defer redConn.Close()
 	redConn.Do("FLUSHALL")
 
-	artificeInstance = NewArtifice(redis, redis, sql, "123400", "faaaaaaake", "sofake", "123456")
+	artificeInstance = NewArtifice(redis, redis, sql, "123400", "faaaaaaake", "sofake", "123456", nsqhelper.Test)
 	artificeInstance.ChangeBasePath("http://127.0.0.1:8080")
 	artificeInstance.ChangeTokenPath("http://127.0.0.1:8080")
 
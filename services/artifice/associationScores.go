@@ -0,0 +1,223 @@
+package artifice
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/antihax/evedata/internal/altscore"
+	"github.com/antihax/evedata/internal/sqlhelper"
+)
+
+// Pairs to score per batch
+const scoreBatchSize = 5000
+
+// Most characters to locate a target before it is too common to link them
+const maxLocators = 10
+
+func init() {
+	registerTrigger("scoreAssociations", scoreAssociations, time.NewTicker(time.Hour))
+}
+
+// Collect the signals which are not mined from killmails or corporation history
+func scoreAssociations(s *Artifice) error {
+	if err := s.scoreLocatorTargets(); err != nil {
+		return err
+	}
+	if err := s.scoreContactSyncs(); err != nil {
+		return err
+	}
+	return s.scoreActivityOverlap()
+}
+
+// Characters who locate the same targets are likely run by the same player.
+// Targets located by many characters say little about any pair of them and
+// would need a pair for every combination, so they are skipped.
+func (s *Artifice) scoreLocatorTargets() error {
+	type locator struct {
+		LocatedCharacterID int32 `db:"locatedCharacterID"`
+		CharacterID        int32 `db:"characterID"`
+	}
+	located := []locator{}
+	if err := s.db.Select(&located, `
+		SELECT DISTINCT L.locatedCharacterID, L.characterID FROM evedata.locatedCharacters L
+		INNER JOIN (
+			SELECT locatedCharacterID FROM evedata.locatedCharacters
+			WHERE time > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 90 DAY)
+			GROUP BY locatedCharacterID
+			HAVING COUNT(DISTINCT characterID) BETWEEN 2 AND ?
+		) T ON T.locatedCharacterID = L.locatedCharacterID
+		WHERE L.time > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 90 DAY)
+		ORDER BY L.locatedCharacterID`, maxLocators); err != nil {
+		return err
+	}
+
+	// Count the shared targets of each pair
+	pairs := make(map[[2]int32]int)
+	for start := 0; start < len(located); {
+		end := start
+		for end < len(located) && located[end].LocatedCharacterID == located[start].LocatedCharacterID {
+			end++
+		}
+		for _, a := range located[start:end] {
+			for _, b := range located[start:end] {
+				if a.CharacterID != b.CharacterID {
+					pairs[[2]int32{a.CharacterID, b.CharacterID}]++
+				}
+			}
+		}
+		start = end
+	}
+
+	values := []string{}
+	for pair, count := range pairs {
+		values = append(values, fmt.Sprintf("(%d,%d,%d,UTC_TIMESTAMP())", pair[0], pair[1], count))
+	}
+
+	inserts := []string{}
+	for start := 0; start < len(values); start += scoreBatchSize {
+		end := min(start+scoreBatchSize, len(values))
+		inserts = append(inserts, fmt.Sprintf(`
+			INSERT INTO evedata.characterAssociationScores (characterID, associateID, locatorTargets, updated)
+				VALUES %s
+			ON DUPLICATE KEY UPDATE
+				locatorTargets = VALUES(locatorTargets),
+				updated = VALUES(updated)`, strings.Join(values[start:end], ",\n")))
+	}
+	return s.replaceSignal("locatorTargets", inserts...)
+}
+
+// Characters syncing contacts from one another, or from the same source, share an owner
+func (s *Artifice) scoreContactSyncs() error {
+	return s.replaceSignal("contactSync", `
+		INSERT INTO evedata.characterAssociationScores (characterID, associateID, contactSync, updated)
+			SELECT characterID, associateID, 1, UTC_TIMESTAMP() FROM (
+				SELECT source AS characterID, destination AS associateID FROM evedata.contactSyncs
+				UNION
+				SELECT destination, source FROM evedata.contactSyncs
+				UNION
+				SELECT A.destination, B.destination FROM evedata.contactSyncs A
+				INNER JOIN evedata.contactSyncs B ON A.source = B.source AND A.destination != B.destination
+			) S
+			WHERE characterID != associateID
+		ON DUPLICATE KEY UPDATE
+			contactSync = 1,
+			updated = VALUES(updated)`)
+}
+
+// replaceSignal clears a signal and inserts its new values in one transaction
+// so pairs are never seen without it
+func (s *Artifice) replaceSignal(signal string, inserts ...string) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(fmt.Sprintf(`
+		UPDATE evedata.characterAssociationScores SET %[1]s = 0 WHERE %[1]s > 0`, signal)); err != nil {
+		return err
+	}
+	for _, insert := range inserts {
+		if _, err := tx.Exec(insert); err != nil {
+			return err
+		}
+	}
+
+	return sqlhelper.RetryTransaction(tx)
+}
+
+// Compare the weekly killmail heatmaps of every associated pair
+func (s *Artifice) scoreActivityOverlap() error {
+	type pair struct {
+		CharacterID int32 `db:"characterID"`
+		AssociateID int32 `db:"associateID"`
+	}
+
+	last := pair{}
+	for {
+		pairs := []pair{}
+		if err := s.db.Select(&pairs, `
+			SELECT characterID, associateID FROM evedata.characterAssociationScores
+			WHERE (characterID, associateID) > (?, ?)
+			ORDER BY characterID, associateID
+			LIMIT ?`, last.CharacterID, last.AssociateID, scoreBatchSize); err != nil {
+			return err
+		}
+		if len(pairs) == 0 {
+			return nil
+		}
+		last = pairs[len(pairs)-1]
+
+		characters := []int32{}
+		seen := make(map[int32]bool)
+		for _, p := range pairs {
+			for _, id := range []int32{p.CharacterID, p.AssociateID} {
+				if !seen[id] {
+					seen[id] = true
+					characters = append(characters, id)
+				}
+			}
+		}
+
+		activity, err := s.getActivity(characters)
+		if err != nil {
+			return err
+		}
+
+		values := []string{}
+		for _, p := range pairs {
+			a, b := activity[p.CharacterID], activity[p.AssociateID]
+			if a == nil || b == nil {
+				continue
+			}
+			values = append(values, fmt.Sprintf("(%d,%d,%f)",
+				p.CharacterID, p.AssociateID, altscore.ActivityOverlap(a, b)))
+		}
+		if len(values) == 0 {
+			continue
+		}
+
+		if err := s.doSQL(fmt.Sprintf(`
+			INSERT INTO evedata.characterAssociationScores (characterID, associateID, activityOverlap)
+				VALUES %s
+			ON DUPLICATE KEY UPDATE activityOverlap = VALUES(activityOverlap)`,
+			strings.Join(values, ",\n"))); err != nil {
+			return err
+		}
+	}
+}
+
+// getActivity builds weekly killmail heatmaps for characters
+func (s *Artifice) getActivity(characters []int32) (map[int32]*altscore.Activity, error) {
+	ids := strings.Trim(strings.Join(strings.Fields(fmt.Sprint(characters)), ","), "[]")
+	rows, err := s.db.Query(`
+		SELECT characterID, (DAYOFWEEK(killTime) - 1) * 24 + HOUR(killTime) AS hour, COUNT(*)
+		FROM evedata.killmailAttackers A
+		INNER JOIN evedata.killmails K ON K.id = A.id
+		WHERE characterID IN (` + ids + `) AND killTime > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 90 DAY)
+		GROUP BY characterID, hour`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	activity := make(map[int32]*altscore.Activity)
+	for rows.Next() {
+		var (
+			characterID int32
+			hour        int
+			count       float64
+		)
+		if err := rows.Scan(&characterID, &hour, &count); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		if activity[characterID] == nil {
+			activity[characterID] = &altscore.Activity{}
+		}
+		activity[characterID][hour] = count
+	}
+	return activity, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/evedata/internal/sqlhelper"
 )
@@ -32,8 +33,8 @@ func characterTransactions(s *Artifice) error {
 	} else {
 		work := []redisqueue.Work{}
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "characterWalletTransactions", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
-			work = append(work, redisqueue.Work{Operation: "characterWalletJournal", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
+			work = append(work, hammerwork.CharacterWalletTransactionsWork(p.CharacterID, p.TokenCharacterID))
+			work = append(work, hammerwork.CharacterWalletJournalWork(p.CharacterID, p.TokenCharacterID))
 		}
 		return s.QueueWork(work, redisqueue.Priority_Normal)
 	}
@@ -45,7 +46,7 @@ func characterAssets(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "characterAssets", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
+			work = append(work, hammerwork.CharacterAssetsWork(p.CharacterID, p.TokenCharacterID))
 		}
 	}
 
@@ -84,12 +85,7 @@ func characterStructures(s *Artifice) error {
 		}
 		if !s.inQueue.CheckWorkExpired("evedata_structurechar_failure",
 			fmt.Sprintf("%d%d", structureID, tokenCharacterID)) {
-			work = append(work, redisqueue.Work{Operation: "characterStructures",
-				Parameter: []interface{}{
-					characterID,
-					tokenCharacterID,
-					structureID,
-				}})
+			work = append(work, hammerwork.CharacterStructuresWork(characterID, tokenCharacterID, structureID))
 		}
 	}
 
@@ -129,12 +125,7 @@ func characterStructureMarket(s *Artifice) error {
 
 		if !s.inQueue.CheckWorkExpired("evedata_structuremarket_failure",
 			fmt.Sprintf("%d%d", structureID, tokenCharacterID)) {
-			work = append(work, redisqueue.Work{Operation: "characterStructureMarket",
-				Parameter: []interface{}{
-					characterID,
-					tokenCharacterID,
-					structureID,
-				}})
+			work = append(work, hammerwork.CharacterStructureMarketWork(characterID, tokenCharacterID, structureID))
 		}
 	}
 
@@ -147,7 +138,7 @@ func characterOrders(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "characterOrders", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
+			work = append(work, hammerwork.CharacterOrdersWork(p.CharacterID, p.TokenCharacterID))
 		}
 	}
 
@@ -160,7 +151,7 @@ func characterNotifications(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "characterNotifications", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
+			work = append(work, hammerwork.CharacterNotificationsWork(p.CharacterID, p.TokenCharacterID))
 		}
 	}
 
@@ -173,7 +164,7 @@ func allianceContacts(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "allianceContacts", Parameter: []int32{p.CharacterID, p.TokenCharacterID, p.AllianceID}})
+			work = append(work, hammerwork.AllianceContactsWork(p.CharacterID, p.TokenCharacterID, p.AllianceID))
 		}
 	}
 
@@ -186,7 +177,7 @@ func corporationContacts(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "corporationContacts", Parameter: []int32{p.CharacterID, p.TokenCharacterID, p.CorporationID}})
+			work = append(work, hammerwork.CorporationContactsWork(p.CharacterID, p.TokenCharacterID, p.CorporationID))
 		}
 	}
 
@@ -220,11 +211,7 @@ func characterContactSync(s *Artifice) error {
 			return err
 		}
 
-		work = append(work, redisqueue.Work{Operation: "characterContactSync", Parameter: []interface{}{
-			cid,
-			source,
-			destinations,
-		}})
+		work = append(work, hammerwork.CharacterContactSyncWork(cid, source, destinations))
 	}
 
 	return s.QueueWork(work, redisqueue.Priority_Normal)
@@ -236,7 +223,7 @@ func characterAuthOwners(s *Artifice) error {
 		return err
 	} else {
 		for _, p := range pairs {
-			work = append(work, redisqueue.Work{Operation: "characterAuthOwner", Parameter: []int32{p.CharacterID, p.TokenCharacterID}})
+			work = append(work, hammerwork.CharacterAuthOwnerWork(p.CharacterID, p.TokenCharacterID))
 		}
 	}
 
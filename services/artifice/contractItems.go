@@ -3,6 +3,7 @@ package artifice
 import (
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 )
 
@@ -35,11 +36,7 @@ func resolveItems(s *Artifice) error {
 			return err
 		}
 
-		work = append(work, redisqueue.Work{
-			Operation: "mutatedItem",
-			Parameter: []interface{}{
-				itemID, typeID,
-			}})
+		work = append(work, hammerwork.MutatedItemWork(itemID, typeID))
 	}
 
 	return s.QueueWork(work, redisqueue.Priority_High)
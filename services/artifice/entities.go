@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 )
 
@@ -22,8 +23,8 @@ func npcCorporationsTrigger(s *Artifice) error {
 
 	work := []redisqueue.Work{}
 	for _, corporation := range corporations {
-		work = append(work, redisqueue.Work{Operation: "corporation", Parameter: corporation})
-		work = append(work, redisqueue.Work{Operation: "loyaltyStore", Parameter: corporation})
+		work = append(work, hammerwork.CorporationWork(corporation))
+		work = append(work, hammerwork.LoyaltyStoreWork(corporation))
 	}
 
 	return s.QueueWork(work, redisqueue.Priority_Lowest)
@@ -37,7 +38,7 @@ func allianceTrigger(s *Artifice) error {
 
 	work := []redisqueue.Work{}
 	for _, alliance := range alliances {
-		work = append(work, redisqueue.Work{Operation: "alliance", Parameter: alliance})
+		work = append(work, hammerwork.AllianceWork(alliance))
 	}
 
 	return s.QueueWork(work, redisqueue.Priority_Lowest)
@@ -65,7 +66,7 @@ func characterUpdate(s *Artifice) error {
 			return err
 		}
 
-		work = append(work, redisqueue.Work{Operation: "character", Parameter: id})
+		work = append(work, hammerwork.CharacterWork(id))
 	}
 
 	return s.QueueWork(work, redisqueue.Priority_Lowest)
@@ -92,7 +93,7 @@ func corporationUpdate(s *Artifice) error {
 			return err
 		}
 
-		work = append(work, redisqueue.Work{Operation: "corporation", Parameter: id})
+		work = append(work, hammerwork.CorporationWork(id))
 
 	}
 
@@ -0,0 +1,98 @@
+package artifice
+
+import (
+	"log"
+	"time"
+
+	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/fpgrowth"
+	"github.com/antihax/evedata/internal/gobcoder"
+	nsq "github.com/nsqio/go-nsq"
+)
+
+// Killmails with fewer or more characters than this say little about who flies together
+const (
+	minKillmailCharacters = 2
+	maxKillmailCharacters = 10
+)
+
+func init() {
+	registerTrigger("killmailRelationships", killmailRelationships, time.NewTicker(time.Minute*5))
+}
+
+// newKillmailWindow holds 90 days of killmail attackers to mine for associations.
+// Associations are stored between pairs so longer patterns are not mined.
+func newKillmailWindow() *fpgrowth.Window {
+	w := fpgrowth.NewWindow(time.Hour*24*90, fpgrowth.Options{
+		MinimumSupport: 2,
+		MinimumLength:  2,
+		MaximumLength:  2,
+	})
+	w.MaxTransactions = 500000
+	return w
+}
+
+// Expire old killmails and store associations from what remains
+func killmailRelationships(s *Artifice) error {
+	s.killmailWindow.Expire(time.Now().UTC())
+	log.Printf("Character Associations: Mining %d killmails", s.killmailWindow.Len())
+	return s.storeAssociations(s.killmailWindow.Patterns(), 1)
+}
+
+// loadKillmailWindow seeds the window with killmails already in the database
+func (s *Artifice) loadKillmailWindow() {
+	rows, err := s.db.Query(`
+        SELECT K.id, UNIX_TIMESTAMP(killTime), GROUP_CONCAT(characterID) 
+        FROM evedata.killmailAttackers A
+        INNER JOIN evedata.killmails K ON K.id = A.id
+        WHERE killTime > DATE_SUB(UTC_TIMESTAMP, INTERVAL 90 DAY) AND characterID > 0 
+        GROUP BY K.id
+        HAVING count(*) >= ? AND count(*) <= ?;
+        `, minKillmailCharacters, maxKillmailCharacters)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var (
+			killmailID int
+			killTime   int64
+			items      string
+		)
+
+		if err := rows.Scan(&killmailID, &killTime, &items); err != nil {
+			log.Println(err)
+			return
+		}
+		if s.killmailWindow.Add(killmailID, SplitToInt(items), time.Unix(killTime, 0).UTC()) {
+			count++
+		}
+	}
+	log.Printf("Character Associations: Loaded %d killmails", count)
+}
+
+// killmailAssociationHandler adds new killmails to the window as they arrive
+func (s *Artifice) killmailAssociationHandler(message *nsq.Message) error {
+	killmail := datapackages.Killmail{}
+	if err := gobcoder.GobDecoder(message.Body, &killmail); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	characters := []int{}
+	seen := make(map[int32]bool)
+	for _, a := range killmail.Kill.Attackers {
+		if a.CharacterId > 0 && !seen[a.CharacterId] {
+			seen[a.CharacterId] = true
+			characters = append(characters, int(a.CharacterId))
+		}
+	}
+
+	if len(characters) >= minKillmailCharacters && len(characters) <= maxKillmailCharacters {
+		s.killmailWindow.Add(int(killmail.Kill.KillmailId), characters, killmail.Kill.KillmailTime.UTC())
+	}
+	return nil
+}
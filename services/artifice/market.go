@@ -4,6 +4,7 @@ import (
 	"context"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 )
 
@@ -15,9 +16,7 @@ func init() {
 func historyTrigger(s *Artifice) error {
 	hour := time.Now().UTC().Hour()
 	if hour == 1 || hour == 13 {
-		work := []redisqueue.Work{}
-		work = append(work, redisqueue.Work{Operation: "marketHistoryTrigger", Parameter: true})
-		return s.QueueWork(work, redisqueue.Priority_High)
+		return hammerwork.QueueMarketHistoryTrigger(s, redisqueue.Priority_High)
 	}
 	return nil
 }
@@ -36,9 +35,7 @@ func structuresTrigger(s *Artifice) error {
 
 	for i := range structure {
 		if !structure[i] {
-			work := []redisqueue.Work{}
-			work = append(work, redisqueue.Work{Operation: "structure", Parameter: structures[i]})
-			err = s.QueueWork(work, redisqueue.Priority_Lowest)
+			err = hammerwork.QueueStructure(s, redisqueue.Priority_Lowest, structures[i])
 			if err != nil {
 				return err
 			}
@@ -21,10 +21,6 @@ func buildRelationships(s *Artifice) error {
 		return err
 	}
 
-	if err := s.buildKillmailRelationships(); err != nil {
-		log.Println(err)
-		return err
-	}
 	if err := s.buildCorpJoinRelationships(); err != nil {
 		log.Println(err)
 		return err
@@ -36,30 +32,20 @@ func buildRelationships(s *Artifice) error {
 // Find relationships between characters in killmails
 func (s *Artifice) cleanupRelationships() error {
 	// Remove any orphan killmails
-	return s.doSQL(`
+	if err := s.doSQL(`
 		DELETE FROM evedata.characterAssociations 
 		WHERE
 			characterID = 0
 			OR added < DATE_SUB(UTC_TIMESTAMP(),
-			INTERVAL 6 MONTH)`)
-}
-
-// Find relationships between characters in killmails
-func (s *Artifice) buildKillmailRelationships() error {
-	rows, err := s.db.Query(`
-        SELECT K.id, GROUP_CONCAT(characterID) 
-        FROM evedata.killmailAttackers A
-        INNER JOIN evedata.killmails K ON K.id = A.id
-        WHERE killTime > DATE_SUB(UTC_TIMESTAMP, INTERVAL 90 DAY) AND characterID > 0 
-        GROUP BY K.id
-        HAVING count(*) > 1 AND count(*) < 11;
-        `)
-	if err != nil {
+			INTERVAL 6 MONTH)`); err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	return s.findRelationships(rows, 1)
+	return s.doSQL(`
+		DELETE FROM evedata.characterAssociationScores 
+		WHERE
+			characterID = 0
+			OR updated < DATE_SUB(UTC_TIMESTAMP(),
+			INTERVAL 6 MONTH)`)
 }
 
 // Find relationships between characters from corp history
@@ -102,21 +88,40 @@ func (s *Artifice) findRelationships(rows *sql.Rows, associationType uint8) erro
 	fp := fpgrowth.NewFPTree(transactions, 2)
 
 	log.Printf("Character Associations: Growth")
-	associations := fp.Growth()
+	return s.storeAssociations(fp.Growth(), associationType)
+}
 
+// Store pairs of associated characters from patterns
+func (s *Artifice) storeAssociations(associations []fpgrowth.Pattern, associationType uint8) error {
 	log.Printf("Character Associations: Build Values")
-	var values []string
+
+	// Pairs appear in many patterns, keep the highest frequency
+	pairs := make(map[[2]int]uint)
 	for _, association := range associations {
 		for _, char1 := range association.Items {
 			for _, char2 := range association.Items {
-				if char1 != char2 {
-					values = append(values, fmt.Sprintf("(%d,%d,%d,UTC_TIMESTAMP(), %d)",
-						char1, char2, association.Frequency, associationType))
+				pair := [2]int{char1, char2}
+				if char1 != char2 && pairs[pair] < association.Frequency {
+					pairs[pair] = association.Frequency
 				}
 			}
 		}
 	}
 
+	var values, signals []string
+	for pair, frequency := range pairs {
+		values = append(values, fmt.Sprintf("(%d,%d,%d,UTC_TIMESTAMP(), %d)",
+			pair[0], pair[1], frequency, associationType))
+		signals = append(signals, fmt.Sprintf("(%d,%d,%d,UTC_TIMESTAMP())",
+			pair[0], pair[1], frequency))
+	}
+
+	// Each source feeds its own scoring signal
+	signal := "coFleet"
+	if associationType == 2 {
+		signal = "corpHops"
+	}
+
 	log.Printf("Character Associations: Update Database")
 	for start := 0; start < len(values); start = start + 20000 {
 		end := min(start+20000, len(values))
@@ -135,6 +140,18 @@ func (s *Artifice) findRelationships(rows *sql.Rows, associationType uint8) erro
 		if err != nil {
 			return err
 		}
+
+		err = s.doSQL(fmt.Sprintf(`
+			INSERT INTO evedata.characterAssociationScores 
+				(characterID, associateID, %[1]s, updated) 
+				VALUES %[2]s 
+			ON DUPLICATE KEY UPDATE 
+				%[1]s = VALUES(%[1]s),
+				updated = VALUES(updated);
+			`, signal, strings.Join(signals[start:end], ",\n")))
+		if err != nil {
+			return err
+		}
 	}
 	log.Printf("Character Associations: Finished")
 	return nil
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
@@ -46,7 +47,7 @@ func warsTrigger(s *Artifice) error {
 		work := []redisqueue.Work{}
 		for i := range known {
 			if !known[i] {
-				work = append(work, redisqueue.Work{Operation: "war", Parameter: wars[i]})
+				work = append(work, hammerwork.WarWork(wars[i]))
 				warChan <- wars[i]
 			}
 		}
@@ -108,7 +109,7 @@ func getWarKills(s *Artifice, id int32) error {
 		work := []redisqueue.Work{}
 		for i := range known {
 			if !known[i] {
-				work = append(work, redisqueue.Work{Operation: "killmail", Parameter: []interface{}{kills[i].KillmailHash, kills[i].KillmailId}})
+				work = append(work, hammerwork.KillmailWork(kills[i].KillmailHash, kills[i].KillmailId))
 
 				// Send to zkillboard
 				zkillChan <- killmail{ID: kills[i].KillmailId, Hash: kills[i].KillmailHash}
@@ -149,7 +150,7 @@ func warsUpdate(s *Artifice) error {
 			return err
 		}
 
-		work = append(work, redisqueue.Work{Operation: "war", Parameter: id})
+		work = append(work, hammerwork.WarWork(id))
 
 	}
 
@@ -178,12 +179,7 @@ func missingKillmails(s *Artifice) error {
 		if err != nil {
 			return err
 		}
-		err = s.QueueWork(
-			[]redisqueue.Work{
-				redisqueue.Work{
-					Operation: "killmail", Parameter: []interface{}{hash, id}},
-			},
-			redisqueue.Priority_Normal)
+		err = hammerwork.QueueKillmail(s, redisqueue.Priority_Normal, hash, id)
 		if err != nil {
 			return err
 		}
@@ -1,10 +1,15 @@
 package conservator
 
 import (
+	"errors"
 	"log"
 	"net"
 	"net/http"
 	"net/rpc"
+	"strings"
+
+	"github.com/antihax/evedata/internal/botservice/mumbleservice"
+	"github.com/antihax/evedata/internal/botservice/slackservice"
 )
 
 func (s *Conservator) runRPC() error {
@@ -51,6 +56,54 @@ func (s *Conservator) VerifyDiscordChannel(args []string, reply *bool) error {
 	return nil
 }
 
+// VerifySlack checks a bot token belongs to the team ID [teamID, token]
+func (s *Conservator) VerifySlack(args []string, reply *bool) error {
+	*reply = false
+	if len(args) != 2 {
+		return errors.New("expected team ID and token")
+	}
+
+	sl, err := slackservice.NewSlackService(args[0], args[1])
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	if name, err := sl.GetName(); err == nil && name != "" {
+		*reply = true
+	}
+	return nil
+}
+
+// VerifyMumble checks the bot can log into a murmur server [address, user:pass]
+func (s *Conservator) VerifyMumble(args []string, reply *bool) error {
+	*reply = false
+	if len(args) != 2 {
+		return errors.New("expected address and authentication")
+	}
+
+	auth := strings.SplitN(args[1], ":", 2)
+	if len(auth) != 2 {
+		return errors.New("authentication must be user:password")
+	}
+
+	m, err := mumbleservice.NewMumbleService(args[0], auth[0], auth[1])
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer m.Close()
+
+	// Reading the groups requires the permissions needed for auth
+	if _, err := m.GetRoles(); err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	*reply = true
+	return nil
+}
+
 func (s *Conservator) GetChannels(integrationID *int32, reply *[][]string) error {
 	// Get the service
 	service, err := s.getService(*integrationID)
@@ -106,6 +159,13 @@ func (s *Conservator) JoinUser(j *JoinUser, reply *bool) error {
 		return err
 	}
 
+	// Mumble members link with their certificate hash, resolved to their registered user
+	if m, ok := service.Server.(*mumbleservice.MumbleService); ok {
+		if j.UserID, err = m.GetUserByHash(j.UserID); err != nil {
+			return err
+		}
+	}
+
 	if err = service.Server.AddUser(j.AccessToken, j.UserID, j.CharacterName); err != nil {
 		return err
 	}
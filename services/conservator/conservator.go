@@ -12,7 +12,7 @@ import (
 	nsq "github.com/nsqio/go-nsq"
 )
 
-var NOTIFICATION_TYPES = []string{"kill", "war", "locator", "application", "structure"}
+var NOTIFICATION_TYPES = []string{"kill", "war", "locator", "application", "structure", "fuel", "anchoring", "moon", "sovereignty", "poco"}
 
 // Conservator Handles our little bot.
 type Conservator struct {
@@ -29,6 +29,12 @@ type Conservator struct {
 
 	solarSystems map[int32]float32
 
+	// Killmail rule data
+	staticLock    sync.RWMutex
+	systemRegions map[int32]int32
+	typeGroups    map[int32]int32
+	typePrices    map[int32]float64
+
 	// Base Data
 	services sync.Map
 	channels sync.Map
@@ -91,6 +97,10 @@ func (s *Conservator) Run() {
 		log.Fatal(err)
 	}
 
+	if err = s.loadKillmailStatics(); err != nil {
+		log.Fatal(err)
+	}
+
 	// Run the API
 	err = s.runRPC()
 	if err != nil {
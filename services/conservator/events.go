@@ -5,15 +5,25 @@ import (
 	"log"
 	"strings"
 
+	"github.com/antihax/evedata/internal/botservice/tsservice"
 	"github.com/antihax/goesi"
 )
 
 func (c *Conservator) checkAllUsers() {
 	c.services.Range(func(ki, vi interface{}) bool {
 		service := vi.(Service)
+
+		// Link any TeamSpeak clients that redeemed a join token
+		if ts, ok := service.Server.(*tsservice.TSService); ok {
+			if err := c.linkTSClients(service.IntegrationID, ts); err != nil {
+				log.Println(err)
+			}
+		}
+
 		members, err := service.Server.GetMembers()
 		if err != nil {
-			return false
+			log.Println(err)
+			return true
 		}
 		for _, m := range members {
 			if err := c.checkUser(m.ID, m.Name, service.IntegrationID, m.Roles); err != nil {
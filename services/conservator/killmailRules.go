@@ -0,0 +1,169 @@
+package conservator
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/antihax/evedata/internal/killfilter"
+	"github.com/antihax/goesi/esi"
+)
+
+// MaxKillmailRules is the most rules a channel may have
+const MaxKillmailRules = 10
+
+// KillmailRule sends killmails matching a filter expression to a channel
+type KillmailRule struct {
+	Name       string `json:"name,omitempty"`
+	Expression string `json:"expression"`
+
+	filter *killfilter.Filter
+}
+
+// CompileRules parses all killmail rules, returning the first error found.
+// Rules that fail to parse are skipped when matching.
+func (c *ChannelOptions) CompileRules() error {
+	if len(c.Killmail.Rules) > MaxKillmailRules {
+		return fmt.Errorf("channels may have at most %d killmail rules", MaxKillmailRules)
+	}
+
+	var firstErr error
+	for i := range c.Killmail.Rules {
+		rule := &c.Killmail.Rules[i]
+		f, err := killfilter.Parse(rule.Expression)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("rule %d %s: %v", i+1, rule.Name, err)
+			}
+			continue
+		}
+		rule.filter = f
+	}
+	return firstErr
+}
+
+// matchRules returns true if any killmail rule matches the kill
+func (c *ChannelOptions) matchRules(k *killfilter.Kill) bool {
+	for _, rule := range c.Killmail.Rules {
+		if rule.filter != nil && rule.filter.Match(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// killFacts collects what killmail rules are evaluated against.
+// Loss and Kill depend on the channel and are set by the caller.
+func (s *Conservator) killFacts(mail *esi.GetKillmailsKillmailIdKillmailHashOk) killfilter.Kill {
+	s.staticLock.RLock()
+	defer s.staticLock.RUnlock()
+
+	k := killfilter.Kill{
+		RegionID:      s.systemRegions[mail.SolarSystemId],
+		SolarSystemID: mail.SolarSystemId,
+		Security:      float64(s.solarSystems[mail.SolarSystemId]),
+		ShipTypeID:    mail.Victim.ShipTypeId,
+		ShipGroupID:   s.typeGroups[mail.Victim.ShipTypeId],
+		Value:         s.typePrices[mail.Victim.ShipTypeId],
+		VictimEntities: []int32{
+			mail.Victim.CharacterId, mail.Victim.CorporationId,
+			mail.Victim.AllianceId, mail.Victim.FactionId,
+		},
+	}
+
+	for _, a := range mail.Attackers {
+		if a.CharacterId != 0 {
+			k.Attackers++
+		}
+		k.AttackerEntities = append(k.AttackerEntities, a.CharacterId, a.CorporationId, a.AllianceId, a.FactionId)
+	}
+
+	for _, item := range mail.Victim.Items {
+		// Blueprint copies have no value
+		if item.Singleton != 2 {
+			k.Value += s.typePrices[item.ItemTypeId] * float64(item.QuantityDestroyed+item.QuantityDropped)
+		}
+		for _, sub := range item.Items {
+			if sub.Singleton != 2 {
+				k.Value += s.typePrices[sub.ItemTypeId] * float64(sub.QuantityDestroyed+sub.QuantityDropped)
+			}
+		}
+	}
+
+	return k
+}
+
+func containsInt32(a int32, list []int32) bool {
+	for _, b := range list {
+		if b == a {
+			return true
+		}
+	}
+	return false
+}
+
+// loadKillmailStatics loads the region of each system and group of each type
+func (s *Conservator) loadKillmailStatics() error {
+	type system struct {
+		SolarSystemID int32 `db:"solarSystemID"`
+		RegionID      int32 `db:"regionID"`
+	}
+	systems := []system{}
+	if err := s.db.Select(&systems, "SELECT solarSystemID, regionID FROM mapSolarSystems"); err != nil {
+		return err
+	}
+
+	type itemType struct {
+		TypeID  int32 `db:"typeID"`
+		GroupID int32 `db:"groupID"`
+	}
+	types := []itemType{}
+	if err := s.db.Select(&types, "SELECT typeID, groupID FROM invTypes"); err != nil {
+		return err
+	}
+
+	regions := make(map[int32]int32)
+	for _, sys := range systems {
+		regions[sys.SolarSystemID] = sys.RegionID
+	}
+
+	groups := make(map[int32]int32)
+	for _, t := range types {
+		groups[t.TypeID] = t.GroupID
+	}
+
+	s.staticLock.Lock()
+	s.systemRegions = regions
+	s.typeGroups = groups
+	s.staticLock.Unlock()
+	return nil
+}
+
+// loadTypePrices loads last month's average prices to value killmails
+func (s *Conservator) loadTypePrices() error {
+	type price struct {
+		TypeID int32   `db:"typeID"`
+		Mean   float64 `db:"mean"`
+	}
+	list := []price{}
+	if err := s.db.Select(&list, `
+		SELECT typeID, mean
+		FROM evedata.typePricesMonthly
+		WHERE month = MONTH(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 28 DAY)) AND
+			year = YEAR(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 28 DAY))`); err != nil {
+		return err
+	}
+
+	prices := make(map[int32]float64)
+	for _, p := range list {
+		prices[p.TypeID] = p.Mean
+	}
+
+	if len(prices) == 0 {
+		log.Println("no type prices available to value killmails")
+	}
+
+	s.staticLock.Lock()
+	s.typePrices = prices
+	s.staticLock.Unlock()
+	return nil
+}
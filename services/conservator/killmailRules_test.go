@@ -0,0 +1,64 @@
+package conservator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKillmailRules(t *testing.T) {
+	opts := ChannelOptions{}
+	err := json.Unmarshal([]byte(`{"killmail":{"rules":[
+		{"name":"Staging capitals","expression":"loss and region = 10000060 and group in (485, 547)"},
+		{"name":"Big solo","expression":"solo and value > 1b"}
+	]}}`), &opts)
+	assert.Nil(t, err)
+	assert.Nil(t, opts.CompileRules())
+
+	c := &Conservator{
+		solarSystems:  map[int32]float32{30004759: -0.4},
+		systemRegions: map[int32]int32{30004759: 10000060},
+		typeGroups:    map[int32]int32{23757: 547},
+		typePrices:    map[int32]float64{23757: 1.5e9, 3001: 1e6},
+	}
+
+	mail := &esi.GetKillmailsKillmailIdKillmailHashOk{
+		SolarSystemId: 30004759,
+		Victim: esi.GetKillmailsKillmailIdKillmailHashVictim{
+			ShipTypeId:    23757,
+			CorporationId: 98000001,
+			AllianceId:    99000001,
+			Items: []esi.GetKillmailsKillmailIdKillmailHashItem{
+				{ItemTypeId: 3001, QuantityDestroyed: 2, QuantityDropped: 1},
+				{ItemTypeId: 3001, QuantityDestroyed: 1, Singleton: 2},
+			},
+		},
+		Attackers: []esi.GetKillmailsKillmailIdKillmailHashAttacker{
+			{CharacterId: 90000002, CorporationId: 98000002},
+			{CorporationId: 1000125}, // NPC
+		},
+	}
+
+	k := c.killFacts(mail)
+	assert.Equal(t, int32(10000060), k.RegionID)
+	assert.Equal(t, int32(547), k.ShipGroupID)
+	assert.Equal(t, 1, k.Attackers)
+	assert.Equal(t, 1.5e9+3e6, k.Value)
+
+	// Solo kill over 1b, but not our loss
+	assert.True(t, opts.matchRules(&k))
+
+	// Our capital loss to a gang
+	k.Attackers = 10
+	assert.False(t, opts.matchRules(&k))
+	k.Loss = containsInt32(99000001, k.VictimEntities)
+	assert.True(t, opts.matchRules(&k))
+
+	// Bad rules are reported and skipped
+	bad := ChannelOptions{}
+	bad.Killmail.Rules = []KillmailRule{{Name: "broken", Expression: "value >"}, {Expression: "gang"}}
+	assert.NotNil(t, bad.CompileRules())
+	assert.True(t, bad.matchRules(&k))
+}
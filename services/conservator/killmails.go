@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/antihax/evedata/internal/botservice"
 	"github.com/antihax/evedata/internal/datapackages"
 
 	"github.com/antihax/evedata/internal/gobcoder"
@@ -49,6 +50,11 @@ func (s *Conservator) atWarWithKillmail(entityID int32, mail *esi.GetKillmailsKi
 }
 
 func (s *Conservator) reportKillmail(mail *esi.GetKillmailsKillmailIdKillmailHashOk) error {
+	facts := s.killFacts(mail)
+
+	// Only build the message once a channel wants it
+	var message *botservice.Message
+
 	s.channels.Range(func(ki, vi interface{}) bool {
 		channel := vi.(Channel)
 
@@ -97,9 +103,18 @@ func (s *Conservator) reportKillmail(mail *esi.GetKillmailsKillmailIdKillmailHas
 			}
 		}
 
+		if !sendMail && len(channel.Options.Killmail.Rules) > 0 {
+			k := facts
+			k.Loss = containsInt32(service.EntityID, k.VictimEntities)
+			k.Kill = containsInt32(service.EntityID, k.AttackerEntities)
+			sendMail = channel.Options.matchRules(&k)
+		}
+
 		if sendMail {
-			if err := service.Server.SendMessageToChannel(channel.ChannelID,
-				fmt.Sprintf("https://www.evedata.org/killmail?id=%d", mail.KillmailId)); err != nil {
+			if message == nil {
+				message = s.killmailMessage(mail, &facts)
+			}
+			if err := service.Server.SendRichMessageToChannel(channel.ChannelID, message); err != nil {
 				log.Println(err)
 			}
 		}
@@ -0,0 +1,108 @@
+package conservator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/antihax/evedata/internal/botservice"
+	"github.com/antihax/evedata/internal/killfilter"
+	"github.com/antihax/goesi/esi"
+)
+
+// maxTopAttackers is how many attackers are listed on killmail messages
+const maxTopAttackers = 3
+
+// entityLink returns a markdown link to an entity, or an empty string if it is unknown
+func (s *Conservator) entityLink(id int32) string {
+	if id == 0 {
+		return ""
+	}
+	entity, err := s.getEntityName(id)
+	if err != nil || entity.Name == "" {
+		return ""
+	}
+	return fmt.Sprintf("[%s](https://www.evedata.org/%s?id=%d)", entity.Name, entity.EntityType, id)
+}
+
+// firstEntityLink returns a link to the first known entity
+func (s *Conservator) firstEntityLink(ids ...int32) string {
+	for _, id := range ids {
+		if link := s.entityLink(id); link != "" {
+			return link
+		}
+	}
+	return "Unknown"
+}
+
+// topAttackers returns the attackers dealing the most damage, with the final blow always included
+func topAttackers(attackers []esi.GetKillmailsKillmailIdKillmailHashAttacker, n int) []esi.GetKillmailsKillmailIdKillmailHashAttacker {
+	sorted := make([]esi.GetKillmailsKillmailIdKillmailHashAttacker, len(attackers))
+	copy(sorted, attackers)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].DamageDone > sorted[j].DamageDone
+	})
+
+	if len(sorted) <= n {
+		return sorted
+	}
+
+	top := sorted[:n]
+	for _, a := range sorted[n:] {
+		if a.FinalBlow {
+			top[n-1] = a
+			break
+		}
+	}
+	return top
+}
+
+// killmailMessage builds the message posted to channels for a killmail
+func (s *Conservator) killmailMessage(mail *esi.GetKillmailsKillmailIdKillmailHashOk, facts *killfilter.Kill) *botservice.Message {
+	shipName, _ := s.getTypeName(mail.Victim.ShipTypeId)
+	systemName, _ := s.getSystemName(mail.SolarSystemId)
+	regionName, _ := s.getCelestialName(facts.RegionID)
+
+	victim := s.firstEntityLink(mail.Victim.CharacterId, mail.Victim.CorporationId)
+	if mail.Victim.CharacterId != 0 {
+		if corp := s.entityLink(mail.Victim.CorporationId); corp != "" {
+			victim += " (" + corp + ")"
+		}
+	}
+
+	m := &botservice.Message{
+		Title:           fmt.Sprintf("%s destroyed in %s", shipName, systemName),
+		URL:             fmt.Sprintf("https://www.evedata.org/killmail?id=%d", mail.KillmailId),
+		Description:     fmt.Sprintf("%s lost their %s", victim, shipName),
+		Color:           botservice.SecurityColor(facts.Security),
+		ThumbnailTypeID: mail.Victim.ShipTypeId,
+		Timestamp:       mail.KillmailTime,
+	}
+
+	m.AddField("Value", botservice.FormatISK(facts.Value), true)
+	m.AddField("System", fmt.Sprintf("%s %.1f (%s)", systemName, botservice.RoundSecurity(facts.Security), regionName), true)
+	m.AddField("Damage Taken", fmt.Sprintf("%d", mail.Victim.DamageTaken), true)
+
+	attackers := []string{}
+	for _, a := range topAttackers(mail.Attackers, maxTopAttackers) {
+		line := s.firstEntityLink(a.CharacterId, a.CorporationId, a.FactionId)
+		if a.ShipTypeId != 0 {
+			if ship, err := s.getTypeName(a.ShipTypeId); err == nil {
+				line += " in " + ship
+			}
+		}
+		if mail.Victim.DamageTaken > 0 {
+			line += fmt.Sprintf(" (%d, %.0f%%)", a.DamageDone, float64(a.DamageDone)*100/float64(mail.Victim.DamageTaken))
+		}
+		if a.FinalBlow {
+			line += " final blow"
+		}
+		attackers = append(attackers, line)
+	}
+
+	if len(attackers) > 0 {
+		m.AddField(fmt.Sprintf("Top Attackers (%d total)", len(mail.Attackers)), strings.Join(attackers, "\n"), false)
+	}
+
+	return m
+}
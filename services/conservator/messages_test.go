@@ -0,0 +1,29 @@
+package conservator
+
+import (
+	"testing"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopAttackers(t *testing.T) {
+	attackers := []esi.GetKillmailsKillmailIdKillmailHashAttacker{
+		{CharacterId: 1, DamageDone: 100},
+		{CharacterId: 2, DamageDone: 500},
+		{CharacterId: 3, DamageDone: 10, FinalBlow: true},
+		{CharacterId: 4, DamageDone: 300},
+		{CharacterId: 5, DamageDone: 200},
+	}
+
+	top := topAttackers(attackers, 3)
+	assert.Len(t, top, 3)
+	assert.Equal(t, int32(2), top[0].CharacterId)
+	assert.Equal(t, int32(4), top[1].CharacterId)
+	assert.Equal(t, int32(3), top[2].CharacterId)
+
+	// Original order is kept
+	assert.Equal(t, int32(1), attackers[0].CharacterId)
+
+	assert.Len(t, topAttackers(attackers[:2], 3), 2)
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/antihax/evedata/internal/botservice"
 	"github.com/antihax/evedata/internal/datapackages"
 	"github.com/antihax/evedata/internal/gobcoder"
 	"github.com/antihax/goesi/notification"
@@ -78,11 +79,16 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 
 		stationName, _ := s.getStationName(l.TargetLocation.Station)
 
-		message := fmt.Sprintf(" %s (https://www.evedata.org/character?id=%d) has been located in %s of %s",
-			character.Name, l.CharacterID, systemName, regionName)
+		message := &botservice.Message{
+			Title:     fmt.Sprintf("%s has been located", character.Name),
+			URL:       fmt.Sprintf("https://www.evedata.org/character?id=%d", l.CharacterID),
+			Color:     botservice.ColorInfo,
+			Timestamp: timestamp,
+		}
+		message.AddField("System", systemName, true).AddField("Region", regionName, true)
 
 		if stationName != "" {
-			message = fmt.Sprintf("%s docked at %s", message, stationName)
+			message.AddField("Docked At", stationName, false)
 		}
 
 		return s.sendNotificationMessage("locator", characterID, notificationID, message)
@@ -93,17 +99,13 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 			log.Println(err)
 		}
 
-		defender, err := s.getEntityName(l.AgainstID)
-		if err != nil {
-			log.Println(err)
+		message := &botservice.Message{
+			Title: "War Declared",
+			Description: fmt.Sprintf("%s just declared war on %s",
+				s.firstEntityLink(l.DeclaredByID), s.firstEntityLink(l.AgainstID)),
+			Color:     botservice.ColorDanger,
+			Timestamp: timestamp,
 		}
-		attacker, err := s.getEntityName(l.DeclaredByID)
-		if err != nil {
-			log.Println(err)
-		}
-
-		message := fmt.Sprintf("[%s](https://www.evedata.org/%s?id=%d) just declared war on [%s](https://www.evedata.org/%s?id=%d)\n",
-			attacker.Name, attacker.EntityType, l.DeclaredByID, defender.Name, defender.EntityType, l.AgainstID)
 
 		return s.sendNotificationMessage("war", characterID, notificationID, message)
 
@@ -117,8 +119,16 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 			}
 			corporation, _ := s.getEntityName(l.CorpID)
 
-			message := fmt.Sprintf("New corporation application from [%s](https://www.evedata.org/character?id=%d) to %s. Application Comment: %s\n",
-				character.Name, l.CharID, corporation.Name, l.ApplicationText)
+			message := &botservice.Message{
+				Title:     fmt.Sprintf("New application from %s", character.Name),
+				URL:       fmt.Sprintf("https://www.evedata.org/character?id=%d", l.CharID),
+				Color:     botservice.ColorInfo,
+				Timestamp: timestamp,
+			}
+			message.AddField("Corporation", corporation.Name, true)
+			if l.ApplicationText != "" {
+				message.AddField("Application Comment", l.ApplicationText, false)
+			}
 
 			return s.sendNotificationMessage("application", characterID, notificationID, message)
 		}
@@ -145,8 +155,9 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 			log.Println(err)
 		}
 
-		message := fmt.Sprintf("@everyone structure is under attack in %s by [%s](https://www.evedata.org/%s?id=%d) S: %.1f%%  A: %.1f%%  H: %.1f%% \n",
-			systemName, attackerName, attackerType, attacker, l.ShieldPercentage, l.ArmorPercentage, l.HullPercentage)
+		message := structureMessage("Structure under attack", systemName, timestamp)
+		message.Description = fmt.Sprintf("Attacked by [%s](https://www.evedata.org/%s?id=%d)", attackerName, attackerType, attacker)
+		addHealthFields(message, l.ShieldPercentage, l.ArmorPercentage, l.HullPercentage)
 
 		return s.sendNotificationMessage("structure", characterID, notificationID, message)
 
@@ -154,16 +165,6 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 		l := notification.OrbitalAttacked{}
 		yaml.Unmarshal([]byte(text), &l)
 
-		attacker := int32(0)
-		attackerType := ""
-		if l.AggressorAllianceID > 0 {
-			attacker = l.AggressorAllianceID
-			attackerType = "alliance"
-		} else if l.AggressorCorpID > 0 {
-			attacker = l.AggressorCorpID
-			attackerType = "corporation"
-		}
-
 		locationName, err := s.getCelestialName(l.PlanetID)
 		if err != nil {
 			log.Println(err)
@@ -176,30 +177,19 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 		if err != nil {
 			log.Println(err)
 		}
-		attackerName, err := s.getEntityName(attacker)
-		if err != nil {
-			log.Println(err)
-		}
 
-		message := fmt.Sprintf("@everyone %s is under attack at %s in %s by [%s](https://www.evedata.org/%s?id=%d) S: %.1f%%\n",
-			structureType, locationName, systemName, attackerName.Name, attackerType, attacker, l.ShieldLevel*100)
+		message := structureMessage(structureType+" under attack", systemName, timestamp)
+		message.ThumbnailTypeID = l.TypeID
+		message.Description = "Attacked by " + s.firstEntityLink(l.AggressorAllianceID, l.AggressorCorpID)
+		message.AddField("Location", locationName, true)
+		message.AddField("Shield", fmt.Sprintf("%.1f%%", l.ShieldLevel*100), true)
 
-		return s.sendNotificationMessage("structure", characterID, notificationID, message)
+		return s.sendPOCOMessage(characterID, notificationID, message)
 
 	case "TowerAlertMsg":
 		l := notification.TowerAlertMsg{}
 		yaml.Unmarshal([]byte(text), &l)
 
-		attacker := int32(0)
-		attackerType := ""
-		if l.AggressorAllianceID > 0 {
-			attacker = l.AggressorAllianceID
-			attackerType = "alliance"
-		} else if l.AggressorCorpID > 0 {
-			attacker = l.AggressorCorpID
-			attackerType = "corporation"
-		}
-
 		locationName, err := s.getCelestialName(l.MoonID)
 		if err != nil {
 			log.Println(err)
@@ -212,12 +202,12 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 		if err != nil {
 			log.Println(err)
 		}
-		attackerName, err := s.getEntityName(attacker)
-		if err != nil {
-			log.Println(err)
-		}
-		message := fmt.Sprintf("@everyone %s is under attack at %s in %s by [%s](https://www.evedata.org/%s?id=%d) S: %.1f%%  A: %.1f%%  H: %.1f%% \n",
-			structureType, locationName, systemName, attackerName.Name, attackerType, attacker, l.ShieldValue*100, l.ArmorValue*100, l.HullValue*100)
+
+		message := structureMessage(structureType+" under attack", systemName, timestamp)
+		message.ThumbnailTypeID = l.TypeID
+		message.Description = "Attacked by " + s.firstEntityLink(l.AggressorAllianceID, l.AggressorCorpID)
+		message.AddField("Location", locationName, true)
+		addHealthFields(message, l.ShieldValue*100, l.ArmorValue*100, l.HullValue*100)
 
 		return s.sendNotificationMessage("structure", characterID, notificationID, message)
 
@@ -225,16 +215,6 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 		l := notification.OrbitalReinforced{}
 		yaml.Unmarshal([]byte(text), &l)
 
-		attacker := int32(0)
-		attackerType := ""
-		if l.AggressorAllianceID > 0 {
-			attacker = l.AggressorAllianceID
-			attackerType = "alliance"
-		} else if l.AggressorCorpID > 0 {
-			attacker = l.AggressorCorpID
-			attackerType = "corporation"
-		}
-
 		locationName, err := s.getCelestialName(l.PlanetID)
 		if err != nil {
 			log.Println(err)
@@ -247,16 +227,14 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 		if err != nil {
 			log.Println(err)
 		}
-		attackerName, err := s.getEntityName(attacker)
-		if err != nil {
-			log.Println(err)
-		}
 
-		message := fmt.Sprintf("@everyone %s was reinforced at %s in %s by [%s](https://www.evedata.org/%s?id=%d).\n\n Timer expires at %s\n",
-			structureType, locationName, systemName, attackerName.Name, attackerType, attacker,
-			time.Unix(datapackages.WintoUnixTimestamp(l.ReinforceExitTime), 0).UTC().String())
+		message := structureMessage(structureType+" reinforced", systemName, timestamp)
+		message.ThumbnailTypeID = l.TypeID
+		message.Description = "Reinforced by " + s.firstEntityLink(l.AggressorAllianceID, l.AggressorCorpID)
+		message.AddField("Location", locationName, true)
+		message.AddField("Timer Expires", timerString(l.ReinforceExitTime), true)
 
-		return s.sendNotificationMessage("structure", characterID, notificationID, message)
+		return s.sendPOCOMessage(characterID, notificationID, message)
 
 	case "StructureLostShields", "StructureLostArmor":
 		l := notification.StructureLostShields{}
@@ -272,16 +250,63 @@ func (s *Conservator) checkNotification(characterID int32, notificationID int64,
 			log.Println(err)
 		}
 
-		message := fmt.Sprintf("@everyone %s entered %s in %s: Timer expires at %s\n",
-			structureType, notificationType, systemName,
-			time.Unix(datapackages.WintoUnixTimestamp(l.Timestamp), 0).UTC().String())
+		title := structureType + " lost shields"
+		if notificationType == "StructureLostArmor" {
+			title = structureType + " lost armor"
+		}
+
+		message := structureMessage(title, systemName, timestamp)
+		message.ThumbnailTypeID = l.StructureTypeID
+		message.AddField("Timer Expires", timerString(l.Timestamp), true)
 
 		return s.sendNotificationMessage("structure", characterID, notificationID, message)
+
+	default:
+		h, ok := notificationHandlers[notificationType]
+		if !ok {
+			return nil
+		}
+
+		message, err := h.build(s, notificationType, text)
+		if err != nil {
+			// Don't requeue notifications we can't read
+			log.Printf("%s notification %d: %v\n", notificationType, notificationID, err)
+			return nil
+		}
+		message.Timestamp = timestamp
+		return s.sendNotificationMessage(h.messageType, characterID, notificationID, message)
 	}
-	return nil
 }
 
-func (s *Conservator) sendNotificationMessage(messageType string, characterID int32, notificationID int64, message string) error {
+// structureMessage starts a structure alert which pings the channel
+func structureMessage(title, systemName string, timestamp time.Time) *botservice.Message {
+	message := &botservice.Message{
+		Content:   "@everyone",
+		Title:     title,
+		Color:     botservice.ColorDanger,
+		Timestamp: timestamp,
+	}
+	message.AddField("System", systemName, true)
+	return message
+}
+
+// addHealthFields adds shield, armor, and hull percentages to a message
+func addHealthFields(message *botservice.Message, shield, armor, hull float64) {
+	message.AddField("Shield", fmt.Sprintf("%.1f%%", shield), true)
+	message.AddField("Armor", fmt.Sprintf("%.1f%%", armor), true)
+	message.AddField("Hull", fmt.Sprintf("%.1f%%", hull), true)
+}
+
+// sendPOCOMessage sends customs office notifications to poco channels, and to structure
+// channels which received them before poco was split out. Channels with both only get one.
+func (s *Conservator) sendPOCOMessage(characterID int32, notificationID int64, message *botservice.Message) error {
+	if err := s.sendNotificationMessage("poco", characterID, notificationID, message); err != nil {
+		return err
+	}
+	return s.sendNotificationMessage("structure", characterID, notificationID, message)
+}
+
+func (s *Conservator) sendNotificationMessage(messageType string, characterID int32, notificationID int64, message *botservice.Message) error {
 	shares, ok := s.notifications[messageType][characterID]
 	if !ok {
 		return nil
@@ -306,7 +331,7 @@ func (s *Conservator) sendNotificationMessage(messageType string, characterID in
 					return err
 				}
 
-				if err := service.Server.SendMessageToChannel(channel.ChannelID, message); err != nil {
+				if err := service.Server.SendRichMessageToChannel(channel.ChannelID, message); err != nil {
 					log.Println(err)
 					return err
 				}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"reflect"
 	"strings"
 	"sync"
@@ -13,6 +14,8 @@ import (
 
 	"github.com/antihax/evedata/internal/botservice"
 	"github.com/antihax/evedata/internal/botservice/discordservice"
+	"github.com/antihax/evedata/internal/botservice/mumbleservice"
+	"github.com/antihax/evedata/internal/botservice/slackservice"
 	"github.com/antihax/evedata/internal/botservice/tsservice"
 )
 
@@ -55,6 +58,9 @@ type ChannelOptions struct {
 		FactionWar       bool `json:"factionWar,omitempty"`
 		SendAll          bool `json:"sendAll,omitempty"`
 		SendAllAbyssalT4 bool `json:"sendAllAbyssalT4,omitempty"`
+
+		// Rules send killmails matching any filter expression
+		Rules []KillmailRule `json:"rules,omitempty"`
 	} `json:"killmail,omitempty"`
 }
 
@@ -64,6 +70,11 @@ type ChannelTypes struct {
 	Kill        bool `json:"kill,omitempty"`        // killmails
 	Structure   bool `json:"structure,omitempty"`   // structure notifications
 	Application bool `json:"application,omitempty"` // applications to corp
+	Fuel        bool `json:"fuel,omitempty"`        // structure fuel, power, and services offline
+	Anchoring   bool `json:"anchoring,omitempty"`   // structures anchoring, unanchoring, and onlining
+	Moon        bool `json:"moon,omitempty"`        // moon extractions
+	Sovereignty bool `json:"sovereignty,omitempty"` // entosis, command nodes, and sov structures
+	Poco        bool `json:"poco,omitempty"`        // customs offices attacked and reinforced
 }
 
 func (c *ChannelTypes) GetServices() string {
@@ -134,6 +145,33 @@ type Share struct {
 	Ignored            int32  `db:"ignored" json:"ignored,omitempty"`
 }
 
+// connection is implemented by integrations holding a connection open to their server
+type connection interface {
+	io.Closer
+	Connected() bool
+}
+
+// existingServer returns the loaded integration for a service if it has not changed.
+// Stale connections are closed so they can be replaced.
+func (s *Conservator) existingServer(service Service) botservice.Integration {
+	vi, ok := s.services.Load(service.IntegrationID)
+	if !ok {
+		return nil
+	}
+	old := vi.(Service)
+
+	conn, isConn := old.Server.(connection)
+	if old.Type == service.Type && old.Address == service.Address &&
+		old.Authentication == service.Authentication && (!isConn || conn.Connected()) {
+		return old.Server
+	}
+
+	if isConn {
+		conn.Close()
+	}
+	return nil
+}
+
 // Load our bot services
 func (s *Conservator) loadServices() error {
 	// Mark what we touch so we can erase any missing items
@@ -144,24 +182,47 @@ func (s *Conservator) loadServices() error {
 		return err
 	}
 
-	// [TODO] Slack, murmur
 	for _, service := range services {
-		var n botservice.Integration
-
 		// Mark what we touch
 		touched[service.IntegrationID] = true
 
-		switch service.Type {
-		case "discord":
-			n = discordservice.NewDiscordService(s.discord, service.Address)
-		case "ts3":
-			auth := strings.Split(service.Authentication, ":")
-			n, err = tsservice.NewTSService(service.Address, auth[0], auth[1])
-			if err != nil {
-				log.Println(err)
+		// Keep the running integration if the service has not changed
+		n := s.existingServer(service)
+		if n == nil {
+			switch service.Type {
+			case "discord":
+				n = discordservice.NewDiscordService(s.discord, service.Address)
+			case "ts3":
+				auth := strings.SplitN(service.Authentication, ":", 2)
+				if len(auth) != 2 {
+					log.Printf("ts3 integration %d has bad authentication\n", service.IntegrationID)
+					continue
+				}
+				n, err = tsservice.NewTSService(service.Address, auth[0], auth[1])
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+			case "slack":
+				n, err = slackservice.NewSlackService(service.Address, service.Authentication)
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+			case "mumble":
+				auth := strings.SplitN(service.Authentication, ":", 2)
+				if len(auth) != 2 {
+					log.Printf("mumble integration %d has bad authentication\n", service.IntegrationID)
+					continue
+				}
+				n, err = mumbleservice.NewMumbleService(service.Address, auth[0], auth[1])
+				if err != nil {
+					log.Println(err)
+					continue
+				}
+			default:
+				return errors.New("unknown service type")
 			}
-		default:
-			return errors.New("unknown service type")
 		}
 
 		// Explode our options into the struct
@@ -180,6 +241,9 @@ func (s *Conservator) loadServices() error {
 		// Remove anything we didn't find
 		if !touched[v.IntegrationID] {
 			s.services.Delete(k)
+			if c, ok := v.Server.(io.Closer); ok {
+				c.Close()
+			}
 		} else {
 			// Update the server name while we are here
 			serverName, err := v.Server.GetName()
@@ -243,6 +307,9 @@ func (s *Conservator) loadChannels() error {
 		// Mark what we touch
 		touched[channel.ChannelID] = true
 		json.Unmarshal([]byte(channel.OptionsJSON), &channel.Options)
+		if err := channel.Options.CompileRules(); err != nil {
+			log.Printf("channel %s: %v\n", channel.ChannelID, err)
+		}
 
 		s.channels.Store(channel.ChannelID, channel)
 	}
@@ -397,6 +464,9 @@ func (s *Conservator) updateData() {
 		if err := s.loadShares(); err != nil {
 			log.Println(err)
 		}
+		if err := s.loadTypePrices(); err != nil {
+			log.Println(err)
+		}
 		s.checkAllUsers()
 		<-throttle
 
@@ -5,9 +5,14 @@ import (
 	"os"
 	"testing"
 
+	"github.com/antihax/evedata/internal/botservice/slackservice"
+	"github.com/antihax/evedata/internal/botservice/slackservice/slackfake"
+	"github.com/antihax/evedata/internal/botservice/tsservice"
+	"github.com/antihax/evedata/internal/botservice/tsservice/tsfake"
 	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/antihax/evedata/internal/sqlhelper"
+	"github.com/nlopes/slack"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +48,16 @@ func TestMain(m *testing.M) {
 			(1123125, 24234235, 234, "locator"),
 			(1123123, 24234234, 567, "war,locator,structure")
 			ON DUPLICATE KEY UPDATE characterID=characterID`,
+		`INSERT INTO evedata.crestTokens
+			(characterID, tokenCharacterID, accessToken, refreshToken, expiry, tokenType, lastStatus, characterName, scopes, authCharacter, corporationID) 
+			VALUES
+			(1123123, 1123123, "", "", UTC_TIMESTAMP(), "", "", "Test", "", 1, 98000001)
+			ON DUPLICATE KEY UPDATE authCharacter = 1, corporationID = 98000001`,
+		`INSERT INTO evedata.entityContacts
+			(entityID, contactID, standing) 
+			VALUES
+			(234, 98000001, 10.0)
+			ON DUPLICATE KEY UPDATE standing = 10.0`,
 	}
 
 	for _, insert := range inserts {
@@ -92,3 +107,79 @@ func TestConservator(t *testing.T) {
 	assert.Zero(t, len(conserv.notifications["kill"]))
 	assert.Zero(t, len(conserv.notifications["locator"]))
 }
+
+func TestTSJoin(t *testing.T) {
+	fake, err := tsfake.NewServer()
+	assert.Nil(t, err)
+	defer fake.Close()
+
+	fake.SetResponse("privilegekeyadd", "token=joinme")
+	fake.SetResponse("customsearch", "cldbid=55 ident=evedata value=1123123")
+
+	ts, err := tsservice.NewTSService(fake.Addr(), "serveradmin", "nothinguseful")
+	assert.Nil(t, err)
+	defer ts.Close()
+
+	service := Service{IntegrationID: 100, EntityID: 234, Type: "ts3", Server: ts}
+	conserv.services.Store(service.IntegrationID, service)
+	defer conserv.services.Delete(service.IntegrationID)
+
+	// No roles configured
+	token := ""
+	err = conserv.JoinTSUser(&JoinUser{IntegrationID: 100, CharacterID: 1123123, CharacterName: "Test"}, &token)
+	assert.NotNil(t, err)
+
+	service.Options.Auth.PlusTen = "7"
+	conserv.services.Store(service.IntegrationID, service)
+	err = conserv.JoinTSUser(&JoinUser{IntegrationID: 100, CharacterID: 1123123, CharacterName: "Test"}, &token)
+	assert.Nil(t, err)
+	assert.Equal(t, "joinme", token)
+	assert.True(t, fake.Received("privilegekeyadd tokentype=0 tokenid1=7"))
+
+	err = conserv.linkTSClients(service.IntegrationID, ts)
+	assert.Nil(t, err)
+
+	var clientID string
+	err = conserv.db.QueryRowx(`SELECT integrationUserID FROM evedata.integrationCharacters WHERE integrationID = 100 AND characterID = 1123123`).Scan(&clientID)
+	assert.Nil(t, err)
+	assert.Equal(t, "55", clientID)
+}
+
+func TestSlackJoin(t *testing.T) {
+	fake := slackfake.NewServer("T1")
+	defer fake.Close()
+
+	fake.AddUser("U1", "Test")
+	fake.AddUser("U2", "Other")
+	fake.AddGroup("S1", "plus ten")
+	fake.AddGroup("S2", "members", "U1", "U2")
+
+	sl, err := slackservice.NewSlackService("T1", "xoxb-test", slack.OptionAPIURL(fake.URL()))
+	assert.Nil(t, err)
+
+	// Character 1123123 is +10 to entity 234 but not a member
+	service := Service{IntegrationID: 101, EntityID: 234, Type: "slack", Services: "auth", Server: sl}
+	service.Options.Auth.PlusTen = "S1"
+	service.Options.Auth.Members = "S2"
+	conserv.services.Store(service.IntegrationID, service)
+	defer conserv.services.Delete(service.IntegrationID)
+
+	ok := false
+	err = conserv.JoinUser(&JoinUser{IntegrationID: 101, UserID: "U3", CharacterID: 1123123, CharacterName: "Test"}, &ok)
+	assert.NotNil(t, err)
+	assert.False(t, ok)
+
+	err = conserv.JoinUser(&JoinUser{IntegrationID: 101, UserID: "U1", CharacterID: 1123123, CharacterName: "Test"}, &ok)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []string{"U1"}, fake.Members("S1"))
+
+	// The periodic check revokes roles from members who do not qualify or never linked
+	members, err := sl.GetMembers()
+	assert.Nil(t, err)
+	for _, m := range members {
+		assert.Nil(t, conserv.checkUser(m.ID, m.Name, service.IntegrationID, m.Roles))
+	}
+	assert.True(t, fake.Disabled("S2"))
+	assert.Equal(t, []string{"U1"}, fake.Members("S1"))
+}
@@ -0,0 +1,420 @@
+package conservator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antihax/evedata/internal/botservice"
+	"github.com/antihax/evedata/internal/datapackages"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// notificationHandler builds a message for a notification type and the share type it is sent under
+type notificationHandler struct {
+	messageType string
+	build       func(s *Conservator, notificationType, text string) (*botservice.Message, error)
+}
+
+// notificationHandlers covers structure, moon mining, sovereignty, and POCO notifications
+var notificationHandlers = map[string]notificationHandler{
+	// Fuel and power
+	"StructureFuelAlert":       {"fuel", (*Conservator).structureFuelAlert},
+	"TowerResourceAlertMsg":    {"fuel", (*Conservator).towerResourceAlert},
+	"StructureServicesOffline": {"fuel", (*Conservator).structureServicesOffline},
+	"StructureWentLowPower":    {"fuel", (*Conservator).structurePower},
+	"StructureWentHighPower":   {"fuel", (*Conservator).structurePower},
+
+	// Anchoring
+	"StructureAnchoring":   {"anchoring", (*Conservator).structureAnchoring},
+	"StructureUnanchoring": {"anchoring", (*Conservator).structureAnchoring},
+	"StructureOnline":      {"anchoring", (*Conservator).structureOnline},
+
+	// Structure state
+	"StructureDestroyed": {"structure", (*Conservator).structureDestroyed},
+
+	// Moon mining
+	"MoonminingExtractionStarted":   {"moon", (*Conservator).moonExtraction},
+	"MoonminingExtractionFinished":  {"moon", (*Conservator).moonExtraction},
+	"MoonminingExtractionCancelled": {"moon", (*Conservator).moonExtraction},
+	"MoonminingAutomaticFracture":   {"moon", (*Conservator).moonExtraction},
+	"MoonminingLaserFired":          {"moon", (*Conservator).moonExtraction},
+
+	// Sovereignty
+	"EntosisCaptureStarted":      {"sovereignty", (*Conservator).sovStructure},
+	"SovStructureDestroyed":      {"sovereignty", (*Conservator).sovStructure},
+	"SovCommandNodeEventStarted": {"sovereignty", (*Conservator).sovCampaign},
+	"SovStructureReinforced":     {"sovereignty", (*Conservator).sovCampaign},
+	"SovAllClaimAquiredMsg":      {"sovereignty", (*Conservator).sovClaim},
+	"SovAllClaimLostMsg":         {"sovereignty", (*Conservator).sovClaim},
+}
+
+// structureInfo is common to most upwell structure notifications
+type structureInfo struct {
+	SolarSystemID   int32 `yaml:"solarsystemID"`
+	StructureID     int64 `yaml:"structureID"`
+	StructureTypeID int32 `yaml:"structureTypeID"`
+}
+
+// upwellMessage starts a message describing an upwell structure
+func (s *Conservator) upwellMessage(info structureInfo, what string, color int) *botservice.Message {
+	typeName, _ := s.getTypeName(info.StructureTypeID)
+	systemName, _ := s.getSystemName(info.SolarSystemID)
+
+	name := s.getStructureName(info.StructureID)
+	if name == "" {
+		name = typeName
+	}
+
+	message := &botservice.Message{
+		Title:           fmt.Sprintf("%s %s", name, what),
+		Color:           color,
+		ThumbnailTypeID: info.StructureTypeID,
+	}
+	message.AddField("System", systemName, true)
+	if typeName != "" && typeName != name {
+		message.AddField("Type", typeName, true)
+	}
+	return message
+}
+
+func (s *Conservator) structureFuelAlert(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		structureInfo     `yaml:",inline"`
+		ListOfTypesAndQty [][]int32 `yaml:"listOfTypesAndQty"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	message := s.upwellMessage(l.structureInfo, "is low on fuel", botservice.ColorWarning)
+	remaining := []string{}
+	for _, t := range l.ListOfTypesAndQty {
+		if len(t) != 2 {
+			continue
+		}
+		typeName, _ := s.getTypeName(t[1])
+		remaining = append(remaining, fmt.Sprintf("%d %s", t[0], typeName))
+	}
+	if len(remaining) > 0 {
+		message.AddField("Remaining", strings.Join(remaining, "\n"), false)
+	}
+	return message, nil
+}
+
+func (s *Conservator) towerResourceAlert(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		MoonID        int32 `yaml:"moonID"`
+		SolarSystemID int32 `yaml:"solarSystemID"`
+		TypeID        int32 `yaml:"typeID"`
+		Wants         []struct {
+			Quantity int32 `yaml:"quantity"`
+			TypeID   int32 `yaml:"typeID"`
+		} `yaml:"wants"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	typeName, _ := s.getTypeName(l.TypeID)
+	moonName, _ := s.getCelestialName(l.MoonID)
+
+	message := &botservice.Message{
+		Title:           typeName + " is low on resources",
+		Color:           botservice.ColorWarning,
+		ThumbnailTypeID: l.TypeID,
+	}
+	message.AddField("Location", moonName, true)
+	for _, want := range l.Wants {
+		wantName, _ := s.getTypeName(want.TypeID)
+		message.AddField(wantName, fmt.Sprintf("%d remaining", want.Quantity), true)
+	}
+	return message, nil
+}
+
+func (s *Conservator) structureServicesOffline(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		structureInfo          `yaml:",inline"`
+		ListOfServiceModuleIDs []int32 `yaml:"listOfServiceModuleIDs"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	message := s.upwellMessage(l.structureInfo, "services went offline", botservice.ColorDanger)
+	services := []string{}
+	for _, id := range l.ListOfServiceModuleIDs {
+		name, _ := s.getTypeName(id)
+		services = append(services, name)
+	}
+	if len(services) > 0 {
+		message.AddField("Services", strings.Join(services, "\n"), false)
+	}
+	return message, nil
+}
+
+func (s *Conservator) structurePower(notificationType, text string) (*botservice.Message, error) {
+	l := structureInfo{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	if notificationType == "StructureWentHighPower" {
+		return s.upwellMessage(l, "went to high power", botservice.ColorInfo), nil
+	}
+	return s.upwellMessage(l, "went to low power", botservice.ColorWarning), nil
+}
+
+func (s *Conservator) structureAnchoring(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		structureInfo `yaml:",inline"`
+		OwnerCorpName string `yaml:"ownerCorpName"`
+		TimeLeft      int64  `yaml:"timeLeft"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	what := "started anchoring"
+	if notificationType == "StructureUnanchoring" {
+		what = "started unanchoring"
+	}
+
+	message := s.upwellMessage(l.structureInfo, what, botservice.ColorWarning)
+	if l.OwnerCorpName != "" {
+		message.AddField("Owner", l.OwnerCorpName, true)
+	}
+	if l.TimeLeft > 0 {
+		message.AddField("Time Left", winDuration(l.TimeLeft).String(), true)
+	}
+	return message, nil
+}
+
+func (s *Conservator) structureOnline(notificationType, text string) (*botservice.Message, error) {
+	l := structureInfo{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+	return s.upwellMessage(l, "is now online", botservice.ColorInfo), nil
+}
+
+func (s *Conservator) structureDestroyed(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		structureInfo `yaml:",inline"`
+		OwnerCorpName string `yaml:"ownerCorpName"`
+		IsAbandoned   bool   `yaml:"isAbandoned"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	message := s.upwellMessage(l.structureInfo, "was destroyed", botservice.ColorDanger)
+	message.Content = "@everyone"
+	if l.OwnerCorpName != "" {
+		message.AddField("Owner", l.OwnerCorpName, true)
+	}
+	if l.IsAbandoned {
+		message.Description = "The structure was abandoned"
+	}
+	return message, nil
+}
+
+func (s *Conservator) moonExtraction(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		MoonID          int32             `yaml:"moonID"`
+		SolarSystemID   int32             `yaml:"solarSystemID"`
+		StructureID     int64             `yaml:"structureID"`
+		StructureName   string            `yaml:"structureName"`
+		StructureTypeID int32             `yaml:"structureTypeID"`
+		OreVolumeByType map[int32]float64 `yaml:"oreVolumeByType"`
+		ReadyTime       int64             `yaml:"readyTime"`
+		AutoTime        int64             `yaml:"autoTime"`
+		StartedBy       int32             `yaml:"startedBy"`
+		FiredBy         int32             `yaml:"firedBy"`
+		CancelledBy     int32             `yaml:"cancelledBy"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	moonName, _ := s.getCelestialName(l.MoonID)
+	name := l.StructureName
+	if name == "" {
+		name, _ = s.getTypeName(l.StructureTypeID)
+	}
+
+	message := &botservice.Message{
+		Color:           botservice.ColorInfo,
+		ThumbnailTypeID: l.StructureTypeID,
+	}
+
+	switch notificationType {
+	case "MoonminingExtractionStarted":
+		message.Title = name + " started a moon extraction"
+		if l.StartedBy != 0 {
+			message.Description = "Started by " + s.firstEntityLink(l.StartedBy)
+		}
+	case "MoonminingExtractionFinished":
+		message.Title = name + " finished a moon extraction"
+		message.Color = botservice.ColorWarning
+	case "MoonminingExtractionCancelled":
+		message.Title = name + " cancelled a moon extraction"
+		if l.CancelledBy != 0 {
+			message.Description = "Cancelled by " + s.firstEntityLink(l.CancelledBy)
+		}
+	case "MoonminingAutomaticFracture":
+		message.Title = name + " automatically fractured the moon"
+		message.Color = botservice.ColorWarning
+	case "MoonminingLaserFired":
+		message.Title = name + " fired the moon drill"
+		message.Color = botservice.ColorWarning
+		if l.FiredBy != 0 {
+			message.Description = "Fired by " + s.firstEntityLink(l.FiredBy)
+		}
+	}
+
+	message.AddField("Moon", moonName, true)
+	if l.ReadyTime > 0 {
+		message.AddField("Ready", timerString(l.ReadyTime), true)
+	}
+	if l.AutoTime > 0 {
+		message.AddField("Auto Fracture", timerString(l.AutoTime), true)
+	}
+
+	if ore := s.oreVolumes(l.OreVolumeByType); ore != "" {
+		message.AddField("Ore", ore, false)
+	}
+	return message, nil
+}
+
+// oreVolumes lists ore in a chunk from largest to smallest
+func (s *Conservator) oreVolumes(volumes map[int32]float64) string {
+	typeIDs := []int32{}
+	for typeID := range volumes {
+		typeIDs = append(typeIDs, typeID)
+	}
+	sort.Slice(typeIDs, func(i, j int) bool {
+		return volumes[typeIDs[i]] > volumes[typeIDs[j]]
+	})
+
+	lines := []string{}
+	for _, typeID := range typeIDs {
+		name, _ := s.getTypeName(typeID)
+		lines = append(lines, fmt.Sprintf("%s: %.0f m3", name, volumes[typeID]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sovCampaignTypes are the structures a sovereignty campaign is against
+var sovCampaignTypes = map[int32]string{
+	1: "Territorial Claim Unit",
+	2: "Infrastructure Hub",
+	3: "Station",
+}
+
+func (s *Conservator) sovStructure(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		SolarSystemID   int32 `yaml:"solarSystemID"`
+		StructureTypeID int32 `yaml:"structureTypeID"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	typeName, _ := s.getTypeName(l.StructureTypeID)
+	systemName, _ := s.getSystemName(l.SolarSystemID)
+
+	message := &botservice.Message{
+		Content:         "@everyone",
+		Title:           typeName + " is being captured",
+		Color:           botservice.ColorDanger,
+		ThumbnailTypeID: l.StructureTypeID,
+	}
+	if notificationType == "SovStructureDestroyed" {
+		message.Title = typeName + " was destroyed"
+	}
+	message.AddField("System", systemName, true)
+	return message, nil
+}
+
+func (s *Conservator) sovCampaign(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		CampaignEventType int32 `yaml:"campaignEventType"`
+		ConstellationID   int32 `yaml:"constellationID"`
+		SolarSystemID     int32 `yaml:"solarSystemID"`
+		DecloakTime       int64 `yaml:"decloakTime"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	structure, ok := sovCampaignTypes[l.CampaignEventType]
+	if !ok {
+		structure = "Sovereignty structure"
+	}
+	systemName, _ := s.getSystemName(l.SolarSystemID)
+
+	message := &botservice.Message{
+		Content: "@everyone",
+		Color:   botservice.ColorDanger,
+	}
+
+	if notificationType == "SovStructureReinforced" {
+		message.Title = structure + " reinforced in " + systemName
+		message.AddField("Command Nodes Decloak", timerString(l.DecloakTime), true)
+	} else {
+		message.Title = "Command nodes spawning for " + structure + " in " + systemName
+		if constellationName, err := s.getCelestialName(l.ConstellationID); err == nil {
+			message.AddField("Constellation", constellationName, true)
+		}
+	}
+	message.AddField("System", systemName, true)
+	return message, nil
+}
+
+func (s *Conservator) sovClaim(notificationType, text string) (*botservice.Message, error) {
+	l := struct {
+		AllianceID    int32 `yaml:"allianceID"`
+		CorpID        int32 `yaml:"corpID"`
+		SolarSystemID int32 `yaml:"solarSystemID"`
+	}{}
+	if err := yaml.Unmarshal([]byte(text), &l); err != nil {
+		return nil, err
+	}
+
+	systemName, _ := s.getSystemName(l.SolarSystemID)
+	message := &botservice.Message{
+		Title:       "Sovereignty claimed in " + systemName,
+		Description: "Claimed by " + s.firstEntityLink(l.AllianceID, l.CorpID),
+		Color:       botservice.ColorInfo,
+	}
+	if notificationType == "SovAllClaimLostMsg" {
+		message.Title = "Sovereignty lost in " + systemName
+		message.Description = "Lost by " + s.firstEntityLink(l.AllianceID, l.CorpID)
+		message.Color = botservice.ColorDanger
+	}
+	return message, nil
+}
+
+// winDuration converts a windows interval in 100 nanosecond units
+func winDuration(t int64) time.Duration {
+	return (time.Duration(t) * 100).Round(time.Minute)
+}
+
+// timerString formats a windows timestamp from a notification
+func timerString(winTime int64) string {
+	return time.Unix(datapackages.WintoUnixTimestamp(winTime), 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+// Obtain Structure name.
+
+func (s *Conservator) getStructureName(id int64) string {
+	ref := ""
+	if err := s.db.QueryRowx(`
+		SELECT stationName FROM evedata.structures WHERE stationID = ?
+		LIMIT 1`, id).Scan(&ref); err != nil {
+		return ""
+	}
+	return ref
+}
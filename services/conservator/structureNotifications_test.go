@@ -0,0 +1,103 @@
+package conservator
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/antihax/evedata/internal/botservice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationHandlerTypes(t *testing.T) {
+	all := ChannelTypes{true, true, true, true, true, true, true, true, true, true}
+	services := strings.Split(all.GetServices(), ",")
+
+	for notificationType, h := range notificationHandlers {
+		assert.True(t, inSlice(h.messageType, NOTIFICATION_TYPES), notificationType)
+		assert.True(t, inSlice(h.messageType, services), notificationType)
+	}
+}
+
+func TestStructureNotifications(t *testing.T) {
+	tests := []struct {
+		notificationType string
+		text             string
+		title            string
+		field            string
+	}{
+		{"StructureFuelAlert", `allianceID: 99000001
+corpName: Test Corp
+listOfTypesAndQty:
+- - 200
+  - 4246
+solarsystemID: 30000142
+structureID: &id001 1020000000000
+structureShowInfoData:
+- showinfo
+- 35832
+- *id001
+structureTypeID: 35832
+`, "is low on fuel", "Remaining"},
+		{"StructureServicesOffline", `listOfServiceModuleIDs:
+- 35894
+solarsystemID: 30000142
+structureID: 1020000000000
+structureTypeID: 35832
+`, "services went offline", "Services"},
+		{"StructureUnanchoring", `ownerCorpName: Test Corp
+solarsystemID: 30000142
+structureID: 1020000000000
+structureTypeID: 35832
+timeLeft: 6048000000000
+`, "started unanchoring", "Time Left"},
+		{"MoonminingExtractionStarted", `autoTime: 131782014000000000
+moonID: 40009082
+oreVolumeByType:
+  45490: 1456203.2
+  46676: 2000000.0
+readyTime: 131781906000000000
+solarSystemID: 30000142
+startedBy: 90000001
+structureID: 1020000000000
+structureName: Jita - Drill
+structureTypeID: 35835
+`, "Jita - Drill started a moon extraction", "Ore"},
+		{"SovStructureReinforced", `campaignEventType: 2
+decloakTime: 131781906000000000
+solarSystemID: 30000142
+`, "Infrastructure Hub reinforced", "Command Nodes Decloak"},
+		{"SovAllClaimLostMsg", `allianceID: 99000001
+corpID: 98000001
+solarSystemID: 30000142
+`, "Sovereignty lost", ""},
+	}
+
+	for _, test := range tests {
+		h, ok := notificationHandlers[test.notificationType]
+		if !assert.True(t, ok, test.notificationType) {
+			continue
+		}
+
+		message, err := h.build(conserv, test.notificationType, test.text)
+		if !assert.Nil(t, err, test.notificationType) {
+			continue
+		}
+		assert.Contains(t, message.Title, test.title)
+		if test.field != "" {
+			assert.True(t, hasField(message, test.field), test.notificationType)
+		}
+	}
+}
+
+func hasField(message *botservice.Message, name string) bool {
+	for _, f := range message.Fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWinDuration(t *testing.T) {
+	assert.Equal(t, "168h0m0s", winDuration(6048000000000).String())
+}
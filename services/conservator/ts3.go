@@ -0,0 +1,110 @@
+package conservator
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/antihax/evedata/internal/botservice/tsservice"
+	"github.com/antihax/goesi"
+)
+
+// JoinTSUser creates a TeamSpeak privilege key for a character.
+// Once the key is redeemed the client is linked to the character and their roles synced.
+func (s *Conservator) JoinTSUser(j *JoinUser, reply *string) error {
+	service, err := s.getService(j.IntegrationID)
+	if err != nil {
+		return err
+	}
+
+	ts, ok := service.Server.(*tsservice.TSService)
+	if !ok {
+		return errors.New("integration is not a TeamSpeak server")
+	}
+
+	// Grant the role the character qualifies for, checkUser adds any others once linked.
+	role, err := s.characterRole(service, j.CharacterID)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		return errors.New("character does not qualify for any role on this server")
+	}
+
+	token, err := ts.CreateJoinToken(role, "EVEData: "+j.CharacterName, strconv.FormatInt(int64(j.CharacterID), 10))
+	if err != nil {
+		return err
+	}
+
+	*reply = token
+	return nil
+}
+
+// linkTSClients records the character for every client that redeemed a join token
+func (c *Conservator) linkTSClients(integrationID int32, ts *tsservice.TSService) error {
+	linked, err := ts.GetLinkedClients()
+	if err != nil {
+		return err
+	}
+
+	for clientID, value := range linked {
+		characterID, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			log.Printf("ts3 client %s has bad link %s\n", clientID, value)
+			continue
+		}
+		if err := c.setMemberStatus(clientID, int32(characterID), integrationID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// characterRole returns the first auth role a character qualifies for,
+// checked in the same order as checkUser.
+func (c *Conservator) characterRole(service *Service, characterID int32) (string, error) {
+	o := service.Options.Auth
+	checks := []struct {
+		role  string
+		query string
+		args  []interface{}
+	}{
+		{o.Members, `
+			SELECT 1 FROM evedata.crestTokens
+				WHERE authCharacter = 1 AND tokenCharacterID = ? AND (allianceID = ? OR corporationID = ?) LIMIT 1;`,
+			[]interface{}{characterID, service.EntityID, service.EntityID}},
+		{o.PlusTen, standingQuery, []interface{}{characterID, service.EntityID, 10}},
+		// Matches getPlusFiveStatus so checkUser does not remove the role again
+		{o.PlusFive, standingQuery, []interface{}{characterID, service.EntityID, 10}},
+		{o.Militia, militiaQuery, []interface{}{characterID, service.FactionID}},
+		{o.AlliedMilitia, militiaQuery, []interface{}{characterID, goesi.FactionAllies[service.FactionID]}},
+	}
+
+	for _, check := range checks {
+		if check.role == "" {
+			continue
+		}
+		// Militia roles need the server to belong to a faction
+		if check.query == militiaQuery && service.FactionID == 0 {
+			continue
+		}
+		found := 0
+		if err := c.db.QueryRowx(check.query, check.args...).Scan(&found); err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+		return check.role, nil
+	}
+	return "", nil
+}
+
+const standingQuery = `
+	SELECT 1 FROM evedata.crestTokens T
+		INNER JOIN evedata.entityContacts E ON E.contactID = T.allianceID OR E.contactID = T.corporationID OR E.contactID = T.tokenCharacterID
+		WHERE T.authCharacter = 1 AND T.tokenCharacterID = ? AND entityID = ? AND standing = ? LIMIT 1;`
+
+const militiaQuery = `
+	SELECT 1 FROM evedata.crestTokens
+		WHERE authCharacter = 1 AND tokenCharacterID = ? AND factionID = ? LIMIT 1;`
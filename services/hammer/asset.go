@@ -2,28 +2,27 @@ package hammer
 
 import (
 	"context"
-	"log"
 	"strconv"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 )
 
 func init() {
-	registerConsumer("characterAssets", characterAssetsConsumer)
+	registerConsumer("characterAssets", hammerwork.CharacterToken{}, characterAssetsConsumer)
 }
 
-func characterAssetsConsumer(s *Hammer, parameter interface{}) {
+func characterAssetsConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	var page int32 = 1
@@ -35,8 +34,7 @@ func characterAssetsConsumer(s *Hammer, parameter interface{}) {
 			})
 		if err != nil {
 			s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-			log.Println(err)
-			return
+			return err
 		}
 
 		assets = append(assets, a...)
@@ -50,7 +48,7 @@ func characterAssetsConsumer(s *Hammer, parameter interface{}) {
 	}
 
 	if len(assets) == 0 {
-		return
+		return nil
 	}
 
 	// Send out the result
@@ -60,7 +58,7 @@ func characterAssetsConsumer(s *Hammer, parameter interface{}) {
 		Assets:           assets,
 	}, "characterAssets")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
@@ -2,37 +2,35 @@ package hammer
 
 import (
 	"context"
-	"log"
 
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 )
 
 func init() {
-	registerConsumer("characterAuthOwner", characterAuthOwner)
-	registerConsumer("corporationContacts", corporationContacts)
-	registerConsumer("allianceContacts", allianceContacts)
+	registerConsumer("characterAuthOwner", hammerwork.CharacterToken{}, characterAuthOwner)
+	registerConsumer("corporationContacts", hammerwork.CorporationContacts{}, corporationContacts)
+	registerConsumer("allianceContacts", hammerwork.AllianceContacts{}, allianceContacts)
 }
 
-func characterAuthOwner(s *Hammer, parameter interface{}) {
+func characterAuthOwner(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	roles, _, err := s.esi.ESI.CharacterApi.GetCharactersCharacterIdRoles(ctx, tokenCharacterID, nil)
 	if err != nil {
 		s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -42,22 +40,21 @@ func characterAuthOwner(s *Hammer, parameter interface{}) {
 		Roles:            roles,
 	}, "characterAuthOwner")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func allianceContacts(s *Hammer, parameter interface{}) {
+func allianceContacts(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
-	allianceID := int32(parameters[2].(int))
+	p := parameter.(hammerwork.AllianceContacts)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
+	allianceID := p.AllianceID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	var page int32 = 1
@@ -66,8 +63,7 @@ func allianceContacts(s *Hammer, parameter interface{}) {
 	for {
 		c, _, err := s.esi.ESI.ContactsApi.GetAlliancesAllianceIdContacts(ctx, allianceID, &esi.GetAlliancesAllianceIdContactsOpts{Page: optional.NewInt32(page)})
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		} else if len(c) == 0 { // end of the pages
 			break
 		}
@@ -78,7 +74,7 @@ func allianceContacts(s *Hammer, parameter interface{}) {
 	}
 	// early out if there are no orders
 	if len(contacts) == 0 {
-		return
+		return nil
 	}
 
 	// Send out the result
@@ -87,22 +83,21 @@ func allianceContacts(s *Hammer, parameter interface{}) {
 		Contacts:   contacts,
 	}, "allianceContacts")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func corporationContacts(s *Hammer, parameter interface{}) {
+func corporationContacts(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
-	corporationID := int32(parameters[2].(int))
+	p := parameter.(hammerwork.CorporationContacts)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
+	corporationID := p.CorporationID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	var page int32 = 1
@@ -114,8 +109,7 @@ func corporationContacts(s *Hammer, parameter interface{}) {
 				Page: optional.NewInt32(page),
 			})
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		} else if len(c) == 0 { // end of the pages
 			break
 		}
@@ -126,7 +120,7 @@ func corporationContacts(s *Hammer, parameter interface{}) {
 	}
 	// early out if there are no orders
 	if len(contacts) == 0 {
-		return
+		return nil
 	}
 
 	// Send out the result
@@ -135,7 +129,7 @@ func corporationContacts(s *Hammer, parameter interface{}) {
 		Contacts:      contacts,
 	}, "corporationContacts")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
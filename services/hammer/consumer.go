@@ -1,11 +1,15 @@
 package hammer
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"log"
+	"reflect"
 	"sync/atomic"
 	"time"
 
+	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -14,24 +18,72 @@ type consumer struct {
 	f    consumerFunc
 }
 
-type consumerFunc func(*Hammer, interface{})
+type consumerFunc func(*Hammer, interface{}) error
 
-// Register a consumer to a queue operation.
-func registerConsumer(name string, f consumerFunc) {
+// Register a consumer to a queue operation. The payload must match the
+// struct registered for the operation in hammerwork.
+func registerConsumer(name string, payload interface{}, f consumerFunc) {
+	if t := redisqueue.OperationPayload(name); t == nil || t != reflect.TypeOf(payload) {
+		panic(fmt.Sprintf("hammer: consumer %s payload %T does not match registered %v", name, payload, t))
+	}
 	consumers = append(consumers, consumer{name, f})
 	consumerMap[name] = f
 }
 
-func (s *Hammer) wait(f consumerFunc, p interface{}) {
+func (s *Hammer) wait(f consumerFunc, w *redisqueue.Work) {
 	// Limit go routines
 	s.wg.Add(1)
 	atomic.AddUint64(&s.activeWorkers, 1)
 	defer func() { <-s.sem; s.wg.Done(); atomic.AddUint64(&s.activeWorkers, ^uint64(0)) }()
-	f(s, p)
+
+	// Return the work to the queue if the consumer panics.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("consumer %s panic: %v %+v\n", w.Operation, r, w.Parameter)
+			if err := s.inQueue.Nack(w); err != nil {
+				log.Println(err)
+			}
+		}
+	}()
+
+	// Keep the lease while long running work is in progress.
+	done := make(chan bool)
+	defer close(done)
+	go s.extendLease(w, done)
+
+	// Return failed work to the queue to be retried or dead lettered.
+	if err := f(s, w.Parameter); err != nil {
+		log.Printf("consumer %s failed: %v %+v\n", w.Operation, err, w.Parameter)
+		if err := s.inQueue.Nack(w); err != nil {
+			log.Println(err)
+		}
+		return
+	}
+
+	if err := s.inQueue.Ack(w); err != nil {
+		log.Println(err)
+	}
+}
+
+// extendLease extends the lease on work at half the visibility timeout until done.
+func (s *Hammer) extendLease(w *redisqueue.Work, done chan bool) {
+	ticker := time.NewTicker(s.inQueue.VisibilityTimeout() / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := s.inQueue.Extend(w); err != nil {
+				log.Printf("consumer %s lease: %v\n", w.Operation, err)
+				return
+			}
+		}
+	}
 }
 
-func (s *Hammer) runConsumers() error {
-	w, err := s.inQueue.GetWork()
+func (s *Hammer) runConsumers(ctx context.Context) error {
+	w, err := s.inQueue.GetWork(ctx)
 	if err != nil {
 		return err
 	}
@@ -40,11 +92,14 @@ func (s *Hammer) runConsumers() error {
 	fn := consumerMap[w.Operation]
 	if fn == nil {
 		log.Printf("unknown operation %s %+v\n", w.Operation, w.Parameter)
+		if err := s.inQueue.DeadLetter(w); err != nil {
+			log.Println(err)
+		}
 		return errors.New("Unknown operation")
 	}
 
 	s.sem <- true
-	go s.wait(fn, w.Parameter)
+	go s.wait(fn, w)
 
 	duration := float64(time.Since(start).Nanoseconds()) / 1000000.0
 	consumerMetrics.With(
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/antihax/evedata/internal/apicache"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 
@@ -15,26 +17,24 @@ import (
 )
 
 func init() {
-	registerConsumer("characterContactSync", characterContactSyncConsumer)
+	registerConsumer("characterContactSync", hammerwork.CharacterContactSync{}, characterContactSyncConsumer)
 }
 
-func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
+func characterContactSyncConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	source := int32(parameters[1].(int))
-	destinations := parameters[2].(string)
+	p := parameter.(hammerwork.CharacterContactSync)
+	characterID := p.CharacterID
+	source := p.Source
+	destinations := p.Destinations
 
 	char, _, err := s.esi.ESI.CharacterApi.GetCharactersCharacterId(nil, int32(source), nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	corp, _, err := s.esi.ESI.CorporationApi.GetCorporationsCorporationId(nil, char.CorporationId, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Find the Entity ID to search for wars.
@@ -57,12 +57,12 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 		cid, err := strconv.ParseInt(cidS, 10, 64)
 		if err != nil {
 			log.Println(err, destinations, cidS, characterID)
-			return
+			return err
 		}
 		a, err := s.tokenStore.GetTokenSource(int32(characterID), int32(cid))
 		if err != nil {
 			log.Println(err, characterID, cidS)
-			return
+			return err
 		}
 		// Save the token.
 		tokens[cid] = characterToken{token: &a, cid: int32(cid)}
@@ -71,15 +71,13 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 	// Active Wars
 	activeWars, err := s.GetActiveWarsByID((int64)(searchID))
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Pending Wars
 	pendingWars, err := s.GetPendingWarsByID((int64)(searchID))
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Faction Wars
@@ -87,8 +85,7 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 	if corp.FactionId > 0 {
 		factionWars, err = s.GetFactionWarEntitiesForID(corp.FactionId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
 
@@ -97,6 +94,7 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 		// authentication token context for destination char
 		contacts := []esi.GetCharactersCharacterIdContacts200Ok{}
 		auth := context.WithValue(context.Background(), goesi.ContextOAuth2, *token.token)
+		auth = apicache.WithBudgetOwner(auth, apicache.CharacterOwner(token.cid))
 		page := int32(1)
 		for {
 			c, _, err := s.esi.ESI.ContactsApi.GetCharactersCharacterIdContacts(auth, (int32)(token.cid),
@@ -105,8 +103,7 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 				})
 			if err != nil {
 				s.tokenStore.CheckSSOError(characterID, token.cid, err)
-				log.Println(err)
-				return
+				return err
 			}
 			if len(c) == 0 {
 				break
@@ -267,6 +264,7 @@ func characterContactSyncConsumer(s *Hammer, parameter interface{}) {
 			}
 		}
 	}
+	return nil
 }
 
 func min(x, y int) int {
@@ -2,20 +2,20 @@ package hammer
 
 import (
 	"context"
-	"log"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 )
 
 func init() {
 	// Resolve mutaplasmids dogma from contracts and things
-	registerConsumer("mutatedItem", func(s *Hammer, parameter interface{}) {
-		itemID := int64(parameter.([]interface{})[0].(int64))
-		typeID := int32(parameter.([]interface{})[1].(int))
+	registerConsumer("mutatedItem", hammerwork.MutatedItem{}, func(s *Hammer, parameter interface{}) error {
+		p := parameter.(hammerwork.MutatedItem)
+		itemID := p.ItemID
+		typeID := p.TypeID
 		h, _, err := s.esi.ESI.DogmaApi.GetDogmaDynamicItemsTypeIdItemId(context.Background(), itemID, typeID, nil)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 
 		// Send out the result
@@ -24,8 +24,8 @@ func init() {
 			ItemID: itemID,
 			TypeID: typeID}, "mutatedItem")
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
+		return nil
 	})
 }
@@ -6,21 +6,22 @@ import (
 	"log"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 )
 
 func init() {
-	registerConsumer("charSearch", charSearchConsumer)
-	registerConsumer("alliance", allianceConsumer)
-	registerConsumer("corporation", corporationConsumer)
-	registerConsumer("corporationHistory", corporationHistoryConsumer)
-	registerConsumer("allianceHistory", allianceHistoryConsumer)
+	registerConsumer("charSearch", hammerwork.CharacterSearch{}, charSearchConsumer)
+	registerConsumer("alliance", hammerwork.Alliance{}, allianceConsumer)
+	registerConsumer("corporation", hammerwork.Corporation{}, corporationConsumer)
+	registerConsumer("corporationHistory", hammerwork.Character{}, corporationHistoryConsumer)
+	registerConsumer("allianceHistory", hammerwork.Corporation{}, allianceHistoryConsumer)
 
-	registerConsumer("character", characterConsumer)
+	registerConsumer("character", hammerwork.Character{}, characterConsumer)
 
-	registerConsumer("loyaltyStore", loyaltyStoreConsumer)
+	registerConsumer("loyaltyStore", hammerwork.Corporation{}, loyaltyStoreConsumer)
 }
 
 // AddAlliance adds an alliance to queue
@@ -28,7 +29,7 @@ func (s *Hammer) AddAlliance(allianceID int32) error {
 	if allianceID > 500000 {
 		if !s.inQueue.CheckWorkExpired("evedata_entity", int64(allianceID)) {
 			return s.inQueue.QueueWork([]redisqueue.Work{
-				{Operation: "alliance", Parameter: allianceID},
+				hammerwork.AllianceWork(allianceID),
 			}, redisqueue.Priority_Low)
 		}
 	}
@@ -40,8 +41,8 @@ func (s *Hammer) AddCorporation(corporationID int32) error {
 	if corporationID > 500000 {
 		if !s.inQueue.CheckWorkExpired("evedata_entity", int64(corporationID)) {
 			return s.inQueue.QueueWork([]redisqueue.Work{
-				{Operation: "corporation", Parameter: corporationID},
-				{Operation: "allianceHistory", Parameter: corporationID},
+				hammerwork.CorporationWork(corporationID),
+				hammerwork.AllianceHistoryWork(corporationID),
 			}, redisqueue.Priority_Low)
 		}
 	}
@@ -53,8 +54,8 @@ func (s *Hammer) AddCharacter(characterID int32) error {
 	if characterID > 500000 {
 		if !s.inQueue.CheckWorkExpired("evedata_entity", int64(characterID)) {
 			return s.inQueue.QueueWork([]redisqueue.Work{
-				{Operation: "character", Parameter: characterID},
-				{Operation: "corporationHistory", Parameter: characterID},
+				hammerwork.CharacterWork(characterID),
+				hammerwork.CorporationHistoryWork(characterID),
 			}, redisqueue.Priority_Low)
 		}
 	}
@@ -102,14 +103,13 @@ func sliceUniq(s []int32) []int32 {
 	return s
 }
 
-func charSearchConsumer(s *Hammer, parameter interface{}) {
-	char := parameter.(string)
+func charSearchConsumer(s *Hammer, parameter interface{}) error {
+	char := parameter.(hammerwork.CharacterSearch).Name
 
 	// Check if we know this character already
 	id, err := s.GetCharacterIDByName(char)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	if id == 0 {
@@ -118,8 +118,7 @@ func charSearchConsumer(s *Hammer, parameter interface{}) {
 				Strict: optional.NewBool(true),
 			})
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 		if len(search.Character) > 0 {
 			for _, newid := range search.Character {
@@ -129,7 +128,7 @@ func charSearchConsumer(s *Hammer, parameter interface{}) {
 	} else { // add the character to the queue so we get latest data.
 		s.AddCharacter(id)
 	}
-	return
+	return nil
 }
 
 // GetCharacterIDByName checks if a character exists in the database
@@ -144,19 +143,17 @@ func (s *Hammer) GetCharacterIDByName(character string) (int32, error) {
 	return id, nil
 }
 
-func allianceConsumer(s *Hammer, parameter interface{}) {
-	allianceID := int32(parameter.(int))
+func allianceConsumer(s *Hammer, parameter interface{}) error {
+	allianceID := parameter.(hammerwork.Alliance).AllianceID
 
 	alliance, _, err := s.esi.ESI.AllianceApi.GetAlliancesAllianceId(context.Background(), allianceID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	allianceCorporations, _, err := s.esi.ESI.AllianceApi.GetAlliancesAllianceIdCorporations(context.Background(), allianceID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -166,14 +163,12 @@ func allianceConsumer(s *Hammer, parameter interface{}) {
 		AllianceCorporations: allianceCorporations,
 	}, "alliance")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	err = s.inQueue.SetWorkExpire("evedata_entity", int64(allianceID), 43200)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Grab intel from meta data
@@ -181,19 +176,17 @@ func allianceConsumer(s *Hammer, parameter interface{}) {
 	for _, corp := range allianceCorporations {
 		err = s.AddCorporation(corp)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
-	return
+	return nil
 }
 
-func loyaltyStoreConsumer(s *Hammer, parameter interface{}) {
-	corporationID := int32(parameter.(int))
+func loyaltyStoreConsumer(s *Hammer, parameter interface{}) error {
+	corporationID := parameter.(hammerwork.Corporation).CorporationID
 	store, _, err := s.esi.ESI.LoyaltyApi.GetLoyaltyStoresCorporationIdOffers(context.Background(), corporationID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 	if len(store) > 0 {
 		// Send out the result
@@ -202,19 +195,17 @@ func loyaltyStoreConsumer(s *Hammer, parameter interface{}) {
 			Store:         store},
 			"loyaltyStore")
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
-	return
+	return nil
 }
 
-func corporationConsumer(s *Hammer, parameter interface{}) {
-	corporationID := int32(parameter.(int))
+func corporationConsumer(s *Hammer, parameter interface{}) error {
+	corporationID := parameter.(hammerwork.Corporation).CorporationID
 	corporation, _, err := s.esi.ESI.CorporationApi.GetCorporationsCorporationId(context.Background(), corporationID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -223,8 +214,7 @@ func corporationConsumer(s *Hammer, parameter interface{}) {
 		Corporation:   corporation,
 	}, "corporation")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	s.inQueue.SetWorkExpire("evedata_entity", int64(corporationID), 43200)
@@ -232,26 +222,24 @@ func corporationConsumer(s *Hammer, parameter interface{}) {
 	// Grab intel from meta data
 	err = s.AddCharacter(corporation.CeoId)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	if corporation.CeoId != corporation.CreatorId {
 		err := s.AddCharacter(corporation.CreatorId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
+	return nil
 }
 
-func corporationHistoryConsumer(s *Hammer, parameter interface{}) {
-	characterID := int32(parameter.(int))
+func corporationHistoryConsumer(s *Hammer, parameter interface{}) error {
+	characterID := parameter.(hammerwork.Character).CharacterID
 
 	corporationHistory, _, err := s.esi.ESI.CharacterApi.GetCharactersCharacterIdCorporationhistory(context.Background(), characterID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -260,27 +248,25 @@ func corporationHistoryConsumer(s *Hammer, parameter interface{}) {
 		CorporationHistory: corporationHistory,
 	}, "corporationHistory")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Add all known corporations
 	for _, corp := range corporationHistory {
 		err = s.AddCorporation(corp.CorporationId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
+	return nil
 }
 
-func allianceHistoryConsumer(s *Hammer, parameter interface{}) {
-	corporationID := int32(parameter.(int))
+func allianceHistoryConsumer(s *Hammer, parameter interface{}) error {
+	corporationID := parameter.(hammerwork.Corporation).CorporationID
 
 	allianceHistory, _, err := s.esi.ESI.CorporationApi.GetCorporationsCorporationIdAlliancehistory(context.Background(), corporationID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -289,17 +275,16 @@ func allianceHistoryConsumer(s *Hammer, parameter interface{}) {
 		AllianceHistory: allianceHistory,
 	}, "allianceHistory")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func characterConsumer(s *Hammer, parameter interface{}) {
-	characterID := int32(parameter.(int))
+func characterConsumer(s *Hammer, parameter interface{}) error {
+	characterID := parameter.(hammerwork.Character).CharacterID
 	character, _, err := s.esi.ESI.CharacterApi.GetCharactersCharacterId(context.Background(), characterID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -308,8 +293,7 @@ func characterConsumer(s *Hammer, parameter interface{}) {
 		Character:   character,
 	}, "character")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	s.inQueue.SetWorkExpire("evedata_entity", int64(characterID), 43200)
@@ -317,8 +301,7 @@ func characterConsumer(s *Hammer, parameter interface{}) {
 	// Grab intel from meta data
 	err = s.AddCorporation(character.CorporationId)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
-
+	return nil
 }
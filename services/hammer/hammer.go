@@ -115,13 +115,21 @@ func (s *Hammer) QueueWork(work []redisqueue.Work, priority int) error {
 func (s *Hammer) Run() {
 	go s.tickWorkersToPrometheus()
 
+	// Stop waiting for work when the service is closed.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.stop
+		cancel()
+	}()
+
 	for {
 		select {
 		case <-s.stop:
 			return
 		default:
-			err := s.runConsumers()
-			if err != nil {
+			err := s.runConsumers(ctx)
+			if err != nil && ctx.Err() == nil {
 				log.Println(err)
 			}
 		}
@@ -129,13 +137,14 @@ func (s *Hammer) Run() {
 }
 
 // GetTokenSourceContext sets a token source to a context value for authentication
+// and charges any ESI errors to the token character's error budget.
 func (s *Hammer) GetTokenSourceContext(c context.Context, characterID, tokenCharacterID int32) (context.Context, error) {
 	tokenSource, err := s.tokenStore.GetTokenSource(characterID, tokenCharacterID)
 	if err != nil {
 		return c, err
 	}
 	auth := context.WithValue(c, goesi.ContextOAuth2, tokenSource)
-	return auth, nil
+	return apicache.WithBudgetOwner(auth, apicache.CharacterOwner(tokenCharacterID)), nil
 }
 
 // QueueResult queues a result to NSQ topic
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/antihax/evedata/internal/redisqueue"
@@ -14,41 +15,39 @@ import (
 
 var (
 	testWork = []redisqueue.Work{
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(1)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(2)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(3)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(4)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(5)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(6)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(7)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(8)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(9)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(10)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(11)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(12)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(13)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(14)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(15)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(16)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(17)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(18)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(19)}},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(20)}},
-		{Operation: "marketOrders", Parameter: int32(1)},
-		{Operation: "war", Parameter: int32(1)},
-		{Operation: "alliance", Parameter: int32(1)},
-		{Operation: "corporation", Parameter: int32(1)},
-		{Operation: "character", Parameter: int32(1)},
-		{Operation: "marketHistoryTrigger", Parameter: int32(1)},
-		{Operation: "structure", Parameter: int64(1)},
-		{Operation: "marketHistory", Parameter: []int32{1, 1}},
-		{Operation: "characterWalletTransactions", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterWalletJournal", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterAssets", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterNotifications", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "loyaltyStore", Parameter: int32(1000001)},
-		{Operation: "wheeeeeeeeee", Parameter: int32(1000001)},
-		{Operation: "charSearch", Parameter: "SomeDude"},
+		hammerwork.KillmailWork("FAKEHASH", 1),
+		hammerwork.KillmailWork("FAKEHASH", 2),
+		hammerwork.KillmailWork("FAKEHASH", 3),
+		hammerwork.KillmailWork("FAKEHASH", 4),
+		hammerwork.KillmailWork("FAKEHASH", 5),
+		hammerwork.KillmailWork("FAKEHASH", 6),
+		hammerwork.KillmailWork("FAKEHASH", 7),
+		hammerwork.KillmailWork("FAKEHASH", 8),
+		hammerwork.KillmailWork("FAKEHASH", 9),
+		hammerwork.KillmailWork("FAKEHASH", 10),
+		hammerwork.KillmailWork("FAKEHASH", 11),
+		hammerwork.KillmailWork("FAKEHASH", 12),
+		hammerwork.KillmailWork("FAKEHASH", 13),
+		hammerwork.KillmailWork("FAKEHASH", 14),
+		hammerwork.KillmailWork("FAKEHASH", 15),
+		hammerwork.KillmailWork("FAKEHASH", 16),
+		hammerwork.KillmailWork("FAKEHASH", 17),
+		hammerwork.KillmailWork("FAKEHASH", 18),
+		hammerwork.KillmailWork("FAKEHASH", 19),
+		hammerwork.KillmailWork("FAKEHASH", 20),
+		hammerwork.WarWork(1),
+		hammerwork.AllianceWork(1),
+		hammerwork.CorporationWork(1),
+		hammerwork.CharacterWork(1),
+		hammerwork.MarketHistoryTriggerWork(),
+		hammerwork.StructureWork(1),
+		hammerwork.MarketHistoryWork(1, 1),
+		hammerwork.CharacterWalletTransactionsWork(1, 1),
+		hammerwork.CharacterWalletJournalWork(1, 1),
+		hammerwork.CharacterAssetsWork(1, 1),
+		hammerwork.CharacterNotificationsWork(1, 1),
+		hammerwork.LoyaltyStoreWork(1000001),
+		hammerwork.CharSearchWork("SomeDude"),
 	}
 )
 
@@ -87,6 +86,14 @@ func TestHammerService(t *testing.T) {
 	err = hammer.QueueWork(testWork, redisqueue.Priority_Low)
 	assert.Nil(t, err)
 
+	// Unknown operations and bad payloads are rejected
+	err = hammer.QueueWork([]redisqueue.Work{{Operation: "wheeeeeeeeee", Parameter: int32(1000001)}}, redisqueue.Priority_Low)
+	assert.NotNil(t, err)
+	err = hammer.QueueWork([]redisqueue.Work{{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(1)}}}, redisqueue.Priority_Low)
+	assert.NotNil(t, err)
+	err = hammer.QueueWork([]redisqueue.Work{hammerwork.KillmailWork("", 1)}, redisqueue.Priority_Low)
+	assert.NotNil(t, err)
+
 	time.Sleep(time.Second)
 	// Wait for the consumers to finish
 
@@ -1,70 +1,62 @@
 package hammer
 
 import (
-	"log"
-
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 )
 
 func init() {
-	registerConsumer("killmail", killmailConsumer)
+	registerConsumer("killmail", hammerwork.Killmail{}, killmailConsumer)
 }
 
-func killmailConsumer(s *Hammer, parameter interface{}) {
-	parameters := parameter.([]interface{})
-	hash := parameters[0].(string)
-	id := int32(parameters[1].(int))
+func killmailConsumer(s *Hammer, parameter interface{}) error {
+	p := parameter.(hammerwork.Killmail)
+	hash := p.Hash
+	id := p.KillmailID
 
 	kill, _, err := s.esi.ESI.KillmailsApi.GetKillmailsKillmailIdKillmailHash(nil, hash, id, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result, but ignore DUST stuff.
 	if kill.Victim.ShipTypeId < 65535 {
 		err = s.QueueResult(&datapackages.Killmail{Hash: hash, Kill: kill}, "killmail")
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
 
 	err = s.AddCharacter(kill.Victim.CharacterId)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	err = s.AddAlliance(kill.Victim.AllianceId)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	err = s.AddCorporation(kill.Victim.CorporationId)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	for _, a := range kill.Attackers {
 		err = s.AddCharacter(a.CharacterId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 		err = s.AddAlliance(a.AllianceId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 
 		err = s.AddCorporation(a.CorporationId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 
 	}
+	return nil
 }
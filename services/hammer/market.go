@@ -7,37 +7,37 @@ import (
 	"strconv"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 )
 
 func init() {
-	registerConsumer("characterStructureMarket", structureOrdersConsumer)
-	registerConsumer("marketHistoryTrigger", marketHistoryTrigger)
-	registerConsumer("marketHistory", marketHistoryConsumer)
+	registerConsumer("characterStructureMarket", hammerwork.CharacterStructure{}, structureOrdersConsumer)
+	registerConsumer("marketHistoryTrigger", hammerwork.MarketHistoryTrigger{}, marketHistoryTrigger)
+	registerConsumer("marketHistory", hammerwork.MarketHistory{}, marketHistoryConsumer)
 }
 
-func structureOrdersConsumer(s *Hammer, parameter interface{}) {
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
-	structureID := parameters[2].(int64)
+func structureOrdersConsumer(s *Hammer, parameter interface{}) error {
+	p := parameter.(hammerwork.CharacterStructure)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
+	structureID := p.StructureID
 
 	if s.inQueue.CheckWorkExpired("evedata_structuremarket_failure",
 		fmt.Sprintf("%d%d", structureID, tokenCharacterID)) {
-		return
+		return nil
 	}
 
 	// early out if we already have this recently
 	if s.inQueue.CheckWorkExpired("evedata_structuremarket", structureID) {
-		return
+		return nil
 	}
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	var page int32 = 1
@@ -49,13 +49,15 @@ func structureOrdersConsumer(s *Hammer, parameter interface{}) {
 			})
 		if err != nil {
 			s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-			if r.StatusCode == 403 {
+			// Forbidden will not succeed on retry
+			if r != nil && r.StatusCode == 403 {
 				err := s.inQueue.SetWorkExpire("evedata_structuremarket_failure", fmt.Sprintf("%d%d", structureID, tokenCharacterID), 86400*3)
 				if err != nil {
 					log.Printf("failed setting failure: %s %d\n", err, structureID)
 				}
+				return nil
 			}
-			return
+			return err
 		} else if len(o) == 0 { // end of the pages
 			break
 		}
@@ -66,22 +68,21 @@ func structureOrdersConsumer(s *Hammer, parameter interface{}) {
 	}
 	// early out if there are no orders
 	if len(orders) == 0 {
-		return
+		return nil
 	}
 
 	// Send out the result
 	err = s.QueueResult(&datapackages.StructureOrders{Orders: orders, StructureID: structureID}, "structureOrders")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func marketHistoryTrigger(s *Hammer, parameter interface{}) {
+func marketHistoryTrigger(s *Hammer, parameter interface{}) error {
 	regions, _, err := s.esi.ESI.UniverseApi.GetUniverseRegions(context.Background(), nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	var page int32 = 1
@@ -105,7 +106,7 @@ func marketHistoryTrigger(s *Hammer, parameter interface{}) {
 			if item.Published && item.MarketGroupId > 0 {
 				work := []redisqueue.Work{}
 				for _, regionID := range regions {
-					work = append(work, redisqueue.Work{Operation: "marketHistory", Parameter: []int32{regionID, itemID}})
+					work = append(work, hammerwork.MarketHistoryWork(regionID, itemID))
 				}
 				s.QueueWork(work, redisqueue.Priority_Lowest)
 			}
@@ -114,19 +115,19 @@ func marketHistoryTrigger(s *Hammer, parameter interface{}) {
 		xpagesS := r.Header.Get("x-pages")
 		xpages, _ := strconv.Atoi(xpagesS)
 		if int32(xpages) == page || len(items) == 0 {
-			return
+			return nil
 		}
 		page++
 	}
 }
 
-func marketHistoryConsumer(s *Hammer, parameter interface{}) {
-	regionID := int32(parameter.([]interface{})[0].(int))
-	typeID := int32(parameter.([]interface{})[1].(int))
+func marketHistoryConsumer(s *Hammer, parameter interface{}) error {
+	p := parameter.(hammerwork.MarketHistory)
+	regionID := p.RegionID
+	typeID := p.TypeID
 	h, _, err := s.esi.ESI.MarketApi.GetMarketsRegionIdHistory(nil, regionID, typeID, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Send out the result
@@ -135,7 +136,7 @@ func marketHistoryConsumer(s *Hammer, parameter interface{}) {
 		RegionID: regionID,
 		TypeID:   typeID}, "marketHistory")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
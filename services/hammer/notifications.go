@@ -7,11 +7,12 @@ import (
 	"github.com/antihax/goesi/esi"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/ghodss/yaml"
 )
 
 func init() {
-	registerConsumer("characterNotifications", characterNotificationsConsumer)
+	registerConsumer("characterNotifications", hammerwork.CharacterToken{}, characterNotificationsConsumer)
 }
 
 func addID(e *[]int32, n int32) {
@@ -20,26 +21,24 @@ func addID(e *[]int32, n int32) {
 	}
 }
 
-func characterNotificationsConsumer(s *Hammer, parameter interface{}) {
+func characterNotificationsConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	notifications, _, err := s.esi.ESI.CharacterApi.GetCharactersCharacterIdNotifications(ctx, tokenCharacterID, nil)
 	if err != nil {
 		s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-		log.Println(err)
-		return
+		return err
 	}
 	if len(notifications) == 0 {
-		return
+		return nil
 	}
 	// see what we can learn about these notifications so our alerts do not fail
 	s.learnFromNotifications(notifications)
@@ -51,9 +50,9 @@ func characterNotificationsConsumer(s *Hammer, parameter interface{}) {
 		Notifications:    notifications,
 	}, "characterNotifications")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
 func (s *Hammer) learnFromNotifications(notifications []esi.GetCharactersCharacterIdNotifications200Ok) {
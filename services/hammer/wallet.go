@@ -2,36 +2,34 @@ package hammer
 
 import (
 	"context"
-	"log"
 
 	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/goesi/esi"
 	"github.com/antihax/goesi/optional"
 )
 
 func init() {
-	registerConsumer("characterWalletTransactions", characterWalletTransactionConsumer)
-	registerConsumer("characterWalletJournal", characterWalletJournalConsumer)
-	registerConsumer("characterOrders", characterOrdersConsumer)
+	registerConsumer("characterWalletTransactions", hammerwork.CharacterToken{}, characterWalletTransactionConsumer)
+	registerConsumer("characterWalletJournal", hammerwork.CharacterToken{}, characterWalletJournalConsumer)
+	registerConsumer("characterOrders", hammerwork.CharacterToken{}, characterOrdersConsumer)
 }
 
-func characterOrdersConsumer(s *Hammer, parameter interface{}) {
+func characterOrdersConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	orders, _, err := s.esi.ESI.MarketApi.GetCharactersCharacterIdOrders(ctx, tokenCharacterID, nil)
 	if err != nil {
 		s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Note: intentionally pass blank orders to force delete of old orders.
@@ -43,31 +41,29 @@ func characterOrdersConsumer(s *Hammer, parameter interface{}) {
 		Orders:           orders,
 	}, "characterOrders")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func characterWalletTransactionConsumer(s *Hammer, parameter interface{}) {
+func characterWalletTransactionConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	transactions, _, err := s.esi.ESI.WalletApi.GetCharactersCharacterIdWalletTransactions(ctx, tokenCharacterID, nil)
 	if err != nil {
 		s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-		log.Println(err)
-		return
+		return err
 	}
 	if len(transactions) == 0 {
-		return
+		return nil
 	}
 
 	last := lowestTransactionID(transactions)
@@ -78,8 +74,7 @@ func characterWalletTransactionConsumer(s *Hammer, parameter interface{}) {
 			})
 		if err != nil {
 			s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-			log.Println(err)
-			return
+			return err
 		}
 		if len(top) == 0 {
 			break
@@ -100,21 +95,20 @@ func characterWalletTransactionConsumer(s *Hammer, parameter interface{}) {
 		Transactions:     transactions,
 	}, "characterWalletTransactions")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
-func characterWalletJournalConsumer(s *Hammer, parameter interface{}) {
+func characterWalletJournalConsumer(s *Hammer, parameter interface{}) error {
 	// dereference the parameters
-	parameters := parameter.([]interface{})
-	characterID := int32(parameters[0].(int))
-	tokenCharacterID := int32(parameters[1].(int))
+	p := parameter.(hammerwork.CharacterToken)
+	characterID := p.CharacterID
+	tokenCharacterID := p.TokenCharacterID
 
 	ctx, err := s.GetTokenSourceContext(context.Background(), characterID, tokenCharacterID)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	page := int32(1)
@@ -126,8 +120,7 @@ func characterWalletJournalConsumer(s *Hammer, parameter interface{}) {
 			})
 		if err != nil {
 			s.tokenStore.CheckSSOError(characterID, tokenCharacterID, err)
-			log.Println(err)
-			return
+			return err
 		}
 		if len(top) == 0 {
 			break
@@ -143,9 +136,9 @@ func characterWalletJournalConsumer(s *Hammer, parameter interface{}) {
 		Journal:          journal,
 	}, "characterWalletJournal")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
+	return nil
 }
 
 func lowestTransactionID(j []esi.GetCharactersCharacterIdWalletTransactions200Ok) int64 {
@@ -4,19 +4,20 @@ import (
 	"context"
 	"log"
 	"time"
+
+	"github.com/antihax/evedata/internal/hammerwork"
 )
 
 func init() {
-	registerConsumer("war", warConsumer)
+	registerConsumer("war", hammerwork.War{}, warConsumer)
 }
 
-func warConsumer(s *Hammer, parameter interface{}) {
-	id := int32(parameter.(int))
+func warConsumer(s *Hammer, parameter interface{}) error {
+	id := parameter.(hammerwork.War).WarID
 
 	war, _, err := s.esi.ESI.WarsApi.GetWarsWarId(context.Background(), id, nil)
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// if the war ended, market it finished
@@ -30,16 +31,14 @@ func warConsumer(s *Hammer, parameter interface{}) {
 	// Send out the result
 	err = s.QueueResult(war, "war")
 	if err != nil {
-		log.Println(err)
-		return
+		return err
 	}
 
 	// Add the alliance corporation for intel purposes
 	if war.Aggressor.AllianceId == 0 {
 		err = s.AddCorporation(war.Aggressor.CorporationId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
 
@@ -47,8 +46,8 @@ func warConsumer(s *Hammer, parameter interface{}) {
 	if war.Defender.AllianceId == 0 {
 		err = s.AddCorporation(war.Defender.CorporationId)
 		if err != nil {
-			log.Println(err)
-			return
+			return err
 		}
 	}
+	return nil
 }
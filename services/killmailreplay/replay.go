@@ -0,0 +1,108 @@
+// Package killmailreplay feeds archived killmails back through the pipeline
+// to backfill derived data and drive integration tests without ESI.
+package killmailreplay
+
+import (
+	"io"
+	"log"
+	"time"
+
+	"github.com/antihax/evedata/internal/datapackages"
+)
+
+// Filter selects which killmails are replayed. Zero values match everything.
+type Filter struct {
+	From         time.Time
+	To           time.Time
+	ShipTypes    map[int32]bool
+	SolarSystems map[int32]bool
+}
+
+// Match checks if a killmail passes the filter
+func (f *Filter) Match(k *datapackages.Killmail) bool {
+	kill := &k.Kill
+	if !f.From.IsZero() && kill.KillmailTime.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !kill.KillmailTime.Before(f.To) {
+		return false
+	}
+	if len(f.ShipTypes) > 0 && !f.ShipTypes[kill.Victim.ShipTypeId] {
+		return false
+	}
+	if len(f.SolarSystems) > 0 && !f.SolarSystems[kill.SolarSystemId] {
+		return false
+	}
+	return true
+}
+
+// Stats of a replay
+type Stats struct {
+	Sent    int
+	Skipped int
+	Failed  int
+}
+
+// Replayer moves killmails from a source to a sink
+type Replayer struct {
+	source Source
+	sink   Sink
+	filter Filter
+	rate   float64
+}
+
+// NewReplayer sends killmails matching filter from source to sink.
+// rate limits killmails per second, zero is unlimited.
+func NewReplayer(source Source, sink Sink, filter Filter, rate float64) *Replayer {
+	return &Replayer{source: source, sink: sink, filter: filter, rate: rate}
+}
+
+// Run replays until the source is exhausted or stop is closed.
+// Bad killmails are logged and counted rather than stopping the replay.
+func (r *Replayer) Run(stop chan bool) Stats {
+	stats := Stats{}
+
+	var throttle <-chan time.Time
+	if r.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / r.rate))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	for {
+		select {
+		case <-stop:
+			return stats
+		default:
+		}
+
+		k, err := r.source.Next()
+		if err == io.EOF {
+			return stats
+		} else if err != nil {
+			log.Println(err)
+			stats.Failed++
+			continue
+		}
+
+		if !r.filter.Match(k) {
+			stats.Skipped++
+			continue
+		}
+
+		if throttle != nil {
+			select {
+			case <-throttle:
+			case <-stop:
+				return stats
+			}
+		}
+
+		if err := r.sink.Send(k); err != nil {
+			log.Println(k.Kill.KillmailId, err)
+			stats.Failed++
+			continue
+		}
+		stats.Sent++
+	}
+}
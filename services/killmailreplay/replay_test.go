@@ -0,0 +1,128 @@
+package killmailreplay
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/gobcoder"
+	"github.com/antihax/goesi/esi"
+	nsq "github.com/nsqio/go-nsq"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSink struct {
+	killmails []*datapackages.Killmail
+}
+
+func (s *recordingSink) Send(k *datapackages.Killmail) error {
+	s.killmails = append(s.killmails, k)
+	return nil
+}
+
+func testKill(id, ship int32, when time.Time) esi.GetKillmailsKillmailIdKillmailHashOk {
+	return esi.GetKillmailsKillmailIdKillmailHashOk{
+		KillmailId:    id,
+		KillmailTime:  when,
+		SolarSystemId: 30000142,
+		Victim:        esi.GetKillmailsKillmailIdKillmailHashVictim{ShipTypeId: ship},
+	}
+}
+
+// writeTestDirectory writes a killmaildumper file and a tailor archive
+func writeTestDirectory(t *testing.T, dir string) {
+	when := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	b, err := json.Marshal(testKill(1, 587, when))
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "abc123.json"), b, 0644))
+
+	b, err = json.Marshal(map[string]interface{}{
+		"killmail": testKill(2, 670, when.Add(time.Hour*48)),
+		"nameMap":  map[int32]string{},
+	})
+	assert.Nil(t, err)
+	var gzb bytes.Buffer
+	gz := gzip.NewWriter(&gzb)
+	gz.Write(b)
+	gz.Close()
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "2.json.gz"), gzb.Bytes(), 0644))
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "README"), []byte("ignored"), 0644))
+}
+
+func TestDirectorySource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "killmailreplay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeTestDirectory(t, dir)
+
+	s, err := NewDirectorySource(dir)
+	assert.Nil(t, err)
+
+	k, err := s.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), k.Kill.KillmailId)
+	assert.Equal(t, "", k.Hash)
+
+	k, err = s.Next()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), k.Kill.KillmailId)
+	assert.Equal(t, "abc123", k.Hash)
+
+	_, err = s.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestReplayFilter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "killmailreplay")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	writeTestDirectory(t, dir)
+
+	replay := func(f Filter) (*recordingSink, Stats) {
+		s, err := NewDirectorySource(dir)
+		assert.Nil(t, err)
+		sink := &recordingSink{}
+		return sink, NewReplayer(s, sink, f, 0).Run(make(chan bool))
+	}
+
+	sink, stats := replay(Filter{})
+	assert.Equal(t, Stats{Sent: 2}, stats)
+	assert.Len(t, sink.killmails, 2)
+
+	sink, stats = replay(Filter{ShipTypes: map[int32]bool{587: true}})
+	assert.Equal(t, Stats{Sent: 1, Skipped: 1}, stats)
+	assert.Equal(t, int32(1), sink.killmails[0].Kill.KillmailId)
+
+	sink, stats = replay(Filter{From: time.Date(2018, 6, 2, 0, 0, 0, 0, time.UTC)})
+	assert.Equal(t, Stats{Sent: 1, Skipped: 1}, stats)
+	assert.Equal(t, int32(2), sink.killmails[0].Kill.KillmailId)
+
+	_, stats = replay(Filter{To: time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC)})
+	assert.Equal(t, Stats{Skipped: 2}, stats)
+}
+
+func TestHandlerSink(t *testing.T) {
+	received := []datapackages.Killmail{}
+	handler := nsq.HandlerFunc(func(m *nsq.Message) error {
+		k := datapackages.Killmail{}
+		err := gobcoder.GobDecoder(m.Body, &k)
+		received = append(received, k)
+		return err
+	})
+
+	sink := NewHandlerSink(handler, handler)
+	err := sink.Send(&datapackages.Killmail{Hash: "abc123", Kill: testKill(1, 587, time.Now().UTC())})
+	assert.Nil(t, err)
+	assert.Len(t, received, 2)
+	assert.Equal(t, "abc123", received[0].Hash)
+	assert.Equal(t, int32(587), received[1].Kill.Victim.ShipTypeId)
+}
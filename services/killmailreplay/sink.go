@@ -0,0 +1,63 @@
+package killmailreplay
+
+import (
+	"crypto/rand"
+
+	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/gobcoder"
+	nsq "github.com/nsqio/go-nsq"
+)
+
+// Sink receives replayed killmails
+type Sink interface {
+	Send(*datapackages.Killmail) error
+}
+
+// NSQSink republishes killmails to the killmail topic for every consumer
+type NSQSink struct {
+	producer *nsq.Producer
+	topic    string
+}
+
+// NewNSQSink publishes to topic through producer
+func NewNSQSink(producer *nsq.Producer, topic string) *NSQSink {
+	return &NSQSink{producer: producer, topic: topic}
+}
+
+// Send publishes the killmail
+func (s *NSQSink) Send(k *datapackages.Killmail) error {
+	b, err := gobcoder.GobEncoder(k)
+	if err != nil {
+		return err
+	}
+	return s.producer.Publish(s.topic, b)
+}
+
+// HandlerSink passes killmails straight to service handlers in process,
+// encoded as they would arrive from NSQ.
+type HandlerSink struct {
+	handlers []nsq.Handler
+}
+
+// NewHandlerSink calls each handler with every killmail
+func NewHandlerSink(handlers ...nsq.Handler) *HandlerSink {
+	return &HandlerSink{handlers: handlers}
+}
+
+// Send calls the handlers in order, stopping at the first error
+func (s *HandlerSink) Send(k *datapackages.Killmail) error {
+	b, err := gobcoder.GobEncoder(k)
+	if err != nil {
+		return err
+	}
+
+	var id nsq.MessageID
+	rand.Read(id[:])
+
+	for _, h := range s.handlers {
+		if err := h.HandleMessage(nsq.NewMessage(id, b)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
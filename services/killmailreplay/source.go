@@ -0,0 +1,152 @@
+package killmailreplay
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/antihax/evedata/internal/archivestore"
+	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/goesi/esi"
+	"github.com/jmoiron/sqlx"
+)
+
+// Source provides killmails to replay. Next returns io.EOF when exhausted.
+type Source interface {
+	Next() (*datapackages.Killmail, error)
+}
+
+// archivedKillmail is the part of a tailor archive we need
+type archivedKillmail struct {
+	Killmail *esi.GetKillmailsKillmailIdKillmailHashOk `json:"killmail"`
+}
+
+// decodeArchive reads a gzipped tailor archive
+func decodeArchive(r io.Reader) (*esi.GetKillmailsKillmailIdKillmailHashOk, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	a := archivedKillmail{}
+	if err := json.NewDecoder(gz).Decode(&a); err != nil {
+		return nil, err
+	}
+	if a.Killmail == nil {
+		return nil, fmt.Errorf("archive has no killmail")
+	}
+	return a.Killmail, nil
+}
+
+// DirectorySource reads killmails from files in a directory.
+// <hash>.json files are written by killmaildumper. <id>.json.gz files are tailor
+// archives, which do not carry the hash.
+type DirectorySource struct {
+	files []string
+}
+
+// NewDirectorySource lists killmail files in path in name order
+func NewDirectorySource(path string) (*DirectorySource, error) {
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &DirectorySource{}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		if strings.HasSuffix(f.Name(), ".json") || strings.HasSuffix(f.Name(), ".json.gz") {
+			s.files = append(s.files, filepath.Join(path, f.Name()))
+		}
+	}
+	sort.Strings(s.files)
+
+	return s, nil
+}
+
+// Next reads the next file
+func (s *DirectorySource) Next() (*datapackages.Killmail, error) {
+	if len(s.files) == 0 {
+		return nil, io.EOF
+	}
+	name := s.files[0]
+	s.files = s.files[1:]
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(name, ".json.gz") {
+		kill, err := decodeArchive(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		return &datapackages.Killmail{Kill: *kill}, nil
+	}
+
+	k := &datapackages.Killmail{Hash: strings.TrimSuffix(filepath.Base(name), ".json")}
+	if err := json.NewDecoder(f).Decode(&k.Kill); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return k, nil
+}
+
+// KillmailKey identifies an archived killmail
+type KillmailKey struct {
+	ID   int32  `db:"id"`
+	Hash string `db:"hash"`
+}
+
+// KillmailsBetween lists killmails nail has recorded in a time range
+func KillmailsBetween(db *sqlx.DB, from, to time.Time) ([]KillmailKey, error) {
+	keys := []KillmailKey{}
+	err := db.Select(&keys, `
+		SELECT id, hash FROM evedata.killmails
+		WHERE killTime >= ? AND killTime < ?
+		ORDER BY killTime, id`, from, to)
+	return keys, err
+}
+
+// ArchiveSource reads killmails from the tailor archive store
+type ArchiveSource struct {
+	store archivestore.ArchiveStore
+	keys  []KillmailKey
+}
+
+// NewArchiveSource reads the listed killmails from store
+func NewArchiveSource(store archivestore.ArchiveStore, keys []KillmailKey) *ArchiveSource {
+	return &ArchiveSource{store: store, keys: keys}
+}
+
+// Next fetches the next killmail from the store
+func (s *ArchiveSource) Next() (*datapackages.Killmail, error) {
+	if len(s.keys) == 0 {
+		return nil, io.EOF
+	}
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+
+	r, err := s.store.Get(fmt.Sprintf("%d.json.gz", key.ID))
+	if err != nil {
+		return nil, fmt.Errorf("%d: %v", key.ID, err)
+	}
+	defer r.Close()
+
+	kill, err := decodeArchive(r)
+	if err != nil {
+		return nil, fmt.Errorf("%d: %v", key.ID, err)
+	}
+	return &datapackages.Killmail{Hash: key.Hash, Kill: *kill}, nil
+}
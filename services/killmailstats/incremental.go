@@ -0,0 +1,210 @@
+package killmailstats
+
+import (
+	"database/sql"
+	"log"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/antihax/evedata/internal/datapackages"
+	"github.com/antihax/evedata/internal/gobcoder"
+	"github.com/antihax/goesi/esi"
+	"github.com/jmoiron/sqlx"
+	nsq "github.com/nsqio/go-nsq"
+)
+
+// loadSecurity reads the rounded security of every solar system
+func (s *KillmailStats) loadSecurity() (map[int32]float64, error) {
+	systems := []struct {
+		SolarSystemID int32   `db:"solarSystemID"`
+		Security      float64 `db:"security"`
+	}{}
+	if err := s.db.Select(&systems, `
+		SELECT solarSystemID, ROUND(security, 1) AS security FROM eve.mapSolarSystems`); err != nil {
+		return nil, err
+	}
+
+	security := make(map[int32]float64)
+	for _, sys := range systems {
+		security[sys.SolarSystemID] = sys.Security
+	}
+	return security, nil
+}
+
+// factsForKillmail collects what the statistics need from a killmail
+func (s *KillmailStats) factsForKillmail(mail *esi.GetKillmailsKillmailIdKillmailHashOk) (*killFacts, bool) {
+	security, ok := s.security[mail.SolarSystemId]
+	if !ok {
+		return nil, false
+	}
+
+	// Mean security of the attacking characters, as tailor stores it
+	meanSecurity := float64(0)
+	count := 0
+	for _, attacker := range mail.Attackers {
+		if attacker.CharacterId > 0 {
+			meanSecurity += float64(attacker.SecurityStatus)
+			count++
+		}
+	}
+	if count > 0 {
+		meanSecurity /= float64(count)
+	}
+
+	return &killFacts{
+		solarSystemID: mail.SolarSystemId,
+		security:      security,
+		warID:         mail.WarId,
+		factionID:     mail.Victim.FactionId,
+		meanSecurity:  meanSecurity,
+	}, true
+}
+
+// characterAge in days when a character died, from their birth date in
+// evedata.characters. Characters not loaded yet count as age zero until
+// reconciliation recounts the month.
+func (s *KillmailStats) characterAge(characterID int32, killTime time.Time) (int, error) {
+	var age int
+	err := s.db.QueryRow(`
+		SELECT DATEDIFF(?, birthDate) + 1 FROM evedata.characters WHERE characterID = ?`,
+		killTime, characterID).Scan(&age)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return age, err
+}
+
+// killmailHandler updates the statistics for a new killmail as it arrives
+func (s *KillmailStats) killmailHandler(message *nsq.Message) error {
+	killmail := datapackages.Killmail{}
+	if err := gobcoder.GobDecoder(message.Body, &killmail); err != nil {
+		log.Println(err)
+		return err
+	}
+	mail := &killmail.Kill
+
+	// Months outside the window are no longer maintained
+	if !mail.KillmailTime.After(windowStart(time.Now())) {
+		return nil
+	}
+
+	facts, ok := s.factsForKillmail(mail)
+	if !ok {
+		return nil
+	}
+	s.markChanged(mail.KillmailTime)
+
+	if err := s.countVictim(mail, facts); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := s.countKillers(mail, facts); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// countVictim records the victim against each stat the kill matches, and recounts
+// the age buckets the victim may have been added to. Replays are harmless.
+func (s *KillmailStats) countVictim(mail *esi.GetKillmailsKillmailIdKillmailHashOk, facts *killFacts) error {
+	age, err := s.characterAge(mail.Victim.CharacterId, mail.KillmailTime)
+	if err != nil {
+		return err
+	}
+	year, month := mail.KillmailTime.Year(), int(mail.KillmailTime.Month())
+
+	for _, stat := range victimStats {
+		if !stat.match(facts) {
+			continue
+		}
+
+		if err := s.doSQL(`
+			INSERT INTO evedata.killmailStatisticsVictims (month, year, stat, characterID, characterAge)
+			VALUES (?, ?, ?, ?, ?)
+			ON DUPLICATE KEY UPDATE characterAge = LEAST(characterAge, VALUES(characterAge));
+		`, month, year, stat.name, mail.Victim.CharacterId, age); err != nil {
+			return err
+		}
+
+		for _, bucket := range characterAges {
+			if age >= bucket {
+				continue
+			}
+			if err := s.doSQL(`
+				INSERT INTO evedata.killmailStatistics (month, year, characterAge, `+stat.name+`)
+				SELECT ?, ?, ?, COUNT(*)
+				FROM evedata.killmailStatisticsVictims
+					WHERE month = ? AND year = ? AND stat = ? AND characterAge < ?
+				ON DUPLICATE KEY UPDATE `+stat.name+` = VALUES(`+stat.name+`);
+			`, month, year, bucket, month, year, stat.name, bucket); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// countKillers adds the kill to each attacking alliance, or corporation without one.
+// Killmails are only counted once; reconciliation restores any lost on errors.
+func (s *KillmailStats) countKillers(mail *esi.GetKillmailsKillmailIdKillmailHashOk, facts *killFacts) error {
+	area := killerArea(facts)
+	if area == "" {
+		return nil
+	}
+
+	res, err := s.db.Exec(`
+		INSERT IGNORE INTO evedata.killmailStatisticsProcessed (id, killTime) VALUES (?, ?)`,
+		mail.KillmailId, mail.KillmailTime)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	killers := make(map[int32]bool)
+	for _, a := range mail.Attackers {
+		if a.AllianceId > 0 {
+			killers[a.AllianceId] = true
+		} else if a.CorporationId > 0 {
+			killers[a.CorporationId] = true
+		}
+	}
+	if len(killers) == 0 {
+		return nil
+	}
+
+	ids := []int32{}
+	for id := range killers {
+		ids = append(ids, id)
+	}
+
+	// Only entities we track are counted
+	q, args, err := sqlx.In(`SELECT id FROM evedata.entities WHERE id IN (?)`, ids)
+	if err != nil {
+		return err
+	}
+	entities := []int64{}
+	if err := s.db.Select(&entities, s.db.Rebind(q), args...); err != nil {
+		return err
+	}
+	if len(entities) == 0 {
+		return nil
+	}
+
+	year, month := mail.KillmailTime.Year(), int(mail.KillmailTime.Month())
+	sq := squirrel.Insert("evedata.killmailKillers").Columns("month", "year", "id", "kills", "area")
+	for _, id := range entities {
+		sq = sq.Values(month, year, id, 1, area)
+	}
+	sqlq, args, err := sq.ToSql()
+	if err != nil {
+		return err
+	}
+
+	return s.doSQL(sqlq+" ON DUPLICATE KEY UPDATE kills = kills + 1", args...)
+}
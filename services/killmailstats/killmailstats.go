@@ -2,33 +2,117 @@
 package killmailstats
 
 import (
+	"log"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/jmoiron/sqlx"
+	nsq "github.com/nsqio/go-nsq"
 )
 
+// reconcileInterval is how often changed months are rebuilt from the killmail tables
+const reconcileInterval = time.Hour * 24
+
 // KillmailStats processes killmail report data
 type KillmailStats struct {
-	db *sqlx.DB
+	stop     chan bool
+	consumer *nsq.Consumer
+	db       *sqlx.DB
+
+	// Rounded security of each solar system
+	security map[int32]float64
+
+	// Months with killmails since the last reconcile
+	changed     map[time.Time]bool
+	changedLock sync.Mutex
 }
 
 // NewKillmailStats Service.
-func NewKillmailStats(db *sqlx.DB) *KillmailStats {
+func NewKillmailStats(db *sqlx.DB, consumerAddresses []string) *KillmailStats {
 	// Setup a new squirrel
 	s := &KillmailStats{
-		db: db,
+		stop:    make(chan bool),
+		db:      db,
+		changed: make(map[time.Time]bool),
+	}
+
+	security, err := s.loadSecurity()
+	if err != nil {
+		log.Fatalln(err)
 	}
+	s.security = security
+
+	nsqcfg := nsq.NewConfig()
+	nsqcfg.MaxInFlight = 10
+	nsqcfg.MsgTimeout = time.Minute
+
+	c, err := nsq.NewConsumer("killmail", "killmailstats", nsqcfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	s.consumer = c
+
+	// Counts are read back after writing, so handle one killmail at a time
+	c.AddHandler(nsq.HandlerFunc(s.killmailHandler))
+	err = c.ConnectToNSQLookupds(consumerAddresses)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Stop the logger being so verbose
+	c.SetLogger(log.New(os.Stderr, "", log.Flags()), nsq.LogLevelError)
 
 	return s
 }
 
 // Close the service
 func (s *KillmailStats) Close() {
-
+	close(s.stop)
+	s.consumer.Stop()
 }
 
-// Run the service
+// Run the service, reconciling the incremental statistics daily. Killmails may
+// have been missed while stopped so the whole window is reconciled on start,
+// after that only the months killmails arrived for.
 func (s *KillmailStats) Run() {
-	s.runStats()
+	s.reconcile(windowMonths(time.Now()))
+
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.reconcile(s.takeChangedMonths())
+		}
+	}
+}
+
+// markChanged notes the month of a killmail for the next reconcile
+func (s *KillmailStats) markChanged(killTime time.Time) {
+	s.changedLock.Lock()
+	defer s.changedLock.Unlock()
+	s.changed[monthStart(killTime)] = true
+}
+
+// takeChangedMonths returns the months changed since the last call, oldest first
+func (s *KillmailStats) takeChangedMonths() []time.Time {
+	s.changedLock.Lock()
+	defer s.changedLock.Unlock()
+
+	months := []time.Time{}
+	for month := range s.changed {
+		months = append(months, month)
+	}
+	s.changed = make(map[time.Time]bool)
+
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+	return months
 }
 
 func (s *KillmailStats) doSQL(stmt string, args ...interface{}) error {
@@ -5,10 +5,10 @@ import (
 	"log"
 	"reflect"
 	"runtime"
+	"time"
 )
 
-type statFunc func(age int) error
-type entityStatFunc func() error
+type entityStatFunc func(start, end time.Time) error
 
 func funcName(f interface{}) string {
 	p := reflect.ValueOf(f).Pointer()
@@ -16,184 +16,186 @@ func funcName(f interface{}) string {
 	return rf.Name()
 }
 
-// Run the service
-func (s *KillmailStats) runStats() {
-	ages := [5]int{18250, 7, 14, 30, 90}
-	stats := []statFunc{
-		s.wars,
-		s.ganks,
-		s.lowsec,
-		s.nullsec,
-		s.highsec,
-		s.wh,
-		s.lowsecFW,
-		s.highsecFW,
-		s.total,
-	}
+// characterAges are the age buckets statistics are counted under
+var characterAges = [5]int{18250, 7, 14, 30, 90}
 
-	entityStats := []entityStatFunc{
-		s.entity_highsec,
-		s.entity_lowsec,
-		s.entity_nullsec,
-		s.entity_wh,
-	}
+// killFacts are the properties of a killmail the statistics depend on
+type killFacts struct {
+	solarSystemID int32
+	security      float64 // Rounded to one decimal place
+	warID         int32
+	factionID     int32
+	meanSecurity  float64 // Of attacking characters
+}
 
-	for _, age := range ages {
-		for _, stat := range stats {
-			fmt.Printf("Processing %d for %s\n", age, funcName(stat))
-			err := stat(age)
-			if err != nil {
-				log.Println(err)
-			}
-		}
-	}
-	for _, stat := range entityStats {
-		fmt.Printf("Processing  %s\n", funcName(stat))
-		err := stat()
-		if err != nil {
-			log.Println(err)
-		}
-	}
+func (k *killFacts) highsec() bool {
+	return k.security >= 0.5
 }
 
-func (s *KillmailStats) lowsec(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, lowsec)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) < 0.5 
-			AND ROUND(S.security, 1) > 0.0
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE lowsec = VALUES(lowsec);
-	`, age, age)
+func (k *killFacts) lowsec() bool {
+	return k.security < 0.5 && k.security > 0.0
 }
 
-func (s *KillmailStats) lowsecFW(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, lowsecFW)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) < 0.5 
-			AND ROUND(S.security, 1) > 0.0
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-			AND K.factionID > 0
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE lowsecFW = VALUES(lowsecFW);
-	`, age, age)
+func (k *killFacts) nullsec() bool {
+	return k.solarSystemID < 31000000 && k.security <= 0.0
 }
 
-func (s *KillmailStats) highsec(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, highsec)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) >= 0.5
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE highsec = VALUES(highsec);
-	`, age, age)
+func (k *killFacts) wh() bool {
+	return k.solarSystemID >= 31000000 && k.solarSystemID < 32000000
 }
 
-func (s *KillmailStats) highsecFW(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, highsecFW)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) >= 0.5 
-			AND K.factionID > 0
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE highsecFW = VALUES(highsecFW);
-	`, age, age)
+// victimStat is a killmailStatistics column counting distinct victims.
+// where and match must agree so incremental counts match reconciliation.
+type victimStat struct {
+	name  string
+	where string // SQL over killmails K, killmailAttributes A and mapSolarSystems S
+	match func(k *killFacts) bool
 }
 
-func (s *KillmailStats) wars(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, wars)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) >= 0.5 AND K.warID > 0
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE wars = VALUES(wars);
-	`, age, age)
+var victimStats = []victimStat{
+	{"wars", "ROUND(S.security, 1) >= 0.5 AND K.warID > 0",
+		func(k *killFacts) bool { return k.highsec() && k.warID > 0 }},
+	{"ganks", "ROUND(S.security, 1) >= 0.5 AND A.meanSecurity < -4.5",
+		func(k *killFacts) bool { return k.highsec() && k.meanSecurity < -4.5 }},
+	{"lowsec", "ROUND(S.security, 1) < 0.5 AND ROUND(S.security, 1) > 0.0",
+		func(k *killFacts) bool { return k.lowsec() }},
+	{"nullsec", "S.solarSystemID < 31000000 AND ROUND(S.security, 1) <= 0.0",
+		func(k *killFacts) bool { return k.nullsec() }},
+	{"highsec", "ROUND(S.security, 1) >= 0.5",
+		func(k *killFacts) bool { return k.highsec() }},
+	{"wh", "S.solarSystemID >= 31000000 AND S.solarSystemID < 32000000",
+		func(k *killFacts) bool { return k.wh() }},
+	{"lowsecFW", "ROUND(S.security, 1) < 0.5 AND ROUND(S.security, 1) > 0.0 AND K.factionID > 0",
+		func(k *killFacts) bool { return k.lowsec() && k.factionID > 0 }},
+	{"highsecFW", "ROUND(S.security, 1) >= 0.5 AND K.factionID > 0",
+		func(k *killFacts) bool { return k.highsec() && k.factionID > 0 }},
+	{"total", "1",
+		func(k *killFacts) bool { return true }},
 }
 
-func (s *KillmailStats) ganks(age int) error {
-	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, ganks)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND ROUND(S.security, 1) >= 0.5 AND A.meanSecurity < -4.5
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)
-		ON DUPLICATE KEY UPDATE ganks = VALUES(ganks);
-	`, age, age)
+// killerArea is the killmailKillers area of a kill, or empty if it has none
+func killerArea(k *killFacts) string {
+	switch {
+	case k.highsec():
+		return "highsec"
+	case k.lowsec():
+		return "lowsec"
+	case k.wh():
+		return "wh"
+	case k.nullsec():
+		return "nullsec"
+	}
+	return ""
+}
+
+// windowStart is the first day of the month sixty days ago, the oldest
+// month statistics are still maintained for.
+func windowStart(now time.Time) time.Time {
+	t := now.UTC().AddDate(0, 0, -60)
+	return monthStart(t)
+}
+
+// monthStart is the first moment of the month t is in
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// windowMonths lists every month still maintained, oldest first
+func windowMonths(now time.Time) []time.Time {
+	months := []time.Time{}
+	for m := windowStart(now); !m.After(now); m = m.AddDate(0, 1, 0) {
+		months = append(months, m)
+	}
+	return months
 }
 
-func (s *KillmailStats) wh(age int) error {
+// reconcile rebuilds the statistics for months from the killmail tables,
+// correcting drift from missed messages and character ages filled in later.
+func (s *KillmailStats) reconcile(months []time.Time) {
+	start := windowStart(time.Now())
+	for _, month := range months {
+		// Months outside the window are no longer maintained
+		if month.Before(start) {
+			continue
+		}
+		end := month.AddDate(0, 1, 0)
+
+		for _, stat := range victimStats {
+			fmt.Printf("Reconciling %s for %s\n", stat.name, month.Format("2006-01"))
+			if err := s.reconcileVictims(stat, month, end); err != nil {
+				log.Println(err)
+				continue
+			}
+			for _, age := range characterAges {
+				if err := s.recountStatistics(stat.name, age, month); err != nil {
+					log.Println(err)
+				}
+			}
+		}
+
+		entityStats := []entityStatFunc{
+			s.entity_highsec,
+			s.entity_lowsec,
+			s.entity_nullsec,
+			s.entity_wh,
+		}
+
+		for _, stat := range entityStats {
+			fmt.Printf("Processing  %s for %s\n", funcName(stat), month.Format("2006-01"))
+			err := stat(month, end)
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+
+	if err := s.pruneWindow(start); err != nil {
+		log.Println(err)
+	}
+}
+
+// reconcileVictims sets the youngest age each victim of a stat was killed at in a month
+func (s *KillmailStats) reconcileVictims(stat victimStat, start, end time.Time) error {
 	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, wh)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
+		INSERT INTO evedata.killmailStatisticsVictims (month, year, stat, characterID, characterAge)
+		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? AS stat, K.victimCharacterID, MIN(A.characterAge)
 		FROM evedata.killmailAttributes A
 		INNER JOIN evedata.killmails K ON K.id = A.id
 		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND S.solarSystemID >= 31000000 AND S.solarSystemID < 32000000
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)       
-		ON DUPLICATE KEY UPDATE wh = VALUES(wh);
-	`, age, age)
+			WHERE `+stat.where+`
+			AND K.killTime >= ? AND K.killTime < ?
+		GROUP BY YEAR(K.killTime), MONTH(K.killTime), K.victimCharacterID
+		ON DUPLICATE KEY UPDATE characterAge = VALUES(characterAge);
+	`, stat.name, start, end)
 }
 
-func (s *KillmailStats) nullsec(age int) error {
+// recountStatistics counts distinct victims of a stat under an age in a month
+func (s *KillmailStats) recountStatistics(stat string, age int, month time.Time) error {
 	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, nullsec)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
-			WHERE characterAge < ?
-			AND S.solarSystemID < 31000000 AND ROUND(S.security, 1) <= 0.0 
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)       
-		ON DUPLICATE KEY UPDATE nullsec = VALUES(nullsec);
-	`, age, age)
+		INSERT INTO evedata.killmailStatistics (month, year, characterAge, `+stat+`)
+		SELECT month, year, ? AS characterAge, COUNT(*)
+		FROM evedata.killmailStatisticsVictims
+			WHERE stat = ? AND characterAge < ?
+			AND year = ? AND month = ?
+		GROUP BY year, month
+		ON DUPLICATE KEY UPDATE `+stat+` = VALUES(`+stat+`);
+	`, age, stat, age, month.Year(), int(month.Month()))
 }
 
-func (s *KillmailStats) total(age int) error {
+// pruneWindow removes incremental state for months no longer maintained
+func (s *KillmailStats) pruneWindow(start time.Time) error {
+	if err := s.doSQL(`
+		DELETE FROM evedata.killmailStatisticsVictims WHERE year * 100 + month < ?;
+	`, start.Year()*100+int(start.Month())); err != nil {
+		return err
+	}
 	return s.doSQL(`
-		INSERT INTO evedata.killmailStatistics  (month, year, characterAge, total)
-		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, ? as characterAge, count(DISTINCT K.victimCharacterID)
-		FROM evedata.killmailAttributes A
-		INNER JOIN evedata.killmails K ON K.id = A.id
-			WHERE characterAge < ?
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01')
-		GROUP BY YEAR(K.killTime), MONTH(K.killTime)       
-		ON DUPLICATE KEY UPDATE total = VALUES(total);
-	`, age, age)
+		DELETE FROM evedata.killmailStatisticsProcessed WHERE killTime < ?;
+	`, start)
 }
 
-func (s *KillmailStats) entity_highsec() error {
+func (s *KillmailStats) entity_highsec(start, end time.Time) error {
 	return s.doSQL(`
 		INSERT INTO evedata.killmailKillers (month, year, id, kills, area)
 		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, E.ID, COUNT(DISTINCT K.id) AS kills, "highsec"
@@ -202,13 +204,13 @@ func (s *KillmailStats) entity_highsec() error {
 				INNER JOIN evedata.entities E ON E.id = IF(A.allianceID , A.allianceID, A.corporationID)
 				INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
 				WHERE ROUND(S.security, 1) >= 0.5
-				AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01') 
+				AND K.killTime >= ? AND K.killTime < ?
 				GROUP BY YEAR(K.killTime), MONTH(K.killTime), E.ID
 				ON DUPLICATE KEY UPDATE kills=VALUES(kills);
-	`)
+	`, start, end)
 }
 
-func (s *KillmailStats) entity_lowsec() error {
+func (s *KillmailStats) entity_lowsec(start, end time.Time) error {
 	return s.doSQL(`
 		INSERT INTO evedata.killmailKillers (month, year, id, kills, area)
 		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, E.ID, COUNT(DISTINCT K.id) AS kills, "lowsec"
@@ -217,14 +219,14 @@ func (s *KillmailStats) entity_lowsec() error {
 				INNER JOIN evedata.entities E ON E.id = IF(A.allianceID , A.allianceID, A.corporationID)
 				INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
 					WHERE ROUND(S.security, 1) < 0.5 AND ROUND(S.security, 1) > 0.0 
-					AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01') 
+					AND K.killTime >= ? AND K.killTime < ?
 
 					GROUP BY YEAR(K.killTime), MONTH(K.killTime), E.ID
 				ON DUPLICATE KEY UPDATE kills=VALUES(kills);
-	`)
+	`, start, end)
 }
 
-func (s *KillmailStats) entity_nullsec() error {
+func (s *KillmailStats) entity_nullsec(start, end time.Time) error {
 	return s.doSQL(`
 	INSERT INTO evedata.killmailKillers (month, year, id, kills, area)
 		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, E.ID, COUNT(DISTINCT K.id) AS kills, "nullsec"
@@ -233,13 +235,13 @@ func (s *KillmailStats) entity_nullsec() error {
 		INNER JOIN evedata.entities E ON E.id = IF(A.allianceID , A.allianceID, A.corporationID)
 		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
 			WHERE S.solarSystemID < 31000000 AND ROUND(S.security, 1) <= 0.0 
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01') 
+			AND K.killTime >= ? AND K.killTime < ?
 		GROUP BY YEAR(K.killTime), MONTH(K.killTime), E.ID
 		ON DUPLICATE KEY UPDATE kills=VALUES(kills);
-	`)
+	`, start, end)
 }
 
-func (s *KillmailStats) entity_wh() error {
+func (s *KillmailStats) entity_wh(start, end time.Time) error {
 	return s.doSQL(`
 	INSERT INTO evedata.killmailKillers (month, year, id, kills, area)
 		SELECT MONTH(K.killTime) AS month, YEAR(K.killTime) AS year, E.ID, COUNT(DISTINCT K.id) AS kills, "wh"
@@ -248,8 +250,8 @@ func (s *KillmailStats) entity_wh() error {
 		INNER JOIN evedata.entities E ON E.id = IF(A.allianceID , A.allianceID, A.corporationID)
 		INNER JOIN mapSolarSystems S ON S.solarSystemID = K.solarSystemID
 			WHERE S.solarSystemID >= 31000000 AND S.solarSystemID < 32000000
-			AND K.killTime > DATE_FORMAT(DATE_SUB(UTC_TIMESTAMP(), INTERVAL 60 DAY), '%Y-%m-01') 
+			AND K.killTime >= ? AND K.killTime < ?
 		GROUP BY YEAR(K.killTime), MONTH(K.killTime), E.ID
 		ON DUPLICATE KEY UPDATE kills=VALUES(kills);
-	`)
+	`, start, end)
 }
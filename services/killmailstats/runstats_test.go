@@ -0,0 +1,67 @@
+package killmailstats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func matchingStats(k *killFacts) []string {
+	names := []string{}
+	for _, stat := range victimStats {
+		if stat.match(k) {
+			names = append(names, stat.name)
+		}
+	}
+	return names
+}
+
+func TestVictimStats(t *testing.T) {
+	gank := &killFacts{solarSystemID: 30000142, security: 0.9, meanSecurity: -6}
+	assert.Equal(t, []string{"ganks", "highsec", "total"}, matchingStats(gank))
+	assert.Equal(t, "highsec", killerArea(gank))
+
+	war := &killFacts{solarSystemID: 30000142, security: 0.5, warID: 10, meanSecurity: -4.5}
+	assert.Equal(t, []string{"wars", "highsec", "total"}, matchingStats(war))
+
+	fw := &killFacts{solarSystemID: 30002813, security: 0.3, factionID: 500001}
+	assert.Equal(t, []string{"lowsec", "lowsecFW", "total"}, matchingStats(fw))
+	assert.Equal(t, "lowsec", killerArea(fw))
+
+	null := &killFacts{solarSystemID: 30004759, security: 0.0}
+	assert.Equal(t, []string{"nullsec", "total"}, matchingStats(null))
+	assert.Equal(t, "nullsec", killerArea(null))
+
+	wh := &killFacts{solarSystemID: 31000005, security: -1}
+	assert.Equal(t, []string{"wh", "total"}, matchingStats(wh))
+	assert.Equal(t, "wh", killerArea(wh))
+}
+
+func TestWindowStart(t *testing.T) {
+	assert.Equal(t, time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC),
+		windowStart(time.Date(2018, 6, 15, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, time.Date(2017, 11, 1, 0, 0, 0, 0, time.UTC),
+		windowStart(time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestWindowMonths(t *testing.T) {
+	assert.Equal(t, []time.Time{
+		time.Date(2018, 4, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, windowMonths(time.Date(2018, 6, 15, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestTakeChangedMonths(t *testing.T) {
+	s := &KillmailStats{changed: make(map[time.Time]bool)}
+	s.markChanged(time.Date(2018, 6, 15, 12, 0, 0, 0, time.UTC))
+	s.markChanged(time.Date(2018, 5, 31, 23, 59, 0, 0, time.UTC))
+	s.markChanged(time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC))
+
+	assert.Equal(t, []time.Time{
+		time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2018, 6, 1, 0, 0, 0, 0, time.UTC),
+	}, s.takeChangedMonths())
+	assert.Len(t, s.takeChangedMonths(), 0)
+}
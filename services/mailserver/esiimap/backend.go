@@ -4,12 +4,15 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/antihax/evedata/internal/redisqueue"
 
 	"github.com/antihax/evedata/internal/tokenstore"
+	"github.com/antihax/evedata/services/mailserver/mailaddress"
 	"github.com/antihax/goesi"
 	"github.com/emersion/go-imap/backend"
+	"github.com/garyburd/redigo/redis"
 )
 
 type Backend struct {
@@ -19,16 +22,27 @@ type Backend struct {
 	cacheQueue       *redisqueue.RedisQueue
 	cacheLookup      chan int32
 	cacheMailingList chan int32
+	state            *stateStore
+	addresses        *mailaddress.Resolver
+
+	// New mail polling
+	updates    chan backend.Update
+	pollers    map[int32]*mailPoller
+	pollerLock sync.Mutex
 }
 
-func New(tokenAPI *tokenstore.TokenServerAPI, esi *goesi.APIClient, tokenAuth *goesi.SSOAuthenticator, q *redisqueue.RedisQueue) *Backend {
+func New(tokenAPI *tokenstore.TokenServerAPI, esi *goesi.APIClient, tokenAuth *goesi.SSOAuthenticator, q *redisqueue.RedisQueue, redis *redis.Pool, addresses *mailaddress.Resolver) *Backend {
 	b := &Backend{
-		tokenAPI,
-		esi,
-		tokenAuth,
-		q,
-		make(chan int32, 1000000),
-		make(chan int32, 1000000),
+		tokenAPI:         tokenAPI,
+		esi:              esi,
+		tokenAuth:        tokenAuth,
+		cacheQueue:       q,
+		cacheLookup:      make(chan int32, 1000000),
+		cacheMailingList: make(chan int32, 1000000),
+		state:            &stateStore{redis: redis},
+		addresses:        addresses,
+		updates:          make(chan backend.Update, 100),
+		pollers:          make(map[int32]*mailPoller),
 	}
 
 	// Start the cache lookup queue
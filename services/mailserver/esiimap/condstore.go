@@ -0,0 +1,144 @@
+package esiimap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	imap "github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/server"
+)
+
+// fetchModSeq is the CONDSTORE fetch item for a message's MODSEQ
+const fetchModSeq imap.FetchItem = "MODSEQ"
+
+// modSeqMailbox is a mailbox that tracks changes for CONDSTORE
+type modSeqMailbox interface {
+	HighestModSeq() uint64
+	ChangedSince(uid bool, seqSet *imap.SeqSet, modSeq uint64) *imap.SeqSet
+}
+
+// modSeqField formats a MODSEQ, which can be larger than the writer's numbers
+func modSeqField(modSeq uint64) []interface{} {
+	return []interface{}{imap.RawString(strconv.FormatUint(modSeq, 10))}
+}
+
+type condstore struct{}
+
+// NewCondstoreExtension adds CONDSTORE (RFC 7162) so clients can sync flag changes.
+// SELECT and EXAMINE report HIGHESTMODSEQ, and FETCH supports the MODSEQ item
+// and the CHANGEDSINCE modifier.
+func NewCondstoreExtension() server.Extension {
+	return &condstore{}
+}
+
+func (ext *condstore) Capabilities(c server.Conn) []string {
+	return []string{"CONDSTORE"}
+}
+
+func (ext *condstore) Command(name string) server.HandlerFactory {
+	switch name {
+	case "SELECT":
+		return func() server.Handler {
+			return &condstoreSelect{}
+		}
+	case "EXAMINE":
+		return func() server.Handler {
+			h := &condstoreSelect{}
+			h.ReadOnly = true
+			return h
+		}
+	case "FETCH":
+		return func() server.Handler {
+			return &condstoreFetch{}
+		}
+	}
+	return nil
+}
+
+// condstoreSelect adds HIGHESTMODSEQ to SELECT and EXAMINE
+type condstoreSelect struct {
+	server.Select
+}
+
+func (cmd *condstoreSelect) Handle(conn server.Conn) error {
+	if err := cmd.Select.Handle(conn); err != nil {
+		return err
+	}
+
+	// Sent before the tagged response
+	if mbox, ok := conn.Context().Mailbox.(modSeqMailbox); ok {
+		return conn.WriteResp(&imap.StatusResp{
+			Type:      imap.StatusRespOk,
+			Code:      "HIGHESTMODSEQ",
+			Arguments: modSeqField(mbox.HighestModSeq()),
+		})
+	}
+	return nil
+}
+
+// condstoreFetch adds the CHANGEDSINCE modifier to FETCH and UID FETCH
+type condstoreFetch struct {
+	server.Fetch
+	changedSince uint64
+}
+
+func (cmd *condstoreFetch) Parse(fields []interface{}) error {
+	if err := cmd.Fetch.Parse(fields); err != nil {
+		return err
+	}
+	if len(fields) < 3 {
+		return nil
+	}
+
+	modifiers, ok := fields[2].([]interface{})
+	if !ok {
+		return fmt.Errorf("fetch modifiers must be a list")
+	}
+	for i := 0; i+1 < len(modifiers); i += 2 {
+		name, _ := modifiers[i].(string)
+		if !strings.EqualFold(name, "CHANGEDSINCE") {
+			return fmt.Errorf("unknown fetch modifier %s", name)
+		}
+		v, err := strconv.ParseUint(fmt.Sprint(modifiers[i+1]), 10, 64)
+		if err != nil {
+			return err
+		}
+		cmd.changedSince = v
+	}
+
+	// CHANGEDSINCE implies MODSEQ
+	if cmd.changedSince > 0 && !hasFetchItem(cmd.Items, fetchModSeq) {
+		cmd.Items = append(cmd.Items, fetchModSeq)
+	}
+	return nil
+}
+
+func (cmd *condstoreFetch) Handle(conn server.Conn) error {
+	cmd.narrow(false, conn)
+	return cmd.Fetch.Handle(conn)
+}
+
+func (cmd *condstoreFetch) UidHandle(conn server.Conn) error {
+	cmd.narrow(true, conn)
+	return cmd.Fetch.UidHandle(conn)
+}
+
+// narrow limits the fetch to messages changed since the CHANGEDSINCE modifier
+func (cmd *condstoreFetch) narrow(uid bool, conn server.Conn) {
+	if cmd.changedSince == 0 {
+		return
+	}
+	if mbox, ok := conn.Context().Mailbox.(modSeqMailbox); ok {
+		cmd.SeqSet = mbox.ChangedSince(uid, cmd.SeqSet, cmd.changedSince)
+	}
+}
+
+func hasFetchItem(items []imap.FetchItem, item imap.FetchItem) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package esiimap
+
+import (
+	"testing"
+
+	"github.com/antihax/goesi/esi"
+	imap "github.com/emersion/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCondstoreFetchParse(t *testing.T) {
+	cmd := &condstoreFetch{}
+	assert.Nil(t, cmd.Parse([]interface{}{"1:*", []interface{}{"FLAGS"}, []interface{}{"CHANGEDSINCE", "12345"}}))
+	assert.Equal(t, uint64(12345), cmd.changedSince)
+	assert.Equal(t, []imap.FetchItem{imap.FetchFlags, fetchModSeq}, cmd.Items)
+
+	cmd = &condstoreFetch{}
+	assert.Nil(t, cmd.Parse([]interface{}{"1", []interface{}{"MODSEQ"}}))
+	assert.Zero(t, cmd.changedSince)
+	assert.Equal(t, []imap.FetchItem{fetchModSeq}, cmd.Items)
+
+	cmd = &condstoreFetch{}
+	assert.NotNil(t, cmd.Parse([]interface{}{"1", "FLAGS", []interface{}{"VANISHED", "1"}}))
+}
+
+func TestChangedSince(t *testing.T) {
+	mbox := &Mailbox{state: newMailboxState()}
+	mbox.messagesSeqNum = []*esi.GetCharactersCharacterIdMail200Ok{{MailId: 500}, {MailId: 300}, {MailId: 700}}
+	mbox.state.uids = map[int32]uint32{500: 1, 300: 2, 700: 5}
+	mbox.state.modSeqs = map[int32]uint64{500: 3, 300: 7}
+	mbox.state.modSeq = 7
+
+	all, _ := imap.ParseSeqSet("1:*")
+	assert.Equal(t, "2", mbox.ChangedSince(true, all, 5).String())
+	assert.Equal(t, "1:2", mbox.ChangedSince(false, all, 1).String())
+	assert.Equal(t, "", mbox.ChangedSince(false, all, 7).String())
+	assert.Equal(t, uint64(7), mbox.HighestModSeq())
+	assert.Equal(t, uint64(1), mbox.modSeq(700))
+
+	// UIDs are per mailbox, not mail IDs
+	set, _ := imap.ParseSeqSet("5")
+	selected := mbox.selected(true, set)
+	assert.Len(t, selected, 1)
+	assert.Equal(t, int32(700), selected[0].MailId)
+	set, _ = imap.ParseSeqSet("700")
+	assert.Len(t, mbox.selected(true, set), 0)
+}
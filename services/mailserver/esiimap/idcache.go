@@ -5,6 +5,7 @@ import (
 	"log"
 	"strings"
 
+	"github.com/antihax/evedata/services/mailserver/mailaddress"
 	"github.com/antihax/goesi/esi"
 )
 
@@ -37,7 +38,7 @@ func (s *Backend) precacheMailingLists() {
 		n, _ := s.cacheQueue.GetCache("addressName", mailingList)
 		t, _ := s.cacheQueue.GetCache("addressType", mailingList)
 		if n == "" || t == "" {
-			s.cacheQueue.SetCache("addressName", mailingList, "## Unknown Mailing List ##")
+			s.cacheQueue.SetCache("addressName", mailingList, mailaddress.UnknownMailingList)
 			s.cacheQueue.SetCache("addressType", mailingList, "mailing_list")
 		}
 	}
@@ -79,7 +80,7 @@ func (s *Backend) lookupAddresses(ids []int32) ([]string, []string, error) {
 					lookup, _, err := s.esi.ESI.UniverseApi.PostUniverseNames(context.Background(), []int32{missingID}, nil)
 					if err != nil {
 						if strings.Contains(err.Error(), "404") {
-							names[missingIdx[i]] = "## Unknown Mailing List ##"
+							names[missingIdx[i]] = mailaddress.UnknownMailingList
 							types[missingIdx[i]] = "mailing_list"
 						} else {
 							return nil, nil, err
@@ -133,5 +134,9 @@ func (u *User) cacheMailingLists(mailingLists []esi.GetCharactersCharacterIdMail
 		log.Println(err)
 		return
 	}
+	if err := u.backend.addresses.CacheMailingLists(ids, names); err != nil {
+		log.Println(err)
+		return
+	}
 	return
 }
@@ -0,0 +1,215 @@
+package esiimap
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/antihax/goesi"
+	"github.com/antihax/goesi/esi"
+	imap "github.com/emersion/go-imap"
+)
+
+// Default EVE mail labels which cannot be deleted
+const (
+	labelInbox       = 1
+	labelSent        = 2
+	labelCorporation = 4
+	labelAlliance    = 8
+)
+
+// labelColor is used for labels created through IMAP
+const labelColor = "#ffffff"
+
+func isDefaultLabel(id int32) bool {
+	return id == labelInbox || id == labelSent || id == labelCorporation || id == labelAlliance
+}
+
+// isInbox is true for INBOX, which holds all mail regardless of label
+func isInbox(name string) bool {
+	return strings.ToUpper(name) == "INBOX"
+}
+
+// withLabel returns labels with id added
+func withLabel(labels []int32, id int32) []int32 {
+	for _, l := range labels {
+		if l == id {
+			return labels
+		}
+	}
+	return append(append([]int32{}, labels...), id)
+}
+
+// withoutLabel returns labels with id removed
+func withoutLabel(labels []int32, id int32) []int32 {
+	out := []int32{}
+	for _, l := range labels {
+		if l != id {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func (u *User) auth() context.Context {
+	return context.WithValue(context.Background(), goesi.ContextOAuth2, u.token)
+}
+
+// createLabel creates a mail label and its mailbox
+func (u *User) createLabel(name string) (*Mailbox, error) {
+	if isInbox(name) {
+		return nil, errors.New("Mailbox already exists")
+	}
+	if _, ok := u.mailbox(name); ok {
+		return nil, errors.New("Mailbox already exists")
+	}
+
+	id, _, err := u.backend.esi.ESI.MailApi.PostCharactersCharacterIdMailLabels(
+		u.auth(),
+		u.characterID,
+		esi.PostCharactersCharacterIdMailLabelsLabel{Name: name, Color: labelColor},
+		nil,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	mailbox := NewMailbox(name, id, u, 0)
+	u.mailboxLock.Lock()
+	u.mailboxes[name] = mailbox
+	u.mailboxLock.Unlock()
+	return mailbox, nil
+}
+
+// deleteLabel deletes a mail label and its mailbox. Mail with the label is kept.
+func (u *User) deleteLabel(name string) error {
+	mailbox, ok := u.mailbox(name)
+	if !ok {
+		return errors.New("No such mailbox")
+	}
+	if isInbox(name) || isDefaultLabel(mailbox.id) {
+		return errors.New("You cannot delete default mailboxes")
+	}
+
+	if _, err := u.backend.esi.ESI.MailApi.DeleteCharactersCharacterIdMailLabelsLabelId(
+		u.auth(), u.characterID, mailbox.id, nil); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := u.backend.state.deleteMailbox(u.characterID, mailbox.id); err != nil {
+		log.Println(err)
+	}
+
+	u.mailboxLock.Lock()
+	delete(u.mailboxes, name)
+	u.mailboxLock.Unlock()
+	return nil
+}
+
+// setLabels replaces the labels on a mail, keeping the read state
+func (mbox *Mailbox) setLabels(m *esi.GetCharactersCharacterIdMail200Ok, labels []int32) error {
+	u := mbox.user
+	if _, err := u.backend.esi.ESI.MailApi.PutCharactersCharacterIdMailMailId(
+		u.auth(),
+		u.characterID,
+		esi.PutCharactersCharacterIdMailMailIdContents{Labels: labels, Read: m.IsRead},
+		m.MailId,
+		nil,
+	); err != nil {
+		log.Println(err)
+		return err
+	}
+	m.Labels = labels
+	return nil
+}
+
+// deleteMail deletes a mail from every label
+func (mbox *Mailbox) deleteMail(m *esi.GetCharactersCharacterIdMail200Ok) error {
+	u := mbox.user
+	if _, err := u.backend.esi.ESI.MailApi.DeleteCharactersCharacterIdMailMailId(
+		u.auth(), u.characterID, m.MailId, nil); err != nil {
+		log.Println(err)
+		return err
+	}
+	return nil
+}
+
+// selected returns the messages in a sequence or UID set
+func (mbox *Mailbox) selected(uid bool, seqSet *imap.SeqSet) []*esi.GetCharactersCharacterIdMail200Ok {
+	messages := []*esi.GetCharactersCharacterIdMail200Ok{}
+	for i, m := range mbox.messages() {
+		seqNum := i + 1
+		if (!uid && seqSet.Contains(uint32(seqNum))) || // SeqNum Match
+			(uid && seqSet.Contains(mbox.uid(m.MailId))) { // UID Match
+			messages = append(messages, m)
+		}
+	}
+	return messages
+}
+
+// destination finds the mailbox messages are copied or moved to
+func (mbox *Mailbox) destination(name string) (*Mailbox, error) {
+	if isInbox(name) {
+		name = "INBOX"
+	}
+	dest, ok := mbox.user.mailbox(name)
+	if !ok {
+		return nil, errors.New("No such mailbox")
+	}
+	return dest, nil
+}
+
+// relabel applies a label change to the selected messages and reloads the destination
+func (mbox *Mailbox) relabel(uid bool, seqSet *imap.SeqSet, dest *Mailbox, move bool) error {
+	mbox.WaitForLoad()
+	for _, m := range mbox.selected(uid, seqSet) {
+		labels := withLabel(m.Labels, dest.id)
+		if move && !isInbox(mbox.name) {
+			labels = withoutLabel(labels, mbox.id)
+		}
+		if err := mbox.setLabels(m, labels); err != nil {
+			return err
+		}
+		if move && !isInbox(mbox.name) {
+			mbox.removeMessage(m.MailId)
+		}
+	}
+	dest.reset()
+	return nil
+}
+
+// removeMessage drops a message from the mailbox
+func (mbox *Mailbox) removeMessage(mailID int32) {
+	mbox.lock.Lock()
+	defer mbox.lock.Unlock()
+	for i, m := range mbox.messagesSeqNum {
+		if m.MailId == mailID {
+			mbox.messagesSeqNum = append(mbox.messagesSeqNum[:i], mbox.messagesSeqNum[i+1:]...)
+			mbox.count--
+			break
+		}
+	}
+	delete(mbox.state.flags, mailID)
+	delete(mbox.state.modSeqs, mailID)
+	delete(mbox.state.uids, mailID)
+
+	if err := mbox.user.backend.state.removeMessage(mbox.user.characterID, mbox.id, mailID); err != nil {
+		log.Println(err)
+	}
+}
+
+// reset clears a loaded mailbox so it loads again when next selected
+func (mbox *Mailbox) reset() {
+	if !mbox.loadStarted {
+		return
+	}
+	mbox.WaitForLoad()
+	mbox.lock.Lock()
+	defer mbox.lock.Unlock()
+	mbox.loadStarted = false
+	mbox.messagesSeqNum = nil
+	mbox.state = newMailboxState()
+}
@@ -0,0 +1,23 @@
+package esiimap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLabels(t *testing.T) {
+	labels := []int32{labelInbox, 256}
+
+	assert.Equal(t, []int32{labelInbox, 256, 512}, withLabel(labels, 512))
+	assert.Equal(t, []int32{labelInbox, 256}, withLabel(labels, 256))
+	assert.Equal(t, []int32{256}, withoutLabel(labels, labelInbox))
+	assert.Equal(t, []int32{}, withoutLabel([]int32{256}, 256))
+
+	// Original is untouched
+	assert.Equal(t, []int32{labelInbox, 256}, labels)
+
+	assert.True(t, isDefaultLabel(labelAlliance))
+	assert.False(t, isDefaultLabel(256))
+	assert.True(t, isInbox("Inbox"))
+}
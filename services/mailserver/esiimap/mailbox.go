@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,11 +28,12 @@ type Mailbox struct {
 	user           *User
 	unreadCount    uint32
 	nextuid        uint32
-	firstuid       uint32
 	count          uint32
 	validity       uint32
 	messageHeaders map[uint32]*esi.GetCharactersCharacterIdMail200Ok
 	messagesSeqNum []*esi.GetCharactersCharacterIdMail200Ok
+	state          *mailboxState
+	lock           sync.RWMutex
 	loaded         sync.WaitGroup
 	loadStarted    bool
 }
@@ -43,6 +45,7 @@ func NewMailbox(ucn string, id int32, u *User, unreadCount int32) *Mailbox {
 		user:           u,
 		unreadCount:    uint32(unreadCount),
 		messageHeaders: make(map[uint32]*esi.GetCharactersCharacterIdMail200Ok),
+		state:          newMailboxState(),
 	}
 }
 
@@ -75,11 +78,10 @@ func (mbox *Mailbox) loadMailbox() {
 	defer mbox.loaded.Done()
 	// Get all mail headers
 	lastMailID := int32(2147483647)
-	maxMailID := int32(0)
-	mbox.validity = uint32(time.Now().Unix())
 
 	var unseen, count, pages uint32
 	messageHeaders := make(map[int32]*esi.GetCharactersCharacterIdMail200Ok)
+	messages := []*esi.GetCharactersCharacterIdMail200Ok{}
 
 	var opts *esi.GetCharactersCharacterIdMailOpts
 	if strings.ToUpper(mbox.Name()) != "INBOX" {
@@ -121,20 +123,12 @@ func (mbox *Mailbox) loadMailbox() {
 			// Cache the message headers
 			if _, ok := messageHeaders[m.MailId]; !ok {
 				messageHeaders[m.MailId] = &mails[i]
-				mbox.messagesSeqNum = append(mbox.messagesSeqNum, &mails[i])
+				messages = append(messages, &mails[i])
 				if !m.IsRead {
 					unseen++
 				}
 				count++
 			}
-
-			// Find the first and last mail ID
-			if m.MailId < lastMailID {
-				lastMailID = m.MailId
-			}
-			if m.MailId > maxMailID {
-				maxMailID = m.MailId
-			}
 		}
 		// Break out at 30 pages (evemail limits to 1500)
 		if pages >= 30 {
@@ -142,18 +136,62 @@ func (mbox *Mailbox) loadMailbox() {
 		}
 	}
 
-	mbox.nextuid = uint32(maxMailID)
-	mbox.firstuid = uint32(lastMailID)
+	// Mail new to the mailbox is given UIDs oldest first
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].MailId < messages[j].MailId
+	})
+	mailIDs := []int32{}
+	for _, m := range messages {
+		mailIDs = append(mailIDs, m.MailId)
+	}
+
+	// Restore UIDs and client flags from previous sessions
+	state, err := mbox.user.backend.state.load(mbox.user.characterID, mbox.id, mailIDs)
+	if err != nil {
+		log.Println(err)
+		state = newMailboxState()
+		state.uidValidity = uint32(time.Now().Unix())
+		for _, m := range messages {
+			state.uids[m.MailId] = state.uidNext
+			state.uidNext++
+		}
+	}
+
+	// Sequence numbers follow UID order
+	sort.Slice(messages, func(i, j int) bool {
+		return state.uids[messages[i].MailId] < state.uids[messages[j].MailId]
+	})
+
+	mbox.lock.Lock()
+	mbox.messagesSeqNum = messages
+	mbox.state = state
+	mbox.validity = state.uidValidity
+	mbox.nextuid = state.uidNext
 	mbox.unreadCount = unseen + 1
 	mbox.count = count
+	mbox.lock.Unlock()
+}
+
+// messages returns a snapshot of the messages in sequence order
+func (mbox *Mailbox) messages() []*esi.GetCharactersCharacterIdMail200Ok {
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
+	return append([]*esi.GetCharactersCharacterIdMail200Ok{}, mbox.messagesSeqNum...)
 }
 
 func (mbox *Mailbox) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
 	mbox.WaitForLoad()
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
 	status := imap.NewMailboxStatus(mbox.name, items)
-	status.Flags = []string{}
-	status.PermanentFlags = []string{"\\Seen"}
-	status.UnseenSeqNum = mbox.firstuid
+	status.Flags = []string{imap.SeenFlag, imap.AnsweredFlag, imap.FlaggedFlag, imap.DeletedFlag, imap.DraftFlag}
+	status.PermanentFlags = append(status.Flags, "\\*")
+	for i, m := range mbox.messagesSeqNum {
+		if !m.IsRead {
+			status.UnseenSeqNum = uint32(i + 1)
+			break
+		}
+	}
 	for _, name := range items {
 		switch name {
 		case imap.StatusMessages:
@@ -177,10 +215,10 @@ func (mbox *Mailbox) ListMessages(uid bool, seqSet *imap.SeqSet, items []imap.Fe
 	mbox.WaitForLoad()
 	wg := sync.WaitGroup{}
 	sem := make(chan bool, 50)
-	for i, m := range mbox.messagesSeqNum {
+	for i, m := range mbox.messages() {
 		seqNum := i + 1
 		if (!uid && seqSet.Contains(uint32(seqNum))) || // SeqNum Match
-			(uid && seqSet.Contains(uint32(m.MailId))) { // UID Match
+			(uid && seqSet.Contains(mbox.uid(m.MailId))) { // UID Match
 			sem <- true
 			wg.Add(1)
 			go func(m *esi.GetCharactersCharacterIdMail200Ok, seqNum int) {
@@ -212,16 +250,15 @@ func (mbox *Mailbox) fetchMessage(m *esi.GetCharactersCharacterIdMail200Ok, i *i
 		case imap.FetchEnvelope:
 			i.Envelope, _ = backendutil.FetchEnvelope(e.Header)
 		case imap.FetchFlags:
-			i.Flags = []string{}
-			if m.IsRead {
-				i.Flags = append(i.Flags, "\\Seen")
-			}
+			i.Flags = mbox.flags(m.MailId, m.IsRead)
 		case imap.FetchInternalDate:
 			i.InternalDate = m.Timestamp
 		case imap.FetchRFC822Size:
 			i.Size = uint32(n) // We're lying
 		case imap.FetchUid:
-			i.Uid = uint32(m.MailId)
+			i.Uid = mbox.uid(m.MailId)
+		case fetchModSeq:
+			i.Items[item] = modSeqField(mbox.modSeq(m.MailId))
 		case imap.FetchRFC822Header:
 			section, err := imap.ParseBodySectionName(item)
 			if err != nil {
@@ -261,16 +298,15 @@ func (mbox *Mailbox) fetchWholeMessage(i *imap.Message, uuid uint32, mailID int3
 		case imap.FetchBody, imap.FetchBodyStructure:
 			i.BodyStructure, _ = backendutil.FetchBodyStructure(e, item == imap.FetchBodyStructure)
 		case imap.FetchFlags:
-			i.Flags = []string{}
-			if m.Read {
-				i.Flags = append(i.Flags, "\\Seen")
-			}
+			i.Flags = mbox.flags(mailID, m.Read)
 		case imap.FetchInternalDate:
 			i.InternalDate = m.Timestamp
 		case imap.FetchRFC822Size:
 			i.Size = uint32(n)
 		case imap.FetchUid:
-			i.Uid = uint32(mailID)
+			i.Uid = mbox.uid(mailID)
+		case fetchModSeq:
+			i.Items[item] = modSeqField(mbox.modSeq(mailID))
 		default:
 			section, err := imap.ParseBodySectionName(item)
 			if err != nil {
@@ -289,30 +325,24 @@ func (mbox *Mailbox) SearchMessages(uid bool, criteria *imap.SearchCriteria) ([]
 	mbox.WaitForLoad()
 	var ids []uint32
 
-	if !uid {
-		for i, msg := range mbox.messagesSeqNum {
-			seqNum := i + 1
-			ok, err := mbox.MatchMessage(msg, uid, uint32(seqNum), uint32(msg.MailId), criteria)
-			if err != nil || !ok {
-				continue
-			}
-			ids = append(ids, uint32(seqNum))
+	for i, msg := range mbox.messages() {
+		seqNum := uint32(i + 1)
+		msgUID := mbox.uid(msg.MailId)
+		ok, err := mbox.MatchMessage(msg, uid, seqNum, msgUID, criteria)
+		if err != nil || !ok {
+			continue
 		}
-	} else {
-		for i, msg := range mbox.messagesSeqNum {
-			seqNum := i + 1
-			ok, err := mbox.MatchMessage(msg, uid, uint32(seqNum), uint32(msg.MailId), criteria)
-			if err != nil || !ok {
-				continue
-			}
-			ids = append(ids, uint32(msg.MailId))
+		if uid {
+			ids = append(ids, msgUID)
+		} else {
+			ids = append(ids, seqNum)
 		}
 	}
 	return ids, nil
 }
 
-func (mbox *Mailbox) MatchMessage(m *esi.GetCharactersCharacterIdMail200Ok, uid bool, id uint32, mailID uint32, c *imap.SearchCriteria) (bool, error) {
-	if !MatchSeqNumAndUid(id, mailID, c) {
+func (mbox *Mailbox) MatchMessage(m *esi.GetCharactersCharacterIdMail200Ok, uid bool, id uint32, msgUID uint32, c *imap.SearchCriteria) (bool, error) {
+	if !MatchSeqNumAndUid(id, msgUID, c) {
 		return false, nil
 	}
 
@@ -332,7 +362,7 @@ func (mbox *Mailbox) makeFakeHeader(m *esi.GetCharactersCharacterIdMail200Ok) (i
 	seen[m.From] = true
 	i := 0
 	for _, r := range m.Recipients {
-		if !seen[r.RecipientId] && r.RecipientType != "mailing_list" {
+		if !seen[r.RecipientId] {
 			i++
 			ids = append(ids, r.RecipientId)
 			idMap[r.RecipientId] = i
@@ -341,7 +371,7 @@ func (mbox *Mailbox) makeFakeHeader(m *esi.GetCharactersCharacterIdMail200Ok) (i
 	}
 
 	// Lookup IDs to names
-	names, _, err := mbox.user.backend.lookupAddresses(ids)
+	names, types, err := mbox.user.backend.lookupAddresses(ids)
 	if err != nil {
 		log.Println(err)
 		return 0, nil, err
@@ -350,11 +380,11 @@ func (mbox *Mailbox) makeFakeHeader(m *esi.GetCharactersCharacterIdMail200Ok) (i
 	// Build the To list
 	to := []string{}
 	for _, r := range m.Recipients {
-		to = append(to, fmt.Sprintf("%s <%d>", names[idMap[r.RecipientId]], r.RecipientId))
+		to = append(to, mbox.address(r.RecipientId, idMap, names, types))
 	}
 
 	// Build our fake mail
-	s := fmt.Sprintf(`From: %s <%d@evedata.org>
+	s := fmt.Sprintf(`From: %s
 To: %s
 Subject: %s
 Date: %s
@@ -362,7 +392,7 @@ Message-ID: <%d@evedata.org/>
 Content-Type: text/plain; charset=UTF-8
 
 Nothing here i'm afraid
-`, names[idMap[m.From]], m.From, strings.Join(to, "; "), m.Subject, m.Timestamp.Format(time.RFC822Z), m.MailId)
+`, mbox.address(m.From, idMap, names, types), strings.Join(to, ", "), m.Subject, m.Timestamp.Format(time.RFC822Z), m.MailId)
 
 	e, err := message.Read(bytes.NewReader([]byte(s)))
 	if err != nil {
@@ -389,7 +419,7 @@ func (mbox *Mailbox) makeFakeBody(m *esi.GetCharactersCharacterIdMailMailIdOk, i
 	}
 
 	// Lookup IDs to names
-	names, _, err := mbox.user.backend.lookupAddresses(ids)
+	names, types, err := mbox.user.backend.lookupAddresses(ids)
 	if err != nil {
 		log.Println(err)
 		return 0, nil, err
@@ -414,11 +444,11 @@ func (mbox *Mailbox) makeFakeBody(m *esi.GetCharactersCharacterIdMailMailIdOk, i
 	// Build the To list
 	to := []string{}
 	for _, r := range m.Recipients {
-		to = append(to, fmt.Sprintf("%s <%d@evedata.org>", names[idMap[r.RecipientId]], r.RecipientId))
+		to = append(to, mbox.address(r.RecipientId, idMap, names, types))
 	}
 
 	// Build our fake mail
-	s := fmt.Sprintf(`From: %s <%d@evedata.org>
+	s := fmt.Sprintf(`From: %s
 To: %s
 Subject: %s
 Date: %s
@@ -426,12 +456,18 @@ Message-ID: <%d@evedata.org/>
 Content-Type: text/plain; charset=UTF-8
 
 %s
-`, names[idMap[m.From]], m.From, strings.Join(to, "; "), m.Subject, m.Timestamp.Format(time.RFC822Z), id, plain)
+`, mbox.address(m.From, idMap, names, types), strings.Join(to, ", "), m.Subject, m.Timestamp.Format(time.RFC822Z), id, plain)
 
 	e, err := message.Read(bytes.NewReader([]byte(s)))
 	return len(s), e, err
 }
 
+// address formats an entity as a friendly address for From and To headers
+func (mbox *Mailbox) address(id int32, idMap map[int32]int, names, types []string) string {
+	i := idMap[id]
+	return mbox.user.backend.addresses.Header(id, names[i], types[i])
+}
+
 func (mbox *Mailbox) SetSubscribed(subscribed bool) error {
 	return nil
 }
@@ -444,52 +480,181 @@ func (mbox *Mailbox) CreateMessage(flags []string, date time.Time, body imap.Lit
 	return errors.New("not supported")
 }
 
-func (mbox *Mailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, op imap.FlagsOp, flags []string) error {
-	if flags[0] != "\\Seen" {
-		return errors.New("Unsupported flag")
+// flags returns the IMAP flags for a message. \Seen comes from ESI, the rest from the state store.
+func (mbox *Mailbox) flags(mailID int32, read bool) []string {
+	flags := []string{}
+	if read {
+		flags = append(flags, imap.SeenFlag)
+	}
+	return append(flags, mbox.storedFlags(mailID)...)
+}
+
+// storedFlags returns the client flags kept for a message
+func (mbox *Mailbox) storedFlags(mailID int32) []string {
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
+	return mbox.state.flags[mailID]
+}
+
+// uid returns the UID of a message in this mailbox
+func (mbox *Mailbox) uid(mailID int32) uint32 {
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
+	return mbox.state.uids[mailID]
+}
+
+// modSeq returns the MODSEQ of the last change to a message
+func (mbox *Mailbox) modSeq(mailID int32) uint64 {
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
+	if modSeq := mbox.state.modSeqs[mailID]; modSeq > 0 {
+		return modSeq
+	}
+	return 1
+}
+
+// HighestModSeq returns the MODSEQ of the latest change to the mailbox for CONDSTORE
+func (mbox *Mailbox) HighestModSeq() uint64 {
+	mbox.WaitForLoad()
+	mbox.lock.RLock()
+	defer mbox.lock.RUnlock()
+	if mbox.state.modSeq > 0 {
+		return mbox.state.modSeq
+	}
+	return 1
+}
+
+// ChangedSince narrows a sequence or UID set to the messages changed after modSeq
+func (mbox *Mailbox) ChangedSince(uid bool, seqSet *imap.SeqSet, modSeq uint64) *imap.SeqSet {
+	mbox.WaitForLoad()
+	changed := &imap.SeqSet{}
+	for i, m := range mbox.messages() {
+		id := uint32(i + 1)
+		if uid {
+			id = mbox.uid(m.MailId)
+		}
+		if seqSet.Contains(id) && mbox.modSeq(m.MailId) > modSeq {
+			changed.AddNum(id)
+		}
 	}
+	return changed
+}
+
+// UpdateMessagesFlags sets \Seen through ESI and keeps other flags in the state store.
+// \Deleted is kept until the mailbox is expunged.
+func (mbox *Mailbox) UpdateMessagesFlags(uid bool, seqSet *imap.SeqSet, op imap.FlagsOp, flags []string) error {
+	seen := containsFlag(flags, imap.SeenFlag)
+	stored := storedFlags(flags)
 
 	mbox.WaitForLoad()
 	wg := sync.WaitGroup{}
 	sem := make(chan bool, 10)
-	auth := context.WithValue(context.Background(), goesi.ContextOAuth2, mbox.user.token)
-	set := false
-	if op[0] == '+' {
-		set = true
-	}
+	auth := mbox.user.auth()
+	state := mbox.user.backend.state
+	characterID := mbox.user.characterID
 
-	for i, m := range mbox.messagesSeqNum {
-		seqNum := i + 1
-		if (!uid && seqSet.Contains(uint32(seqNum))) || // SeqNum Match
-			(uid && seqSet.Contains(uint32(m.MailId))) { // UID Match
-			sem <- true
-			wg.Add(1)
-			m.IsRead = set // Hack this... hopefully it sticks
-			go func(i int32, r bool) {
-				defer func() { wg.Done(); <-sem }()
-				_, err := mbox.user.backend.esi.ESI.MailApi.PutCharactersCharacterIdMailMailId(
-					auth,
-					mbox.user.characterID,
-					esi.PutCharactersCharacterIdMailMailIdContents{Read: r},
-					i,
-					nil,
-				)
-				if err != nil {
-					log.Println(err)
-				}
-			}(mbox.messagesSeqNum[i].MailId, set)
+	for _, m := range mbox.selected(uid, seqSet) {
+		read := applyRead(m.IsRead, op, seen)
+
+		current := mbox.storedFlags(m.MailId)
+		updated := applyFlags(current, op, stored)
+		if !sameFlags(current, updated) {
+			modSeq, err := state.setFlags(characterID, mbox.id, m.MailId, updated)
+			if err != nil {
+				log.Println(err)
+				return err
+			}
+			mbox.lock.Lock()
+			if len(updated) == 0 {
+				delete(mbox.state.flags, m.MailId)
+			} else {
+				mbox.state.flags[m.MailId] = updated
+			}
+			mbox.state.modSeqs[m.MailId] = modSeq
+			mbox.state.modSeq = modSeq
+			mbox.lock.Unlock()
+		}
+
+		if read == m.IsRead {
+			continue
 		}
+
+		if modSeq, err := state.bumpModSeq(characterID, mbox.id, m.MailId); err != nil {
+			log.Println(err)
+		} else {
+			mbox.lock.Lock()
+			mbox.state.modSeqs[m.MailId] = modSeq
+			mbox.state.modSeq = modSeq
+			mbox.lock.Unlock()
+		}
+
+		sem <- true
+		wg.Add(1)
+		m.IsRead = read // Hack this... hopefully it sticks
+		go func(m *esi.GetCharactersCharacterIdMail200Ok, r bool) {
+			defer func() { wg.Done(); <-sem }()
+			_, err := mbox.user.backend.esi.ESI.MailApi.PutCharactersCharacterIdMailMailId(
+				auth,
+				characterID,
+				esi.PutCharactersCharacterIdMailMailIdContents{Read: r, Labels: m.Labels},
+				m.MailId,
+				nil,
+			)
+			if err != nil {
+				log.Println(err)
+			}
+		}(m, read)
 	}
 
 	wg.Wait()
 	return nil
-
 }
 
+// CopyMessages adds the destination label to the messages
 func (mbox *Mailbox) CopyMessages(uid bool, seqset *imap.SeqSet, destName string) error {
-	return nil
+	dest, err := mbox.destination(destName)
+	if err != nil {
+		return err
+	}
+	return mbox.relabel(uid, seqset, dest, false)
 }
 
+// MoveMessages swaps this mailbox's label for the destination label.
+// INBOX holds all mail, so moving out of it only adds the label.
+func (mbox *Mailbox) MoveMessages(uid bool, seqset *imap.SeqSet, destName string) error {
+	dest, err := mbox.destination(destName)
+	if err != nil {
+		return err
+	}
+	return mbox.relabel(uid, seqset, dest, true)
+}
+
+// Expunge removes this mailbox's label from \Deleted messages. Messages are deleted through ESI
+// when expunged from INBOX, or when they have no labels left.
 func (mbox *Mailbox) Expunge() error {
+	mbox.WaitForLoad()
+
+	expunged := []int32{}
+	for _, m := range mbox.messages() {
+		if !containsFlag(mbox.storedFlags(m.MailId), imap.DeletedFlag) {
+			continue
+		}
+
+		labels := withoutLabel(m.Labels, mbox.id)
+		var err error
+		if isInbox(mbox.name) || len(labels) == 0 {
+			err = mbox.deleteMail(m)
+		} else {
+			err = mbox.setLabels(m, labels)
+		}
+		if err != nil {
+			return err
+		}
+		expunged = append(expunged, m.MailId)
+	}
+
+	for _, mailID := range expunged {
+		mbox.removeMessage(mailID)
+	}
 	return nil
 }
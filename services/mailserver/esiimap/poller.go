@@ -0,0 +1,276 @@
+package esiimap
+
+import (
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	imap "github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+)
+
+// Poll limits. ESI caches mail for 30 seconds.
+const (
+	minPollInterval   = time.Second * 30
+	errorPollInterval = time.Minute * 5
+)
+
+// mailPoller polls a character's mail once for all of their open connections
+type mailPoller struct {
+	characterID int32
+	backend     *Backend
+	lastMailID  int32
+	polled      bool // lastMailID is set, even if the mailbox was empty
+	users       map[*User]bool
+	lock        sync.Mutex
+	stop        chan bool
+}
+
+// Updates sends new mail to connections that are idle or selecting a mailbox
+func (s *Backend) Updates() <-chan backend.Update {
+	return s.updates
+}
+
+// subscribe starts polling for a user, sharing the poller with the character's other connections
+func (s *Backend) subscribe(u *User) {
+	s.pollerLock.Lock()
+	defer s.pollerLock.Unlock()
+
+	p, ok := s.pollers[u.characterID]
+	if !ok {
+		p = &mailPoller{
+			characterID: u.characterID,
+			backend:     s,
+			users:       make(map[*User]bool),
+			stop:        make(chan bool),
+		}
+		s.pollers[u.characterID] = p
+		go p.run()
+	}
+
+	p.lock.Lock()
+	p.users[u] = true
+	p.lock.Unlock()
+}
+
+// unsubscribe stops polling for a user, stopping the poller with the last connection
+func (s *Backend) unsubscribe(u *User) {
+	s.pollerLock.Lock()
+	defer s.pollerLock.Unlock()
+
+	p, ok := s.pollers[u.characterID]
+	if !ok {
+		return
+	}
+
+	p.lock.Lock()
+	delete(p.users, u)
+	empty := len(p.users) == 0
+	p.lock.Unlock()
+
+	if empty {
+		close(p.stop)
+		delete(s.pollers, u.characterID)
+	}
+}
+
+func (p *mailPoller) run() {
+	for {
+		wait, err := p.poll()
+		if err != nil {
+			log.Println(err)
+			wait = errorPollInterval
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-time.After(wait):
+		}
+	}
+}
+
+// anyUser returns a connection to borrow a token from
+func (p *mailPoller) anyUser() *User {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for u := range p.users {
+		return u
+	}
+	return nil
+}
+
+// poll checks for mail newer than the last poll and returns how long until the cache expires
+func (p *mailPoller) poll() (time.Duration, error) {
+	u := p.anyUser()
+	if u == nil {
+		return minPollInterval, nil
+	}
+
+	mails, res, err := p.backend.esi.ESI.MailApi.GetCharactersCharacterIdMail(u.auth(), p.characterID, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	p.deliver(p.update(mails))
+
+	return cacheWait(res), nil
+}
+
+// update records the newest mail seen and returns the mail to deliver.
+// The first poll only finds where we are.
+func (p *mailPoller) update(mails []esi.GetCharactersCharacterIdMail200Ok) []esi.GetCharactersCharacterIdMail200Ok {
+	newMail := newMailSince(mails, p.lastMailID)
+	if len(newMail) > 0 {
+		p.lastMailID = newMail[len(newMail)-1].MailId
+	}
+
+	if !p.polled {
+		p.polled = true
+		return nil
+	}
+	return newMail
+}
+
+// deliver sends new mail to every connection
+func (p *mailPoller) deliver(mails []esi.GetCharactersCharacterIdMail200Ok) {
+	if len(mails) == 0 {
+		return
+	}
+
+	p.lock.Lock()
+	users := []*User{}
+	for u := range p.users {
+		users = append(users, u)
+	}
+	p.lock.Unlock()
+
+	for _, u := range users {
+		u.newMail(mails)
+	}
+}
+
+// newMailSince returns mail after lastMailID, oldest first
+func newMailSince(mails []esi.GetCharactersCharacterIdMail200Ok, lastMailID int32) []esi.GetCharactersCharacterIdMail200Ok {
+	newMail := []esi.GetCharactersCharacterIdMail200Ok{}
+	for _, m := range mails {
+		if m.MailId > lastMailID {
+			newMail = append(newMail, m)
+		}
+	}
+	sort.Slice(newMail, func(i, j int) bool {
+		return newMail[i].MailId < newMail[j].MailId
+	})
+	return newMail
+}
+
+// cacheWait returns the time until the response cache expires
+func cacheWait(res *http.Response) time.Duration {
+	if res == nil {
+		return minPollInterval
+	}
+	expires, err := http.ParseTime(res.Header.Get("Expires"))
+	if err != nil {
+		return minPollInterval
+	}
+	wait := time.Until(expires)
+	if wait < minPollInterval {
+		return minPollInterval
+	}
+	return wait
+}
+
+// newMail adds mail to the user's loaded mailboxes and notifies their connections
+func (u *User) newMail(mails []esi.GetCharactersCharacterIdMail200Ok) {
+	for _, box := range u.mailboxList() {
+		if !box.loadStarted {
+			continue
+		}
+
+		added := []*esi.GetCharactersCharacterIdMail200Ok{}
+		for i := range mails {
+			if isInbox(box.name) || containsLabel(mails[i].Labels, box.id) {
+				m := mails[i]
+				added = append(added, &m)
+			}
+		}
+
+		if box.addMessages(added) {
+			status, err := box.Status([]imap.StatusItem{imap.StatusMessages, imap.StatusRecent, imap.StatusUnseen, imap.StatusUidNext})
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+
+			update := &backend.MailboxUpdate{
+				Update:        backend.NewUpdate(u.username, box.name),
+				MailboxStatus: status,
+			}
+
+			// Don't hold up the poller if the server is busy
+			select {
+			case u.backend.updates <- update:
+			default:
+			}
+		}
+	}
+}
+
+// addMessages appends new mail to a loaded mailbox, returning true if any were new.
+// New mail gets the next UIDs in the mailbox.
+func (mbox *Mailbox) addMessages(mails []*esi.GetCharactersCharacterIdMail200Ok) bool {
+	mbox.WaitForLoad()
+	mbox.lock.Lock()
+	defer mbox.lock.Unlock()
+
+	newMail := []*esi.GetCharactersCharacterIdMail200Ok{}
+	mailIDs := []int32{}
+	for _, m := range mails {
+		if _, ok := mbox.state.uids[m.MailId]; ok {
+			continue
+		}
+		newMail = append(newMail, m)
+		mailIDs = append(mailIDs, m.MailId)
+	}
+	if len(newMail) == 0 {
+		return false
+	}
+
+	uids, uidNext, modSeq, err := mbox.user.backend.state.assignUIDs(mbox.user.characterID, mbox.id, mailIDs)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+
+	// Another connection may have assigned some already
+	sort.Slice(newMail, func(i, j int) bool {
+		return uids[newMail[i].MailId] < uids[newMail[j].MailId]
+	})
+
+	for _, m := range newMail {
+		mbox.user.backend.cacheLookup <- m.From
+		mbox.messagesSeqNum = append(mbox.messagesSeqNum, m)
+		mbox.state.uids[m.MailId] = uids[m.MailId]
+		mbox.state.modSeqs[m.MailId] = modSeq
+		mbox.count++
+		if !m.IsRead {
+			mbox.unreadCount++
+		}
+	}
+	mbox.state.uidNext = uidNext
+	mbox.state.modSeq = modSeq
+	mbox.nextuid = uidNext
+	return true
+}
+
+func containsLabel(labels []int32, id int32) bool {
+	for _, l := range labels {
+		if l == id {
+			return true
+		}
+	}
+	return false
+}
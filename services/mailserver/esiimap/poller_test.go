@@ -0,0 +1,57 @@
+package esiimap
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewMailSince(t *testing.T) {
+	mails := []esi.GetCharactersCharacterIdMail200Ok{
+		{MailId: 30}, {MailId: 25}, {MailId: 20}, {MailId: 10},
+	}
+
+	newMail := newMailSince(mails, 20)
+	assert.Len(t, newMail, 2)
+	assert.Equal(t, int32(25), newMail[0].MailId)
+	assert.Equal(t, int32(30), newMail[1].MailId)
+
+	assert.Len(t, newMailSince(mails, 30), 0)
+}
+
+func TestPollerUpdate(t *testing.T) {
+	p := &mailPoller{}
+
+	// An empty mailbox is still a baseline
+	assert.Len(t, p.update(nil), 0)
+	assert.True(t, p.polled)
+
+	newMail := p.update([]esi.GetCharactersCharacterIdMail200Ok{{MailId: 10}})
+	assert.Len(t, newMail, 1)
+	assert.Equal(t, int32(10), p.lastMailID)
+
+	assert.Len(t, p.update([]esi.GetCharactersCharacterIdMail200Ok{{MailId: 10}}), 0)
+
+	// Existing mail is not delivered on the first poll
+	p = &mailPoller{}
+	assert.Len(t, p.update([]esi.GetCharactersCharacterIdMail200Ok{{MailId: 20}, {MailId: 10}}), 0)
+	assert.Equal(t, int32(20), p.lastMailID)
+	assert.Len(t, p.update([]esi.GetCharactersCharacterIdMail200Ok{{MailId: 30}, {MailId: 20}}), 1)
+}
+
+func TestCacheWait(t *testing.T) {
+	assert.Equal(t, minPollInterval, cacheWait(nil))
+
+	res := &http.Response{Header: http.Header{}}
+	assert.Equal(t, minPollInterval, cacheWait(res))
+
+	res.Header.Set("Expires", time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))
+	assert.Equal(t, minPollInterval, cacheWait(res))
+
+	res.Header.Set("Expires", time.Now().Add(time.Minute*5).UTC().Format(http.TimeFormat))
+	wait := cacheWait(res)
+	assert.True(t, wait > time.Minute*4 && wait <= time.Minute*5)
+}
@@ -0,0 +1,304 @@
+package esiimap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	imap "github.com/emersion/go-imap"
+	"github.com/garyburd/redigo/redis"
+)
+
+// stateStore keeps per character mailbox state in redis so UIDs and client
+// flags survive between sessions. \Seen lives in ESI and is never stored.
+// UIDs are assigned per mailbox from UIDNEXT, so mail copied or moved into a
+// mailbox is always above the UIDNEXT clients last saw.
+type stateStore struct {
+	redis *redis.Pool
+}
+
+// assignUIDsScript gives UIDs to mail new to a mailbox, in the order given,
+// and records the change in MODSEQ.
+// KEYS: state, UIDs, MODSEQs
+// ARGV: UIDVALIDITY for a new mailbox, mail IDs...
+// Returns UIDNEXT, HIGHESTMODSEQ, and the UID of each mail ID.
+var assignUIDsScript = redis.NewScript(3, `
+local state, uids, modseqs = KEYS[1], KEYS[2], KEYS[3]
+local modseq = tonumber(redis.call("HGET", state, "modseq") or 0)
+local changed = false
+
+-- UIDs were once ESI mail IDs, start again with a new UIDVALIDITY
+if redis.call("HSETNX", state, "uidmap", 1) == 1 then
+	redis.call("HSET", state, "uidvalidity", ARGV[1])
+	redis.call("HSET", state, "uidnext", 1)
+	redis.call("DEL", uids)
+	modseq = redis.call("HINCRBY", state, "modseq", 1)
+	changed = true
+end
+
+local uidnext = tonumber(redis.call("HGET", state, "uidnext"))
+local result = {0, 0}
+for i = 2, #ARGV do
+	local uid = redis.call("HGET", uids, ARGV[i])
+	if not uid then
+		if not changed then
+			modseq = redis.call("HINCRBY", state, "modseq", 1)
+			changed = true
+		end
+		uid = uidnext
+		uidnext = uidnext + 1
+		redis.call("HSET", uids, ARGV[i], uid)
+		redis.call("HSET", modseqs, ARGV[i], modseq)
+	end
+	result[#result + 1] = tonumber(uid)
+end
+redis.call("HSET", state, "uidnext", uidnext)
+
+result[1] = uidnext
+result[2] = modseq
+return result
+`)
+
+// mailboxState is the stored state of one mailbox
+type mailboxState struct {
+	uidValidity uint32
+	uidNext     uint32
+	modSeq      uint64
+	uids        map[int32]uint32
+	flags       map[int32][]string
+	modSeqs     map[int32]uint64
+}
+
+func newMailboxState() *mailboxState {
+	return &mailboxState{
+		uidNext: 1,
+		uids:    make(map[int32]uint32),
+		flags:   make(map[int32][]string),
+		modSeqs: make(map[int32]uint64),
+	}
+}
+
+func stateKey(characterID, labelID int32) string {
+	return fmt.Sprintf("evedata-imap:%d:%d", characterID, labelID)
+}
+
+func flagsKey(characterID, labelID int32) string {
+	return fmt.Sprintf("evedata-imap-flags:%d:%d", characterID, labelID)
+}
+
+func modSeqKey(characterID, labelID int32) string {
+	return fmt.Sprintf("evedata-imap-modseq:%d:%d", characterID, labelID)
+}
+
+func uidsKey(characterID, labelID int32) string {
+	return fmt.Sprintf("evedata-imap-uids:%d:%d", characterID, labelID)
+}
+
+// load returns the mailbox state with UIDs for the mail in it, oldest first
+func (s *stateStore) load(characterID, labelID int32, mailIDs []int32) (*mailboxState, error) {
+	uids, _, _, err := s.assignUIDs(characterID, labelID, mailIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	meta, err := redis.StringMap(conn.Do("HGETALL", stateKey(characterID, labelID)))
+	if err != nil {
+		return nil, err
+	}
+	flags, err := redis.StringMap(conn.Do("HGETALL", flagsKey(characterID, labelID)))
+	if err != nil {
+		return nil, err
+	}
+	modSeqs, err := redis.StringMap(conn.Do("HGETALL", modSeqKey(characterID, labelID)))
+	if err != nil {
+		return nil, err
+	}
+
+	state := newMailboxState()
+	state.uids = uids
+
+	v, _ := strconv.ParseUint(meta["uidvalidity"], 10, 32)
+	state.uidValidity = uint32(v)
+	v, _ = strconv.ParseUint(meta["uidnext"], 10, 32)
+	state.uidNext = uint32(v)
+	state.modSeq, _ = strconv.ParseUint(meta["modseq"], 10, 64)
+
+	for id, f := range flags {
+		mailID, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			continue
+		}
+		state.flags[int32(mailID)] = strings.Fields(f)
+	}
+	for id, m := range modSeqs {
+		mailID, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			continue
+		}
+		state.modSeqs[int32(mailID)], _ = strconv.ParseUint(m, 10, 64)
+	}
+
+	return state, nil
+}
+
+// assignUIDs returns the UID of each mail, assigning the next UIDs to mail new to the mailbox
+// in the order given. UIDNEXT and HIGHESTMODSEQ are returned after the change.
+func (s *stateStore) assignUIDs(characterID, labelID int32, mailIDs []int32) (map[int32]uint32, uint32, uint64, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	args := []interface{}{
+		stateKey(characterID, labelID), uidsKey(characterID, labelID), modSeqKey(characterID, labelID),
+		time.Now().Unix(),
+	}
+	for _, id := range mailIDs {
+		args = append(args, id)
+	}
+
+	result, err := redis.Int64s(assignUIDsScript.Do(conn, args...))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(result) != len(mailIDs)+2 {
+		return nil, 0, 0, fmt.Errorf("expected %d UIDs, got %d", len(mailIDs), len(result)-2)
+	}
+
+	uids := make(map[int32]uint32)
+	for i, id := range mailIDs {
+		uids[id] = uint32(result[i+2])
+	}
+	return uids, uint32(result[0]), uint64(result[1]), nil
+}
+
+// setFlags stores the client flags for a message and returns its new MODSEQ
+func (s *stateStore) setFlags(characterID, labelID, mailID int32, flags []string) (uint64, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	modSeq, err := redis.Uint64(conn.Do("HINCRBY", stateKey(characterID, labelID), "modseq", 1))
+	if err != nil {
+		return 0, err
+	}
+
+	conn.Send("MULTI")
+	if len(flags) == 0 {
+		conn.Send("HDEL", flagsKey(characterID, labelID), mailID)
+	} else {
+		conn.Send("HSET", flagsKey(characterID, labelID), mailID, strings.Join(flags, " "))
+	}
+	conn.Send("HSET", modSeqKey(characterID, labelID), mailID, modSeq)
+	if _, err := conn.Do("EXEC"); err != nil {
+		return 0, err
+	}
+	return modSeq, nil
+}
+
+// bumpModSeq records a change to a message that has no stored flags, such as \Seen
+func (s *stateStore) bumpModSeq(characterID, labelID, mailID int32) (uint64, error) {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	modSeq, err := redis.Uint64(conn.Do("HINCRBY", stateKey(characterID, labelID), "modseq", 1))
+	if err != nil {
+		return 0, err
+	}
+	_, err = conn.Do("HSET", modSeqKey(characterID, labelID), mailID, modSeq)
+	return modSeq, err
+}
+
+// removeMessage forgets an expunged message. It gets a new UID if it returns.
+func (s *stateStore) removeMessage(characterID, labelID, mailID int32) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+
+	conn.Send("MULTI")
+	conn.Send("HDEL", flagsKey(characterID, labelID), mailID)
+	conn.Send("HDEL", modSeqKey(characterID, labelID), mailID)
+	conn.Send("HDEL", uidsKey(characterID, labelID), mailID)
+	conn.Send("HINCRBY", stateKey(characterID, labelID), "modseq", 1)
+	_, err := conn.Do("EXEC")
+	return err
+}
+
+// deleteMailbox forgets a deleted mailbox
+func (s *stateStore) deleteMailbox(characterID, labelID int32) error {
+	conn := s.redis.Get()
+	defer conn.Close()
+	_, err := conn.Do("DEL", stateKey(characterID, labelID), flagsKey(characterID, labelID),
+		modSeqKey(characterID, labelID), uidsKey(characterID, labelID))
+	return err
+}
+
+// storedFlags removes flags which are not kept in the store
+func storedFlags(flags []string) []string {
+	out := []string{}
+	for _, f := range flags {
+		if !strings.EqualFold(f, imap.SeenFlag) && !strings.EqualFold(f, imap.RecentFlag) && !containsFlag(out, f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// applyFlags changes flags the way STORE does
+func applyFlags(current []string, op imap.FlagsOp, flags []string) []string {
+	switch op {
+	case imap.SetFlags:
+		return append([]string{}, flags...)
+	case imap.AddFlags:
+		out := append([]string{}, current...)
+		for _, f := range flags {
+			if !containsFlag(out, f) {
+				out = append(out, f)
+			}
+		}
+		return out
+	case imap.RemoveFlags:
+		out := []string{}
+		for _, f := range current {
+			if !containsFlag(flags, f) {
+				out = append(out, f)
+			}
+		}
+		return out
+	}
+	return current
+}
+
+// applyRead changes the read state the way STORE does. Replacing the flags
+// without \Seen keeps mail read so clients flagging mail do not mark it unread.
+func applyRead(read bool, op imap.FlagsOp, seen bool) bool {
+	switch op {
+	case imap.AddFlags, imap.SetFlags:
+		return read || seen
+	case imap.RemoveFlags:
+		return read && !seen
+	}
+	return read
+}
+
+// containsFlag compares flags without case as IMAP does
+func containsFlag(flags []string, flag string) bool {
+	for _, f := range flags {
+		if strings.EqualFold(f, flag) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameFlags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, f := range a {
+		if !containsFlag(b, f) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package esiimap
+
+import (
+	"testing"
+
+	"github.com/antihax/evedata/internal/redigohelper"
+	imap "github.com/emersion/go-imap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssignUIDs(t *testing.T) {
+	s := &stateStore{redis: redigohelper.ConnectRedisTestPool()}
+
+	// A new mailbox starts at UID 1, oldest mail first
+	state, err := s.load(1, 256, []int32{300, 500})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int32]uint32{300: 1, 500: 2}, state.uids)
+	assert.Equal(t, uint32(3), state.uidNext)
+	assert.NotZero(t, state.uidValidity)
+	validity := state.uidValidity
+
+	// Older mail copied in later is still above UIDNEXT
+	uids, uidNext, modSeq, err := s.assignUIDs(1, 256, []int32{100, 300})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int32]uint32{100: 3, 300: 1}, uids)
+	assert.Equal(t, uint32(4), uidNext)
+	assert.Equal(t, modSeq, state.modSeq+1)
+
+	// Expunged mail is new again if it returns
+	assert.Nil(t, s.removeMessage(1, 256, 300))
+	state, err = s.load(1, 256, []int32{100, 300, 500})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int32]uint32{100: 3, 300: 4, 500: 2}, state.uids)
+	assert.Equal(t, validity, state.uidValidity)
+	assert.Equal(t, state.modSeq, state.modSeqs[300])
+}
+
+func TestFlags(t *testing.T) {
+	// \Seen is kept in ESI and \Recent is never stored
+	assert.Equal(t, []string{imap.FlaggedFlag, "$Work"},
+		storedFlags([]string{imap.SeenFlag, imap.FlaggedFlag, imap.RecentFlag, "$Work", "\\flagged"}))
+
+	current := []string{imap.FlaggedFlag}
+	assert.Equal(t, []string{imap.FlaggedFlag, imap.AnsweredFlag}, applyFlags(current, imap.AddFlags, []string{imap.AnsweredFlag, "\\FLAGGED"}))
+	assert.Equal(t, []string{}, applyFlags(current, imap.RemoveFlags, []string{"\\flagged"}))
+	assert.Equal(t, []string{imap.DeletedFlag}, applyFlags(current, imap.SetFlags, []string{imap.DeletedFlag}))
+
+	// Original is untouched
+	assert.Equal(t, []string{imap.FlaggedFlag}, current)
+
+	// Replacing flags only marks mail read when \Seen is given
+	assert.True(t, applyRead(true, imap.SetFlags, false))
+	assert.True(t, applyRead(false, imap.SetFlags, true))
+	assert.False(t, applyRead(false, imap.SetFlags, false))
+	assert.False(t, applyRead(true, imap.RemoveFlags, true))
+
+	assert.True(t, sameFlags([]string{imap.FlaggedFlag, "$Work"}, []string{"$work", "\\Flagged"}))
+	assert.False(t, sameFlags([]string{imap.FlaggedFlag}, []string{imap.AnsweredFlag}))
+}
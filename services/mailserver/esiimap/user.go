@@ -5,6 +5,7 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/antihax/goesi"
 	"github.com/emersion/go-imap/backend"
@@ -17,6 +18,7 @@ type User struct {
 	backend     *Backend
 	characterID int32
 	mailboxes   map[string]*Mailbox
+	mailboxLock sync.RWMutex
 }
 
 func NewUser(username string, token oauth2.TokenSource, backend *Backend, characterID int32) *User {
@@ -28,6 +30,7 @@ func NewUser(username string, token oauth2.TokenSource, backend *Backend, charac
 		mailboxes:   make(map[string]*Mailbox),
 	}
 	user.loadMailboxes()
+	backend.subscribe(user)
 	return user
 }
 
@@ -49,7 +52,9 @@ func (u *User) loadMailboxes() error {
 		if strings.ToUpper(box.Name) == "INBOX" {
 			box.Name = "INBOX"
 		}
+		u.mailboxLock.Lock()
 		u.mailboxes[box.Name] = NewMailbox(box.Name, box.LabelId, u, box.UnreadCount)
+		u.mailboxLock.Unlock()
 	}
 
 	go func() {
@@ -68,15 +73,34 @@ func (u *User) loadMailboxes() error {
 	return nil
 }
 
-func (u *User) ListMailboxes(subscribed bool) (mailboxes []backend.Mailbox, err error) {
+// mailboxList returns a snapshot of the user's mailboxes
+func (u *User) mailboxList() []*Mailbox {
+	u.mailboxLock.RLock()
+	defer u.mailboxLock.RUnlock()
+	mailboxes := []*Mailbox{}
 	for _, box := range u.mailboxes {
 		mailboxes = append(mailboxes, box)
 	}
+	return mailboxes
+}
+
+// mailbox finds a mailbox by name
+func (u *User) mailbox(name string) (*Mailbox, bool) {
+	u.mailboxLock.RLock()
+	defer u.mailboxLock.RUnlock()
+	mailbox, ok := u.mailboxes[name]
+	return mailbox, ok
+}
+
+func (u *User) ListMailboxes(subscribed bool) (mailboxes []backend.Mailbox, err error) {
+	for _, box := range u.mailboxList() {
+		mailboxes = append(mailboxes, box)
+	}
 	return mailboxes, nil
 }
 
 func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
-	mailbox, ok := u.mailboxes[name]
+	mailbox, ok := u.mailbox(name)
 	if !ok {
 		log.Printf("Cant find mailbox %s", name)
 		return mailbox, errors.New("No such mailbox")
@@ -86,18 +110,46 @@ func (u *User) GetMailbox(name string) (backend.Mailbox, error) {
 	return mailbox, nil
 }
 
+// CreateMailbox creates an EVE mail label
 func (u *User) CreateMailbox(name string) error {
-	return errors.New("You cannot create mailboxes")
+	_, err := u.createLabel(name)
+	return err
 }
 
+// DeleteMailbox deletes an EVE mail label, leaving the mail in other mailboxes
 func (u *User) DeleteMailbox(name string) error {
-	return errors.New("You cannot delete mailboxes")
+	return u.deleteLabel(name)
 }
 
+// RenameMailbox creates a label with the new name, moves all mail to it, and deletes
+// the old label as ESI cannot rename labels.
 func (u *User) RenameMailbox(existingName, newName string) error {
-	return errors.New("You cannot rename mailboxes")
+	existing, ok := u.mailbox(existingName)
+	if !ok {
+		return errors.New("No such mailbox")
+	}
+	if isInbox(existingName) || isDefaultLabel(existing.id) {
+		return errors.New("You cannot rename default mailboxes")
+	}
+
+	renamed, err := u.createLabel(newName)
+	if err != nil {
+		return err
+	}
+
+	existing.Load()
+	existing.WaitForLoad()
+	for _, m := range existing.messages() {
+		if err := existing.setLabels(m, withLabel(withoutLabel(m.Labels, existing.id), renamed.id)); err != nil {
+			return err
+		}
+	}
+
+	return u.deleteLabel(existingName)
 }
 
+// Logout stops polling for new mail for this connection
 func (u *User) Logout() error {
+	u.backend.unsubscribe(u)
 	return nil
 }
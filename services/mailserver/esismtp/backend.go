@@ -2,25 +2,25 @@ package esismtp
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"io"
 	"log"
 	"strconv"
 	"strings"
 
 	"github.com/antihax/goesi/esi"
-	"github.com/jaytaylor/html2text"
 	"github.com/veqryn/go-email/email"
 
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/evedata/internal/tokenstore"
+	"github.com/antihax/evedata/services/mailserver/mailaddress"
 	"github.com/antihax/goesi"
 	smtp "github.com/emersion/go-smtp"
 	"golang.org/x/oauth2"
 )
 
-func New(tokenAPI *tokenstore.TokenServerAPI, esi *goesi.APIClient, tokenAuth *goesi.SSOAuthenticator, q *redisqueue.RedisQueue) *Backend {
-	return &Backend{tokenAPI, esi, tokenAuth, q}
+func New(tokenAPI *tokenstore.TokenServerAPI, esi *goesi.APIClient, tokenAuth *goesi.SSOAuthenticator, q *redisqueue.RedisQueue, addresses *mailaddress.Resolver) *Backend {
+	return &Backend{tokenAPI, esi, tokenAuth, q, addresses}
 }
 
 type Backend struct {
@@ -28,6 +28,7 @@ type Backend struct {
 	esi        *goesi.APIClient
 	tokenAuth  *goesi.SSOAuthenticator
 	cacheQueue *redisqueue.RedisQueue
+	addresses  *mailaddress.Resolver
 }
 
 func (s *Backend) Login(username, password string) (smtp.User, error) {
@@ -51,7 +52,8 @@ func (s *Backend) Login(username, password string) (smtp.User, error) {
 		username:    username,
 		token:       ts,
 		backend:     s,
-		characterID: u.TokenCharacterID}, nil
+		characterID: u.TokenCharacterID,
+		maxCost:     u.MaxCost}, nil
 }
 
 // Require clients to authenticate using SMTP AUTH before sending emails
@@ -59,11 +61,15 @@ func (bkd *Backend) AnonymousLogin() (smtp.User, error) {
 	return nil, smtp.ErrAuthRequired
 }
 
+// maxPastedAddresses limits lookups for addresses pasted into a message
+const maxPastedAddresses = 50
+
 type User struct {
 	username    string
 	token       oauth2.TokenSource
 	backend     *Backend
 	characterID int32
+	maxCost     int64
 }
 
 func (u *User) Send(from string, to []string, r io.Reader) error {
@@ -71,71 +77,66 @@ func (u *User) Send(from string, to []string, r io.Reader) error {
 	var ids []int32
 
 	if len(to) > 50 {
-		return errors.New("Cannot send to more than 50 recepients at a time")
+		return &smtp.SMTPError{Code: 452, Message: "Cannot send to more than 50 recipients at a time"}
 	}
 
-	// Find all the recepients and validate they are id numbers
+	// Resolve all the recepients to EVE IDs
 	for _, email := range to {
-		s := strings.Split(email, "@")
-		id, err := strconv.ParseInt(s[0], 10, 32)
-		if err != nil {
+		id, err := u.backend.addresses.Resolve(email)
+		if err == mailaddress.ErrUnknownAddress {
+			return &smtp.SMTPError{Code: 550, Message: email + " does not match a character, corp.ticker, alliance.ticker, or list.name"}
+		} else if err != nil {
 			log.Println(err)
-			return err
+			return &smtp.SMTPError{Code: 451, Message: "Could not look up " + email + ", try again later"}
 		}
-		ids = append(ids, int32(id))
+		ids = append(ids, id)
 	}
 
 	// Lookup the IDs
 	_, types, err := u.backend.lookupAddresses(ids)
 	if err != nil {
 		log.Println(err)
-		return err
+		return &smtp.SMTPError{Code: 451, Message: "Could not look up recipients, try again later"}
 	}
 
 	mail, err := email.ParseMessage(r)
 	if err != nil {
 		log.Println(err)
-		return err
+		return &smtp.SMTPError{Code: 554, Message: "Could not parse message"}
 	}
 	subject := mail.Header.Get("Subject")
-
-	body := ""
-	preference := 0
-
-	// find usable plain text or decode html
-	for _, part := range mail.MessagesAll() {
-		mediaType, _, _ := part.Header.ContentType()
-		switch mediaType {
-		case "text/plain":
-			body = string(part.Body)
-			preference = 2
-			break
-		case "text/html":
-			if preference < 1 {
-				preference = 1
-				body = string(part.Body)
-				// Convert to text/plain
-				body = strings.Replace(body, "\n", "<br>", -1) // Hack for breaks..
-				body, err = html2text.FromString(body, html2text.Options{PrettyTables: true})
-				if err != nil {
-					log.Println(err)
-					return err
-				}
-			}
-		}
+	if subject == "" {
+		return &smtp.SMTPError{Code: 554, Message: "EVE mail requires a subject"}
 	}
-	if preference == 0 {
+
+	// Find usable text, dropping attachments EVE cannot carry
+	body, attachments, ok := messageBody(mail)
+	if !ok && len(attachments) == 0 {
 		log.Println("Could not find usable part")
-		return errors.New("Could not find a useable part")
+		return &smtp.SMTPError{Code: 554, Message: "Could not find a useable part"}
 	}
 
-	if subject == "" || body == "" {
-		log.Println("Did not understand mail format")
-		return errors.New("Did not understand mail format")
-	}
+	// Turn pasted addresses and entity links into showinfo links
+	entities := u.resolveEntities(entityAddresses(body))
 
+	footer := []string{}
+	if f := attachmentFooter(attachments); f != "" {
+		footer = append(footer, f)
+	}
 	if !strings.Contains(body, "https://www.evedata.org/") {
-		body += "\n\nSent via EVEMail Proxy - https://www.evedata.org/"
+		footer = append(footer, proxyFooter)
+	}
+	if len(footer) > 0 {
+		body += "<br><br>" + strings.Join(footer, "<br>")
+	}
+
+	body, err = eveMarkup(body, entities)
+	if err != nil {
+		log.Println(err)
+		return &smtp.SMTPError{Code: 554, Message: "Could not convert message to EVE mail"}
+	}
+	if len(body) > maxBodyLength {
+		return &smtp.SMTPError{Code: 552, Message: fmt.Sprintf("Message is too long for EVE mail (%d of %d characters)", len(body), maxBodyLength)}
 	}
 
 	// Build the recepient list
@@ -152,15 +153,48 @@ func (u *User) Send(from string, to []string, r io.Reader) error {
 	auth := context.WithValue(context.Background(), goesi.ContextOAuth2, u.token)
 	_, _, err = u.backend.esi.ESI.MailApi.PostCharactersCharacterIdMail(auth, u.characterID,
 		esi.PostCharactersCharacterIdMailMail{
-			ApprovedCost: 0,
+			ApprovedCost: u.maxCost,
 			Subject:      subject,
 			Body:         body,
 			Recipients:   recepients,
 		}, nil)
 	if err != nil {
 		log.Println(err)
+		return sendError(err, u.maxCost)
+	}
+	return nil
+}
+
+// resolveEntities resolves pasted addresses to entities, skipping any that are unknown
+func (u *User) resolveEntities(addresses []string) map[string]entity {
+	entities := make(map[string]entity)
+	if len(addresses) > maxPastedAddresses {
+		addresses = addresses[:maxPastedAddresses]
+	}
+
+	resolved := []string{}
+	ids := []int32{}
+	for _, address := range addresses {
+		id, err := u.backend.addresses.Resolve(address)
+		if err != nil {
+			continue
+		}
+		resolved = append(resolved, address)
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return entities
+	}
+
+	names, categories, err := u.backend.lookupAddresses(ids)
+	if err != nil {
+		log.Println(err)
+		return entities
+	}
+	for i, address := range resolved {
+		entities[address] = entity{id: ids[i], name: names[i], category: categories[i]}
 	}
-	return err
+	return entities
 }
 
 func (u *User) Logout() error {
@@ -195,7 +229,7 @@ func (s *Backend) lookupAddresses(ids []int32) ([]string, []string, error) {
 					lookup, _, err := s.esi.ESI.UniverseApi.PostUniverseNames(context.Background(), []int32{missingID}, nil)
 					if err != nil {
 						if strings.Contains(err.Error(), "404") {
-							names[missingIdx[i]] = "## Unknown Mailing List ##"
+							names[missingIdx[i]] = mailaddress.UnknownMailingList
 							types[missingIdx[i]] = "mailing_list"
 						} else {
 							return nil, nil, err
@@ -0,0 +1,283 @@
+package esismtp
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EVE showinfo type IDs for entities
+const (
+	characterTypeID   = 1377
+	corporationTypeID = 2
+	allianceTypeID    = 16159
+)
+
+// Default EVE mail font
+const (
+	fontSize    = 12
+	fontColor   = "#bfffffff"
+	headingSize = 18
+)
+
+var (
+	// Addresses we hand out through IMAP, such as "Name <bob.smith@evedata.org>"
+	addressRe = regexp.MustCompile(`<?(?:mailto:)?([A-Za-z0-9.'_-]+)@evedata\.org>?`)
+
+	// Entity pages pasted as links
+	entityPageRe = regexp.MustCompile(`^https?://(?:www\.)?evedata\.org/(character|corporation|alliance)\?id=([0-9]+)`)
+	zkillPageRe  = regexp.MustCompile(`^https?://(?:www\.)?zkillboard\.com/(character|corporation|alliance)/([0-9]+)`)
+	urlRe        = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+	colorRe     = regexp.MustCompile(`^#([0-9a-fA-F]{6})$`)
+	extraBreaks = regexp.MustCompile(`(<br>){3,}`)
+	whitespace  = regexp.MustCompile(`\s+`)
+)
+
+// entity is a resolved character, corporation, or alliance
+type entity struct {
+	id       int32
+	name     string
+	category string
+}
+
+// showInfo returns the EVE client link for an entity
+func showInfo(id int32, category string) string {
+	switch category {
+	case "character":
+		return fmt.Sprintf("showinfo:%d//%d", characterTypeID, id)
+	case "corporation":
+		return fmt.Sprintf("showinfo:%d//%d", corporationTypeID, id)
+	case "alliance":
+		return fmt.Sprintf("showinfo:%d//%d", allianceTypeID, id)
+	}
+	return ""
+}
+
+// entityAddresses finds the local parts of pasted addresses, and IDs in entity links,
+// which may become showinfo links
+func entityAddresses(body string) []string {
+	seen := make(map[string]bool)
+	addresses := []string{}
+	add := func(s string) {
+		s = strings.ToLower(s)
+		if !seen[s] {
+			seen[s] = true
+			addresses = append(addresses, s)
+		}
+	}
+
+	for _, m := range addressRe.FindAllStringSubmatch(body, -1) {
+		add(m[1])
+	}
+	for _, href := range urlRe.FindAllString(body, -1) {
+		if m := entityPage(href); m != nil {
+			add(m[2])
+		}
+	}
+	return addresses
+}
+
+func entityPage(href string) []string {
+	if m := entityPageRe.FindStringSubmatch(href); m != nil {
+		return m
+	}
+	return zkillPageRe.FindStringSubmatch(href)
+}
+
+// textToHTML converts a text/plain body so it can go through the same conversion as HTML
+func textToHTML(text string) string {
+	text = html.EscapeString(strings.Replace(text, "\r\n", "\n", -1))
+	return strings.Replace(text, "\n", "<br>", -1)
+}
+
+// markupConverter converts HTML into EVE mail markup, which only understands
+// font, links, and basic styling.
+type markupConverter struct {
+	entities map[string]entity
+	out      bytes.Buffer
+	inLink   bool
+}
+
+// eveMarkup converts an HTML body into EVE mail markup
+func eveMarkup(body string, entities map[string]entity) (string, error) {
+	doc, err := xhtml.Parse(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	c := &markupConverter{entities: entities}
+	c.walk(doc)
+
+	s := extraBreaks.ReplaceAllString(c.out.String(), "<br><br>")
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "<br>") {
+		s = strings.TrimSpace(strings.TrimPrefix(s, "<br>"))
+	}
+	for strings.HasSuffix(s, "<br>") {
+		s = strings.TrimSpace(strings.TrimSuffix(s, "<br>"))
+	}
+
+	return fmt.Sprintf(`<font size="%d" color="%s">%s</font>`, fontSize, fontColor, s), nil
+}
+
+func (c *markupConverter) walk(n *xhtml.Node) {
+	switch n.Type {
+	case xhtml.TextNode:
+		c.text(n.Data)
+		return
+	case xhtml.CommentNode, xhtml.DoctypeNode:
+		return
+	case xhtml.ElementNode:
+		switch n.DataAtom {
+		case atom.Head, atom.Script, atom.Style, atom.Title:
+			return
+		case atom.Br:
+			c.out.WriteString("<br>")
+			return
+		case atom.Hr:
+			c.out.WriteString("<br>--------------------<br>")
+			return
+		case atom.Img:
+			if alt := attr(n, "alt"); alt != "" {
+				c.text("[" + alt + "]")
+			}
+			return
+		case atom.A:
+			c.link(n)
+			return
+		}
+	}
+
+	open, close := c.tag(n)
+	c.out.WriteString(open)
+	c.children(n)
+	c.out.WriteString(close)
+}
+
+func (c *markupConverter) children(n *xhtml.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.walk(child)
+	}
+}
+
+// tag returns the markup around an element's children
+func (c *markupConverter) tag(n *xhtml.Node) (string, string) {
+	if n.Type != xhtml.ElementNode {
+		return "", ""
+	}
+
+	switch n.DataAtom {
+	case atom.B, atom.Strong:
+		return "<b>", "</b>"
+	case atom.I, atom.Em:
+		return "<i>", "</i>"
+	case atom.U:
+		return "<u>", "</u>"
+	case atom.H1, atom.H2, atom.H3:
+		return fmt.Sprintf(`<br><font size="%d"><b>`, headingSize), "</b></font><br>"
+	case atom.H4, atom.H5, atom.H6:
+		return "<br><b>", "</b><br>"
+	case atom.P, atom.Div, atom.Blockquote, atom.Pre, atom.Table, atom.Tr, atom.Ul, atom.Ol:
+		return "", "<br>"
+	case atom.Li:
+		return "• ", "<br>"
+	case atom.Td, atom.Th:
+		return "", " "
+	case atom.Font:
+		if color := eveColor(attr(n, "color")); color != "" {
+			return fmt.Sprintf(`<font color="%s">`, color), "</font>"
+		}
+	}
+	return "", ""
+}
+
+// link converts an anchor, turning entity pages and addresses into showinfo links
+func (c *markupConverter) link(n *xhtml.Node) {
+	href := strings.TrimSpace(attr(n, "href"))
+
+	target := ""
+	if m := addressRe.FindStringSubmatch(href); m != nil && strings.HasPrefix(href, "mailto:") {
+		target = c.showInfo(m[1])
+	} else if m := entityPage(href); m != nil {
+		target = c.showInfo(m[2])
+	}
+	if target == "" && (strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://")) {
+		target = href
+	}
+
+	if target == "" || c.inLink {
+		c.children(n)
+		return
+	}
+
+	c.out.WriteString(`<a href="` + html.EscapeString(target) + `">`)
+	c.inLink = true
+	c.children(n)
+	c.inLink = false
+	c.out.WriteString("</a>")
+}
+
+func (c *markupConverter) showInfo(address string) string {
+	e, ok := c.entities[strings.ToLower(address)]
+	if !ok {
+		return ""
+	}
+	return showInfo(e.id, e.category)
+}
+
+// text writes escaped text, replacing pasted addresses with showinfo links.
+// A name pasted before the address is folded into the link.
+func (c *markupConverter) text(s string) {
+	s = whitespace.ReplaceAllString(s, " ")
+
+	last := 0
+	for _, m := range addressRe.FindAllStringSubmatchIndex(s, -1) {
+		e, ok := c.entities[strings.ToLower(s[m[2]:m[3]])]
+		target := showInfo(e.id, e.category)
+		if !ok || target == "" {
+			continue
+		}
+		if e.name == "" {
+			e.name = s[m[2]:m[3]]
+		}
+
+		before := s[last:m[0]]
+		trimmed := strings.TrimRight(before, " \"")
+		if len(trimmed) >= len(e.name) && strings.EqualFold(trimmed[len(trimmed)-len(e.name):], e.name) {
+			before = strings.TrimRight(trimmed[:len(trimmed)-len(e.name)], "\"")
+		}
+		c.out.WriteString(html.EscapeString(before))
+
+		if c.inLink {
+			c.out.WriteString(html.EscapeString(e.name))
+		} else {
+			c.out.WriteString(`<a href="` + target + `">` + html.EscapeString(e.name) + `</a>`)
+		}
+		last = m[1]
+	}
+	c.out.WriteString(html.EscapeString(s[last:]))
+}
+
+// eveColor converts #rrggbb to EVE's #aarrggbb
+func eveColor(color string) string {
+	m := colorRe.FindStringSubmatch(strings.TrimSpace(color))
+	if m == nil {
+		return ""
+	}
+	return "#ff" + strings.ToLower(m[1])
+}
+
+func attr(n *xhtml.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package esismtp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEVEMarkup(t *testing.T) {
+	entities := map[string]entity{
+		"90000001":  {id: 90000001, name: "Bob Smith", category: "character"},
+		"bob.smith": {id: 90000001, name: "Bob Smith", category: "character"},
+		"98000001":  {id: 98000001, name: "My Corp", category: "corporation"},
+	}
+
+	body, err := eveMarkup(`<p>Hello <b>world</b></p><p>Visit <a href="https://example.com">here</a></p>`, entities)
+	assert.Nil(t, err)
+	assert.Equal(t, `<font size="12" color="#bfffffff">Hello <b>world</b><br>Visit <a href="https://example.com">here</a></font>`, body)
+
+	// Pasted addresses become showinfo links with the name folded in
+	body, err = eveMarkup(textToHTML("Hi Bob Smith <90000001@evedata.org>\nthanks"), entities)
+	assert.Nil(t, err)
+	assert.Equal(t, `<font size="12" color="#bfffffff">Hi <a href="showinfo:1377//90000001">Bob Smith</a><br>thanks</font>`, body)
+
+	body, err = eveMarkup(textToHTML(`"Bob Smith" <Bob.Smith@evedata.org> wrote:`), entities)
+	assert.Nil(t, err)
+	assert.Equal(t, `<font size="12" color="#bfffffff"><a href="showinfo:1377//90000001">Bob Smith</a> wrote:</font>`, body)
+
+	body, err = eveMarkup(`<a href="https://zkillboard.com/corporation/98000001/">My Corp</a>`, entities)
+	assert.Nil(t, err)
+	assert.Equal(t, `<font size="12" color="#bfffffff"><a href="showinfo:2//98000001">My Corp</a></font>`, body)
+
+	body, err = eveMarkup(`<html><head><style>p{}</style></head><body><font color="#00FF00">green</font><script>x</script></body></html>`, entities)
+	assert.Nil(t, err)
+	assert.Equal(t, `<font size="12" color="#bfffffff"><font color="#ff00ff00">green</font></font>`, body)
+
+	assert.Equal(t, []string{"90000001", "corp.tick", "98000001", "99000001"},
+		entityAddresses(`Bob &lt;90000001@evedata.org&gt; corp.TICK@evedata.org https://zkillboard.com/corporation/98000001/ https://www.evedata.org/alliance?id=99000001`))
+}
+
+func TestAttachmentFooter(t *testing.T) {
+	assert.Equal(t, "", attachmentFooter(nil))
+	assert.Equal(t, "<i>1 attachment was removed as EVE mail cannot carry files: a.pdf (2.0 KB)</i>",
+		attachmentFooter([]attachment{{name: "a.pdf", size: 2048}}))
+	assert.Equal(t, "<i>2 attachments were removed as EVE mail cannot carry files: a.png (10 B), b.zip (1.5 MB)</i>",
+		attachmentFooter([]attachment{{name: "a.png", size: 10}, {name: "b.zip", size: 1572864}}))
+}
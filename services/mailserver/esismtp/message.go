@@ -0,0 +1,126 @@
+package esismtp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/antihax/goesi/esi"
+	smtp "github.com/emersion/go-smtp"
+	"github.com/veqryn/go-email/email"
+)
+
+// maxBodyLength is the longest body EVE mail accepts
+const maxBodyLength = 10000
+
+const proxyFooter = `Sent via EVEMail Proxy - <a href="https://www.evedata.org/">https://www.evedata.org/</a>`
+
+// attachment is a part which cannot be sent through EVE mail
+type attachment struct {
+	name string
+	size int
+}
+
+// messageBody finds the best text part as HTML, preferring text/html over text/plain,
+// and collects everything else as dropped attachments.
+func messageBody(mail *email.Message) (string, []attachment, bool) {
+	body := ""
+	preference := 0
+	attachments := []attachment{}
+
+	for _, part := range mail.MessagesAll() {
+		mediaType, params, _ := part.Header.ContentType()
+		if strings.HasPrefix(mediaType, "multipart/") || mediaType == "message/rfc822" {
+			continue
+		}
+
+		disposition, dispositionParams, _ := part.Header.ContentDisposition()
+		if disposition != "attachment" {
+			switch mediaType {
+			case "text/html":
+				if preference < 2 {
+					preference = 2
+					body = string(part.Body)
+				}
+				continue
+			case "text/plain", "":
+				if preference < 1 {
+					preference = 1
+					body = textToHTML(string(part.Body))
+				}
+				continue
+			}
+		}
+
+		name := dispositionParams["filename"]
+		if name == "" {
+			name = params["name"]
+		}
+		if name == "" {
+			name = mediaType
+		}
+		attachments = append(attachments, attachment{name: name, size: len(part.Body)})
+	}
+
+	return body, attachments, preference > 0
+}
+
+// attachmentFooter summarises attachments which were removed
+func attachmentFooter(attachments []attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+
+	files := []string{}
+	for _, a := range attachments {
+		files = append(files, fmt.Sprintf("%s (%s)", html.EscapeString(a.name), byteSize(a.size)))
+	}
+
+	s := "attachments were"
+	if len(attachments) == 1 {
+		s = "attachment was"
+	}
+	return fmt.Sprintf("<i>%d %s removed as EVE mail cannot carry files: %s</i>", len(attachments), s, strings.Join(files, ", "))
+}
+
+func byteSize(n int) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// sendError turns an ESI error into an SMTP reply so the client can tell the user why
+func sendError(err error, maxCost int64) error {
+	detail := err.Error()
+	if e, ok := err.(esi.GenericSwaggerError); ok {
+		body := struct {
+			Error string `json:"error"`
+		}{}
+		if json.Unmarshal(e.Body(), &body) == nil && body.Error != "" {
+			detail = body.Error
+		}
+	}
+
+	status, _ := strconv.Atoi(strings.SplitN(err.Error(), " ", 2)[0])
+
+	switch {
+	case strings.Contains(detail, "ContactCostNotApproved"):
+		return &smtp.SMTPError{Code: 554, Message: fmt.Sprintf(
+			"A recipient charges more CSPA than your approved %d ISK. Raise the maximum on https://www.evedata.org/account", maxCost)}
+	case strings.Contains(detail, "ContactOwnerUnreachable"):
+		return &smtp.SMTPError{Code: 550, Message: "A recipient has blocked you: " + detail}
+	case status == 401 || status == 403:
+		return &smtp.SMTPError{Code: 550, Message: "Not permitted to send mail, check the EVE Mail Proxy scopes for this character"}
+	case status == 420 || status == 429:
+		return &smtp.SMTPError{Code: 451, Message: "EVE mail rate limit reached, try again later"}
+	case status >= 500 && status != 520:
+		return &smtp.SMTPError{Code: 451, Message: "EVE mail is unavailable, try again later: " + detail}
+	}
+	return &smtp.SMTPError{Code: 554, Message: "EVE mail rejected the message: " + detail}
+}
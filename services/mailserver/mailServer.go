@@ -8,6 +8,7 @@ import (
 
 	"github.com/antihax/evedata/services/mailserver/esiimap"
 	"github.com/antihax/evedata/services/mailserver/esismtp"
+	"github.com/antihax/evedata/services/mailserver/mailaddress"
 	imap "github.com/emersion/go-imap/server"
 	smtp "github.com/emersion/go-smtp"
 
@@ -16,6 +17,7 @@ import (
 	"github.com/antihax/evedata/internal/tokenstore"
 	"github.com/antihax/goesi"
 	"github.com/garyburd/redigo/redis"
+	"github.com/jmoiron/sqlx"
 )
 
 // MailServer provides token information.
@@ -31,7 +33,7 @@ type MailServer struct {
 }
 
 // NewMailServer Service.
-func NewMailServer(redis *redis.Pool, ledis *redis.Pool, clientID, secret string) (*MailServer, error) {
+func NewMailServer(redis *redis.Pool, ledis *redis.Pool, db *sqlx.DB, clientID, secret string) (*MailServer, error) {
 
 	// Get a caching http client
 	httpClient := apicache.CreateHTTPClientCache(ledis)
@@ -48,8 +50,10 @@ func NewMailServer(redis *redis.Pool, ledis *redis.Pool, clientID, secret string
 
 	q := redisqueue.NewRedisQueue(redis, "mailserver_queue")
 
-	imap := imap.New(esiimap.New(tokenServer, esiClient, auth, q))
-	smtp := smtp.NewServer(esismtp.New(tokenServer, esiClient, auth, q))
+	addresses := mailaddress.NewResolver(db, redis, esiClient)
+
+	imap := imap.New(esiimap.New(tokenServer, esiClient, auth, q, redis, addresses))
+	smtp := smtp.NewServer(esismtp.New(tokenServer, esiClient, auth, q, addresses))
 
 	// haproxy handles encryption
 	imap.AllowInsecureAuth = true
@@ -67,6 +71,7 @@ func NewMailServer(redis *redis.Pool, ledis *redis.Pool, clientID, secret string
 	}
 
 	imap.ErrorLog = log.New(os.Stdout, "INFO: ", log.Lshortfile)
+	imap.Enable(esiimap.NewCondstoreExtension())
 
 	smtp.Domain = "localhost"
 
@@ -0,0 +1,243 @@
+// Package mailaddress maps EVE entities to and from friendly mail proxy addresses
+// such as bob.smith@, corp.TICK@, alliance.TICK@ and list.name@.
+package mailaddress
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/antihax/goesi"
+	"github.com/garyburd/redigo/redis"
+	"github.com/jmoiron/sqlx"
+)
+
+// Domain of the mail proxy
+const Domain = "evedata.org"
+
+// UnknownMailingList is the name cached for mailing lists we cannot see
+const UnknownMailingList = "## Unknown Mailing List ##"
+
+// Address prefixes for entities other than characters
+const (
+	characterPrefix   = "character."
+	corporationPrefix = "corp."
+	alliancePrefix    = "alliance."
+	listPrefix        = "list."
+)
+
+// Redis hashes of mailing list names to IDs, and corporation and alliance IDs to tickers
+const (
+	mailingListKey = "evedata-mail-lists"
+	tickerKey      = "evedata-mail-tickers"
+)
+
+// ErrUnknownAddress is returned when an address does not match an entity
+var ErrUnknownAddress = errors.New("Unknown address")
+
+// Resolver resolves friendly addresses against the entity tables and cached mailing lists
+type Resolver struct {
+	db    *sqlx.DB
+	redis *redis.Pool
+	esi   *goesi.APIClient
+}
+
+// NewResolver creates a new address resolver
+func NewResolver(db *sqlx.DB, redis *redis.Pool, esi *goesi.APIClient) *Resolver {
+	return &Resolver{db: db, redis: redis, esi: esi}
+}
+
+// LocalPart turns a name into the local part of an address. Spaces become dots and
+// anything which cannot appear in an address is dropped.
+func LocalPart(name string) string {
+	local := []rune{}
+	for _, r := range strings.TrimSpace(name) {
+		switch {
+		case r == ' ':
+			if len(local) > 0 && local[len(local)-1] != '.' {
+				local = append(local, '.')
+			}
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '\'' || r == '_'):
+			local = append(local, r)
+		}
+	}
+	return string(local)
+}
+
+// name turns the local part of an address back into a name
+func name(local string) string {
+	return strings.Replace(local, ".", " ", -1)
+}
+
+// hasPrefix compares prefixes without case
+func hasPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// Parse splits an address into the category it names and the name, ticker or ID within it
+func Parse(address string) (string, string) {
+	local := strings.SplitN(address, "@", 2)[0]
+
+	if _, err := strconv.ParseInt(local, 10, 32); err == nil {
+		return "id", local
+	}
+
+	switch {
+	case hasPrefix(local, corporationPrefix):
+		return "corporation", local[len(corporationPrefix):]
+	case hasPrefix(local, alliancePrefix):
+		return "alliance", local[len(alliancePrefix):]
+	case hasPrefix(local, listPrefix):
+		return "mailing_list", local[len(listPrefix):]
+	case hasPrefix(local, characterPrefix):
+		return "character", name(local[len(characterPrefix):])
+	}
+	return "character", name(local)
+}
+
+// Resolve returns the EVE ID an address refers to
+func (r *Resolver) Resolve(address string) (int32, error) {
+	category, value := Parse(address)
+	if value == "" {
+		return 0, ErrUnknownAddress
+	}
+
+	var (
+		id  int32
+		err error
+	)
+
+	switch category {
+	case "id":
+		i, _ := strconv.ParseInt(value, 10, 32)
+		return int32(i), nil
+	case "character":
+		id, err = r.resolveCharacter(value)
+	case "corporation":
+		err = r.db.Get(&id, `
+			SELECT corporationID FROM evedata.corporations
+			WHERE ticker = ? AND dead = 0
+			ORDER BY memberCount DESC LIMIT 1`, value)
+	case "alliance":
+		err = r.db.Get(&id, `
+			SELECT allianceID FROM evedata.alliances
+			WHERE shortName = ? AND dead = 0
+			ORDER BY memberCount DESC LIMIT 1`, value)
+	case "mailing_list":
+		id, err = r.resolveMailingList(value)
+	}
+
+	if err == sql.ErrNoRows || err == redis.ErrNil || (err == nil && id == 0) {
+		return 0, ErrUnknownAddress
+	}
+	return id, err
+}
+
+func (r *Resolver) resolveCharacter(name string) (int32, error) {
+	var id int32
+	err := r.db.Get(&id, `SELECT characterID FROM evedata.characters WHERE name = ? LIMIT 1`, name)
+	if err != sql.ErrNoRows {
+		return id, err
+	}
+
+	// We may not know them yet
+	ids, _, err := r.esi.ESI.UniverseApi.PostUniverseIds(context.Background(), []string{name}, nil)
+	if err != nil {
+		return 0, err
+	}
+	for _, c := range ids.Characters {
+		if strings.EqualFold(c.Name, name) {
+			return c.Id, nil
+		}
+	}
+	return 0, ErrUnknownAddress
+}
+
+func (r *Resolver) resolveMailingList(local string) (int32, error) {
+	conn := r.redis.Get()
+	defer conn.Close()
+	id, err := redis.Int(conn.Do("HGET", mailingListKey, strings.ToLower(local)))
+	return int32(id), err
+}
+
+// CacheMailingLists records mailing list names so list.<name>@ addresses resolve
+func (r *Resolver) CacheMailingLists(ids []int32, names []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	conn := r.redis.Get()
+	defer conn.Close()
+
+	args := []interface{}{mailingListKey}
+	for i := range ids {
+		args = append(args, strings.ToLower(LocalPart(names[i])), ids[i])
+	}
+	_, err := conn.Do("HMSET", args...)
+	return err
+}
+
+// Address returns the friendly address for an entity, falling back to its ID
+func (r *Resolver) Address(id int32, name, category string) string {
+	local := ""
+
+	switch category {
+	case "character":
+		local = LocalPart(name)
+		if hasPrefix(local, corporationPrefix) || hasPrefix(local, alliancePrefix) ||
+			hasPrefix(local, listPrefix) || hasPrefix(local, characterPrefix) {
+			local = characterPrefix + local
+		}
+	case "corporation":
+		if ticker := r.ticker(id, category); ticker != "" {
+			local = corporationPrefix + ticker
+		}
+	case "alliance":
+		if ticker := r.ticker(id, category); ticker != "" {
+			local = alliancePrefix + ticker
+		}
+	case "mailing_list":
+		if name != UnknownMailingList && LocalPart(name) != "" {
+			local = listPrefix + LocalPart(name)
+		}
+	}
+
+	if local == "" {
+		local = strconv.Itoa(int(id))
+	}
+	return local + "@" + Domain
+}
+
+// Header formats an entity for a From or To header
+func (r *Resolver) Header(id int32, name, category string) string {
+	a := mail.Address{Name: name, Address: r.Address(id, name, category)}
+	return a.String()
+}
+
+// ticker finds a corporation or alliance ticker, caching it in redis
+func (r *Resolver) ticker(id int32, category string) string {
+	conn := r.redis.Get()
+	defer conn.Close()
+
+	ticker, err := redis.String(conn.Do("HGET", tickerKey, id))
+	if err == nil {
+		return ticker
+	}
+
+	if category == "corporation" {
+		err = r.db.Get(&ticker, `SELECT ticker FROM evedata.corporations WHERE corporationID = ?`, id)
+	} else {
+		err = r.db.Get(&ticker, `SELECT shortName FROM evedata.alliances WHERE allianceID = ?`, id)
+	}
+	if err != nil || LocalPart(ticker) != ticker {
+		// Tickers with spaces or symbols cannot be addressed
+		return ""
+	}
+
+	conn.Do("HSET", tickerKey, id, ticker)
+	return ticker
+}
@@ -0,0 +1,42 @@
+package mailaddress
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalPart(t *testing.T) {
+	assert.Equal(t, "Bob.Smith", LocalPart("Bob Smith"))
+	assert.Equal(t, "Bob.O'Neil-Jones", LocalPart(" Bob  O'Neil-Jones "))
+	assert.Equal(t, "My.List", LocalPart("My List!"))
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		address  string
+		category string
+		value    string
+	}{
+		{"90000001@evedata.org", "id", "90000001"},
+		{"bob.smith@evedata.org", "character", "bob smith"},
+		{"character.Corp.Bob@evedata.org", "character", "Corp Bob"},
+		{"corp.TICK@evedata.org", "corporation", "TICK"},
+		{"Alliance.ALLY@evedata.org", "alliance", "ALLY"},
+		{"list.My.List@evedata.org", "mailing_list", "My.List"},
+	}
+
+	for _, test := range tests {
+		category, value := Parse(test.address)
+		assert.Equal(t, test.category, category, test.address)
+		assert.Equal(t, test.value, value, test.address)
+	}
+}
+
+func TestAddress(t *testing.T) {
+	r := &Resolver{}
+	assert.Equal(t, "Bob.Smith@evedata.org", r.Address(90000001, "Bob Smith", "character"))
+	assert.Equal(t, "character.Corp.Bob@evedata.org", r.Address(90000001, "Corp Bob", "character"))
+	assert.Equal(t, "list.My.List@evedata.org", r.Address(145000001, "My List", "mailing_list"))
+	assert.Equal(t, "145000001@evedata.org", r.Address(145000001, UnknownMailingList, "mailing_list"))
+}
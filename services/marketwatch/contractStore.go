@@ -104,9 +104,11 @@ func (s *MarketWatch) getContractStore(locationID int64) *sync.Map {
 	return s.contracts[locationID]
 }
 
-// createContractStore for a location
+// createContractStore for a location, keeping any restored from a snapshot
 func (s *MarketWatch) createContractStore(locationID int64) {
 	s.cmutex.Lock()
 	defer s.cmutex.Unlock()
-	s.contracts[locationID] = &sync.Map{}
+	if s.contracts[locationID] == nil {
+		s.contracts[locationID] = &sync.Map{}
+	}
 }
@@ -127,6 +127,8 @@ func (s *MarketWatch) contractWorker(regionID int32) {
 			},
 		).Observe(float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond))
 
+		s.feed.publishContracts(int64(regionID), newContracts, changes, deletions)
+
 		if len(newContracts) > 0 {
 			s.contractChan <- newContracts
 		}
@@ -0,0 +1,207 @@
+package marketwatch
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/antihax/goesi/esi"
+)
+
+// feedBuffer is how many messages a client may fall behind before it is dropped
+const feedBuffer = 1000
+
+// Subscription filters what a feed client receives. Empty filters match everything.
+type Subscription struct {
+	Regions    map[int64]bool // Regional markets
+	Structures map[int64]bool // Structure markets, or orders located in a station or structure
+	Types      map[int32]bool // Order types
+	Contracts  bool           // Include contracts in subscribed regions
+}
+
+// parseSubscription reads comma separated regions, structures and types from a query
+func parseSubscription(query url.Values) (*Subscription, error) {
+	sub := &Subscription{
+		Regions:    make(map[int64]bool),
+		Structures: make(map[int64]bool),
+		Types:      make(map[int32]bool),
+		Contracts:  query.Get("contracts") == "true",
+	}
+
+	for _, p := range []struct {
+		name string
+		set  func(int64)
+	}{
+		{"regions", func(id int64) { sub.Regions[id] = true }},
+		{"structures", func(id int64) { sub.Structures[id] = true }},
+		{"types", func(id int64) { sub.Types[int32(id)] = true }},
+	} {
+		for _, v := range strings.Split(query.Get(p.name), ",") {
+			if v == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, errors.New(p.name + " must be a comma separated list of IDs")
+			}
+			p.set(id)
+		}
+	}
+
+	return sub, nil
+}
+
+// matchesMarket checks if every order in a market is within the subscribed locations
+func (sub *Subscription) matchesMarket(marketID int64) bool {
+	return (len(sub.Regions) == 0 && len(sub.Structures) == 0) ||
+		sub.Regions[marketID] || sub.Structures[marketID]
+}
+
+// matches checks if an order in a market is subscribed to
+func (sub *Subscription) matches(marketID, locationID int64, typeID int32) bool {
+	if len(sub.Types) > 0 && !sub.Types[typeID] {
+		return false
+	}
+	return sub.matchesMarket(marketID) || sub.Structures[locationID]
+}
+
+// matchesContracts checks if contracts in a region are subscribed to
+func (sub *Subscription) matchesContracts(regionID int64) bool {
+	return sub.Contracts && (len(sub.Regions) == 0 || sub.Regions[regionID])
+}
+
+// filterOrders returns orders in a market matching the subscription
+func (sub *Subscription) filterOrders(marketID int64, orders []esi.GetMarketsRegionIdOrders200Ok) []esi.GetMarketsRegionIdOrders200Ok {
+	m := []esi.GetMarketsRegionIdOrders200Ok{}
+	for _, o := range orders {
+		if sub.matches(marketID, o.LocationId, o.TypeId) {
+			m = append(m, o)
+		}
+	}
+	return m
+}
+
+// filterChanges returns changes in a market matching the subscription
+func (sub *Subscription) filterChanges(marketID int64, changes []OrderChange) []OrderChange {
+	m := []OrderChange{}
+	for _, c := range changes {
+		if sub.matches(marketID, c.LocationId, c.TypeID) {
+			m = append(m, c)
+		}
+	}
+	return m
+}
+
+// feedClient is a subscriber waiting on deltas
+type feedClient struct {
+	sub  *Subscription
+	send chan Message
+}
+
+// feed fans out market deltas to subscribed clients
+type feed struct {
+	lock    sync.RWMutex
+	clients map[*feedClient]bool
+}
+
+func newFeed() *feed {
+	return &feed{clients: make(map[*feedClient]bool)}
+}
+
+// subscribe a new client. Deltas queue up while the client is sent its snapshot.
+func (f *feed) subscribe(sub *Subscription) *feedClient {
+	c := &feedClient{sub: sub, send: make(chan Message, feedBuffer)}
+	f.lock.Lock()
+	f.clients[c] = true
+	f.lock.Unlock()
+	return c
+}
+
+// unsubscribe a client and close its channel
+func (f *feed) unsubscribe(c *feedClient) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.clients[c] {
+		delete(f.clients, c)
+		close(c.send)
+	}
+}
+
+// publish market deltas to every client subscribed to them
+func (f *feed) publish(marketID int64, additions []esi.GetMarketsRegionIdOrders200Ok, changes, deletions []OrderChange) {
+	f.broadcast(func(sub *Subscription) []Message {
+		messages := []Message{}
+		if m := sub.filterOrders(marketID, additions); len(m) > 0 {
+			messages = append(messages, Message{Action: "addition", Payload: m})
+		}
+		if m := sub.filterChanges(marketID, changes); len(m) > 0 {
+			messages = append(messages, Message{Action: "change", Payload: m})
+		}
+		if m := sub.filterChanges(marketID, deletions); len(m) > 0 {
+			messages = append(messages, Message{Action: "deletion", Payload: m})
+		}
+		return messages
+	})
+}
+
+// publishTrades sends inferred trades in a market to subscribed clients
+func (f *feed) publishTrades(marketID int64, trades []InferredTrade) {
+	f.broadcast(func(sub *Subscription) []Message {
+		m := []InferredTrade{}
+		for _, t := range trades {
+			if sub.matches(marketID, t.LocationID, t.TypeID) {
+				m = append(m, t)
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return []Message{{Action: "trade", Payload: m}}
+	})
+}
+
+// publishContracts sends contract deltas to clients subscribed to the region
+func (f *feed) publishContracts(regionID int64, additions []FullContract, changes, deletions []ContractChange) {
+	f.broadcast(func(sub *Subscription) []Message {
+		messages := []Message{}
+		if !sub.matchesContracts(regionID) {
+			return messages
+		}
+		if len(additions) > 0 {
+			messages = append(messages, Message{Action: "contractAddition", Payload: additions})
+		}
+		if len(changes) > 0 {
+			messages = append(messages, Message{Action: "contractChange", Payload: changes})
+		}
+		if len(deletions) > 0 {
+			messages = append(messages, Message{Action: "contractDeletion", Payload: deletions})
+		}
+		return messages
+	})
+}
+
+// broadcast messages without blocking. Clients too slow to keep up are dropped
+// so they cannot hold back the market workers; they reconnect for a fresh snapshot.
+func (f *feed) broadcast(filter func(*Subscription) []Message) {
+	slow := []*feedClient{}
+
+	f.lock.RLock()
+	for c := range f.clients {
+	Messages:
+		for _, m := range filter(c.sub) {
+			select {
+			case c.send <- m:
+			default:
+				slow = append(slow, c)
+				break Messages
+			}
+		}
+	}
+	f.lock.RUnlock()
+
+	for _, c := range slow {
+		f.unsubscribe(c)
+	}
+}
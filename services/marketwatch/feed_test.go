@@ -0,0 +1,77 @@
+package marketwatch
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSubscription(t *testing.T) {
+	q, _ := url.ParseQuery("regions=10000002,10000043&structures=1022734985679&types=34&contracts=true")
+	sub, err := parseSubscription(q)
+	assert.Nil(t, err)
+	assert.Equal(t, map[int64]bool{10000002: true, 10000043: true}, sub.Regions)
+	assert.Equal(t, map[int64]bool{1022734985679: true}, sub.Structures)
+	assert.Equal(t, map[int32]bool{34: true}, sub.Types)
+	assert.True(t, sub.Contracts)
+
+	// Empty filters match everything
+	sub, err = parseSubscription(url.Values{"regions": {""}})
+	assert.Nil(t, err)
+	assert.Len(t, sub.Regions, 0)
+	assert.False(t, sub.Contracts)
+
+	for _, bad := range []string{"regions=jita", "structures=1,,x", "types=34.5"} {
+		q, _ := url.ParseQuery(bad)
+		_, err := parseSubscription(q)
+		assert.NotNil(t, err, bad)
+	}
+}
+
+func TestSubscriptionMatches(t *testing.T) {
+	const (
+		theForge  = 10000002
+		domain    = 10000043
+		jita      = 60003760
+		amarr     = 60008494
+		structure = 1022734985679
+	)
+
+	tests := []struct {
+		name       string
+		query      string
+		marketID   int64
+		locationID int64
+		typeID     int32
+		matches    bool
+	}{
+		{"everything", "", theForge, jita, 34, true},
+		{"region", "regions=10000002", theForge, jita, 34, true},
+		{"other region", "regions=10000002", domain, amarr, 34, false},
+		{"type", "types=34,35", theForge, jita, 35, true},
+		{"other type", "types=34,35", theForge, jita, 36, false},
+		{"type in other region", "regions=10000002&types=34", domain, amarr, 34, false},
+		{"structure market", "structures=1022734985679", structure, structure, 34, true},
+		{"structure order in region", "structures=1022734985679", domain, structure, 34, true},
+		{"station order", "structures=60003760", theForge, jita, 34, true},
+		{"other station", "structures=60003760", theForge, amarr, 34, false},
+		{"structure with other type", "structures=1022734985679&types=34", structure, structure, 35, false},
+	}
+
+	for _, test := range tests {
+		q, _ := url.ParseQuery(test.query)
+		sub, err := parseSubscription(q)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.matches, sub.matches(test.marketID, test.locationID, test.typeID), test.name)
+	}
+}
+
+func TestSubscriptionMatchesContracts(t *testing.T) {
+	sub, _ := parseSubscription(url.Values{"regions": {"10000002"}})
+	assert.False(t, sub.matchesContracts(10000002))
+
+	sub.Contracts = true
+	assert.True(t, sub.matchesContracts(10000002))
+	assert.False(t, sub.matchesContracts(10000043))
+}
@@ -109,6 +109,18 @@ func (s *MarketWatch) marketWorker(regionID int32) {
 
 		//	fmt.Printf("%s %d (%d)\n", time.Since(start).String(), regionID, numOrders)
 
+		s.feed.publish(int64(regionID), newOrders, changes, deletions)
+
+		trades := []InferredTrade{}
+		if s.markPulled(int64(regionID), start) {
+			trades = inferTrades(s.getMarketStore(int64(regionID)),
+				s.withoutWatchedStructures(changes), s.withoutWatchedStructures(deletions))
+		}
+		s.feed.publishTrades(int64(regionID), trades)
+		if len(trades) > 0 {
+			s.tradeChan <- trades
+		}
+
 		if len(newOrders) > 0 {
 			s.orderChan <- newOrders
 		}
@@ -99,11 +99,24 @@ func (s *MarketWatch) getMarketStore(locationID int64) *sync.Map {
 	return s.market[locationID]
 }
 
-// createMarketStore for a location
+// markPulled records a pull of a market starting at t. It reports if the previous
+// pull was recent enough for the changes between them to be inferred as trades,
+// which is never the case for the first pull after starting or restoring a snapshot.
+func (s *MarketWatch) markPulled(locationID int64, t time.Time) bool {
+	s.mmutex.Lock()
+	defer s.mmutex.Unlock()
+	last, ok := s.pulls[locationID]
+	s.pulls[locationID] = t
+	return ok && t.Sub(last) <= maxTradeGap
+}
+
+// createMarketStore for a location, keeping any restored from a snapshot
 func (s *MarketWatch) createMarketStore(locationID int64) {
 	s.mmutex.Lock()
 	defer s.mmutex.Unlock()
-	s.market[locationID] = &sync.Map{}
+	if s.market[locationID] == nil {
+		s.market[locationID] = &sync.Map{}
+	}
 }
 
 // getStructureState for a location
@@ -9,6 +9,7 @@ import (
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/antihax/goesi/esi"
 	"github.com/garyburd/redigo/redis"
+	"github.com/gorilla/websocket"
 	"github.com/jmoiron/sqlx"
 
 	"github.com/antihax/goesi"
@@ -32,12 +33,21 @@ type MarketWatch struct {
 	contractChangeChan chan []ContractChange
 	orderDeleteChan    chan []OrderChange
 	contractDeleteChan chan []ContractChange
+	tradeChan          chan []InferredTrade
 
 	// Database pool
 	db *sqlx.DB
 
+	// websocket subscribers
+	feed     *feed
+	upgrader *websocket.Upgrader
+
+	// file to snapshot the stores to, empty to disable
+	snapshotFile string
+
 	// data store
 	market     map[int64]*sync.Map
+	pulls      map[int64]time.Time // Last pull of each market, guarded by mmutex
 	structures map[int64]*Structure
 	contracts  map[int64]*sync.Map
 	mmutex     sync.RWMutex // Market mutex for the main map
@@ -46,7 +56,7 @@ type MarketWatch struct {
 }
 
 // NewMarketWatch creates a new MarketWatch microservice
-func NewMarketWatch(refresh, tokenClientID, tokenSecret string, db *sqlx.DB, ledis *redis.Pool) *MarketWatch {
+func NewMarketWatch(refresh, tokenClientID, tokenSecret string, db *sqlx.DB, ledis *redis.Pool, snapshotFile string, allowedOrigins []string) *MarketWatch {
 	// Get a caching http client
 	cache := apicache.CreateLimitedHTTPClientCache(ledis)
 
@@ -78,6 +88,12 @@ func NewMarketWatch(refresh, tokenClientID, tokenSecret string, db *sqlx.DB, led
 		// database pool
 		db: db,
 
+		// websocket subscribers
+		feed:     newFeed(),
+		upgrader: newUpgrader(allowedOrigins),
+
+		snapshotFile: snapshotFile,
+
 		// ESI SSO Handler
 		doAuth:    doAuth,
 		token:     &token,
@@ -90,9 +106,11 @@ func NewMarketWatch(refresh, tokenClientID, tokenSecret string, db *sqlx.DB, led
 		contractChangeChan: make(chan []ContractChange, 10000),
 		orderDeleteChan:    make(chan []OrderChange, 10000),
 		contractDeleteChan: make(chan []ContractChange, 10000),
+		tradeChan:          make(chan []InferredTrade, 10000),
 
 		// Market Data Map
 		market:     make(map[int64]*sync.Map),
+		pulls:      make(map[int64]time.Time),
 		structures: make(map[int64]*Structure),
 		contracts:  make(map[int64]*sync.Map),
 	}
@@ -100,8 +118,14 @@ func NewMarketWatch(refresh, tokenClientID, tokenSecret string, db *sqlx.DB, led
 
 // Run starts the market watch service
 func (s *MarketWatch) Run() error {
+	// Restore state so the first pull only reports real changes
+	if err := s.loadSnapshot(); err != nil {
+		log.Println(err)
+	}
+	go s.snapshotter()
 	go s.startUpMarketWorkers()
 	go s.sqlPumps()
+	go s.tradeAggregator()
 	return nil
 }
 
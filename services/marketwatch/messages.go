@@ -10,50 +10,58 @@ type Message struct {
 	Payload interface{} `json:"payload"`
 }
 
-func (s *MarketWatch) dumpMarket(channels map[string]bool, send chan interface{}) {
-	// Prevent changes to the map while we loop
-	s.mmutex.RLock()
-	defer s.mmutex.RUnlock()
+// dumpMarket sends a snapshot of everything matching a subscription, one message per market
+func (s *MarketWatch) dumpMarket(sub *Subscription, send func(interface{}) error) error {
+	// Copy the maps so writing to slow clients does not hold the locks
+	markets, contracts := s.marketStores(), s.contractStores()
 
 	// loop all the locations
-	if channels["market"] {
-		for _, r := range s.market {
-			// Build a list
-			m := []esi.GetMarketsRegionIdOrders200Ok{}
-			r.Range(
-				func(k, v interface{}) bool {
-					o := v.(Order)
+	for marketID, r := range markets {
+		// Build a list
+		m := []esi.GetMarketsRegionIdOrders200Ok{}
+		r.Range(
+			func(k, v interface{}) bool {
+				o := v.(Order)
+				if sub.matches(marketID, o.Order.LocationId, o.Order.TypeId) {
 					m = append(m, o.Order)
-					return true
-				})
-			// send the list out
-			if len(m) > 0 {
-				send <- Message{
-					Action:  "addition",
-					Payload: m,
 				}
+				return true
+			})
+		// send the list out
+		if len(m) > 0 {
+			if err := send(Message{
+				Action:  "addition",
+				Payload: m,
+			}); err != nil {
+				return err
 			}
 		}
 	}
 
 	// loop all the locations
-	if channels["contract"] {
-		for _, r := range s.contracts {
-			// Build a list
-			m := []FullContract{}
-			r.Range(
-				func(k, v interface{}) bool {
-					o := v.(Contract)
-					m = append(m, o.Contract)
-					return true
-				})
-			// send the list out
-			if len(m) > 0 {
-				send <- Message{
-					Action:  "contractAddition",
-					Payload: m,
-				}
+	for regionID, r := range contracts {
+		if !sub.matchesContracts(regionID) {
+			continue
+		}
+
+		// Build a list
+		m := []FullContract{}
+		r.Range(
+			func(k, v interface{}) bool {
+				o := v.(Contract)
+				m = append(m, o.Contract)
+				return true
+			})
+		// send the list out
+		if len(m) > 0 {
+			if err := send(Message{
+				Action:  "contractAddition",
+				Payload: m,
+			}); err != nil {
+				return err
 			}
 		}
 	}
+
+	return send(Message{Action: "snapshot"})
 }
@@ -146,6 +146,8 @@ func (s *MarketWatch) sqlPumps() {
 			s.saveDeletions(v)
 		case v := <-s.orderChangeChan:
 			s.saveChanges(v)
+		case v := <-s.tradeChan:
+			s.saveTrades(v)
 
 		case v := <-s.contractChan:
 			s.saveContractAdditions(v)
@@ -0,0 +1,197 @@
+package marketwatch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/antihax/evedata/internal/msgpackcodec"
+)
+
+const (
+	// snapshotPeriod is how often the stores are saved
+	snapshotPeriod = time.Minute * 10
+
+	// maxSnapshotAge is the oldest snapshot worth restoring. Older state is replaced by a fresh pull.
+	maxSnapshotAge = time.Hour * 6
+)
+
+// snapshot of the in-memory stores. The first pull after a restore reconciles against
+// it so only real additions, changes and deletions are emitted.
+type snapshot struct {
+	Taken      time.Time
+	Markets    map[int64][]Order
+	Contracts  map[int64][]Contract
+	Structures map[int64]time.Time // Structures and when failed ones may be retried
+}
+
+// takeSnapshot copies the stores
+func (s *MarketWatch) takeSnapshot() *snapshot {
+	snap := &snapshot{
+		Taken:      time.Now().UTC(),
+		Markets:    make(map[int64][]Order),
+		Contracts:  make(map[int64][]Contract),
+		Structures: make(map[int64]time.Time),
+	}
+
+	for locationID, m := range s.marketStores() {
+		orders := []Order{}
+		m.Range(func(k, v interface{}) bool {
+			orders = append(orders, v.(Order))
+			return true
+		})
+		snap.Markets[locationID] = orders
+	}
+
+	for locationID, m := range s.contractStores() {
+		contracts := []Contract{}
+		m.Range(func(k, v interface{}) bool {
+			contracts = append(contracts, v.(Contract))
+			return true
+		})
+		snap.Contracts[locationID] = contracts
+	}
+
+	s.smutex.RLock()
+	for structureID, state := range s.structures {
+		snap.Structures[structureID] = state.restart
+	}
+	s.smutex.RUnlock()
+
+	return snap
+}
+
+// restoreSnapshot fills the stores before any workers start
+func (s *MarketWatch) restoreSnapshot(snap *snapshot) {
+	for locationID, orders := range snap.Markets {
+		s.createMarketStore(locationID)
+		m := s.getMarketStore(locationID)
+		for _, o := range orders {
+			m.Store(o.Order.OrderId, o)
+		}
+	}
+
+	for locationID, contracts := range snap.Contracts {
+		s.createContractStore(locationID)
+		m := s.getContractStore(locationID)
+		for _, c := range contracts {
+			m.Store(c.Contract.Contract.ContractId, c)
+		}
+	}
+
+	for structureID, restart := range snap.Structures {
+		s.createStructureState(structureID).restart = restart
+	}
+}
+
+// SaveSnapshot writes the stores to the snapshot file
+func (s *MarketWatch) SaveSnapshot() error {
+	if s.snapshotFile == "" {
+		return nil
+	}
+	return s.writeSnapshot(s.takeSnapshot())
+}
+
+// writeSnapshot compresses a snapshot to the snapshot file
+func (s *MarketWatch) writeSnapshot(snap *snapshot) error {
+	b, err := (&msgpackcodec.MsgPackCodec{}).Marshal(snap)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz, err := gzip.NewWriterLevel(&buf, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(b); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	// Write beside the old snapshot and swap so a crash never leaves half a file
+	tmp := s.snapshotFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.snapshotFile)
+}
+
+// loadSnapshot restores the stores from the snapshot file if it is recent enough
+func (s *MarketWatch) loadSnapshot() error {
+	if s.snapshotFile == "" {
+		return nil
+	}
+
+	f, err := os.Open(s.snapshotFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	b, err := ioutil.ReadAll(gz)
+	if err != nil {
+		return err
+	}
+
+	snap := &snapshot{}
+	if err := (&msgpackcodec.MsgPackCodec{}).Unmarshal(b, snap); err != nil {
+		return err
+	}
+
+	if time.Since(snap.Taken) > maxSnapshotAge {
+		log.Printf("ignoring snapshot from %s\n", snap.Taken)
+		return nil
+	}
+
+	s.restoreSnapshot(snap)
+	log.Printf("restored snapshot from %s with %d markets and %d contract regions\n",
+		snap.Taken, len(snap.Markets), len(snap.Contracts))
+	return nil
+}
+
+// snapshotter saves the stores periodically
+func (s *MarketWatch) snapshotter() {
+	for {
+		time.Sleep(snapshotPeriod)
+		if err := s.SaveSnapshot(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// marketStores copies the market map so it can be walked without holding the lock
+func (s *MarketWatch) marketStores() map[int64]*sync.Map {
+	s.mmutex.RLock()
+	defer s.mmutex.RUnlock()
+	markets := make(map[int64]*sync.Map, len(s.market))
+	for id, m := range s.market {
+		markets[id] = m
+	}
+	return markets
+}
+
+// contractStores copies the contract map so it can be walked without holding the lock
+func (s *MarketWatch) contractStores() map[int64]*sync.Map {
+	s.cmutex.RLock()
+	defer s.cmutex.RUnlock()
+	contracts := make(map[int64]*sync.Map, len(s.contracts))
+	for id, m := range s.contracts {
+		contracts[id] = m
+	}
+	return contracts
+}
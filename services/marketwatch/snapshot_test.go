@@ -0,0 +1,61 @@
+package marketwatch
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSnapshotTest(file string) *MarketWatch {
+	return &MarketWatch{
+		snapshotFile: file,
+		market:       make(map[int64]*sync.Map),
+		contracts:    make(map[int64]*sync.Map),
+		structures:   make(map[int64]*Structure),
+		pulls:        make(map[int64]time.Time),
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "marketwatch.snapshot")
+	restart := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+
+	s := newSnapshotTest(file)
+	s.createMarketStore(10000002)
+	s.storeData(10000002, Order{Order: esi.GetMarketsRegionIdOrders200Ok{OrderId: 100, LocationId: 60003760, TypeId: 34, Price: 5}})
+	s.createContractStore(10000002)
+	s.storeContract(10000002, Contract{Contract: FullContract{Contract: esi.GetContractsPublicRegionId200Ok{ContractId: 200, Price: 1000}}})
+	s.createStructureState(1022734985679).restart = restart
+	assert.Nil(t, s.SaveSnapshot())
+
+	// Everything comes back
+	r := newSnapshotTest(file)
+	assert.Nil(t, r.loadSnapshot())
+
+	o, ok := r.getMarketStore(10000002).Load(int64(100))
+	assert.True(t, ok)
+	assert.Equal(t, float64(5), o.(Order).Order.Price)
+	assert.Equal(t, int32(34), o.(Order).Order.TypeId)
+
+	c, ok := r.getContractStore(10000002).Load(int32(200))
+	assert.True(t, ok)
+	assert.Equal(t, float64(1000), c.(Contract).Contract.Contract.Price)
+
+	assert.NotNil(t, r.getStructureState(1022734985679))
+	assert.True(t, restart.Equal(r.getStructureState(1022734985679).restart))
+
+	// Stale snapshots are replaced by a fresh pull
+	stale := s.takeSnapshot()
+	stale.Taken = time.Now().UTC().Add(-maxSnapshotAge - time.Minute)
+	assert.Nil(t, s.writeSnapshot(stale))
+
+	r = newSnapshotTest(file)
+	assert.Nil(t, r.loadSnapshot())
+	assert.Nil(t, r.getMarketStore(10000002))
+	assert.Nil(t, r.getContractStore(10000002))
+	assert.Nil(t, r.getStructureState(1022734985679))
+}
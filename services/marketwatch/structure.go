@@ -157,6 +157,17 @@ func (s *MarketWatch) structureWorker(structureID int64) {
 			},
 		).Observe(float64(time.Since(start).Nanoseconds()) / float64(time.Millisecond))
 
+		s.feed.publish(structureID, newOrders, changes, deletions)
+
+		trades := []InferredTrade{}
+		if s.markPulled(structureID, start) {
+			trades = inferTrades(s.getMarketStore(structureID), changes, deletions)
+		}
+		s.feed.publishTrades(structureID, trades)
+		if len(trades) > 0 {
+			s.tradeChan <- trades
+		}
+
 		if len(newOrders) > 0 {
 			s.orderChan <- newOrders
 		}
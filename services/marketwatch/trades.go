@@ -0,0 +1,162 @@
+package marketwatch
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+)
+
+// maxTradeGap is the longest time between market pulls that trades are inferred over.
+// Markets are pulled as their five minute cache expires; after a longer gap an order
+// may have been partly filled and then cancelled, so vanished orders cannot be told apart.
+const maxTradeGap = time.Minute * 10
+
+// InferredTrade is a fill or cancellation deduced from changes between market pulls
+type InferredTrade struct {
+	OrderID      int64     `json:"order_id"`
+	LocationID   int64     `json:"location_id"`
+	TypeID       int32     `json:"type_id"`
+	IsBuyOrder   bool      `json:"is_buy_order"`
+	Kind         string    `json:"kind"` // partial, fill or cancel
+	Price        float64   `json:"price"`
+	Quantity     int32     `json:"quantity"`
+	VolumeRemain int32     `json:"volume_remain"`
+	Time         time.Time `json:"time"`
+}
+
+// bookSide identifies one side of the book for a type at a location
+type bookSide struct {
+	locationID int64
+	typeID     int32
+	isBuyOrder bool
+}
+
+// inferTrades turns order changes and deletions into trades. Volume drops are partial
+// fills. Orders which vanish before expiring were either filled or cancelled; one priced
+// at or better than everything left on its side of the book is taken as filled, as anyone
+// trading against it would have taken a better priced order first.
+func inferTrades(market *sync.Map, changes, deletions []OrderChange) []InferredTrade {
+	trades := []InferredTrade{}
+
+	for _, c := range changes {
+		if c.VolumeChange > 0 {
+			trades = append(trades, newInferredTrade(c, "partial", c.VolumeChange, c.VolumeRemain))
+		}
+	}
+
+	// Find what was left on the book for each vanished order
+	best := make(map[bookSide]float64)
+	for _, c := range deletions {
+		best[bookSide{c.LocationId, c.TypeID, c.IsBuyOrder}] = 0
+	}
+	market.Range(func(k, v interface{}) bool {
+		o := v.(Order).Order
+		side := bookSide{o.LocationId, o.TypeId, o.IsBuyOrder}
+		price, ok := best[side]
+		if ok && (price == 0 || (o.IsBuyOrder && o.Price > price) || (!o.IsBuyOrder && o.Price < price)) {
+			best[side] = o.Price
+		}
+		return true
+	})
+
+	for _, c := range deletions {
+		// Expired orders were neither filled nor cancelled
+		if !c.Issued.Add(time.Hour * 24 * time.Duration(c.Duration)).After(c.TimeChanged) {
+			continue
+		}
+
+		price := best[bookSide{c.LocationId, c.TypeID, c.IsBuyOrder}]
+		if price == 0 || (c.IsBuyOrder && c.Price >= price) || (!c.IsBuyOrder && c.Price <= price) {
+			trades = append(trades, newInferredTrade(c, "fill", c.VolumeChange, 0))
+		} else {
+			trades = append(trades, newInferredTrade(c, "cancel", c.VolumeChange, c.VolumeChange))
+		}
+	}
+
+	return trades
+}
+
+func newInferredTrade(c OrderChange, kind string, quantity, remain int32) InferredTrade {
+	return InferredTrade{
+		OrderID:      c.OrderID,
+		LocationID:   c.LocationId,
+		TypeID:       c.TypeID,
+		IsBuyOrder:   c.IsBuyOrder,
+		Kind:         kind,
+		Price:        c.Price,
+		Quantity:     quantity,
+		VolumeRemain: remain,
+		Time:         c.TimeChanged,
+	}
+}
+
+// withoutWatchedStructures drops changes to orders in structures with a running worker.
+// Orders in public structures are seen in both the region and the structure; trades
+// are only inferred by the structure worker so an order is not both filled and cancelled.
+func (s *MarketWatch) withoutWatchedStructures(changes []OrderChange) []OrderChange {
+	kept := []OrderChange{}
+	for _, c := range changes {
+		if state := s.getStructureState(c.LocationId); state == nil || !state.running {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
+
+// saveTrades stores inferred trades. A structure worker starting between pulls can
+// see an order the region already reported, so duplicates are ignored.
+func (s *MarketWatch) saveTrades(trades []InferredTrade) {
+	for start := 0; start < len(trades); start += 80 {
+		end := start + 80
+		if end > len(trades) {
+			end = len(trades)
+		}
+
+		trade := squirrel.Insert("evedata.marketInferredTrades").Options("IGNORE").Columns(
+			"orderID", "kind", "volumeRemain", "locationID", "typeID", "isBuyOrder", "price", "quantity", "time",
+		)
+		for _, t := range trades[start:end] {
+			trade = trade.Values(t.OrderID, t.Kind, t.VolumeRemain, t.LocationID, t.TypeID, t.IsBuyOrder, t.Price, t.Quantity, t.Time)
+		}
+
+		sqlq, args, err := trade.ToSql()
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := s.doSQL(sqlq, args...); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// tradeAggregator rolls inferred fills up into hourly volume per type and location
+func (s *MarketWatch) tradeAggregator() {
+	for {
+		time.Sleep(time.Minute * 5)
+
+		// Recalculate the recent hours as late trades arrive
+		if err := s.doSQL(`
+			INSERT INTO evedata.marketIntradayVolume
+				(locationID, typeID, hour, buyVolume, sellVolume, buyISK, sellISK, trades)
+				SELECT locationID, typeID, DATE_FORMAT(time, "%Y-%m-%d %H:00:00") AS hour,
+					SUM(IF(isBuyOrder, quantity, 0)), SUM(IF(isBuyOrder, 0, quantity)),
+					SUM(IF(isBuyOrder, quantity * price, 0)), SUM(IF(isBuyOrder, 0, quantity * price)),
+					COUNT(*)
+				FROM evedata.marketInferredTrades
+				WHERE kind != "cancel" AND time > DATE_SUB(DATE_FORMAT(UTC_TIMESTAMP(), "%Y-%m-%d %H:00:00"), INTERVAL 2 HOUR)
+				GROUP BY locationID, typeID, hour
+			ON DUPLICATE KEY UPDATE
+				buyVolume = VALUES(buyVolume), sellVolume = VALUES(sellVolume),
+				buyISK = VALUES(buyISK), sellISK = VALUES(sellISK),
+				trades = VALUES(trades)`); err != nil {
+			log.Println(err)
+		}
+
+		if err := s.doSQL(`DELETE FROM evedata.marketInferredTrades WHERE time < DATE_SUB(UTC_TIMESTAMP(), INTERVAL 30 DAY)`); err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package marketwatch
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antihax/goesi/esi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferTrades(t *testing.T) {
+	now := time.Now().UTC()
+	issued := now.Add(-time.Hour)
+
+	// What is left on the book at Jita 4-4 for Tritanium
+	book := []esi.GetMarketsRegionIdOrders200Ok{
+		{OrderId: 100, LocationId: 60003760, TypeId: 34, Price: 5},
+		{OrderId: 101, LocationId: 60003760, TypeId: 34, Price: 6},
+		{OrderId: 102, LocationId: 60003760, TypeId: 34, Price: 3, IsBuyOrder: true},
+	}
+
+	vanished := func(orderID int64, price float64, isBuyOrder bool) OrderChange {
+		return OrderChange{
+			OrderID: orderID, LocationId: 60003760, TypeID: 34, IsBuyOrder: isBuyOrder,
+			Price: price, VolumeChange: 8, Duration: 90, Issued: issued, Changed: true, TimeChanged: now,
+		}
+	}
+
+	tests := []struct {
+		name      string
+		changes   []OrderChange
+		deletions []OrderChange
+		kind      string
+		quantity  int32
+		remain    int32
+	}{
+		{
+			name:     "partial fill",
+			changes:  []OrderChange{{OrderID: 100, LocationId: 60003760, TypeID: 34, Price: 5, VolumeChange: 5, VolumeRemain: 10, Changed: true, TimeChanged: now}},
+			kind:     "partial",
+			quantity: 5,
+			remain:   10,
+		},
+		{
+			name:    "price change only",
+			changes: []OrderChange{{OrderID: 100, LocationId: 60003760, TypeID: 34, Price: 4, Changed: true, TimeChanged: now}},
+		},
+		{
+			name:      "sell filled below the book",
+			deletions: []OrderChange{vanished(1, 4, false)},
+			kind:      "fill",
+			quantity:  8,
+		},
+		{
+			name:      "sell filled at the best price",
+			deletions: []OrderChange{vanished(1, 5, false)},
+			kind:      "fill",
+			quantity:  8,
+		},
+		{
+			name:      "sell cancelled behind the book",
+			deletions: []OrderChange{vanished(1, 5.5, false)},
+			kind:      "cancel",
+			quantity:  8,
+			remain:    8,
+		},
+		{
+			name:      "buy filled at the best price",
+			deletions: []OrderChange{vanished(1, 3, true)},
+			kind:      "fill",
+			quantity:  8,
+		},
+		{
+			name:      "buy cancelled behind the book",
+			deletions: []OrderChange{vanished(1, 2, true)},
+			kind:      "cancel",
+			quantity:  8,
+			remain:    8,
+		},
+		{
+			name: "last order on its side filled",
+			deletions: []OrderChange{{
+				OrderID: 1, LocationId: 60008494, TypeID: 34, Price: 9, VolumeChange: 1,
+				Duration: 90, Issued: issued, Changed: true, TimeChanged: now,
+			}},
+			kind:     "fill",
+			quantity: 1,
+		},
+		{
+			name: "expiry skipped",
+			deletions: []OrderChange{{
+				OrderID: 1, LocationId: 60003760, TypeID: 34, Price: 1, VolumeChange: 8,
+				Duration: 0, Issued: issued, Changed: true, TimeChanged: now,
+			}},
+		},
+	}
+
+	for _, test := range tests {
+		market := &sync.Map{}
+		for _, o := range book {
+			market.Store(o.OrderId, Order{Touched: now, Order: o})
+		}
+
+		trades := inferTrades(market, test.changes, test.deletions)
+		if test.kind == "" {
+			assert.Len(t, trades, 0, test.name)
+			continue
+		}
+		if assert.Len(t, trades, 1, test.name) {
+			assert.Equal(t, test.kind, trades[0].Kind, test.name)
+			assert.Equal(t, test.quantity, trades[0].Quantity, test.name)
+			assert.Equal(t, test.remain, trades[0].VolumeRemain, test.name)
+			assert.Equal(t, now, trades[0].Time, test.name)
+		}
+	}
+}
+
+func TestMarkPulled(t *testing.T) {
+	s := &MarketWatch{pulls: make(map[int64]time.Time)}
+	start := time.Now()
+
+	// Nothing to compare the first pull with
+	assert.False(t, s.markPulled(10000002, start))
+	assert.True(t, s.markPulled(10000002, start.Add(time.Minute*5)))
+
+	// Changes over a long gap are not trades
+	assert.False(t, s.markPulled(10000002, start.Add(time.Hour)))
+	assert.True(t, s.markPulled(10000002, start.Add(time.Hour+time.Minute*5)))
+}
+
+func TestWithoutWatchedStructures(t *testing.T) {
+	s := &MarketWatch{structures: make(map[int64]*Structure)}
+	s.createStructureState(1022734985679).running = true
+	s.createStructureState(1022734985680)
+
+	changes := []OrderChange{
+		{OrderID: 1, LocationId: 60003760},
+		{OrderID: 2, LocationId: 1022734985679},
+		{OrderID: 3, LocationId: 1022734985680},
+	}
+
+	kept := s.withoutWatchedStructures(changes)
+	if assert.Len(t, kept, 2) {
+		assert.Equal(t, int64(1), kept[0].OrderID)
+		assert.Equal(t, int64(3), kept[1].OrderID)
+	}
+}
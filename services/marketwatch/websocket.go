@@ -0,0 +1,105 @@
+package marketwatch
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	feedWriteWait  = time.Second * 10
+	feedPingPeriod = time.Second * 30
+)
+
+// newUpgrader only accepts browsers on the allowed origins. Other clients do not
+// send an Origin header and are always accepted.
+func newUpgrader(allowedOrigins []string) *websocket.Upgrader {
+	allowed := make(map[string]bool)
+	for _, origin := range allowedOrigins {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			allowed[strings.ToLower(origin)] = true
+		}
+	}
+
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024 * 64,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || allowed[strings.ToLower(origin)]
+		},
+	}
+}
+
+// FeedHandler streams market orders over a websocket. Clients filter with comma separated
+// regions, structures and types query parameters, and contracts=true for contracts.
+// A snapshot of matching orders as "addition" messages is followed by a "snapshot"
+// message, then "addition", "change" and "deletion" deltas as markets are polled along
+// with "trade" messages for fills and cancellations inferred from them.
+// Deltas may repeat orders already in the snapshot so additions should be treated as upserts.
+func (s *MarketWatch) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	sub, err := parseSubscription(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer conn.Close()
+
+	// Start collecting deltas before the snapshot so nothing is missed
+	c := s.feed.subscribe(sub)
+	defer s.feed.unsubscribe(c)
+
+	// Discard anything the client sends, noticing when it goes away
+	gone := make(chan bool)
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(m interface{}) error {
+		conn.SetWriteDeadline(time.Now().Add(feedWriteWait))
+		return conn.WriteJSON(m)
+	}
+
+	if err := s.dumpMarket(sub, send); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(feedPingPeriod)
+	defer ping.Stop()
+
+	for {
+		select {
+		case m, ok := <-c.send:
+			if !ok {
+				// Dropped for falling behind
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"),
+					time.Now().Add(feedWriteWait))
+				return
+			}
+			if err := send(m); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(feedWriteWait)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package marketwatch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpgraderCheckOrigin(t *testing.T) {
+	upgrader := newUpgrader([]string{"https://www.evedata.org", " https://evedata.org ", ""})
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"", true}, // not a browser
+		{"https://www.evedata.org", true},
+		{"HTTPS://WWW.EVEDATA.ORG", true},
+		{"https://evedata.org", true},
+		{"http://www.evedata.org", false},
+		{"https://evil.example.com", false},
+		{"null", false},
+	}
+
+	for _, test := range tests {
+		r, _ := http.NewRequest("GET", "http://marketwatch:3000/feed", nil)
+		if test.origin != "" {
+			r.Header.Set("Origin", test.origin)
+		}
+		assert.Equal(t, test.allowed, upgrader.CheckOrigin(r), test.origin)
+	}
+
+	// Only non browser clients without an allow list
+	r, _ := http.NewRequest("GET", "http://marketwatch:3000/feed", nil)
+	r.Header.Set("Origin", "https://www.evedata.org")
+	assert.False(t, newUpgrader(nil).CheckOrigin(r))
+}
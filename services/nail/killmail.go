@@ -20,6 +20,11 @@ func spawnKillmailConsumer(s *Nail, consumer *nsq.Consumer) {
 	consumer.AddConcurrentHandlers(s.wait(nsq.HandlerFunc(s.killmailHandler)), 25)
 }
 
+// KillmailHandler processes killmail messages without NSQ, for replays
+func (s *Nail) KillmailHandler() nsq.Handler {
+	return nsq.HandlerFunc(s.killmailHandler)
+}
+
 func (s *Nail) killmailHandler(message *nsq.Message) error {
 	killmail := datapackages.Killmail{}
 	err := gobcoder.GobDecoder(message.Body, &killmail)
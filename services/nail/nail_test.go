@@ -8,6 +8,7 @@ import (
 
 	"github.com/antihax/evedata/internal/datapackages"
 	"github.com/antihax/evedata/internal/gobcoder"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/nsqhelper"
 	"github.com/antihax/evedata/internal/redigohelper"
 	"github.com/antihax/evedata/internal/redisqueue"
@@ -19,22 +20,22 @@ import (
 
 var (
 	testWork = []redisqueue.Work{
-		{Operation: "structure", Parameter: int64(1000000017013)},
-		{Operation: "structure", Parameter: int64(1000000025062)},
-		{Operation: "killmail", Parameter: []interface{}{"FAKEHASH", int32(56271)}},
-		{Operation: "marketHistoryTrigger", Parameter: int32(1)},
-		{Operation: "marketHistory", Parameter: []int32{1, 1}},
-		{Operation: "war", Parameter: int32(1)},
-		{Operation: "alliance", Parameter: int32(1)},
-		{Operation: "corporation", Parameter: int32(1)},
-		{Operation: "character", Parameter: int32(1)},
-		{Operation: "characterWalletTransactions", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterWalletJournal", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterAssets", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "loyaltyStore", Parameter: int32(1000001)},
-		{Operation: "characterNotifications", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "characterAuthOwner", Parameter: []int32{int32(1), int32(1)}},
-		{Operation: "charSearch", Parameter: "SomeDOtherude"},
+		hammerwork.StructureWork(1000000017013),
+		hammerwork.StructureWork(1000000025062),
+		hammerwork.KillmailWork("FAKEHASH", 56271),
+		hammerwork.MarketHistoryTriggerWork(),
+		hammerwork.MarketHistoryWork(1, 1),
+		hammerwork.WarWork(1),
+		hammerwork.AllianceWork(1),
+		hammerwork.CorporationWork(1),
+		hammerwork.CharacterWork(1),
+		hammerwork.CharacterWalletTransactionsWork(1, 1),
+		hammerwork.CharacterWalletJournalWork(1, 1),
+		hammerwork.CharacterAssetsWork(1, 1),
+		hammerwork.LoyaltyStoreWork(1000001),
+		hammerwork.CharacterNotificationsWork(1, 1),
+		hammerwork.CharacterAuthOwnerWork(1, 1),
+		hammerwork.CharSearchWork("SomeDOtherude"),
 	}
 	ham          *hammer.Hammer
 	nailInstance *Nail
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/goesi/esi"
 )
@@ -28,7 +29,7 @@ func init() {
 		work := []redisqueue.Work{}
 		for _, corp := range corporations {
 			// Queue for corp data collection
-			work = append(work, redisqueue.Work{Operation: "corporation", Parameter: corp})
+			work = append(work, hammerwork.CorporationWork(corp))
 
 			// Get corporation loyalty store
 			wg.Add(1)
@@ -0,0 +1,974 @@
+package squirrel
+
+import (
+	"archive/zip"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/antihax/evedata/internal/sqlhelper"
+	"github.com/jmoiron/sqlx"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SDE importing loads CCP's static data export YAML archive (sde.zip) into the
+// eve tables without network access. Fuzzwork's SQL conversion of the same data
+// can be loaded with the mysql client directly.
+
+// sdeTable is the rows of an eve table read from the export
+type sdeTable struct {
+	name    string
+	columns []string // Primary key columns first
+	keys    int
+	prune   bool // Delete rows no longer in the export
+	rows    [][]interface{}
+}
+
+// TableDiff counts how an export differs from a table
+type TableDiff struct {
+	Added     int
+	Changed   int
+	Removed   int
+	Unchanged int
+}
+
+// AttributeChange is a type attribute added, changed or removed by the export
+type AttributeChange struct {
+	TypeID      int32
+	AttributeID int32
+	Old         *float64
+	New         *float64
+}
+
+// SDEReport summarizes an import
+type SDEReport struct {
+	Tables     map[string]*TableDiff
+	Attributes []AttributeChange
+}
+
+func (r *SDEReport) String() string {
+	lines := []string{}
+	names := []string{}
+	for name := range r.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d := r.Tables[name]
+		lines = append(lines, fmt.Sprintf("%s: %d added, %d changed, %d removed, %d unchanged",
+			name, d.Added, d.Changed, d.Removed, d.Unchanged))
+	}
+
+	for _, a := range r.Attributes {
+		lines = append(lines, fmt.Sprintf("type %d attribute %d: %s -> %s",
+			a.TypeID, a.AttributeID, formatAttribute(a.Old), formatAttribute(a.New)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatAttribute(v *float64) string {
+	if v == nil {
+		return "none"
+	}
+	return strconv.FormatFloat(*v, 'g', -1, 64)
+}
+
+// ImportSDE loads the export at path into the eve tables, writing only rows
+// which differ, and reports what changed. The import is one transaction so a
+// failure leaves the tables as they were.
+func ImportSDE(db *sqlx.DB, path string) (*SDEReport, error) {
+	tables, err := readSDE(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	report := &SDEReport{Tables: make(map[string]*TableDiff)}
+	for _, t := range tables {
+		existing, err := loadExisting(tx, t)
+		if err != nil {
+			return nil, err
+		}
+
+		diff := diffTable(t, existing)
+		report.Tables[t.name] = &diff.TableDiff
+		if t.name == "eve.dgmTypeAttributes" {
+			report.Attributes = attributeChanges(diff)
+		}
+
+		if err := writeDiff(tx, t, diff); err != nil {
+			return nil, err
+		}
+		log.Printf("imported %s\n", t.name)
+	}
+
+	if err := sqlhelper.RetryTransaction(tx); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// localized text in the export keyed by language
+type localized map[string]string
+
+func (l localized) english() string {
+	return l["en"]
+}
+
+type sdeCategory struct {
+	Name      localized `yaml:"name"`
+	IconID    *int32    `yaml:"iconID"`
+	Published bool      `yaml:"published"`
+}
+
+type sdeGroup struct {
+	CategoryID           int32     `yaml:"categoryID"`
+	Name                 localized `yaml:"name"`
+	IconID               *int32    `yaml:"iconID"`
+	UseBasePrice         bool      `yaml:"useBasePrice"`
+	Anchored             bool      `yaml:"anchored"`
+	Anchorable           bool      `yaml:"anchorable"`
+	FittableNonSingleton bool      `yaml:"fittableNonSingleton"`
+	Published            bool      `yaml:"published"`
+}
+
+type sdeType struct {
+	GroupID       int32     `yaml:"groupID"`
+	Name          localized `yaml:"name"`
+	Description   localized `yaml:"description"`
+	Mass          float64   `yaml:"mass"`
+	Volume        float64   `yaml:"volume"`
+	Capacity      float64   `yaml:"capacity"`
+	PortionSize   int32     `yaml:"portionSize"`
+	RaceID        *int32    `yaml:"raceID"`
+	BasePrice     *float64  `yaml:"basePrice"`
+	Published     bool      `yaml:"published"`
+	MarketGroupID *int32    `yaml:"marketGroupID"`
+	IconID        *int32    `yaml:"iconID"`
+	SoundID       *int32    `yaml:"soundID"`
+	GraphicID     *int32    `yaml:"graphicID"`
+}
+
+type sdeName struct {
+	ItemID   int64  `yaml:"itemID"`
+	ItemName string `yaml:"itemName"`
+}
+
+type sdeRace struct {
+	RaceID           int32  `yaml:"raceID"`
+	RaceName         string `yaml:"raceName"`
+	Description      string `yaml:"description"`
+	IconID           *int32 `yaml:"iconID"`
+	ShortDescription string `yaml:"shortDescription"`
+}
+
+type sdeTypeAttribute struct {
+	TypeID      int32    `yaml:"typeID"`
+	AttributeID int32    `yaml:"attributeID"`
+	ValueInt    *int64   `yaml:"valueInt"`
+	ValueFloat  *float64 `yaml:"valueFloat"`
+}
+
+type sdeMarketGroup struct {
+	Name          localized `yaml:"nameID"`
+	Description   localized `yaml:"descriptionID"`
+	IconID        *int32    `yaml:"iconID"`
+	ParentGroupID *int32    `yaml:"parentGroupID"`
+	HasTypes      bool      `yaml:"hasTypes"`
+}
+
+type sdeAttributeType struct {
+	AttributeID   int32    `yaml:"attributeID"`
+	AttributeName string   `yaml:"attributeName"`
+	Description   *string  `yaml:"description"`
+	IconID        *int32   `yaml:"iconID"`
+	DefaultValue  *float64 `yaml:"defaultValue"`
+	Published     bool     `yaml:"published"`
+	DisplayName   *string  `yaml:"displayName"`
+	UnitID        *int32   `yaml:"unitID"`
+	Stackable     bool     `yaml:"stackable"`
+	HighIsGood    bool     `yaml:"highIsGood"`
+	CategoryID    *int32   `yaml:"categoryID"`
+}
+
+type sdeEffect struct {
+	EffectID                       int32       `yaml:"effectID"`
+	EffectName                     string      `yaml:"effectName"`
+	EffectCategory                 int32       `yaml:"effectCategory"`
+	PreExpression                  *int32      `yaml:"preExpression"`
+	PostExpression                 *int32      `yaml:"postExpression"`
+	Description                    *string     `yaml:"description"`
+	GUID                           *string     `yaml:"guid"`
+	IconID                         *int32      `yaml:"iconID"`
+	IsOffensive                    bool        `yaml:"isOffensive"`
+	IsAssistance                   bool        `yaml:"isAssistance"`
+	DurationAttributeID            *int32      `yaml:"durationAttributeID"`
+	TrackingSpeedAttributeID       *int32      `yaml:"trackingSpeedAttributeID"`
+	DischargeAttributeID           *int32      `yaml:"dischargeAttributeID"`
+	RangeAttributeID               *int32      `yaml:"rangeAttributeID"`
+	FalloffAttributeID             *int32      `yaml:"falloffAttributeID"`
+	DisallowAutoRepeat             bool        `yaml:"disallowAutoRepeat"`
+	Published                      bool        `yaml:"published"`
+	DisplayName                    *string     `yaml:"displayName"`
+	IsWarpSafe                     bool        `yaml:"isWarpSafe"`
+	RangeChance                    bool        `yaml:"rangeChance"`
+	ElectronicChance               bool        `yaml:"electronicChance"`
+	PropulsionChance               bool        `yaml:"propulsionChance"`
+	Distribution                   *int32      `yaml:"distribution"`
+	SFXName                        *string     `yaml:"sfxName"`
+	NPCUsageChanceAttributeID      *int32      `yaml:"npcUsageChanceAttributeID"`
+	NPCActivationChanceAttributeID *int32      `yaml:"npcActivationChanceAttributeID"`
+	FittingUsageChanceAttributeID  *int32      `yaml:"fittingUsageChanceAttributeID"`
+	ModifierInfo                   interface{} `yaml:"modifierInfo"`
+}
+
+type sdeTypeEffect struct {
+	TypeID    int32 `yaml:"typeID"`
+	EffectID  int32 `yaml:"effectID"`
+	IsDefault bool  `yaml:"isDefault"`
+}
+
+type sdeStation struct {
+	StationID                int64   `yaml:"stationID"`
+	Security                 float64 `yaml:"security"`
+	DockingCostPerVolume     float64 `yaml:"dockingCostPerVolume"`
+	MaxShipVolumeDockable    float64 `yaml:"maxShipVolumeDockable"`
+	OfficeRentalCost         int64   `yaml:"officeRentalCost"`
+	OperationID              *int32  `yaml:"operationID"`
+	StationTypeID            int32   `yaml:"stationTypeID"`
+	CorporationID            int32   `yaml:"corporationID"`
+	SolarSystemID            int32   `yaml:"solarSystemID"`
+	ConstellationID          int32   `yaml:"constellationID"`
+	RegionID                 int32   `yaml:"regionID"`
+	StationName              string  `yaml:"stationName"`
+	X                        float64 `yaml:"x"`
+	Y                        float64 `yaml:"y"`
+	Z                        float64 `yaml:"z"`
+	ReprocessingEfficiency   float64 `yaml:"reprocessingEfficiency"`
+	ReprocessingStationsTake float64 `yaml:"reprocessingStationsTake"`
+	ReprocessingHangarFlag   int32   `yaml:"reprocessingHangarFlag"`
+}
+
+type sdeRegion struct {
+	RegionID int32     `yaml:"regionID"`
+	Center   []float64 `yaml:"center"`
+}
+
+type sdeConstellation struct {
+	ConstellationID int32     `yaml:"constellationID"`
+	Center          []float64 `yaml:"center"`
+}
+
+type sdeSolarSystem struct {
+	SolarSystemID int32                  `yaml:"solarSystemID"`
+	Security      float64                `yaml:"security"`
+	SecurityClass string                 `yaml:"securityClass"`
+	Center        []float64              `yaml:"center"`
+	Star          *sdeStar               `yaml:"star"`
+	Planets       map[int64]sdeCelestial `yaml:"planets"`
+	Stargates     map[int64]sdeCelestial `yaml:"stargates"`
+}
+
+type sdeStar struct {
+	ID     int64    `yaml:"id"`
+	TypeID int32    `yaml:"typeID"`
+	Radius *float64 `yaml:"radius"`
+}
+
+// sdeCelestial is anything placed in a solar system
+type sdeCelestial struct {
+	TypeID         int32                  `yaml:"typeID"`
+	Position       []float64              `yaml:"position"`
+	Radius         *float64               `yaml:"radius"`
+	CelestialIndex *int32                 `yaml:"celestialIndex"`
+	Moons          map[int64]sdeCelestial `yaml:"moons"`
+	AsteroidBelts  map[int64]sdeCelestial `yaml:"asteroidBelts"`
+	NPCStations    map[int64]sdeCelestial `yaml:"npcStations"`
+	Destination    int64                  `yaml:"destination"` // Stargates
+}
+
+// nullable turns missing optional values into NULL
+func nullable(v interface{}) interface{} {
+	switch p := v.(type) {
+	case *int32:
+		if p != nil {
+			return *p
+		}
+	case *int64:
+		if p != nil {
+			return *p
+		}
+	case *float64:
+		if p != nil {
+			return *p
+		}
+	case *string:
+		if p != nil {
+			return *p
+		}
+	}
+	return nil
+}
+
+// position splits coordinates into x, y and z, or NULLs when missing
+func position(p []float64) []interface{} {
+	if len(p) != 3 {
+		return []interface{}{nil, nil, nil}
+	}
+	return []interface{}{p[0], p[1], p[2]}
+}
+
+// sortedIDs orders the IDs of a map of celestials
+func sortedIDs(m map[int64]sdeCelestial) []int64 {
+	ids := []int64{}
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// sdeArchive indexes the files of the export, ignoring the top level sde directory
+type sdeArchive struct {
+	files map[string]*zip.File
+}
+
+func (a *sdeArchive) decode(name string, v interface{}) error {
+	f, ok := a.files[name]
+	if !ok {
+		return fmt.Errorf("%s is missing from the export", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	return nil
+}
+
+// readSDE reads every table we import from the export
+func readSDE(file string) ([]*sdeTable, error) {
+	z, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Close()
+
+	a := &sdeArchive{files: make(map[string]*zip.File)}
+	for _, f := range z.File {
+		a.files[strings.TrimPrefix(f.Name, "sde/")] = f
+	}
+
+	categories := make(map[int32]sdeCategory)
+	if err := a.decode("fsd/categoryIDs.yaml", &categories); err != nil {
+		return nil, err
+	}
+	categoryTable := &sdeTable{name: "eve.invCategories", keys: 1,
+		columns: []string{"categoryID", "categoryName", "iconID", "published"}}
+	for id, c := range categories {
+		categoryTable.rows = append(categoryTable.rows, []interface{}{id, c.Name.english(), nullable(c.IconID), c.Published})
+	}
+
+	groups := make(map[int32]sdeGroup)
+	if err := a.decode("fsd/groupIDs.yaml", &groups); err != nil {
+		return nil, err
+	}
+	groupTable := &sdeTable{name: "eve.invGroups", keys: 1,
+		columns: []string{"groupID", "categoryID", "groupName", "iconID", "useBasePrice",
+			"anchored", "anchorable", "fittableNonSingleton", "published"}}
+	for id, g := range groups {
+		groupTable.rows = append(groupTable.rows, []interface{}{id, g.CategoryID, g.Name.english(), nullable(g.IconID),
+			g.UseBasePrice, g.Anchored, g.Anchorable, g.FittableNonSingleton, g.Published})
+	}
+
+	types := make(map[int32]sdeType)
+	if err := a.decode("fsd/typeIDs.yaml", &types); err != nil {
+		return nil, err
+	}
+	typeTable := &sdeTable{name: "eve.invTypes", keys: 1,
+		columns: []string{"typeID", "groupID", "typeName", "description", "mass", "volume", "capacity",
+			"portionSize", "raceID", "basePrice", "published", "marketGroupID", "iconID", "soundID", "graphicID"}}
+	for id, t := range types {
+		typeTable.rows = append(typeTable.rows, []interface{}{id, t.GroupID, t.Name.english(), t.Description.english(),
+			t.Mass, t.Volume, t.Capacity, t.PortionSize, nullable(t.RaceID), nullable(t.BasePrice), t.Published,
+			nullable(t.MarketGroupID), nullable(t.IconID), nullable(t.SoundID), nullable(t.GraphicID)})
+	}
+
+	names := []sdeName{}
+	if err := a.decode("bsd/invNames.yaml", &names); err != nil {
+		return nil, err
+	}
+	nameTable := &sdeTable{name: "eve.invNames", keys: 1, columns: []string{"itemID", "itemName"}}
+	nameMap := make(map[int64]string)
+	for _, n := range names {
+		nameTable.rows = append(nameTable.rows, []interface{}{n.ItemID, n.ItemName})
+		nameMap[n.ItemID] = n.ItemName
+	}
+
+	races := []sdeRace{}
+	if err := a.decode("bsd/chrRaces.yaml", &races); err != nil {
+		return nil, err
+	}
+	raceTable := &sdeTable{name: "eve.chrRaces", keys: 1,
+		columns: []string{"raceID", "raceName", "description", "iconID", "shortDescription"}}
+	for _, r := range races {
+		raceTable.rows = append(raceTable.rows, []interface{}{r.RaceID, r.RaceName, r.Description, nullable(r.IconID), r.ShortDescription})
+	}
+
+	attributes := []sdeTypeAttribute{}
+	if err := a.decode("bsd/dgmTypeAttributes.yaml", &attributes); err != nil {
+		return nil, err
+	}
+	attributeTable := &sdeTable{name: "eve.dgmTypeAttributes", keys: 2, prune: true,
+		columns: []string{"typeID", "attributeID", "valueInt", "valueFloat"}}
+	for _, t := range attributes {
+		attributeTable.rows = append(attributeTable.rows, []interface{}{t.TypeID, t.AttributeID, nullable(t.ValueInt), nullable(t.ValueFloat)})
+	}
+
+	marketGroupTable, err := readMarketGroups(a)
+	if err != nil {
+		return nil, err
+	}
+
+	dogmaTables, err := readDogma(a)
+	if err != nil {
+		return nil, err
+	}
+
+	stationTable, err := readStations(a)
+	if err != nil {
+		return nil, err
+	}
+
+	universeTables, err := readUniverse(a, nameMap, types)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := []*sdeTable{
+		categoryTable, groupTable, typeTable, nameTable, raceTable, attributeTable, marketGroupTable,
+	}
+	tables = append(tables, dogmaTables...)
+	tables = append(tables, universeTables...)
+	return append(tables, stationTable), nil
+}
+
+// readMarketGroups reads the market group tree
+func readMarketGroups(a *sdeArchive) (*sdeTable, error) {
+	groups := make(map[int32]sdeMarketGroup)
+	if err := a.decode("fsd/marketGroups.yaml", &groups); err != nil {
+		return nil, err
+	}
+	table := &sdeTable{name: "eve.invMarketGroups", keys: 1,
+		columns: []string{"marketGroupID", "parentGroupID", "marketGroupName", "description", "iconID", "hasTypes"}}
+	for id, g := range groups {
+		table.rows = append(table.rows, []interface{}{id, nullable(g.ParentGroupID), g.Name.english(),
+			g.Description.english(), nullable(g.IconID), g.HasTypes})
+	}
+	return table, nil
+}
+
+// readDogma reads the attribute and effect definitions, and the effects of each type
+func readDogma(a *sdeArchive) ([]*sdeTable, error) {
+	attributes := []sdeAttributeType{}
+	if err := a.decode("bsd/dgmAttributeTypes.yaml", &attributes); err != nil {
+		return nil, err
+	}
+	attributeTable := &sdeTable{name: "eve.dgmAttributeTypes", keys: 1,
+		columns: []string{"attributeID", "attributeName", "description", "iconID", "defaultValue",
+			"published", "displayName", "unitID", "stackable", "highIsGood", "categoryID"}}
+	for _, t := range attributes {
+		attributeTable.rows = append(attributeTable.rows, []interface{}{t.AttributeID, t.AttributeName,
+			nullable(t.Description), nullable(t.IconID), nullable(t.DefaultValue), t.Published, nullable(t.DisplayName),
+			nullable(t.UnitID), t.Stackable, t.HighIsGood, nullable(t.CategoryID)})
+	}
+
+	effects := []sdeEffect{}
+	if err := a.decode("bsd/dgmEffects.yaml", &effects); err != nil {
+		return nil, err
+	}
+	effectTable := &sdeTable{name: "eve.dgmEffects", keys: 1,
+		columns: []string{"effectID", "effectName", "effectCategory", "preExpression", "postExpression",
+			"description", "guid", "iconID", "isOffensive", "isAssistance", "durationAttributeID",
+			"trackingSpeedAttributeID", "dischargeAttributeID", "rangeAttributeID", "falloffAttributeID",
+			"disallowAutoRepeat", "published", "displayName", "isWarpSafe", "rangeChance", "electronicChance",
+			"propulsionChance", "distribution", "sfxName", "npcUsageChanceAttributeID",
+			"npcActivationChanceAttributeID", "fittingUsageChanceAttributeID", "modifierInfo"}}
+	for _, e := range effects {
+		modifierInfo, err := modifierInfoText(e.ModifierInfo)
+		if err != nil {
+			return nil, fmt.Errorf("effect %d: %v", e.EffectID, err)
+		}
+		effectTable.rows = append(effectTable.rows, []interface{}{e.EffectID, e.EffectName, e.EffectCategory,
+			nullable(e.PreExpression), nullable(e.PostExpression), nullable(e.Description), nullable(e.GUID),
+			nullable(e.IconID), e.IsOffensive, e.IsAssistance, nullable(e.DurationAttributeID),
+			nullable(e.TrackingSpeedAttributeID), nullable(e.DischargeAttributeID), nullable(e.RangeAttributeID),
+			nullable(e.FalloffAttributeID), e.DisallowAutoRepeat, e.Published, nullable(e.DisplayName),
+			e.IsWarpSafe, e.RangeChance, e.ElectronicChance, e.PropulsionChance, nullable(e.Distribution),
+			nullable(e.SFXName), nullable(e.NPCUsageChanceAttributeID), nullable(e.NPCActivationChanceAttributeID),
+			nullable(e.FittingUsageChanceAttributeID), modifierInfo})
+	}
+
+	typeEffects := []sdeTypeEffect{}
+	if err := a.decode("bsd/dgmTypeEffects.yaml", &typeEffects); err != nil {
+		return nil, err
+	}
+	typeEffectTable := &sdeTable{name: "eve.dgmTypeEffects", keys: 2, prune: true,
+		columns: []string{"typeID", "effectID", "isDefault"}}
+	for _, t := range typeEffects {
+		typeEffectTable.rows = append(typeEffectTable.rows, []interface{}{t.TypeID, t.EffectID, t.IsDefault})
+	}
+
+	return []*sdeTable{attributeTable, effectTable, typeEffectTable}, nil
+}
+
+// modifierInfoText stores an effect's modifiers as the YAML text the dogma package reads
+func modifierInfoText(modifiers interface{}) (interface{}, error) {
+	switch m := modifiers.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return m, nil
+	}
+	b, err := yaml.Marshal(modifiers)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// readStations reads NPC stations. Player structures share the table so it is never pruned.
+func readStations(a *sdeArchive) (*sdeTable, error) {
+	stations := []sdeStation{}
+	if err := a.decode("bsd/staStations.yaml", &stations); err != nil {
+		return nil, err
+	}
+	table := &sdeTable{name: "eve.staStations", keys: 1,
+		columns: []string{"stationID", "security", "dockingCostPerVolume", "maxShipVolumeDockable",
+			"officeRentalCost", "operationID", "stationTypeID", "corporationID", "solarSystemID",
+			"constellationID", "regionID", "stationName", "x", "y", "z", "reprocessingEfficiency",
+			"reprocessingStationsTake", "reprocessingHangarFlag"}}
+	for _, s := range stations {
+		table.rows = append(table.rows, []interface{}{s.StationID, s.Security, s.DockingCostPerVolume,
+			s.MaxShipVolumeDockable, s.OfficeRentalCost, nullable(s.OperationID), s.StationTypeID, s.CorporationID,
+			s.SolarSystemID, s.ConstellationID, s.RegionID, s.StationName, s.X, s.Y, s.Z,
+			s.ReprocessingEfficiency, s.ReprocessingStationsTake, s.ReprocessingHangarFlag})
+	}
+	return table, nil
+}
+
+// universe collects the map tables while walking the universe
+type universe struct {
+	names map[int64]string
+	types map[int32]sdeType
+
+	regions, constellations, systems, denormalize, jumps *sdeTable
+}
+
+// addItem adds an item to mapDenormalize. Regions, constellations and solar
+// systems have NULL for the locations above them.
+func (u *universe) addItem(itemID int64, typeID int32, location []interface{}, orbitID interface{},
+	position []interface{}, radius, security, celestialIndex, orbitIndex interface{}) {
+	var groupID interface{}
+	if t, ok := u.types[typeID]; ok {
+		groupID = t.GroupID
+	}
+	row := []interface{}{itemID, typeID, groupID}
+	row = append(row, location...)
+	row = append(row, orbitID)
+	row = append(row, position...)
+	row = append(row, radius, u.names[itemID], security, celestialIndex, orbitIndex)
+	u.denormalize.rows = append(u.denormalize.rows, row)
+}
+
+// readUniverse walks fsd/universe/<space>/<region>/<constellation>/<system> for the map tables
+func readUniverse(a *sdeArchive, names map[int64]string, types map[int32]sdeType) ([]*sdeTable, error) {
+	u := &universe{
+		names:   names,
+		types:   types,
+		regions: &sdeTable{name: "eve.mapRegions", keys: 1, columns: []string{"regionID", "regionName"}},
+		constellations: &sdeTable{name: "eve.mapConstellations", keys: 1,
+			columns: []string{"constellationID", "regionID", "constellationName"}},
+		systems: &sdeTable{name: "eve.mapSolarSystems", keys: 1,
+			columns: []string{"solarSystemID", "regionID", "constellationID", "solarSystemName",
+				"security", "securityClass", "x", "y", "z"}},
+		denormalize: &sdeTable{name: "eve.mapDenormalize", keys: 1,
+			columns: []string{"itemID", "typeID", "groupID", "solarSystemID", "constellationID", "regionID",
+				"orbitID", "x", "y", "z", "radius", "itemName", "security", "celestialIndex", "orbitIndex"}},
+		jumps: &sdeTable{name: "eve.mapSolarSystemJumps", keys: 2, prune: true,
+			columns: []string{"fromSolarSystemID", "toSolarSystemID", "fromRegionID", "fromConstellationID",
+				"toConstellationID", "toRegionID"}},
+	}
+
+	// Sort by depth so parents are read before their children
+	files := []string{}
+	for name := range a.files {
+		if strings.HasPrefix(name, "fsd/universe/") && strings.HasSuffix(name, ".staticdata") {
+			files = append(files, name)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		di, dj := strings.Count(files[i], "/"), strings.Count(files[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return files[i] < files[j]
+	})
+
+	regions := make(map[string]int32)
+	constellations := make(map[string]int32)
+
+	// Stargates are joined up once every system is read
+	type gate struct {
+		systemID, constellationID, regionID int32
+		destination                         int64
+	}
+	gates := make(map[int64]gate)
+
+	for _, name := range files {
+		dir := path.Dir(name)
+		switch path.Base(name) {
+		case "region.staticdata":
+			r := sdeRegion{}
+			if err := a.decode(name, &r); err != nil {
+				return nil, err
+			}
+			regions[dir] = r.RegionID
+			u.regions.rows = append(u.regions.rows, []interface{}{r.RegionID, names[int64(r.RegionID)]})
+			u.addItem(int64(r.RegionID), 3, []interface{}{nil, nil, nil}, nil, position(r.Center), nil, nil, nil, nil)
+
+		case "constellation.staticdata":
+			c := sdeConstellation{}
+			if err := a.decode(name, &c); err != nil {
+				return nil, err
+			}
+			regionID := regions[path.Dir(dir)]
+			constellations[dir] = c.ConstellationID
+			u.constellations.rows = append(u.constellations.rows, []interface{}{
+				c.ConstellationID, regionID, names[int64(c.ConstellationID)]})
+			u.addItem(int64(c.ConstellationID), 4, []interface{}{nil, nil, regionID}, nil, position(c.Center), nil, nil, nil, nil)
+
+		case "solarsystem.staticdata":
+			s := sdeSolarSystem{}
+			if err := a.decode(name, &s); err != nil {
+				return nil, err
+			}
+			if len(s.Center) != 3 {
+				return nil, fmt.Errorf("%s: center must have three coordinates", name)
+			}
+			constellation := path.Dir(dir)
+			regionID, constellationID := regions[path.Dir(constellation)], constellations[constellation]
+			u.systems.rows = append(u.systems.rows, []interface{}{
+				s.SolarSystemID, regionID, constellationID,
+				names[int64(s.SolarSystemID)], s.Security, s.SecurityClass, s.Center[0], s.Center[1], s.Center[2]})
+			u.addItem(int64(s.SolarSystemID), 5, []interface{}{nil, constellationID, regionID}, nil,
+				position(s.Center), nil, s.Security, nil, nil)
+
+			u.solarSystem(&s, []interface{}{s.SolarSystemID, constellationID, regionID})
+			for id, g := range s.Stargates {
+				gates[id] = gate{s.SolarSystemID, constellationID, regionID, g.Destination}
+			}
+		}
+	}
+
+	for _, g := range gates {
+		to, ok := gates[g.destination]
+		if !ok {
+			continue
+		}
+		u.jumps.rows = append(u.jumps.rows, []interface{}{
+			g.systemID, to.systemID, g.regionID, g.constellationID, to.constellationID, to.regionID})
+	}
+
+	return []*sdeTable{u.regions, u.constellations, u.systems, u.denormalize, u.jumps}, nil
+}
+
+// solarSystem adds the star, planets, moons, belts, stations and stargates of a system
+// to mapDenormalize. Moons and belts are numbered around their planet in ID order.
+func (u *universe) solarSystem(s *sdeSolarSystem, location []interface{}) {
+	var starID interface{}
+	if s.Star != nil {
+		starID = s.Star.ID
+		// Coordinates within a system are relative to its star
+		u.addItem(s.Star.ID, s.Star.TypeID, location, nil, position([]float64{0, 0, 0}), nullable(s.Star.Radius), s.Security, nil, nil)
+	}
+
+	stations := func(orbitID int64, c sdeCelestial, celestialIndex interface{}) {
+		for _, id := range sortedIDs(c.NPCStations) {
+			station := c.NPCStations[id]
+			u.addItem(id, station.TypeID, location, orbitID, position(station.Position),
+				nullable(station.Radius), s.Security, celestialIndex, nil)
+		}
+	}
+
+	for _, planetID := range sortedIDs(s.Planets) {
+		planet := s.Planets[planetID]
+		celestialIndex := nullable(planet.CelestialIndex)
+		u.addItem(planetID, planet.TypeID, location, starID, position(planet.Position),
+			nullable(planet.Radius), s.Security, celestialIndex, nil)
+		stations(planetID, planet, celestialIndex)
+
+		for i, moonID := range sortedIDs(planet.Moons) {
+			moon := planet.Moons[moonID]
+			u.addItem(moonID, moon.TypeID, location, planetID, position(moon.Position),
+				nullable(moon.Radius), s.Security, celestialIndex, int32(i+1))
+			stations(moonID, moon, celestialIndex)
+		}
+		for i, beltID := range sortedIDs(planet.AsteroidBelts) {
+			belt := planet.AsteroidBelts[beltID]
+			u.addItem(beltID, belt.TypeID, location, planetID, position(belt.Position),
+				nullable(belt.Radius), s.Security, celestialIndex, int32(i+1))
+		}
+	}
+
+	for _, gateID := range sortedIDs(s.Stargates) {
+		gate := s.Stargates[gateID]
+		u.addItem(gateID, gate.TypeID, location, nil, position(gate.Position), nullable(gate.Radius), s.Security, nil, nil)
+	}
+}
+
+// existingRow is a row currently in the database
+type existingRow []sql.NullString
+
+// rowKey makes a map key of the primary key values
+func rowKey(values []interface{}) string {
+	key := make([]string, len(values))
+	for i, v := range values {
+		key[i] = fmt.Sprint(v)
+	}
+	return strings.Join(key, "/")
+}
+
+// loadExisting reads the current rows of a table keyed by primary key
+func loadExisting(tx *sqlx.Tx, t *sdeTable) (map[string]existingRow, error) {
+	rows, err := tx.Query("SELECT " + strings.Join(t.columns, ", ") + " FROM " + t.name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[string]existingRow)
+	for rows.Next() {
+		row := make(existingRow, len(t.columns))
+		dest := make([]interface{}, len(row))
+		for i := range row {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		key := make([]interface{}, t.keys)
+		for i := range key {
+			key[i] = row[i].String
+		}
+		existing[rowKey(key)] = row
+	}
+	return existing, rows.Err()
+}
+
+// sameValue compares a database value to an export value, numerically where possible
+func sameValue(old sql.NullString, v interface{}) bool {
+	if v == nil || !old.Valid {
+		return v == nil && !old.Valid
+	}
+
+	var n float64
+	switch x := v.(type) {
+	case string:
+		return old.String == x
+	case bool:
+		if x {
+			n = 1
+		}
+	case int32:
+		n = float64(x)
+	case int64:
+		n = float64(x)
+	case float64:
+		n = x
+	default:
+		return old.String == fmt.Sprint(v)
+	}
+
+	o, err := strconv.ParseFloat(old.String, 64)
+	if err != nil {
+		return false
+	}
+	// FLOAT columns only hold single precision
+	return math.Abs(o-n) <= 1e-6*math.Max(math.Abs(o), math.Abs(n))
+}
+
+// rowChange is an export row replacing a different database row
+type rowChange struct {
+	old existingRow
+	new []interface{}
+}
+
+// sdeDiff is how a table needs to change to match the export
+type sdeDiff struct {
+	TableDiff
+	added   [][]interface{}
+	changed []rowChange
+	removed []existingRow
+}
+
+// diffTable compares export rows to the existing rows
+func diffTable(t *sdeTable, existing map[string]existingRow) *sdeDiff {
+	diff := &sdeDiff{}
+	seen := make(map[string]bool)
+
+	for _, row := range t.rows {
+		key := rowKey(row[:t.keys])
+		seen[key] = true
+
+		old, ok := existing[key]
+		if !ok {
+			diff.added = append(diff.added, row)
+			continue
+		}
+
+		same := true
+		for i := t.keys; i < len(row); i++ {
+			if !sameValue(old[i], row[i]) {
+				same = false
+				break
+			}
+		}
+		if same {
+			diff.Unchanged++
+		} else {
+			diff.changed = append(diff.changed, rowChange{old: old, new: row})
+		}
+	}
+
+	for key, old := range existing {
+		if !seen[key] {
+			diff.removed = append(diff.removed, old)
+		}
+	}
+
+	diff.Added, diff.Changed, diff.Removed = len(diff.added), len(diff.changed), len(diff.removed)
+	return diff
+}
+
+// attributeValue is the value of a dgmTypeAttributes row, valueFloat taking precedence
+func attributeValue(valueInt, valueFloat interface{}) *float64 {
+	for _, v := range []interface{}{valueFloat, valueInt} {
+		switch x := v.(type) {
+		case float64:
+			return &x
+		case int64:
+			f := float64(x)
+			return &f
+		case sql.NullString:
+			if x.Valid {
+				if f, err := strconv.ParseFloat(x.String, 64); err == nil {
+					return &f
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func parseID(s sql.NullString) int32 {
+	id, _ := strconv.ParseInt(s.String, 10, 32)
+	return int32(id)
+}
+
+// attributeChanges lists type attribute differences, ordered by type and attribute
+func attributeChanges(diff *sdeDiff) []AttributeChange {
+	changes := []AttributeChange{}
+	for _, row := range diff.added {
+		changes = append(changes, AttributeChange{TypeID: row[0].(int32), AttributeID: row[1].(int32),
+			New: attributeValue(row[2], row[3])})
+	}
+	for _, c := range diff.changed {
+		changes = append(changes, AttributeChange{TypeID: c.new[0].(int32), AttributeID: c.new[1].(int32),
+			Old: attributeValue(c.old[2], c.old[3]), New: attributeValue(c.new[2], c.new[3])})
+	}
+	for _, row := range diff.removed {
+		changes = append(changes, AttributeChange{TypeID: parseID(row[0]), AttributeID: parseID(row[1]),
+			Old: attributeValue(row[2], row[3])})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].TypeID != changes[j].TypeID {
+			return changes[i].TypeID < changes[j].TypeID
+		}
+		return changes[i].AttributeID < changes[j].AttributeID
+	})
+	return changes
+}
+
+// writeDiff upserts added and changed rows, and removes missing rows from pruned tables
+func writeDiff(tx *sqlx.Tx, t *sdeTable, diff *sdeDiff) error {
+	rows := diff.added
+	for _, c := range diff.changed {
+		rows = append(rows, c.new)
+	}
+
+	updates := []string{}
+	for _, c := range t.columns[t.keys:] {
+		updates = append(updates, c+" = VALUES("+c+")")
+	}
+
+	for start := 0; start < len(rows); start += 80 {
+		end := start + 80
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		insert := sq.Insert(t.name).Columns(t.columns...)
+		for _, row := range rows[start:end] {
+			insert = insert.Values(row...)
+		}
+		sqlq, args, err := insert.ToSql()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqlq+" ON DUPLICATE KEY UPDATE "+strings.Join(updates, ", "), args...); err != nil {
+			return err
+		}
+	}
+
+	if !t.prune {
+		return nil
+	}
+	where := []string{}
+	for _, c := range t.columns[:t.keys] {
+		where = append(where, c+" = ?")
+	}
+	for _, row := range diff.removed {
+		args := []interface{}{}
+		for _, v := range row[:t.keys] {
+			args = append(args, v.String)
+		}
+		if _, err := tx.Exec("DELETE FROM "+t.name+" WHERE "+strings.Join(where, " AND "), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
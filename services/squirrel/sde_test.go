@@ -0,0 +1,316 @@
+package squirrel
+
+import (
+	"archive/zip"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testSDE = map[string]string{
+	"sde/fsd/categoryIDs.yaml": `
+6:
+    name:
+        en: Ship
+    published: true
+`,
+	"sde/fsd/groupIDs.yaml": `
+25:
+    categoryID: 6
+    name:
+        en: Frigate
+    published: true
+`,
+	"sde/fsd/typeIDs.yaml": `
+587:
+    groupID: 25
+    mass: 1067000.0
+    name:
+        de: Rifter
+        en: Rifter
+    portionSize: 1
+    published: true
+    raceID: 2
+    volume: 27289.0
+`,
+	"sde/bsd/invNames.yaml": `
+-   itemID: 10000002
+    itemName: The Forge
+-   itemID: 20000020
+    itemName: Kimotoro
+-   itemID: 30000142
+    itemName: Jita
+-   itemID: 30000144
+    itemName: Perimeter
+-   itemID: 40009077
+    itemName: Jita IV
+-   itemID: 40009081
+    itemName: Jita IV - Moon 4
+`,
+	"sde/bsd/chrRaces.yaml": `
+-   raceID: 2
+    raceName: Minmatar
+    shortDescription: Tribal
+`,
+	"sde/bsd/dgmTypeAttributes.yaml": `
+-   attributeID: 37
+    typeID: 587
+    valueFloat: 355.0
+-   attributeID: 4
+    typeID: 587
+    valueInt: 1067000
+`,
+	"sde/fsd/marketGroups.yaml": `
+4:
+    descriptionID:
+        en: Capsuleer spaceships of all sizes and roles.
+    hasTypes: false
+    iconID: 1443
+    nameID:
+        en: Ships
+`,
+	"sde/bsd/dgmAttributeTypes.yaml": `
+-   attributeID: 37
+    attributeName: maxVelocity
+    defaultValue: 0.0
+    displayName: Maximum Velocity
+    highIsGood: true
+    published: true
+    stackable: false
+    unitID: 11
+`,
+	"sde/bsd/dgmEffects.yaml": `
+-   effectCategory: 0
+    effectID: 1230
+    effectName: shipVelocityBonusMF
+    modifierInfo:
+    -   domain: shipID
+        func: ItemModifier
+        modifiedAttributeID: 37
+        modifyingAttributeID: 1
+        operation: 6
+    published: false
+`,
+	"sde/bsd/dgmTypeEffects.yaml": `
+-   effectID: 1230
+    isDefault: false
+    typeID: 587
+`,
+	"sde/bsd/staStations.yaml": `
+-   constellationID: 20000020
+    corporationID: 1000035
+    dockingCostPerVolume: 0.0
+    maxShipVolumeDockable: 50000000.0
+    officeRentalCost: 10000
+    operationID: 26
+    regionID: 10000002
+    reprocessingEfficiency: 0.5
+    reprocessingHangarFlag: 4
+    reprocessingStationsTake: 0.05
+    security: 0.945913
+    solarSystemID: 30000142
+    stationID: 60003760
+    stationName: Jita IV - Moon 4 - Caldari Navy Assembly Plant
+    stationTypeID: 1529
+    x: -107303362560.0
+    y: -18744975360.0
+    z: 436489052160.0
+`,
+	"sde/fsd/universe/eve/TheForge/region.staticdata": `
+regionID: 10000002
+`,
+	"sde/fsd/universe/eve/TheForge/Kimotoro/constellation.staticdata": `
+constellationID: 20000020
+`,
+	"sde/fsd/universe/eve/TheForge/Kimotoro/Jita/solarsystem.staticdata": `
+center:
+- -1.29e+17
+- 6.07e+16
+- 1.17e+17
+securityClass: B
+security: 0.945913
+solarSystemID: 30000142
+star:
+    id: 40009076
+    radius: 49000000
+    typeID: 6
+planets:
+    40009077:
+        celestialIndex: 4
+        position:
+        - 1
+        - 2
+        - 3
+        typeID: 13
+        moons:
+            40009080:
+                position:
+                - 4
+                - 5
+                - 6
+                typeID: 14
+            40009081:
+                position:
+                - 7
+                - 8
+                - 9
+                typeID: 14
+                npcStations:
+                    60003760:
+                        position:
+                        - 10
+                        - 11
+                        - 12
+                        typeID: 1529
+        asteroidBelts:
+            40009078:
+                position:
+                - 13
+                - 14
+                - 15
+                typeID: 15
+stargates:
+    50001248:
+        destination: 50001249
+        position:
+        - 16
+        - 17
+        - 18
+        typeID: 3867
+`,
+	"sde/fsd/universe/eve/TheForge/Kimotoro/Perimeter/solarsystem.staticdata": `
+center:
+- -1.29e+17
+- 6.07e+16
+- 1.17e+17
+securityClass: B
+security: 0.95
+solarSystemID: 30000144
+stargates:
+    50001249:
+        destination: 50001248
+        typeID: 3867
+`,
+}
+
+func writeTestSDE(t *testing.T) string {
+	f, err := ioutil.TempFile("", "sde")
+	assert.Nil(t, err)
+	defer f.Close()
+
+	z := zip.NewWriter(f)
+	for name, content := range testSDE {
+		w, err := z.Create(name)
+		assert.Nil(t, err)
+		w.Write([]byte(content))
+	}
+	assert.Nil(t, z.Close())
+	return f.Name()
+}
+
+func findTable(tables []*sdeTable, name string) *sdeTable {
+	for _, t := range tables {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+// findRow finds a row by its first key column
+func findRow(t *sdeTable, key interface{}) []interface{} {
+	for _, row := range t.rows {
+		if row[0] == key {
+			return row
+		}
+	}
+	return nil
+}
+
+func TestReadSDE(t *testing.T) {
+	file := writeTestSDE(t)
+	defer os.Remove(file)
+
+	tables, err := readSDE(file)
+	assert.Nil(t, err)
+	assert.Len(t, tables, 16)
+
+	types := findTable(tables, "eve.invTypes")
+	assert.Equal(t, []interface{}{int32(587), int32(25), "Rifter", "", 1067000.0, 27289.0, 0.0,
+		int32(1), int32(2), nil, true, nil, nil, nil, nil}, types.rows[0])
+
+	systems := findTable(tables, "eve.mapSolarSystems")
+	assert.Equal(t, []interface{}{int32(30000142), int32(10000002), int32(20000020), "Jita",
+		0.945913, "B", -1.29e+17, 6.07e+16, 1.17e+17}, findRow(systems, int32(30000142)))
+
+	constellations := findTable(tables, "eve.mapConstellations")
+	assert.Equal(t, []interface{}{int32(20000020), int32(10000002), "Kimotoro"}, constellations.rows[0])
+
+	marketGroups := findTable(tables, "eve.invMarketGroups")
+	assert.Equal(t, []interface{}{int32(4), nil, "Ships", "Capsuleer spaceships of all sizes and roles.",
+		int32(1443), false}, marketGroups.rows[0])
+
+	effects := findTable(tables, "eve.dgmEffects")
+	modifierInfo := effects.rows[0][len(effects.rows[0])-1].(string)
+	assert.Contains(t, modifierInfo, "modifiedAttributeID: 37")
+
+	stations := findTable(tables, "eve.staStations")
+	assert.False(t, stations.prune)
+	assert.Equal(t, "Jita IV - Moon 4 - Caldari Navy Assembly Plant", stations.rows[0][11])
+
+	denormalize := findTable(tables, "eve.mapDenormalize")
+	assert.Len(t, denormalize.rows, 12)
+	assert.Equal(t, []interface{}{int64(40009081), int32(14), nil, int32(30000142), int32(20000020), int32(10000002),
+		int64(40009077), 7.0, 8.0, 9.0, nil, "Jita IV - Moon 4", 0.945913, int32(4), int32(2)},
+		findRow(denormalize, int64(40009081)))
+	assert.Equal(t, int64(40009081), findRow(denormalize, int64(60003760))[6])
+	assert.Equal(t, int64(40009076), findRow(denormalize, int64(40009077))[6])
+	assert.Equal(t, int32(5), findRow(denormalize, int64(30000142))[1])
+
+	jumps := findTable(tables, "eve.mapSolarSystemJumps")
+	assert.Len(t, jumps.rows, 2)
+	assert.Equal(t, []interface{}{int32(30000142), int32(30000144), int32(10000002), int32(20000020),
+		int32(20000020), int32(10000002)}, findRow(jumps, int32(30000142)))
+
+	_, err = readSDE(filepath.Join(os.TempDir(), "missing-sde.zip"))
+	assert.NotNil(t, err)
+}
+
+func TestDiffTable(t *testing.T) {
+	file := writeTestSDE(t)
+	defer os.Remove(file)
+
+	tables, err := readSDE(file)
+	assert.Nil(t, err)
+	attributes := findTable(tables, "eve.dgmTypeAttributes")
+
+	null := sql.NullString{}
+	value := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	existing := map[string]existingRow{
+		"587/37": {value("587"), value("37"), null, value("330")},
+		"587/4":  {value("587"), value("4"), value("1067000"), null},
+		"587/9":  {value("587"), value("9"), null, value("350")},
+	}
+
+	diff := diffTable(attributes, existing)
+	assert.Equal(t, TableDiff{Changed: 1, Removed: 1, Unchanged: 1}, diff.TableDiff)
+
+	changes := attributeChanges(diff)
+	assert.Len(t, changes, 2)
+	assert.Equal(t, int32(9), changes[0].AttributeID)
+	assert.Equal(t, 350.0, *changes[0].Old)
+	assert.Nil(t, changes[0].New)
+	assert.Equal(t, int32(37), changes[1].AttributeID)
+	assert.Equal(t, 330.0, *changes[1].Old)
+	assert.Equal(t, 355.0, *changes[1].New)
+
+	// Single precision columns are not reported as changed
+	assert.True(t, sameValue(value("1.06700e+06"), 1067000.0))
+	assert.True(t, sameValue(value("1"), true))
+	assert.False(t, sameValue(null, int32(1)))
+	assert.True(t, sameValue(null, nil))
+}
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -48,7 +49,7 @@ func init() {
 	chanKillmailAttributes = make(chan KillmailAttributes, 1000)
 }
 
-// saveKillmail saves the data to backblaze b2 in json.gz format
+// saveKillmail saves the data to the archive store in json.gz format
 func (s *Tailor) saveKillmail(pack *KillmailAttributes) error {
 	b, err := json.Marshal(pack)
 	if err != nil {
@@ -76,11 +77,10 @@ func (s *Tailor) saveKillmail(pack *KillmailAttributes) error {
 		return err
 	}
 	if len(gzb.Bytes()) > 0 {
-		metadata := make(map[string]string)
-		_, err = s.bucket.UploadFile(
+		err = s.store.Put(
 			fmt.Sprintf("%d.json.gz", pack.Killmail.KillmailId),
-			metadata,
 			bytes.NewReader(gzb.Bytes()),
+			int64(gzb.Len()),
 		)
 		if err != nil {
 			log.Println(err)
@@ -103,6 +103,11 @@ func timeStage(s string, t time.Time) {
 	).Observe(float64(time.Since(t).Nanoseconds() / 1000000))
 }
 
+// KillmailHandler processes killmail messages without NSQ, for replays
+func (s *Tailor) KillmailHandler() nsq.Handler {
+	return nsq.HandlerFunc(s.killmailHandler)
+}
+
 func (s *Tailor) killmailHandler(message *nsq.Message) error {
 	killmail := datapackages.Killmail{}
 	start := time.Now()
@@ -113,12 +118,12 @@ func (s *Tailor) killmailHandler(message *nsq.Message) error {
 	timeStage("decode", start)
 	start = time.Now()
 
-	attr, err := getAttributesForKillmail(&killmail.Kill)
+	attr, err := s.getAttributesForKillmail(&killmail.Kill)
 	if err != nil {
 		log.Println(err)
 		return err
 	}
-	timeStage("axiom", start)
+	timeStage("attributes", start)
 	start = time.Now()
 
 	names, err := s.resolveNames(&killmail.Kill)
@@ -163,6 +168,7 @@ func (s *Tailor) killmailHandler(message *nsq.Message) error {
 		DNA:        dna,
 	}
 	// Add the package to the list
+	s.pending.Add(1)
 	chanKillmailAttributes <- pack
 
 	err = s.saveKillmail(&pack)
@@ -336,88 +342,142 @@ func (s *Tailor) resolveNames(kill *esi.GetKillmailsKillmailIdKillmailHashOk) (m
 	return ids, nil
 }
 
+// attributeColumns of killmailAttributes, starting with the key
+var attributeColumns = []string{
+	"id", "eHP", "DPS", "Alpha", "scanResolution", "signatureRadiusNoMWD",
+	"signatureRadius", "agility", "warpSpeed", "speedNoMWD", "speed", "remoteArmorRepair",
+	"remoteShieldRepair", "remoteEnergyTransfer", "energyNeutralization", "sensorStrength",
+	"RPS", "CPURemaining", "powerRemaining", "capacitorNoMWD", "capacitor",
+	"capacitorTimeNoMWD", "capacitorTime", "fittedValue", "totalValue", "stasisWebifierStrength", "totalWarpScrambleStrength",
+	"meanSecurity",
+}
+
 // killmailConsumer receives killmails from NSQ and dumps attributes to SQL
 func (s *Tailor) killmailConsumer() {
 	for {
 		a := <-chanKillmailAttributes
-		b := a.Attributes.Ship
-		pm := a.PriceMap
-
-		// figure out the mean security of the attackers.
-		meanSecurity := float32(0.0)
-		count := 0
-		for _, attacker := range a.Killmail.Attackers {
-			if attacker.CharacterId > 0 {
-				meanSecurity += attacker.SecurityStatus
-				count++
-			}
-		}
-		if count > 0 {
-			meanSecurity = meanSecurity / float32(count)
+		s.storeAttributes(&a)
+		s.pending.Done()
+	}
+}
+
+// storeAttributes saves the attributes of a killmail
+func (s *Tailor) storeAttributes(a *KillmailAttributes) {
+	b := a.Attributes.Ship
+	pm := a.PriceMap
+
+	// figure out the mean security of the attackers.
+	meanSecurity := float32(0.0)
+	count := 0
+	for _, attacker := range a.Killmail.Attackers {
+		if attacker.CharacterId > 0 {
+			meanSecurity += attacker.SecurityStatus
+			count++
 		}
+	}
+	if count > 0 {
+		meanSecurity = meanSecurity / float32(count)
+	}
 
-		value := pm[a.Attributes.TypeID]
-		totalValue := pm[a.Attributes.TypeID]
-		if value > 0 {
-			for _, e := range a.Attributes.Modules {
-				value += pm[int32(e["typeID"])]
-				if pm[int32(e["chargeID"])] > 0 {
-					value += pm[int32(e["chargeID"])]
-				}
-			}
-			for _, e := range a.Attributes.Drones {
-				value += pm[int32(e["typeID"])] * e["quantity"]
+	value := pm[a.Attributes.TypeID]
+	totalValue := pm[a.Attributes.TypeID]
+	if value > 0 {
+		for _, e := range a.Attributes.Modules {
+			value += pm[int32(e["typeID"])]
+			if pm[int32(e["chargeID"])] > 0 {
+				value += pm[int32(e["chargeID"])]
 			}
 		}
+		for _, e := range a.Attributes.Drones {
+			value += pm[int32(e["typeID"])] * e["quantity"]
+		}
+	}
 
-		for _, e := range a.Killmail.Victim.Items {
-			for _, e := range e.Items {
-				totalValue += pm[e.ItemTypeId] * float64(e.QuantityDestroyed+e.QuantityDropped)
-			}
+	for _, e := range a.Killmail.Victim.Items {
+		for _, e := range e.Items {
 			totalValue += pm[e.ItemTypeId] * float64(e.QuantityDestroyed+e.QuantityDropped)
 		}
+		totalValue += pm[e.ItemTypeId] * float64(e.QuantityDestroyed+e.QuantityDropped)
+	}
 
-		sq := squirrel.Insert("evedata.killmailAttributes").Columns(
-			"id", "eHP", "DPS", "Alpha", "scanResolution", "signatureRadiusNoMWD",
-			"signatureRadius", "agility", "warpSpeed", "speedNoMWD", "speed", "remoteArmorRepair",
-			"remoteShieldRepair", "remoteEnergyTransfer", "energyNeutralization", "sensorStrength",
-			"RPS", "CPURemaining", "powerRemaining", "capacitorNoMWD", "capacitor",
-			"capacitorTimeNoMWD", "capacitorTime", "fittedValue", "totalValue", "stasisWebifierStrength", "totalWarpScrambleStrength",
-			"meanSecurity",
-		)
+	sq := squirrel.Insert("evedata.killmailAttributes").Columns(attributeColumns...)
 
-		sq = sq.Values(
-			a.Killmail.KillmailId, b["avgEHP"], b["totalDPS"], b["totalAlphaDamage"], b["scanResolution"], b["signatureRadius"],
-			b["signatureRadiusMWD"], b["agility"], b["warpSpeedMultiplier"], b["maxVelocity"], b["maxVelocityMWD"], b["remoteArmorRepairPerSecond"],
-			b["remoteShieldBonusAmountPerSecond"], b["remotePowerTransferAmountPerSecond"], b["energyNeutralizerAmountPerSecond"],
-			b["scanRadarStrength"]+b["scanLadarStrength"]+b["scanMagnetometricStrength"]+b["scanGravimetricStrength"],
-			b["avgRPS"], b["cpuRemaining"], b["powerRemaining"], b["capacitorFraction"], b["capacitorFractionMWD"],
-			b["capacitorDuration"]*100000, b["capacitorDurationMWD"]*100000, value, totalValue, b["stasisWebifierStrength"], b["totalWarpScrambleStrength"],
-			meanSecurity,
-		)
+	sq = sq.Values(
+		a.Killmail.KillmailId, b["avgEHP"], b["totalDPS"], b["totalAlphaDamage"], b["scanResolution"], b["signatureRadius"],
+		b["signatureRadiusMWD"], b["agility"], b["warpSpeedMultiplier"], b["maxVelocity"], b["maxVelocityMWD"], b["remoteArmorRepairPerSecond"],
+		b["remoteShieldBonusAmountPerSecond"], b["remotePowerTransferAmountPerSecond"], b["energyNeutralizerAmountPerSecond"],
+		b["scanRadarStrength"]+b["scanLadarStrength"]+b["scanMagnetometricStrength"]+b["scanGravimetricStrength"],
+		b["avgRPS"], b["cpuRemaining"], b["powerRemaining"], b["capacitorFraction"], b["capacitorFractionMWD"],
+		b["capacitorDuration"]*100000, b["capacitorDurationMWD"]*100000, value, totalValue, b["stasisWebifierStrength"], b["totalWarpScrambleStrength"],
+		meanSecurity,
+	)
 
-		sqlq, args, err := sq.ToSql()
-		if err != nil {
-			log.Println(err)
-			continue
-		}
+	sqlq, args, err := sq.ToSql()
+	if err != nil {
+		log.Println(err)
+		return
+	}
 
-		err = s.doSQL(sqlq+" ON DUPLICATE KEY UPDATE id = id, speed = VALUES(speed), meanSecurity = VALUES(meanSecurity)", args...)
-		if err != nil {
-			log.Println(err)
-			continue
-		}
+	// Replace everything so replays backfill corrected attributes
+	updates := []string{}
+	for _, c := range attributeColumns[1:] {
+		updates = append(updates, c+" = VALUES("+c+")")
+	}
+
+	err = s.doSQL(sqlq+" ON DUPLICATE KEY UPDATE "+strings.Join(updates, ", "), args...)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+}
+
+// getAttributesForKillmail evaluates the victim's fit, asking axiom if the
+// dogma data cannot and a fallback is configured.
+func (s *Tailor) getAttributesForKillmail(km *esi.GetKillmailsKillmailIdKillmailHashOk) (*attributes.Attributes, error) {
+	attr, err := s.evaluateKillmail(km)
+	if err == nil {
+		return attr, nil
+	}
+	if s.axiomURL == "" {
+		return nil, err
 	}
+	metricAxiomFallback.Inc()
+	return s.getAxiomAttributes(km)
+}
+
+// evaluateKillmail computes attributes in process from the dogma tables
+func (s *Tailor) evaluateKillmail(km *esi.GetKillmailsKillmailIdKillmailHashOk) (*attributes.Attributes, error) {
+	if s.dogma == nil {
+		return nil, errors.New("dogma data not loaded")
+	}
+
+	fit, err := s.dogma.FitFromKillmail(km)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.dogma.Evaluate(fit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attributes.Attributes{
+		TypeID:  a.TypeID,
+		Ship:    a.Ship,
+		Modules: a.Modules,
+		Drones:  a.Drones,
+	}, nil
 }
 
-func getAttributesForKillmail(km *esi.GetKillmailsKillmailIdKillmailHashOk) (*attributes.Attributes, error) {
+// getAxiomAttributes asks the axiom service to evaluate the killmail
+func (s *Tailor) getAxiomAttributes(km *esi.GetKillmailsKillmailIdKillmailHashOk) (*attributes.Attributes, error) {
 	j, err := json.Marshal(km)
 	if err != nil {
 		return nil, err
 	}
 	ctx, cncl := context.WithTimeout(context.Background(), time.Second*30)
 	defer cncl()
-	req, err := http.NewRequest("POST", "http://axiom.evedata:3005/killmail", bytes.NewBuffer(j))
+	req, err := http.NewRequest("POST", s.axiomURL, bytes.NewBuffer(j))
 	if err != nil {
 		return nil, err
 	}
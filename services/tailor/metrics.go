@@ -21,11 +21,19 @@ var (
 	},
 		[]string{"status"},
 	)
+
+	metricAxiomFallback = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "evedata",
+		Subsystem: "tailor",
+		Name:      "axiomFallback",
+		Help:      "Count of killmails evaluated by axiom.",
+	})
 )
 
 func init() {
 	prometheus.MustRegister(
 		metricStageTime,
 		metricStatus,
+		metricAxiomFallback,
 	)
 }
@@ -4,33 +4,46 @@ package tailor
 import (
 	"log"
 	"os"
+	"sync"
 	"time"
 
+	"github.com/antihax/evedata/internal/archivestore"
+	"github.com/antihax/evedata/internal/dogma"
 	"github.com/antihax/evedata/internal/sqlhelper"
 	"github.com/jmoiron/sqlx"
 	nsq "github.com/nsqio/go-nsq"
-	backblaze "gopkg.in/kothar/go-backblaze.v0"
 )
 
-// Tailor dumps killmails to b2 files for killboard.
+// Tailor dumps killmails to archive files for killboard.
 type Tailor struct {
 	stop     chan bool
 	consumer *nsq.Consumer
 	db       *sqlx.DB
-	b2       *backblaze.B2
-	bucket   *backblaze.Bucket
+	store    archivestore.ArchiveStore
+	dogma    *dogma.Data
+	axiomURL string
+	pending  sync.WaitGroup
 }
 
-// NewTailor Service.
-func NewTailor(db *sqlx.DB, b2 *backblaze.B2, consumerAddresses []string) *Tailor {
+// NewTailor Service. Attributes are evaluated in process, falling back to
+// the axiom service at axiomURL when it is not empty.
+func NewTailor(db *sqlx.DB, store archivestore.ArchiveStore, consumerAddresses []string, axiomURL string) *Tailor {
 	// Setup a new artifice
 	s := &Tailor{
-		stop: make(chan bool),
-		db:   db,
-		b2:   b2,
+		stop:     make(chan bool),
+		db:       db,
+		store:    store,
+		axiomURL: axiomURL,
 	}
 
-	b2.MaxIdleUploads = 100
+	d, err := dogma.LoadData(db)
+	if err != nil {
+		if axiomURL == "" {
+			log.Fatalln(err)
+		}
+		log.Println("dogma unavailable, using axiom only:", err)
+	}
+	s.dogma = d
 
 	nsqcfg := nsq.NewConfig()
 	nsqcfg.MaxInFlight = 50
@@ -42,11 +55,6 @@ func NewTailor(db *sqlx.DB, b2 *backblaze.B2, consumerAddresses []string) *Tailo
 	}
 	s.consumer = c
 
-	s.bucket, err = s.b2.Bucket("evedata-killmails")
-	if err != nil {
-		log.Fatalln(err)
-	}
-
 	c.AddConcurrentHandlers(nsq.HandlerFunc(s.killmailHandler), 100)
 	err = c.ConnectToNSQLookupds(consumerAddresses)
 	if err != nil {
@@ -60,10 +68,12 @@ func NewTailor(db *sqlx.DB, b2 *backblaze.B2, consumerAddresses []string) *Tailo
 	return s
 }
 
-// Close the conservator service
+// Close the tailor service once queued attributes are stored
 func (s *Tailor) Close() {
 	close(s.stop)
 	s.consumer.Stop()
+	<-s.consumer.StopChan
+	s.pending.Wait()
 }
 
 func (s *Tailor) doSQL(stmt string, args ...interface{}) error {
@@ -61,11 +61,12 @@ func (s TokenServer) GetMailUser(ctx context.Context, u *tokenstore.MailUserRequ
 		CharacterID      int32  `db:"characterID"`
 		TokenCharacterID int32  `db:"tokenCharacterID"`
 		Password         string `db:"mailPassword"`
+		MaxCost          int64  `db:"mailMaxCost"`
 	}
 
 	t := MailUser{}
 	err := s.db.QueryRowx(
-		`	SELECT characterID, tokenCharacterID, mailPassword FROM evedata.crestTokens
+		`	SELECT characterID, tokenCharacterID, mailPassword, mailMaxCost FROM evedata.crestTokens
 			WHERE tokenCharacterID = ? AND mailPassword != "" AND scopes LIKE "%read_mail%"
 			LIMIT 1;`, u.CharacterID).StructScan(&t)
 	if err != nil {
@@ -85,5 +86,5 @@ func (s TokenServer) GetMailUser(ctx context.Context, u *tokenstore.MailUserRequ
 		return nil, err
 	}
 
-	return &tokenstore.MailUser{Token: token, CharacterID: t.CharacterID, TokenCharacterID: t.TokenCharacterID}, nil
+	return &tokenstore.MailUser{Token: token, CharacterID: t.CharacterID, TokenCharacterID: t.TokenCharacterID, MaxCost: t.MaxCost}, nil
 }
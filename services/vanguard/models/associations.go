@@ -1,11 +1,27 @@
 package models
 
+import "github.com/antihax/evedata/internal/altscore"
+
 type KnownAlts struct {
 	CharacterID   int64  `db:"characterID" json:"id"`
 	CharacterName string `db:"characterName" json:"name"`
 	Frequency     int    `db:"frequency" json:"frequency"`
 	Type          string `db:"type" json:"type"`
 	Source        uint8  `db:"source" json:"source"`
+
+	altscore.Signals
+	Score   float64           `db:"-" json:"score"`
+	Reasons []altscore.Reason `db:"-" json:"reasons"`
+}
+
+// scoreKnownAlts fills in the alt score and the reasons for it. Only public signals
+// are used as anyone can view associates.
+func scoreKnownAlts(ref []KnownAlts) {
+	for i := range ref {
+		ref[i].Type = "character"
+		ref[i].Signals = ref[i].Signals.Public()
+		ref[i].Score, ref[i].Reasons = altscore.Score(ref[i].Signals)
+	}
 }
 
 // Obtain Character Associates by ID.
@@ -13,11 +29,15 @@ type KnownAlts struct {
 func GetCharacterKnownAssociates(id int64) ([]KnownAlts, error) {
 	ref := []KnownAlts{}
 	if err := database.Select(&ref, `
-		SELECT 	associateID AS characterID,
+		SELECT 	A.associateID AS characterID,
 				frequency,
 				C.name AS characterName,
-				IFNULL(source, 0) AS source
+				IFNULL(source, 0) AS source,
+				IFNULL(coFleet, IF(source = 1, frequency, 0)) AS coFleet,
+				IFNULL(corpHops, IF(source = 2, frequency, 0)) AS corpHops,
+				IFNULL(activityOverlap, 0) AS activityOverlap
 		FROM evedata.characterAssociations A
+		LEFT OUTER JOIN evedata.characterAssociationScores S ON S.characterID = A.characterID AND S.associateID = A.associateID
 		INNER JOIN evedata.characters C ON A.associateID = C.characterID
 		INNER JOIN evedata.characters M ON A.characterID = M.characterID
 		WHERE A.characterID = ?
@@ -26,9 +46,7 @@ func GetCharacterKnownAssociates(id int64) ([]KnownAlts, error) {
 		return nil, err
 	}
 
-	for i := range ref {
-		ref[i].Type = "character"
-	}
+	scoreKnownAlts(ref)
 
 	return ref, nil
 }
@@ -36,23 +54,25 @@ func GetCharacterKnownAssociates(id int64) ([]KnownAlts, error) {
 func GetCorporationKnownAssociates(id int64) ([]KnownAlts, error) {
 	ref := []KnownAlts{}
 	if err := database.Select(&ref, `
-		SELECT	associateID AS characterID,
+		SELECT	A.associateID AS characterID,
 				SUM(frequency) AS frequency,
 				C.name AS characterName,
-				IFNULL(source, 0) AS source
+				IFNULL(source, 0) AS source,
+				MAX(IFNULL(coFleet, IF(source = 1, frequency, 0))) AS coFleet,
+				MAX(IFNULL(corpHops, IF(source = 2, frequency, 0))) AS corpHops,
+				MAX(IFNULL(activityOverlap, 0)) AS activityOverlap
 		FROM evedata.characterAssociations A
+        LEFT OUTER JOIN evedata.characterAssociationScores S ON S.characterID = A.characterID AND S.associateID = A.associateID
         INNER JOIN evedata.characters C ON A.associateID = C.characterID
         INNER JOIN evedata.characters M ON A.characterID = M.characterID
         WHERE A.characterID IN (SELECT characterID FROM evedata.characters WHERE corporationID = ?)
         AND (M.allianceID != C.allianceID OR C.allianceID = 0) AND M.corporationID != C.corporationID
-        GROUP BY associateID
+        GROUP BY A.associateID
 		`, id); err != nil {
 		return nil, err
 	}
 
-	for i := range ref {
-		ref[i].Type = "character"
-	}
+	scoreKnownAlts(ref)
 
 	return ref, nil
 }
@@ -60,23 +80,77 @@ func GetCorporationKnownAssociates(id int64) ([]KnownAlts, error) {
 func GetAllianceKnownAssociates(id int64) ([]KnownAlts, error) {
 	ref := []KnownAlts{}
 	if err := database.Select(&ref, `
-		SELECT	associateID AS characterID,
+		SELECT	A.associateID AS characterID,
 				SUM(frequency) AS frequency,
 				C.name AS characterName,
-				IFNULL(source, 0) AS source
+				IFNULL(source, 0) AS source,
+				MAX(IFNULL(coFleet, IF(source = 1, frequency, 0))) AS coFleet,
+				MAX(IFNULL(corpHops, IF(source = 2, frequency, 0))) AS corpHops,
+				MAX(IFNULL(activityOverlap, 0)) AS activityOverlap
 		FROM evedata.characterAssociations A
+        LEFT OUTER JOIN evedata.characterAssociationScores S ON S.characterID = A.characterID AND S.associateID = A.associateID
         INNER JOIN evedata.characters C ON A.associateID = C.characterID
         INNER JOIN evedata.characters M ON A.characterID = M.characterID
         WHERE A.characterID IN (SELECT characterID FROM evedata.characters WHERE allianceID = ?)
         AND (M.allianceID != C.allianceID OR C.allianceID = 0) AND M.corporationID != C.corporationID
-        GROUP BY associateID
+        GROUP BY A.associateID
+		`, id); err != nil {
+		return nil, err
+	}
+
+	scoreKnownAlts(ref)
+
+	return ref, nil
+}
+
+// GetCharacterAssociationScores scores every pair with signals for a character using all
+// of them, so pairs seen only by locator agents or contact syncs are included.
+// This exposes private character data and is only for recruiters.
+func GetCharacterAssociationScores(id int64) ([]KnownAlts, error) {
+	ref := []KnownAlts{}
+	if err := database.Select(&ref, `
+		SELECT	S.associateID AS characterID,
+				IFNULL(frequency, 0) AS frequency,
+				C.name AS characterName,
+				IFNULL(source, 0) AS source,
+				coFleet, corpHops, locatorTargets, activityOverlap, contactSync
+		FROM evedata.characterAssociationScores S
+		LEFT OUTER JOIN evedata.characterAssociations A ON A.characterID = S.characterID AND A.associateID = S.associateID
+		INNER JOIN evedata.characters C ON S.associateID = C.characterID
+		WHERE S.characterID = ?
 		`, id); err != nil {
 		return nil, err
 	}
 
 	for i := range ref {
 		ref[i].Type = "character"
+		ref[i].Score, ref[i].Reasons = altscore.Score(ref[i].Signals)
 	}
 
 	return ref, nil
 }
+
+// IsRecruiter checks the character has a Personnel Manager or Director token
+func IsRecruiter(characterID int32) (bool, error) {
+	for _, role := range []string{"Personnel_Manager", "Director"} {
+		entities, err := GetEntitiesWithRole(characterID, role)
+		if err != nil {
+			return false, err
+		}
+		if len(entities) > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FilterKnownAlts keeps associates meeting a minimum score and frequency
+func FilterKnownAlts(ref []KnownAlts, minScore float64, minFrequency int) []KnownAlts {
+	filtered := []KnownAlts{}
+	for _, alt := range ref {
+		if alt.Score >= minScore && alt.Frequency >= minFrequency {
+			filtered = append(filtered, alt)
+		}
+	}
+	return filtered
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"testing"
+	"time"
 )
 
 func TestGetCharacterKnownAssociates(t *testing.T) {
@@ -36,3 +37,79 @@ func TestGetAllianceKnownAssociates(t *testing.T) {
 		return
 	}
 }
+
+func TestKnownAssociatesScore(t *testing.T) {
+	_, err := database.Exec(`
+			INSERT IGNORE INTO evedata.characterAssociationScores
+				(characterID, associateID, coFleet, corpHops, updated) VALUES
+			 (1001, 1002, 3, 1, UTC_TIMESTAMP);
+		`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	alts, err := GetCharacterKnownAssociates(1001)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	for _, alt := range alts {
+		if alt.CharacterID == 1002 && (alt.Score <= 0 || len(alt.Reasons) != 2) {
+			t.Errorf("expected score with two reasons, got %f %v", alt.Score, alt.Reasons)
+		}
+	}
+
+	if len(FilterKnownAlts(alts, 1.1, 0)) != 0 {
+		t.Error("expected no associates above a score of 1")
+	}
+}
+
+func TestCharacterAssociationScores(t *testing.T) {
+	if err := UpdateCharacter(1003, "dude 3", 1, 1, 147035273, 0, 1, "male", -10, time.Now()); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Only seen by locator agents so it is not a public associate
+	_, err := database.Exec(`
+			INSERT IGNORE INTO evedata.characterAssociationScores
+				(characterID, associateID, locatorTargets, contactSync, updated) VALUES
+			 (1001, 1003, 2, 1, UTC_TIMESTAMP);
+		`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	alts, err := GetCharacterKnownAssociates(1001)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, alt := range alts {
+		if alt.CharacterID == 1003 {
+			t.Error("private signals shown as a public associate")
+		}
+	}
+
+	alts, err = GetCharacterAssociationScores(1001)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	found := false
+	for _, alt := range alts {
+		if alt.CharacterID == 1003 {
+			found = true
+			if alt.Score <= 0 || len(alt.Reasons) != 2 {
+				t.Errorf("expected score with two reasons, got %f %v", alt.Score, alt.Reasons)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected associate from private signals")
+	}
+}
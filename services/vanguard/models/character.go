@@ -46,6 +46,7 @@ type CRESTToken struct {
 	SharingInt       string              `db:"sharingint" json:"_,omitempty"`
 	Sharing          []conservator.Share `json:"sharing"`
 	MailPassword     int                 `db:"mailPassword" json:"mailPassword"`
+	MailMaxCost      int64               `db:"mailMaxCost" json:"mailMaxCost"`
 }
 
 type IntegrationToken struct {
@@ -106,7 +107,7 @@ func SetCursorCharacter(characterID int32, cursorCharacterID int32) error {
 func GetCRESTTokens(characterID int32, ownerHash string) ([]CRESTToken, error) {
 	tokens := []CRESTToken{}
 	if err := database.Select(&tokens, `
-		SELECT T.characterID, T.tokenCharacterID, characterName, IF(mailPassword != "", 1, 0) AS mailPassword,
+		SELECT T.characterID, T.tokenCharacterID, characterName, IF(mailPassword != "", 1, 0) AS mailPassword, mailMaxCost,
 		lastCode, lastStatus, scopes, authCharacter, C1.name AS corporationName, A1.name AS allianceName,
 		T.corporationID, T.allianceID,
 		IFNULL(
@@ -214,6 +215,17 @@ func SetMailPassword(characterID, tokenCharacterID int32, ownerHash, password st
 	return nil
 }
 
+// SetMailMaxCost sets the maximum CSPA charge approved for mail sent through the mail proxy
+func SetMailMaxCost(characterID, tokenCharacterID int32, ownerHash string, maxCost int64) error {
+	if _, err := database.Exec(`UPDATE evedata.crestTokens
+		SET mailMaxCost = ?
+		WHERE characterID = ? AND tokenCharacterID = ? AND characterOwnerHash = ?;
+		`, maxCost, characterID, tokenCharacterID, ownerHash); err != nil {
+		return err
+	}
+	return nil
+}
+
 func GetIntegrationTokens(characterID int32) ([]IntegrationToken, error) {
 	tokens := []IntegrationToken{}
 	if err := database.Select(&tokens, `
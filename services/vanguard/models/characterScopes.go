@@ -52,6 +52,11 @@ var shareReasons = map[string]string{
 	"locator":     "Locator Responses",
 	"structure":   "Corporation structures under attack",
 	"war":         "War Declared on Corporation",
+	"fuel":        "Structure fuel and services",
+	"anchoring":   "Structures anchoring and unanchoring",
+	"moon":        "Moon extractions",
+	"sovereignty": "Sovereignty campaigns",
+	"poco":        "Customs offices under attack",
 }
 
 func GetCharacterShareGroups() map[string]string {
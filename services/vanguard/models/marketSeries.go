@@ -0,0 +1,320 @@
+package models
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Market series limits
+const (
+	MaxMarketSeries     = 50
+	MaxMarketSeriesDays = 365 * 3
+)
+
+// TheForgeRegionID is the region Jita is in, which spreads are measured against
+const TheForgeRegionID = 10000002
+
+// MarketSeriesOptions selects the series and indicators to return
+type MarketSeriesOptions struct {
+	TypeIDs        []int32
+	RegionIDs      []int32
+	From           time.Time
+	To             time.Time
+	Interval       string // day, week, or month
+	MovingAverages []int  // windows in bars
+	Volatility     int    // window in bars, zero for none
+	Spread         bool   // percentage from The Forge
+}
+
+// MarketSeries is resampled history for one type in one region
+type MarketSeries struct {
+	TypeID     int32       `json:"typeID"`
+	TypeName   string      `json:"typeName"`
+	RegionID   int32       `json:"regionID"`
+	RegionName string      `json:"regionName"`
+	Bars       []MarketBar `json:"bars"`
+}
+
+// MarketBar is price and volume over an interval. Indicators are nil until their window fills.
+type MarketBar struct {
+	Date    time.Time `json:"date"`
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Close   float64   `json:"close"`
+	Average float64   `json:"average"`
+	Volume  int64     `json:"volume"`
+	Orders  int64     `json:"orders"`
+
+	MovingAverages map[int]float64 `json:"movingAverages,omitempty"`
+	Volatility     *float64        `json:"volatility,omitempty"`
+	Spread         *float64        `json:"spread,omitempty"`
+}
+
+// MarketHistoryDay is one day of history for a type in a region
+type MarketHistoryDay struct {
+	Date       time.Time `db:"date"`
+	TypeID     int32     `db:"typeID"`
+	TypeName   string    `db:"typeName"`
+	RegionID   int32     `db:"regionID"`
+	RegionName string    `db:"regionName"`
+	Low        float64   `db:"low"`
+	High       float64   `db:"high"`
+	Mean       float64   `db:"mean"`
+	Quantity   int64     `db:"quantity"`
+	Orders     int64     `db:"orders"`
+}
+
+// ValidMarketInterval returns true if the interval can be resampled to
+func ValidMarketInterval(interval string) bool {
+	return interval == "day" || interval == "week" || interval == "month"
+}
+
+// GetMarketSeries returns resampled history with indicators for each type and region
+func GetMarketSeries(o MarketSeriesOptions) ([]MarketSeries, error) {
+	regionIDs := o.RegionIDs
+	if o.Spread && !containsRegion(regionIDs, TheForgeRegionID) {
+		regionIDs = append(append([]int32{}, regionIDs...), TheForgeRegionID)
+	}
+
+	// Start early enough to fill the indicator windows and the first open
+	days, err := getMarketHistoryDays(o.TypeIDs, regionIDs, o.From.AddDate(0, 0, -warmupDays(o)), o.To)
+	if err != nil {
+		return nil, err
+	}
+
+	series := ResampleMarketHistory(days, o.Interval)
+	for i := range series {
+		addMovingAverages(series[i].Bars, o.MovingAverages)
+		addVolatility(series[i].Bars, o.Volatility)
+	}
+
+	if o.Spread {
+		addSpreads(series)
+	}
+
+	// Trim the warm up, and The Forge if we only fetched it for spreads
+	out := []MarketSeries{}
+	for _, s := range series {
+		if !containsRegion(o.RegionIDs, s.RegionID) {
+			continue
+		}
+		first := 0
+		for first < len(s.Bars) && s.Bars[first].Date.Before(bucketStart(o.From, o.Interval)) {
+			first++
+		}
+		s.Bars = s.Bars[first:]
+		out = append(out, s)
+	}
+
+	return out, nil
+}
+
+func getMarketHistoryDays(typeIDs, regionIDs []int32, from, to time.Time) ([]MarketHistoryDay, error) {
+	if len(typeIDs) == 0 || len(regionIDs) == 0 {
+		return []MarketHistoryDay{}, nil
+	}
+
+	args := []interface{}{}
+	for _, id := range typeIDs {
+		args = append(args, id)
+	}
+	for _, id := range regionIDs {
+		args = append(args, id)
+	}
+	args = append(args, from.Format("2006-01-02"), to.Format("2006-01-02"))
+
+	days := []MarketHistoryDay{}
+	if err := database.Select(&days, `
+		SELECT H.date, H.itemID AS typeID, T.typeName, H.regionID, R.regionName,
+			H.low, H.high, H.mean, H.quantity, H.orders
+		FROM evedata.market_history H
+		INNER JOIN invTypes T ON T.typeID = H.itemID
+		INNER JOIN mapRegions R ON R.regionID = H.regionID
+		WHERE H.itemID IN (?`+strings.Repeat(",?", len(typeIDs)-1)+`)
+			AND H.regionID IN (?`+strings.Repeat(",?", len(regionIDs)-1)+`)
+			AND H.date BETWEEN ? AND ?
+		ORDER BY H.itemID, H.regionID, H.date
+	`, args...); err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// warmupDays is how much history before the range is needed for indicators
+func warmupDays(o MarketSeriesOptions) int {
+	bars := o.Volatility + 1
+	for _, w := range o.MovingAverages {
+		if w > bars {
+			bars = w
+		}
+	}
+
+	switch o.Interval {
+	case "week":
+		return bars * 7
+	case "month":
+		return bars * 31
+	}
+	return bars
+}
+
+// bucketStart returns the start of the interval a date falls in. Weeks start on Monday.
+func bucketStart(t time.Time, interval string) time.Time {
+	t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case "week":
+		return t.AddDate(0, 0, -((int(t.Weekday()) + 6) % 7))
+	case "month":
+		return t.AddDate(0, 0, 1-t.Day())
+	}
+	return t
+}
+
+// ResampleMarketHistory groups days sorted by type, region, and date into bars.
+// Open is the previous close, falling back to the first mean of the bar.
+func ResampleMarketHistory(days []MarketHistoryDay, interval string) []MarketSeries {
+	series := []MarketSeries{}
+
+	var (
+		bar       *MarketBar
+		prevClose float64
+		turnover  float64
+	)
+
+	for _, d := range days {
+		last := len(series) - 1
+		if last < 0 || series[last].TypeID != d.TypeID || series[last].RegionID != d.RegionID {
+			series = append(series, MarketSeries{
+				TypeID:     d.TypeID,
+				TypeName:   d.TypeName,
+				RegionID:   d.RegionID,
+				RegionName: d.RegionName,
+				Bars:       []MarketBar{},
+			})
+			last++
+			bar = nil
+			prevClose = 0
+		}
+		s := &series[last]
+
+		start := bucketStart(d.Date, interval)
+		if bar == nil || !bar.Date.Equal(start) {
+			open := prevClose
+			if open == 0 {
+				open = d.Mean
+			}
+			s.Bars = append(s.Bars, MarketBar{Date: start, Open: open, High: d.High, Low: d.Low})
+			bar = &s.Bars[len(s.Bars)-1]
+			turnover = 0
+		}
+
+		bar.High = math.Max(bar.High, d.High)
+		bar.Low = math.Min(bar.Low, d.Low)
+		bar.Close = d.Mean
+		bar.Volume += d.Quantity
+		bar.Orders += d.Orders
+
+		turnover += d.Mean * float64(d.Quantity)
+		if bar.Volume > 0 {
+			bar.Average = turnover / float64(bar.Volume)
+		} else {
+			bar.Average = d.Mean
+		}
+
+		prevClose = d.Mean
+	}
+
+	return series
+}
+
+// addMovingAverages adds simple moving averages of the close for each window
+func addMovingAverages(bars []MarketBar, windows []int) {
+	for _, w := range windows {
+		if w < 1 {
+			continue
+		}
+		sum := 0.0
+		for i := range bars {
+			sum += bars[i].Close
+			if i >= w {
+				sum -= bars[i-w].Close
+			}
+			if i >= w-1 {
+				if bars[i].MovingAverages == nil {
+					bars[i].MovingAverages = make(map[int]float64)
+				}
+				bars[i].MovingAverages[w] = sum / float64(w)
+			}
+		}
+	}
+}
+
+// addVolatility adds the standard deviation of log returns of the close over the window
+func addVolatility(bars []MarketBar, window int) {
+	if window < 2 {
+		return
+	}
+
+	returns := make([]float64, len(bars))
+	for i := 1; i < len(bars); i++ {
+		if bars[i-1].Close > 0 && bars[i].Close > 0 {
+			returns[i] = math.Log(bars[i].Close / bars[i-1].Close)
+		}
+	}
+
+	for i := window; i < len(bars); i++ {
+		r := returns[i-window+1 : i+1]
+		mean := 0.0
+		for _, v := range r {
+			mean += v
+		}
+		mean /= float64(len(r))
+
+		variance := 0.0
+		for _, v := range r {
+			variance += (v - mean) * (v - mean)
+		}
+		v := math.Sqrt(variance / float64(len(r)-1))
+		bars[i].Volatility = &v
+	}
+}
+
+// addSpreads adds the percentage each close is above or below The Forge for the same type
+func addSpreads(series []MarketSeries) {
+	forge := make(map[int32]map[time.Time]float64)
+	for _, s := range series {
+		if s.RegionID != TheForgeRegionID {
+			continue
+		}
+		closes := make(map[time.Time]float64)
+		for _, b := range s.Bars {
+			closes[b.Date] = b.Close
+		}
+		forge[s.TypeID] = closes
+	}
+
+	for _, s := range series {
+		if s.RegionID == TheForgeRegionID {
+			continue
+		}
+		for i := range s.Bars {
+			jita, ok := forge[s.TypeID][s.Bars[i].Date]
+			if !ok || jita == 0 {
+				continue
+			}
+			spread := (s.Bars[i].Close - jita) / jita * 100
+			s.Bars[i].Spread = &spread
+		}
+	}
+}
+
+func containsRegion(regions []int32, regionID int32) bool {
+	for _, r := range regions {
+		if r == regionID {
+			return true
+		}
+	}
+	return false
+}
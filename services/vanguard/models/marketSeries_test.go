@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMarketSeries(t *testing.T) {
+	_, err := GetMarketSeries(MarketSeriesOptions{
+		TypeIDs:        []int32{34, 35},
+		RegionIDs:      []int32{10000043},
+		From:           time.Now().UTC().AddDate(0, -1, 0),
+		To:             time.Now().UTC(),
+		Interval:       "week",
+		MovingAverages: []int{2, 4},
+		Volatility:     3,
+		Spread:         true,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestResampleMarketHistory(t *testing.T) {
+	day := func(d int, regionID int32, low, high, mean float64, quantity int64) MarketHistoryDay {
+		return MarketHistoryDay{
+			Date:     time.Date(2018, 5, d, 0, 0, 0, 0, time.UTC),
+			TypeID:   34,
+			RegionID: regionID,
+			Low:      low, High: high, Mean: mean,
+			Quantity: quantity, Orders: 1,
+		}
+	}
+
+	// 2018-05-06 is a Sunday
+	days := []MarketHistoryDay{
+		day(4, 10000002, 4, 6, 5, 100),
+		day(5, 10000002, 3, 7, 6, 100),
+		day(6, 10000002, 5, 9, 8, 200),
+		day(7, 10000002, 7, 10, 9, 100),
+		day(8, 10000002, 8, 12, 10, 100),
+		day(7, 10000043, 9, 11, 10, 10),
+		day(8, 10000043, 9, 13, 11, 10),
+	}
+
+	weekly := ResampleMarketHistory(days, "week")
+	assert.Len(t, weekly, 2)
+	assert.Len(t, weekly[0].Bars, 2)
+
+	w := weekly[0].Bars[0]
+	assert.Equal(t, time.Date(2018, 4, 30, 0, 0, 0, 0, time.UTC), w.Date)
+	assert.Equal(t, 5.0, w.Open)
+	assert.Equal(t, 9.0, w.High)
+	assert.Equal(t, 3.0, w.Low)
+	assert.Equal(t, 8.0, w.Close)
+	assert.Equal(t, int64(400), w.Volume)
+	assert.Equal(t, 6.75, w.Average)
+
+	// Open follows the previous close
+	assert.Equal(t, 8.0, weekly[0].Bars[1].Open)
+	assert.Equal(t, 10.0, weekly[0].Bars[1].Close)
+
+	daily := ResampleMarketHistory(days, "day")
+	assert.Len(t, daily[0].Bars, 5)
+	assert.Len(t, daily[1].Bars, 2)
+
+	addMovingAverages(daily[0].Bars, []int{3})
+	assert.Nil(t, daily[0].Bars[1].MovingAverages)
+	assert.InDelta(t, 19.0/3, daily[0].Bars[2].MovingAverages[3], 0.0001)
+	assert.InDelta(t, 9.0, daily[0].Bars[4].MovingAverages[3], 0.0001)
+
+	addVolatility(daily[0].Bars, 2)
+	assert.Nil(t, daily[0].Bars[1].Volatility)
+	assert.NotNil(t, daily[0].Bars[2].Volatility)
+
+	addSpreads(daily)
+	assert.Nil(t, daily[0].Bars[0].Spread)
+	assert.InDelta(t, 10.0/9*100-100, *daily[1].Bars[0].Spread, 0.0001)
+	assert.InDelta(t, 10.0, *daily[1].Bars[1].Spread, 0.0001)
+
+	assert.Equal(t, time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC), bucketStart(days[3].Date, "month"))
+}
@@ -142,25 +142,68 @@ func DeleteIntegrationChannel(integrationID int32, channelID string) error {
 }
 
 func AddDiscordService(characterID, entityID int32, serverID string) error {
-	// verify this user is able to create a discord service for this entity
+	return addService(characterID, entityID, serverID, "", "discord")
+}
+
+// AddSlackService adds a slack workspace using a bot token
+func AddSlackService(characterID, entityID int32, teamID, token string) error {
+	return addService(characterID, entityID, teamID, token, "slack")
+}
+
+// AddMumbleService adds a murmur server with user:password authentication
+func AddMumbleService(characterID, entityID int32, address, authentication string) error {
+	return addService(characterID, entityID, address, authentication, "mumble")
+}
+
+// IsEntityDirector checks the character has a Director token for the entity
+func IsEntityDirector(characterID, entityID int32) (bool, error) {
 	entities, err := GetEntitiesWithRole(characterID, "Director")
+	if err != nil {
+		return false, err
+	}
+	return entityInSlice(entityID, entities), nil
+}
+
+func addService(characterID, entityID int32, address, authentication, serviceType string) error {
+	// verify this user is able to create a service for this entity
+	ok, err := IsEntityDirector(characterID, entityID)
 	if err != nil {
 		return err
 	}
 
-	if !entityInSlice(entityID, entities) {
-		return errors.New("character is unauthorized to create this discord entry")
+	if !ok {
+		return errors.New("character is unauthorized to create this " + serviceType + " entry")
 	}
 
-	if _, err := database.Exec(`
-		INSERT INTO evedata.integrations	(entityID, address, type, options)
-			VALUES(?,?,'discord','')
-			ON DUPLICATE KEY UPDATE entityID = entityID`,
-		entityID, serverID); err != nil {
+	tx, err := Begin()
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	// Replace the credentials of an existing service for this entity.
+	// Authentication is part of the unique key so the insert alone would add a second service.
+	res, err := tx.Exec(`
+		UPDATE evedata.integrations SET authentication = ?
+			WHERE address = ? AND type = ? AND entityID = ?`,
+		authentication, address, serviceType, entityID)
+	if err != nil {
+		return err
+	}
+
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		if _, err := tx.Exec(`
+			INSERT INTO evedata.integrations	(entityID, address, authentication, type, options)
+				VALUES(?,?,?,?,'')
+				ON DUPLICATE KEY UPDATE entityID = entityID`,
+			entityID, address, authentication, serviceType); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
 func DeleteService(characterID, integrationID int32) error {
@@ -226,30 +269,30 @@ type AvailableIntegrations struct {
 func GetAvailableIntegrations(characterID int32) ([]AvailableIntegrations, error) {
 	integrations := []AvailableIntegrations{}
 	if err := database.Select(&integrations, `
-		SELECT integrationID, address, reason, S.name, characterName, T.characterID, tokenCharacterID, 
-			integrationUserID, type, entityID, IFNULL(A.name, C.name) AS entityName, IF(A.name IS NULL, "corporation", "alliance") AS entityType
+		SELECT integrationID, address, reason, S.name, characterName, S.characterID, tokenCharacterID, 
+			IFNULL(integrationUserID, "") AS integrationUserID, integrationType AS type, entityID, IFNULL(A.name, C.name) AS entityName, IF(A.name IS NULL, "corporation", "alliance") AS entityType
 		FROM
 		(
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "member" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "member" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 			(C.corporationID = B.entityID 					   
 			OR C.allianceID = B.entityID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%member%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "militia" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "militia" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 			B.factionID > 0 AND B.factionID = C.factionID
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%militia%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "alliedMilitia" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "alliedMilitia" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 			B.factionID > 0 AND B.factionID = evedata.alliedMilita(C.factionID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%alliedMilitia%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+5" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+5" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.entityContacts E ON E.entityID = B.entityID AND E.standing = 5.0
 		INNER JOIN evedata.crestTokens C ON  C.authCharacter = 1 AND
@@ -258,7 +301,7 @@ func GetAvailableIntegrations(characterID int32) ([]AvailableIntegrations, error
 			OR E.contactID = C.allianceID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%plusFive%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+10" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+10" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.entityContacts E ON E.entityID = B.entityID AND E.standing = 10.0
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
@@ -267,10 +310,10 @@ func GetAvailableIntegrations(characterID int32) ([]AvailableIntegrations, error
 			OR E.contactID = C.allianceID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%plusTen%"
 		) S 
-		INNER JOIN evedata.integrationTokens T ON S.characterID = T.characterID
+		LEFT OUTER JOIN evedata.integrationTokens T ON S.characterID = T.characterID AND T.type = S.integrationType
 		LEFT OUTER JOIN evedata.corporations C ON C.corporationID = S.entityID
 		LEFT OUTER JOIN evedata.alliances A ON A.allianceID = S.entityID
-		WHERE T.characterID = ?
+		WHERE S.characterID = ? AND (T.characterID IS NOT NULL OR S.integrationType IN ("ts3", "slack", "mumble"))
 		GROUP BY address `, characterID); err != nil {
 		return nil, err
 	}
@@ -280,30 +323,31 @@ func GetAvailableIntegrations(characterID int32) ([]AvailableIntegrations, error
 func GetIntegrationsForCharacter(characterID, integrationID int32) (*AvailableIntegrations, error) {
 	integration := []AvailableIntegrations{}
 	if err := database.Select(&integration, `	
-		SELECT integrationID, address, accessToken, refreshToken, expiry, S.name, characterName, T.characterID, tokenCharacterID, 
-		integrationUserID, type, entityID, IFNULL(A.name, C.name) AS entityName, IF(A.name IS NULL, "corporation", "alliance") AS entityType
+		SELECT integrationID, address, IFNULL(accessToken, "") AS accessToken, IFNULL(refreshToken, "") AS refreshToken, 
+		IFNULL(expiry, UTC_TIMESTAMP()) AS expiry, S.name, characterName, S.characterID, tokenCharacterID, 
+		IFNULL(integrationUserID, "") AS integrationUserID, integrationType AS type, entityID, IFNULL(A.name, C.name) AS entityName, IF(A.name IS NULL, "corporation", "alliance") AS entityType
 		FROM
 		(
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "member" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "member" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 		(C.corporationID = B.entityID 					   
 		OR C.allianceID = B.entityID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%member%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "militia" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "militia" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 		B.factionID > 0 AND B.factionID = C.factionID
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%militia%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "alliedMilitia" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "alliedMilitia" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
 		B.factionID > 0 AND B.factionID = evedata.alliedMilita(C.factionID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%alliedMilitia%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+5" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+5" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.entityContacts E ON E.entityID = B.entityID AND E.standing = 5.0
 		INNER JOIN evedata.crestTokens C ON  C.authCharacter = 1 AND
@@ -312,7 +356,7 @@ func GetIntegrationsForCharacter(characterID, integrationID int32) (*AvailableIn
 		OR E.contactID = C.allianceID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%plusFive%"
 		UNION
-		SELECT integrationID, address, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+10" AS reason
+		SELECT integrationID, address, B.type AS integrationType, B.entityID, name, characterName, C.characterID, tokenCharacterID, "+10" AS reason
 		FROM evedata.integrations B
 		INNER JOIN evedata.entityContacts E ON E.entityID = B.entityID AND E.standing = 10.0
 		INNER JOIN evedata.crestTokens C ON C.authCharacter = 1 AND
@@ -321,12 +365,15 @@ func GetIntegrationsForCharacter(characterID, integrationID int32) (*AvailableIn
 		OR E.contactID = C.allianceID)
 		WHERE FIND_IN_SET(B.services, "auth") AND options LIKE "%plusTen%"
 		) S 
-		INNER JOIN evedata.integrationTokens T ON S.characterID = T.characterID
+		LEFT OUTER JOIN evedata.integrationTokens T ON S.characterID = T.characterID AND T.type = S.integrationType
 		LEFT OUTER JOIN evedata.corporations C ON C.corporationID = S.entityID
 		LEFT OUTER JOIN evedata.alliances A ON A.allianceID = S.entityID
-		WHERE T.characterID = ? AND integrationID = ?
+		WHERE S.characterID = ? AND integrationID = ? AND (T.characterID IS NOT NULL OR S.integrationType IN ("ts3", "slack", "mumble"))
 		GROUP BY address `, characterID, integrationID); err != nil {
 		return nil, err
 	}
+	if len(integration) == 0 {
+		return nil, errors.New("integration is not available to this character")
+	}
 	return &integration[0], nil
 }
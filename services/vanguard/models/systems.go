@@ -1,5 +1,7 @@
 package models
 
+import "github.com/antihax/evedata/internal/routeplanner"
+
 type SystemVertex struct {
 	FromSolarSystemID int    `db:"fromSolarSystemID"`
 	Connections       string `db:"connections"`
@@ -84,3 +86,54 @@ func GetNullSystems() ([]NullSystems, error) {
 	}
 	return s, nil
 }
+
+// GetRouteMap loads the systems and stargates of known space for route planning
+func GetRouteMap() (*routeplanner.Map, error) {
+	systems := []routeplanner.System{}
+	if err := database.Select(&systems, `
+		SELECT solarSystemID, solarSystemName, regionID, security, x, y, z
+		FROM eve.mapSolarSystems
+	`); err != nil {
+		return nil, err
+	}
+
+	jumps := []struct {
+		From int32 `db:"fromSolarSystemID"`
+		To   int32 `db:"toSolarSystemID"`
+	}{}
+	if err := database.Select(&jumps, `
+		SELECT fromSolarSystemID, toSolarSystemID
+		FROM eve.mapSolarSystemJumps
+	`); err != nil {
+		return nil, err
+	}
+
+	gates := make(map[int32][]int32)
+	for _, j := range jumps {
+		gates[j.From] = append(gates[j.From], j.To)
+	}
+
+	return routeplanner.NewMap(systems, gates), nil
+}
+
+// GetRecentSystemKills counts killmails in each system over the last hour
+func GetRecentSystemKills() (map[int32]int, error) {
+	s := []struct {
+		SolarSystemID int32 `db:"solarSystemID"`
+		Kills         int   `db:"kills"`
+	}{}
+	if err := database.Select(&s, `
+		SELECT solarSystemID, COUNT(*) AS kills
+		FROM evedata.killmails
+		WHERE killTime > DATE_SUB(UTC_TIMESTAMP(), INTERVAL 1 HOUR)
+		GROUP BY solarSystemID
+	`); err != nil {
+		return nil, err
+	}
+
+	kills := make(map[int32]int)
+	for _, k := range s {
+		kills[k.SolarSystemID] = k.Kills
+	}
+	return kills, nil
+}
@@ -1,6 +1,10 @@
 package models
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/antihax/evedata/internal/routeplanner"
+)
 
 func TestGetSystemVertices(t *testing.T) {
 	_, err := GetSystemVertices()
@@ -25,3 +29,25 @@ func TestGetNullSystems(t *testing.T) {
 		return
 	}
 }
+
+func TestGetRouteMap(t *testing.T) {
+	m, err := GetRouteMap()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	// Jita to Amarr
+	if _, err := m.Route(30000142, 30002187, routeplanner.Options{Mode: routeplanner.Safest}); err != nil {
+		t.Error(err)
+		return
+	}
+}
+
+func TestGetRecentSystemKills(t *testing.T) {
+	_, err := GetRecentSystemKills()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+}
@@ -65,6 +65,18 @@ CREATE TABLE `characterAssociations` (
   PRIMARY KEY (`characterID`,`associateID`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8;
 
+CREATE TABLE `characterAssociationScores` (
+  `characterID` int(10) unsigned NOT NULL,
+  `associateID` int(10) unsigned NOT NULL,
+  `coFleet` smallint(5) unsigned NOT NULL DEFAULT '0',
+  `corpHops` smallint(5) unsigned NOT NULL DEFAULT '0',
+  `locatorTargets` smallint(5) unsigned NOT NULL DEFAULT '0',
+  `activityOverlap` float NOT NULL DEFAULT '0',
+  `contactSync` tinyint(1) unsigned NOT NULL DEFAULT '0',
+  `updated` datetime DEFAULT NULL,
+  PRIMARY KEY (`characterID`,`associateID`)
+) ENGINE=TokuDB DEFAULT CHARSET=utf8;
+
 CREATE TABLE `characterKillmailAssociations` (
   `characterID` int(10) unsigned NOT NULL,
   `associateID` int(10) unsigned NOT NULL,
@@ -203,6 +215,7 @@ CREATE TABLE `crestTokens` (
   `allianceID` int(11) NOT NULL DEFAULT '0',
   `factionID` int(11) NOT NULL DEFAULT '0',
   `mailPassword` varchar(100) NOT NULL DEFAULT '',
+  `mailMaxCost` bigint(20) NOT NULL DEFAULT '0',
   PRIMARY KEY (`characterID`,`tokenCharacterID`),
   KEY `tokenCharacterID` (`tokenCharacterID`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8;
@@ -264,7 +277,7 @@ CREATE TABLE `httpErrors` (
 CREATE TABLE `integrationChannels` (
   `integrationID` int(10) unsigned NOT NULL,
   `channelID` varchar(255) COLLATE utf8_bin NOT NULL,
-  `services` set('locator','kill','structure','application','war') COLLATE utf8_bin NOT NULL,
+  `services` set('locator','kill','structure','application','war','fuel','anchoring','moon','sovereignty','poco') COLLATE utf8_bin NOT NULL,
   `options` text COLLATE utf8_bin NOT NULL,
   `channelName` varchar(255) COLLATE utf8_bin NOT NULL DEFAULT 'unknown',
   PRIMARY KEY (`channelID`,`integrationID`)
@@ -313,7 +326,7 @@ CREATE TABLE `integrations` (
   `entityID` int(11) NOT NULL DEFAULT '0',
   `address` varchar(255) COLLATE utf8_bin NOT NULL,
   `authentication` varchar(255) COLLATE utf8_bin NOT NULL DEFAULT '',
-  `type` enum('discord','ts3','slack') COLLATE utf8_bin NOT NULL,
+  `type` enum('discord','ts3','slack','mumble') COLLATE utf8_bin NOT NULL,
   `services` set('auth') COLLATE utf8_bin NOT NULL DEFAULT '',
   `options` text COLLATE utf8_bin NOT NULL,
   `characterID` int(11) NOT NULL DEFAULT '0',
@@ -442,6 +455,23 @@ CREATE TABLE `killmailStatistics` (
   PRIMARY KEY (`month`,`year`,`characterAge`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
+CREATE TABLE `killmailStatisticsVictims` (
+  `month` tinyint(3) unsigned NOT NULL,
+  `year` smallint(5) unsigned NOT NULL,
+  `stat` varchar(16) NOT NULL,
+  `characterID` int(10) unsigned NOT NULL,
+  `characterAge` smallint(6) NOT NULL DEFAULT '0',
+  PRIMARY KEY (`month`,`year`,`stat`,`characterID`),
+  KEY `stat` (`stat`,`year`,`month`,`characterAge`)
+) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+CREATE TABLE `killmailStatisticsProcessed` (
+  `id` int(10) unsigned NOT NULL,
+  `killTime` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `killTime` (`killTime`)
+) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
 CREATE TABLE `killmails` (
   `id` int(9) unsigned NOT NULL,
   `solarSystemID` int(8) unsigned NOT NULL,
@@ -478,7 +508,8 @@ CREATE TABLE `locatedCharacters` (
   `stationID` int(11) NOT NULL,
   `locatedCharacterID` int(11) NOT NULL,
   `time` datetime NOT NULL,
-  PRIMARY KEY (`notificationID`)
+  PRIMARY KEY (`notificationID`),
+  KEY `locatedCharacterID` (`locatedCharacterID`,`time`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8;
 
 CREATE TABLE `locatorShareWith` (
@@ -544,6 +575,34 @@ CREATE TABLE `marketHistoryStatistics` (
   PRIMARY KEY (`itemID`,`regionID`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
 
+CREATE TABLE `marketInferredTrades` (
+  `orderID` bigint(20) unsigned NOT NULL,
+  `kind` enum('partial','fill','cancel') NOT NULL,
+  `volumeRemain` int(11) unsigned NOT NULL,
+  `locationID` bigint(20) unsigned NOT NULL,
+  `typeID` int(10) unsigned NOT NULL,
+  `isBuyOrder` tinyint(4) unsigned NOT NULL,
+  `price` decimal(22,2) unsigned NOT NULL,
+  `quantity` int(11) unsigned NOT NULL,
+  `time` datetime NOT NULL,
+  PRIMARY KEY (`orderID`,`kind`,`volumeRemain`),
+  KEY `ix_time` (`time`),
+  KEY `ix_location_type_time` (`locationID`,`typeID`,`time`)
+) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin;
+
+CREATE TABLE `marketIntradayVolume` (
+  `locationID` bigint(20) unsigned NOT NULL,
+  `typeID` int(10) unsigned NOT NULL,
+  `hour` datetime NOT NULL,
+  `buyVolume` bigint(20) unsigned NOT NULL DEFAULT '0',
+  `sellVolume` bigint(20) unsigned NOT NULL DEFAULT '0',
+  `buyISK` decimal(30,2) unsigned NOT NULL DEFAULT '0.00',
+  `sellISK` decimal(30,2) unsigned NOT NULL DEFAULT '0.00',
+  `trades` int(10) unsigned NOT NULL DEFAULT '0',
+  PRIMARY KEY (`locationID`,`typeID`,`hour`),
+  KEY `ix_type_hour` (`typeID`,`hour`)
+) ENGINE=TokuDB DEFAULT CHARSET=utf8;
+
 CREATE TABLE `marketOrderHistory` (
   `orderID` bigint(20) unsigned NOT NULL,
   `locationID` bigint(20) unsigned NOT NULL,
@@ -649,7 +708,7 @@ CREATE TABLE `sharing` (
   `characterID` int(11) unsigned NOT NULL,
   `tokenCharacterID` int(11) unsigned NOT NULL,
   `entityID` int(11) unsigned NOT NULL,
-  `types` set('locator','kill','structure','application','war') COLLATE utf8_bin NOT NULL,
+  `types` set('locator','kill','structure','application','war','fuel','anchoring','moon','sovereignty','poco') COLLATE utf8_bin NOT NULL,
   `ignored` tinyint(4) NOT NULL DEFAULT '0',
   PRIMARY KEY (`characterID`,`tokenCharacterID`,`entityID`)
 ) ENGINE=TokuDB DEFAULT CHARSET=utf8 COLLATE=utf8_bin COMMENT='For sharing character information with entities.';
@@ -40,6 +40,7 @@ func init() {
 	vanguard.AddAuthRoute("POST", "/U/joinIntegration", apiJoinIntegration)
 
 	vanguard.AddAuthRoute("POST", "/U/setMailPassword", apiSetMailPassword)
+	vanguard.AddAuthRoute("POST", "/U/setMailMaxCost", apiSetMailMaxCost)
 
 }
 
@@ -272,6 +273,41 @@ func apiJoinIntegration(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// TeamSpeak clients join by redeeming a privilege key
+	if i.Type == "ts3" {
+		key := ""
+		if err := g.RPCall("Conservator.JoinTSUser", conservator.JoinUser{
+			IntegrationID: i.IntegrationID,
+			CharacterName: i.CharacterName,
+			CharacterID:   i.TokenCharacterID,
+		}, &key); err != nil {
+			httpErr(w, err)
+			return
+		}
+		renderJSON(w, struct {
+			Token string `json:"token"`
+		}{key}, 0)
+		return
+	}
+
+	// Slack and Mumble members give their own ID which the conservator verifies
+	if i.Type == "slack" || i.Type == "mumble" {
+		userID := r.FormValue("userID")
+		if userID == "" {
+			httpErrCode(w, errors.New("userID is required to join "+i.Type), http.StatusBadRequest)
+			return
+		}
+		if err := g.RPCall("Conservator.JoinUser", conservator.JoinUser{
+			IntegrationID: i.IntegrationID,
+			UserID:        userID,
+			CharacterName: i.CharacterName,
+			CharacterID:   i.TokenCharacterID,
+		}, &ok); err != nil || !ok {
+			httpErr(w, err)
+		}
+		return
+	}
+
 	token := &oauth2.Token{
 		Expiry:       i.Expiry,
 		AccessToken:  i.AccessToken,
@@ -390,3 +426,34 @@ func apiSetMailPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func apiSetMailMaxCost(w http.ResponseWriter, r *http.Request) {
+	s := vanguard.SessionFromContext(r.Context())
+	if s == nil {
+		httpErrCode(w, errors.New("could not find session"), http.StatusUnauthorized)
+		return
+	}
+
+	char, ok := s.Values["character"].(goesi.VerifyResponse)
+	if !ok {
+		httpErrCode(w, errors.New("could not find verify response to change mail cost"), http.StatusForbidden)
+		return
+	}
+
+	tokenCharacterID, err := strconv.ParseInt(r.FormValue("tokenCharacterID"), 10, 32)
+	if err != nil {
+		httpErrCode(w, errors.New("invalid tokenCharacterID"), http.StatusBadRequest)
+		return
+	}
+
+	maxCost, err := strconv.ParseInt(r.FormValue("maxCost"), 10, 64)
+	if err != nil || maxCost < 0 {
+		httpErrCode(w, errors.New("invalid maxCost"), http.StatusBadRequest)
+		return
+	}
+
+	if err := models.SetMailMaxCost(char.CharacterID, int32(tokenCharacterID), char.CharacterOwnerHash, maxCost); err != nil {
+		httpErr(w, err)
+		return
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 
 	"github.com/antihax/goesi"
@@ -308,10 +309,7 @@ func eveTokenAnswer(w http.ResponseWriter, r *http.Request) {
 	// Urgently get their corp roles if they give us permission to read.
 	if strings.Contains(v.Scopes, "read_corporation_roles") {
 		if err = c.OutQueue.QueueWork(
-			[]redisqueue.Work{
-				{Operation: "characterAuthOwner",
-					Parameter: []int32{char.CharacterID, v.CharacterID}},
-			},
+			[]redisqueue.Work{hammerwork.CharacterAuthOwnerWork(char.CharacterID, v.CharacterID)},
 			redisqueue.Priority_Urgent); err != nil {
 			httpErr(w, err)
 			return
@@ -28,6 +28,7 @@ func init() {
 	vanguard.AddRoute("GET", "/J/corporationHistory", corporationHistoryAPI)
 	vanguard.AddRoute("GET", "/J/corporationsForAlliance", corporationsForAllianceAPI)
 	vanguard.AddRoute("GET", "/J/knownAssociatesForEntity", knownAssociatesForEntityAPI)
+	vanguard.AddAuthRoute("GET", "/U/associationScores", associationScoresAPI)
 	vanguard.AddRoute("GET", "/J/allianceHistoryForEntity", allianceHistoryForEntityAPI)
 	vanguard.AddRoute("GET", "/J/corporationHistoryForEntity", corporationHistoryForEntityAPI)
 	vanguard.AddRoute("GET", "/J/allianceJoinHistoryForEntity", allianceJoinHistoryForEntityAPI)
@@ -207,6 +208,21 @@ func knownAssociatesForEntityAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Optional thresholds to filter weak associations
+	minScore, minFrequency := 0.0, 0
+	if r.FormValue("minScore") != "" {
+		if minScore, err = strconv.ParseFloat(r.FormValue("minScore"), 64); err != nil {
+			httpErr(w, err)
+			return
+		}
+	}
+	if r.FormValue("minFrequency") != "" {
+		if minFrequency, err = strconv.Atoi(r.FormValue("minFrequency")); err != nil {
+			httpErr(w, err)
+			return
+		}
+	}
+
 	var v []models.KnownAlts
 	if entityType == "alliance" {
 		v, err = models.GetAllianceKnownAssociates(id)
@@ -224,7 +240,57 @@ func knownAssociatesForEntityAPI(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	renderJSON(w, v, time.Hour*12)
+	renderJSON(w, models.FilterKnownAlts(v, minScore, minFrequency), time.Hour*12)
+}
+
+// associationScoresAPI scores a character's associates with every signal, including
+// locator agents and contact syncs, for recruiters vetting them.
+func associationScoresAPI(w http.ResponseWriter, r *http.Request) {
+	s := vanguard.SessionFromContext(r.Context())
+
+	// Get the sessions main characterID
+	characterID, ok := s.Values["characterID"].(int32)
+	if !ok {
+		httpErrCode(w, nil, http.StatusUnauthorized)
+		return
+	}
+
+	if recruiter, err := models.IsRecruiter(characterID); err != nil {
+		httpErr(w, err)
+		return
+	} else if !recruiter {
+		httpErrCode(w, errors.New("character is not a director or personnel manager"), http.StatusForbidden)
+		return
+	}
+
+	id, err := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	// Optional thresholds to filter weak associations
+	minScore, minFrequency := 0.0, 0
+	if r.FormValue("minScore") != "" {
+		if minScore, err = strconv.ParseFloat(r.FormValue("minScore"), 64); err != nil {
+			httpErr(w, err)
+			return
+		}
+	}
+	if r.FormValue("minFrequency") != "" {
+		if minFrequency, err = strconv.Atoi(r.FormValue("minFrequency")); err != nil {
+			httpErr(w, err)
+			return
+		}
+	}
+
+	v, err := models.GetCharacterAssociationScores(id)
+	if err != nil {
+		httpErrCode(w, err, http.StatusNotFound)
+		return
+	}
+
+	renderJSON(w, models.FilterKnownAlts(v, minScore, minFrequency), 0)
 }
 
 func allianceJoinHistoryForEntityAPI(w http.ResponseWriter, r *http.Request) {
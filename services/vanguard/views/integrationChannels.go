@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/antihax/evedata/internal/killfilter"
 	"github.com/antihax/evedata/services/conservator"
 
 	"github.com/antihax/evedata/services/vanguard"
@@ -17,6 +18,7 @@ func init() {
 	vanguard.AddAuthRoute("POST", "/U/integrationChannels", apiAddIntegrationChannel)
 	vanguard.AddAuthRoute("DELETE", "/U/integrationChannels", apiDeleteIntegrationChannel)
 
+	vanguard.AddAuthRoute("GET", "/U/integrationChannelOptions", apiGetIntegrationChannelOptions)
 	vanguard.AddAuthRoute("PUT", "/U/integrationChannelOptions", apiSetIntegrationChannelOptions)
 
 	vanguard.AddAuthRoute("GET", "/U/integrationRoles", apiGetIntegrationRoles)
@@ -97,23 +99,31 @@ func apiAddIntegrationChannel(w http.ResponseWriter, r *http.Request) {
 			httpErr(w, err)
 			return
 		}
-		if ok {
-			channels := [][]string{}
-			if err := g.RPCall("Conservator.GetChannels", service.IntegrationID, &channels); err != nil {
-				httpErr(w, err)
-				return
-			}
-			for _, ch := range channels {
-				if ch[0] == channelID {
-					channelName = ch[1]
-					break
-				}
-			}
+		if !ok {
+			httpErr(w, errors.New("serverID is invalid or the bot has no access"))
+			return
+		}
+	}
+
+	// Find the channel name, this also verifies the bot can see the channel
+	channels := [][]string{}
+	if err := g.RPCall("Conservator.GetChannels", service.IntegrationID, &channels); err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	ok = false
+	for _, ch := range channels {
+		if ch[0] == channelID {
+			channelName = ch[1]
+			ok = true
+			break
 		}
 	}
 
 	if !ok {
-		httpErr(w, errors.New("serverID is invalid or the bot has no access"))
+		httpErr(w, errors.New("channelID is invalid or the bot has no access"))
+		return
 	}
 
 	if err = models.AddIntegrationChannel(service.IntegrationID, channelID, channelName); err != nil {
@@ -160,6 +170,12 @@ func apiSetIntegrationChannelOptions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Verify the killmail rules parse
+	if err := chanOpts.CompileRules(); err != nil {
+		httpErrCode(w, err, http.StatusBadRequest)
+		return
+	}
+
 	//Unmarshal and format to our set string
 	chanServices := conservator.ChannelTypes{}
 	if err := json.Unmarshal([]byte(r.FormValue("services")), &chanServices); err != nil {
@@ -178,3 +194,20 @@ func apiSetIntegrationChannelOptions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// apiGetIntegrationChannelOptions describes the fields available to killmail rules
+func apiGetIntegrationChannelOptions(w http.ResponseWriter, r *http.Request) {
+	type schema struct {
+		MaxRules  int                `json:"maxRules"`
+		MaxLength int                `json:"maxLength"`
+		Operators []string           `json:"operators"`
+		Fields    []killfilter.Field `json:"fields"`
+	}
+
+	renderJSON(w, schema{
+		MaxRules:  conservator.MaxKillmailRules,
+		MaxLength: killfilter.MaxLength,
+		Operators: []string{"=", "!=", "<", "<=", ">", ">=", "in", "and", "or", "not"},
+		Fields:    killfilter.Fields(),
+	}, time.Hour)
+}
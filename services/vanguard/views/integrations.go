@@ -3,6 +3,7 @@ package views
 import (
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
@@ -25,6 +26,8 @@ func init() {
 	vanguard.AddAuthRoute("GET", "/U/integrations", apiGetIntegrations)
 	vanguard.AddAuthRoute("DELETE", "/U/integrations", apiDeleteIntegration)
 	vanguard.AddAuthRoute("POST", "/U/integrationsDiscord", apiAddDiscordIntegration)
+	vanguard.AddAuthRoute("POST", "/U/integrationsSlack", apiAddSlackIntegration)
+	vanguard.AddAuthRoute("POST", "/U/integrationsMumble", apiAddMumbleIntegration)
 	vanguard.AddAuthRoute("POST", "/U/integrationShareToggleIgnore", apiIntegrationToggleIgnore)
 
 	// Integration Details
@@ -106,6 +109,113 @@ func apiAddDiscordIntegration(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
+func apiAddSlackIntegration(w http.ResponseWriter, r *http.Request) {
+	s := vanguard.SessionFromContext(r.Context())
+	g := vanguard.GlobalsFromContext(r.Context())
+
+	// Get the sessions main characterID
+	characterID, ok := s.Values["characterID"].(int32)
+	if !ok {
+		httpErrCode(w, nil, http.StatusUnauthorized)
+		return
+	}
+
+	teamID := r.FormValue("teamID")
+	token := r.FormValue("token")
+	if teamID == "" || token == "" {
+		httpErrCode(w, errors.New("teamID and token are required"), http.StatusBadRequest)
+		return
+	}
+
+	entityID, err := strconv.ParseInt(r.FormValue("entityID"), 10, 64)
+	if err != nil {
+		httpErrCode(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Only directors may have the conservator connect out to a server
+	if director, err := models.IsEntityDirector(characterID, int32(entityID)); err != nil {
+		httpErr(w, err)
+		return
+	} else if !director {
+		httpErrCode(w, errors.New("character is not a director of this entity"), http.StatusForbidden)
+		return
+	}
+
+	// Verify the token belongs to the workspace
+	if err = g.RPCall("Conservator.VerifySlack", []string{teamID, token}, &ok); err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	if !ok {
+		httpErr(w, errors.New("teamID or token is invalid"))
+		return
+	}
+
+	if err = models.AddSlackService(characterID, int32(entityID), teamID, token); err != nil {
+		httpErr(w, err)
+		return
+	}
+}
+
+func apiAddMumbleIntegration(w http.ResponseWriter, r *http.Request) {
+	s := vanguard.SessionFromContext(r.Context())
+	g := vanguard.GlobalsFromContext(r.Context())
+
+	// Get the sessions main characterID
+	characterID, ok := s.Values["characterID"].(int32)
+	if !ok {
+		httpErrCode(w, nil, http.StatusUnauthorized)
+		return
+	}
+
+	// Validate the address is host:port
+	address := r.FormValue("address")
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		httpErrCode(w, err, http.StatusBadRequest)
+		return
+	}
+
+	username := r.FormValue("username")
+	if username == "" {
+		httpErrCode(w, errors.New("username is required"), http.StatusBadRequest)
+		return
+	}
+	authentication := username + ":" + r.FormValue("password")
+
+	entityID, err := strconv.ParseInt(r.FormValue("entityID"), 10, 64)
+	if err != nil {
+		httpErrCode(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Only directors may have the conservator connect out to a server
+	if director, err := models.IsEntityDirector(characterID, int32(entityID)); err != nil {
+		httpErr(w, err)
+		return
+	} else if !director {
+		httpErrCode(w, errors.New("character is not a director of this entity"), http.StatusForbidden)
+		return
+	}
+
+	// Verify the bot can log in and manage groups
+	if err = g.RPCall("Conservator.VerifyMumble", []string{address, authentication}, &ok); err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	if !ok {
+		httpErr(w, errors.New("server is unreachable or the bot has no access to the root channel ACL"))
+		return
+	}
+
+	if err = models.AddMumbleService(characterID, int32(entityID), address, authentication); err != nil {
+		httpErr(w, err)
+		return
+	}
+}
+
 func apiGetIntegrations(w http.ResponseWriter, r *http.Request) {
 	s := vanguard.SessionFromContext(r.Context())
 
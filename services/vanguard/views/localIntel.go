@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 	"github.com/antihax/evedata/services/vanguard"
 	"github.com/antihax/evedata/services/vanguard/models"
@@ -64,7 +65,7 @@ func localIntel(w http.ResponseWriter, r *http.Request) {
 	// Add any characters we do not know to the list
 	newNames := removeDuplicatesAndValidate(names)
 	for _, name := range newNames {
-		work = append(work, redisqueue.Work{Operation: "charSearch", Parameter: name})
+		work = append(work, hammerwork.CharSearchWork(name))
 	}
 	c.OutQueue.QueueWork(work, redisqueue.Priority_Urgent)
 
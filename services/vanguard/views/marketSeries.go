@@ -0,0 +1,196 @@
+package views
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/antihax/evedata/services/vanguard"
+	"github.com/antihax/evedata/services/vanguard/models"
+)
+
+func init() {
+	vanguard.AddRoute("GET", "/J/marketSeries", marketSeriesAPI)
+}
+
+// marketSeriesAPI returns resampled history for several types and regions.
+// typeIDs and regionIDs are comma separated, from and to are YYYY-MM-DD,
+// interval is day, week, or month, ma is comma separated windows in bars,
+// volatility is a window in bars, spread=1 adds the spread against The Forge,
+// and format=csv returns CSV instead of JSON.
+func marketSeriesAPI(w http.ResponseWriter, r *http.Request) {
+	o, err := parseMarketSeriesOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	v, err := models.GetMarketSeries(o)
+	if err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	if r.FormValue("format") == "csv" {
+		cache(w, time.Hour*12)
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=marketSeries.csv")
+		if err := writeMarketSeriesCSV(w, v, o); err != nil {
+			httpErr(w, err)
+		}
+		return
+	}
+
+	renderJSON(w, v, time.Hour*12)
+}
+
+func parseMarketSeriesOptions(r *http.Request) (models.MarketSeriesOptions, error) {
+	o := models.MarketSeriesOptions{
+		Interval: r.FormValue("interval"),
+		Spread:   r.FormValue("spread") == "1" || r.FormValue("spread") == "true",
+	}
+	var err error
+
+	if o.TypeIDs, err = parseIDList(r.FormValue("typeIDs")); err != nil {
+		return o, errors.New("invalid typeIDs")
+	}
+	if o.RegionIDs, err = parseIDList(r.FormValue("regionIDs")); err != nil {
+		return o, errors.New("invalid regionIDs")
+	}
+	if len(o.TypeIDs) == 0 || len(o.RegionIDs) == 0 {
+		return o, errors.New("typeIDs and regionIDs are required")
+	}
+	if len(o.TypeIDs)*len(o.RegionIDs) > models.MaxMarketSeries {
+		return o, fmt.Errorf("at most %d type and region combinations are allowed", models.MaxMarketSeries)
+	}
+
+	if o.Interval == "" {
+		o.Interval = "day"
+	}
+	if !models.ValidMarketInterval(o.Interval) {
+		return o, errors.New("interval must be day, week, or month")
+	}
+
+	o.To = time.Now().UTC()
+	if to := r.FormValue("to"); to != "" {
+		if o.To, err = time.Parse("2006-01-02", to); err != nil {
+			return o, errors.New("invalid to date")
+		}
+	}
+	o.From = o.To.AddDate(0, 0, -90)
+	if from := r.FormValue("from"); from != "" {
+		if o.From, err = time.Parse("2006-01-02", from); err != nil {
+			return o, errors.New("invalid from date")
+		}
+	}
+	if o.From.After(o.To) {
+		return o, errors.New("from must be before to")
+	}
+	if o.To.Sub(o.From) > time.Hour*24*models.MaxMarketSeriesDays {
+		return o, fmt.Errorf("at most %d days are allowed", models.MaxMarketSeriesDays)
+	}
+
+	if ma := r.FormValue("ma"); ma != "" {
+		for _, s := range strings.Split(ma, ",") {
+			window, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || window < 2 || window > 365 {
+				return o, errors.New("moving average windows must be between 2 and 365")
+			}
+			o.MovingAverages = append(o.MovingAverages, window)
+		}
+	}
+
+	if vol := r.FormValue("volatility"); vol != "" {
+		if o.Volatility, err = strconv.Atoi(vol); err != nil || o.Volatility < 2 || o.Volatility > 365 {
+			return o, errors.New("volatility window must be between 2 and 365")
+		}
+	}
+
+	return o, nil
+}
+
+// parseIDList parses a comma separated list of IDs
+func parseIDList(s string) ([]int32, error) {
+	ids := []int32{}
+	if s == "" {
+		return ids, nil
+	}
+	for _, v := range strings.Split(s, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+func writeMarketSeriesCSV(w http.ResponseWriter, series []models.MarketSeries, o models.MarketSeriesOptions) error {
+	windows := append([]int{}, o.MovingAverages...)
+	sort.Ints(windows)
+
+	header := []string{"typeID", "typeName", "regionID", "regionName", "date",
+		"open", "high", "low", "close", "average", "volume", "orders"}
+	for _, ma := range windows {
+		header = append(header, fmt.Sprintf("ma%d", ma))
+	}
+	if o.Volatility > 0 {
+		header = append(header, "volatility")
+	}
+	if o.Spread {
+		header = append(header, "spread")
+	}
+
+	c := csv.NewWriter(w)
+	if err := c.Write(header); err != nil {
+		return err
+	}
+
+	for _, s := range series {
+		for _, b := range s.Bars {
+			row := []string{
+				strconv.Itoa(int(s.TypeID)), s.TypeName,
+				strconv.Itoa(int(s.RegionID)), s.RegionName,
+				b.Date.Format("2006-01-02"),
+				formatCSVFloat(b.Open), formatCSVFloat(b.High), formatCSVFloat(b.Low),
+				formatCSVFloat(b.Close), formatCSVFloat(b.Average),
+				strconv.FormatInt(b.Volume, 10), strconv.FormatInt(b.Orders, 10),
+			}
+			for _, ma := range windows {
+				if v, ok := b.MovingAverages[ma]; ok {
+					row = append(row, formatCSVFloat(v))
+				} else {
+					row = append(row, "")
+				}
+			}
+			if o.Volatility > 0 {
+				row = append(row, formatCSVPointer(b.Volatility))
+			}
+			if o.Spread {
+				row = append(row, formatCSVPointer(b.Spread))
+			}
+			if err := c.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.Flush()
+	return c.Error()
+}
+
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatCSVPointer(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return formatCSVFloat(*v)
+}
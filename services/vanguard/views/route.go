@@ -0,0 +1,152 @@
+package views
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/antihax/evedata/internal/routeplanner"
+	"github.com/antihax/evedata/services/vanguard"
+	"github.com/antihax/evedata/services/vanguard/models"
+)
+
+// maxJumpRange is the furthest any ship can jump, in light years
+const maxJumpRange = 10
+
+var routeModes = map[string]routeplanner.Mode{
+	"":           routeplanner.Shortest,
+	"shortest":   routeplanner.Shortest,
+	"safest":     routeplanner.Safest,
+	"lesssecure": routeplanner.LessSecure,
+}
+
+// The map is loaded on first use; recent kills are refreshed every few minutes
+var routeState struct {
+	sync.Mutex
+	routeMap    *routeplanner.Map
+	kills       map[int32]int
+	killsLoaded time.Time
+}
+
+func init() {
+	vanguard.AddRoute("GET", "/J/route", routeAPI)
+}
+
+// routeAPI returns the systems along a route between from and to.
+// mode is shortest, safest, or lesssecure, avoidSystems and avoidRegions are
+// comma separated IDs, and killPenalty is the extra jumps each kill in the last
+// hour costs. jumpRange in light years routes by jump drive instead, with
+// fatigueBonus the fraction of distance ignored by fatigue and fatigue the
+// pilot's current fatigue in minutes.
+func routeAPI(w http.ResponseWriter, r *http.Request) {
+	from, to, o, err := parseRouteOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m, kills, err := getRouteState(o.KillPenalty > 0)
+	if err != nil {
+		httpErr(w, err)
+		return
+	}
+	o.Kills = kills
+
+	v, err := m.Route(from, to, o)
+	if err == routeplanner.ErrUnknownSystem || err == routeplanner.ErrNoRoute {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	} else if err != nil {
+		httpErr(w, err)
+		return
+	}
+
+	if o.KillPenalty > 0 {
+		renderJSON(w, v, time.Minute*5)
+	} else {
+		renderJSON(w, v, time.Hour*24)
+	}
+}
+
+// getRouteState returns the map, loading it if needed, and recent kills if wanted
+func getRouteState(wantKills bool) (*routeplanner.Map, map[int32]int, error) {
+	routeState.Lock()
+	defer routeState.Unlock()
+
+	if routeState.routeMap == nil {
+		m, err := models.GetRouteMap()
+		if err != nil {
+			return nil, nil, err
+		}
+		routeState.routeMap = m
+	}
+
+	if !wantKills {
+		return routeState.routeMap, nil, nil
+	}
+
+	if time.Since(routeState.killsLoaded) > time.Minute*5 {
+		kills, err := models.GetRecentSystemKills()
+		if err != nil {
+			return nil, nil, err
+		}
+		routeState.kills = kills
+		routeState.killsLoaded = time.Now()
+	}
+
+	return routeState.routeMap, routeState.kills, nil
+}
+
+func parseRouteOptions(r *http.Request) (int32, int32, routeplanner.Options, error) {
+	o := routeplanner.Options{}
+
+	from, err := strconv.ParseInt(r.FormValue("from"), 10, 32)
+	if err != nil {
+		return 0, 0, o, errors.New("invalid from")
+	}
+	to, err := strconv.ParseInt(r.FormValue("to"), 10, 32)
+	if err != nil {
+		return 0, 0, o, errors.New("invalid to")
+	}
+
+	mode, ok := routeModes[r.FormValue("mode")]
+	if !ok {
+		return 0, 0, o, errors.New("mode must be shortest, safest, or lesssecure")
+	}
+	o.Mode = mode
+
+	if o.AvoidSystems, err = parseIDList(r.FormValue("avoidSystems")); err != nil {
+		return 0, 0, o, errors.New("invalid avoidSystems")
+	}
+	if o.AvoidRegions, err = parseIDList(r.FormValue("avoidRegions")); err != nil {
+		return 0, 0, o, errors.New("invalid avoidRegions")
+	}
+
+	if v := r.FormValue("killPenalty"); v != "" {
+		if o.KillPenalty, err = strconv.ParseFloat(v, 64); err != nil || o.KillPenalty < 0 {
+			return 0, 0, o, errors.New("killPenalty must be zero or more")
+		}
+	}
+
+	if v := r.FormValue("jumpRange"); v != "" {
+		if o.JumpRange, err = strconv.ParseFloat(v, 64); err != nil || o.JumpRange <= 0 || o.JumpRange > maxJumpRange {
+			return 0, 0, o, errors.New("jumpRange must be between 0 and 10 light years")
+		}
+	}
+	if v := r.FormValue("fatigueBonus"); v != "" {
+		if o.FatigueBonus, err = strconv.ParseFloat(v, 64); err != nil || o.FatigueBonus < 0 || o.FatigueBonus >= 1 {
+			return 0, 0, o, errors.New("fatigueBonus must be at least 0 and below 1")
+		}
+	}
+	if v := r.FormValue("fatigue"); v != "" {
+		minutes, err := strconv.ParseFloat(v, 64)
+		if err != nil || minutes < 0 {
+			return 0, 0, o, errors.New("fatigue must be zero or more minutes")
+		}
+		o.Fatigue = time.Duration(minutes * float64(time.Minute))
+	}
+
+	return int32(from), int32(to), o, nil
+}
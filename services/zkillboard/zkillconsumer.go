@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/antihax/evedata/internal/hammerwork"
 	"github.com/antihax/evedata/internal/redisqueue"
 )
 
@@ -29,8 +30,7 @@ func (s *ZKillboard) redisQ() error {
 	if k.Package.KillID > 0 {
 		if !s.outQueue.CheckWorkCompleted("evedata_known_kills", int64(k.Package.KillID)) {
 			err = s.outQueue.QueueWork(
-				[]redisqueue.Work{
-					{Operation: "killmail", Parameter: []interface{}{k.Package.ZKB.Hash, k.Package.KillID}}},
+				[]redisqueue.Work{hammerwork.KillmailWork(k.Package.ZKB.Hash, k.Package.KillID)},
 				redisqueue.Priority_High,
 			)
 			if err != nil {
@@ -83,7 +83,7 @@ func (s *ZKillboard) apiConsumer() error {
 			}
 			if !s.outQueue.CheckWorkCompleted("evedata_known_kills", id) {
 				// Add to the killmail queue
-				kills = append(kills, redisqueue.Work{Operation: "killmail", Parameter: []interface{}{hash.(string), (int32)(id)}})
+				kills = append(kills, hammerwork.KillmailWork(hash.(string), (int32)(id)))
 				if err != nil {
 					log.Println(err)
 					continue